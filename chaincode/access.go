@@ -0,0 +1,57 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// BeforeTransaction is registered as the contract's before hook. It rejects
+// the call with an *utils.AccessDeniedError when the caller's roles are not
+// allowed to invoke the requested function.
+func BeforeTransaction(ctx contractapi.TransactionContextInterface) error {
+	fn, _ := ctx.GetStub().GetFunctionAndParameters()
+	return utils.AssertFunctionAccess(ctx, utils.FunctionName(fn))
+}
+
+func (s *SmartContract) SetAccessPolicy(ctx contractapi.TransactionContextInterface, args string) error {
+	entityPolicy := models.AccessPolicy{}
+	inputInterface, err := utils.Unmarshal(args, entityPolicy)
+	if err != nil {
+		return err
+	}
+	input := inputInterface.(*models.AccessPolicy)
+
+	if err := utils.ValidateAccessPolicy(input); err != nil {
+		return utils.Validation("invalid access policy: %v", err)
+	}
+
+	clientID, err := utils.GetIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := utils.GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy := models.AccessPolicy{
+		MspRoles:       input.MspRoles,
+		AttributeRoles: input.AttributeRoles,
+		Functions:      input.Functions,
+		UpdatedBy:      clientID,
+		UpdatedAt:      timestamp,
+		DocType:        models.Config,
+	}
+
+	return utils.PutConfig(ctx, utils.AccessPolicyConfig, policy)
+}
+
+func (s *SmartContract) GetAccessPolicy(ctx contractapi.TransactionContextInterface) (*models.AccessPolicy, error) {
+	return utils.GetAccessPolicy(ctx)
+}
+
+func (s *SmartContract) GetCallerAccess(ctx contractapi.TransactionContextInterface) (*models.CallerAccess, error) {
+	return utils.GetCallerAccess(ctx)
+}
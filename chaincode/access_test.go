@@ -0,0 +1,147 @@
+package chaincode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func TestNewChaincode(t *testing.T) {
+	sc := &SmartContract{}
+	sc.BeforeTransaction = BeforeTransaction
+	if _, err := contractapi.NewChaincode(sc); err != nil {
+		t.Fatalf("NewChaincode: %v", err)
+	}
+}
+
+// Every exported transaction must appear in the default access table,
+// otherwise it is unreachable.
+func TestDefaultFunctionRolesCoverContract(t *testing.T) {
+	contractType := reflect.TypeOf(&SmartContract{})
+	ignored := map[string]bool{}
+	for _, name := range []string{"GetAfterTransaction", "GetBeforeTransaction", "GetIgnoreContractInterface",
+		"GetInfo", "GetName", "GetTransactionContextHandler", "GetUnknownTransaction"} {
+		ignored[name] = true
+	}
+
+	for i := 0; i < contractType.NumMethod(); i++ {
+		name := contractType.Method(i).Name
+		if ignored[name] {
+			continue
+		}
+		if _, ok := utils.DefaultFunctionRoles[name]; !ok {
+			t.Errorf("transaction %s is missing from DefaultFunctionRoles", name)
+		}
+	}
+}
+
+func TestBeforeTransaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity *mocks.MockClientIdentity
+		function string
+		allowed  bool
+	}{
+		{"nectec creates staff", nectecUser, "CreateNectecStaff", true},
+		{"regulator cannot create staff", regulatorUser, "CreateNectecStaff", false},
+		{"farmer creates own profile", farmerUser, "CreateFarmerProfile", true},
+		{"farmer cannot create gap", farmerUser, "CreateGAP", false},
+		{"packer creates packing", packerUser, "CreatePacking", true},
+		{"namespaced function name", packerUser, "SmartContract:createPacking", true},
+		{"anyone with a role reads", exporterUser, "ReadGap", true},
+		{"no role", strangerUser, "ReadGap", false},
+		{"unknown function", nectecUser, "DropEverything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := mocks.NewMockContext(tt.identity)
+			ctx.Stub.BeginTx(tt.function)
+			err := BeforeTransaction(ctx)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected access, got %v", err)
+			}
+			if !tt.allowed && !isAccessDenied(err) {
+				t.Fatalf("expected access denied, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAdminAttributeBootstrapsNectec(t *testing.T) {
+	admin := mocks.NewMockClientIdentity("x509::CN=admin", "NectecMSP", map[string]string{"hf.Type": "admin"})
+	ctx := mocks.NewMockContext(admin)
+
+	caller, err := utils.GetCallerAccess(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(caller.Roles, []models.Role{models.RoleNectec}) {
+		t.Fatalf("roles = %v, want [nectec]", caller.Roles)
+	}
+	if caller.Id != "x509::CN=admin" {
+		t.Fatalf("id = %q", caller.Id)
+	}
+}
+
+func TestSetAccessPolicy(t *testing.T) {
+	s, ctx := newTestContract()
+
+	policy := models.AccessPolicy{
+		MspRoles: map[string][]models.Role{"RegulatorMSP": {models.RoleRegulator}},
+		Functions: map[string][]models.Role{
+			"ReadGap": {models.RoleNectec},
+		},
+	}
+
+	if err := submit(ctx.As(regulatorUser), "SetAccessPolicy", func() error {
+		return s.SetAccessPolicy(ctx.As(regulatorUser), toJSON(t, policy))
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator set policy: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "SetAccessPolicy", func() error {
+		return s.SetAccessPolicy(ctx, toJSON(t, policy))
+	})
+
+	stored, err := s.GetAccessPolicy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.UpdatedBy != nectecUser.ID || stored.DocType != models.Config {
+		t.Fatalf("unexpected stored policy %+v", stored)
+	}
+
+	// The stored policy replaces the attribute mapping, so only the MSP
+	// mapping remains.
+	regulatorByMsp := mocks.NewMockClientIdentity("x509::CN=reg2", "RegulatorMSP", nil)
+	if err := submit(ctx.As(regulatorByMsp), "ReadGap", func() error { return nil }); !isAccessDenied(err) {
+		t.Fatalf("ReadGap override: expected access denied, got %v", err)
+	}
+	if err := submit(ctx.As(regulatorByMsp), "ReadFarmerProfile", func() error { return nil }); err != nil {
+		t.Fatalf("ReadFarmerProfile: %v", err)
+	}
+
+	// Configuration lives under a composite key and stays out of range scans.
+	for _, key := range ctx.Stub.Keys() {
+		if !strings.HasPrefix(key, "\x00") {
+			t.Fatalf("unexpected plain key %q", key)
+		}
+	}
+}
+
+func TestSetAccessPolicyRejectsUnknownRole(t *testing.T) {
+	s, ctx := newTestContract()
+
+	err := submit(ctx, "SetAccessPolicy", func() error {
+		return s.SetAccessPolicy(ctx, `{"functions":{"ReadGap":["admin"]}}`)
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown role") {
+		t.Fatalf("expected unknown role error, got %v", err)
+	}
+}
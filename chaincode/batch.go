@@ -0,0 +1,37 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+type batchImportFunc func(ctx contractapi.TransactionContextInterface, args string, dryRun bool) (*models.BatchImportResult, error)
+
+// batchImports maps each batch import transaction to the import it runs.
+var batchImports = map[string]batchImportFunc{
+	farmerImport.Function:    farmerImport.Run,
+	gapImport.Function:       gapImport.Run,
+	gmpImport.Function:       gmpImport.Run,
+	packerImport.Function:    packerImport.Run,
+	exporterImport.Function:  exporterImport.Run,
+	plantTypeImport.Function: plantTypeImport.Run,
+	hscodeImport.Function:    hscodeImport.Run,
+	packagingImport.Function: packagingImport.Run,
+}
+
+// DryRunBatchImport reports the status of every row args would import with
+// the batch import transaction function, without writing anything. The
+// caller needs the roles of function itself.
+func (s *SmartContract) DryRunBatchImport(ctx contractapi.TransactionContextInterface, function string, args string) (*models.BatchImportResult, error) {
+	run, ok := batchImports[function]
+	if !ok {
+		return nil, utils.Validation("%s is not a batch import", function)
+	}
+
+	if err := utils.AssertFunctionAccess(ctx, function); err != nil {
+		return nil, err
+	}
+
+	return run(ctx, args, true)
+}
@@ -0,0 +1,119 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestBatchImport(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G1","certId":"GAP-1"},{"id":"G2","certId":"GAP-OLD","expireDate":"2023-01-01"}]`)
+	})
+
+	batch := `[
+		{"id":"G3","certId":"GAP-3","areaRai":4},
+		{"id":"G1","certId":"GAP-9"},
+		{"id":"G3","certId":"GAP-3"},
+		{"id":"G4"},
+		{"id":"G5","certId":"GAP-5","areaRai":-1},
+		{"id":"G6","certId":"GAP-6","issueDate":"soon"},
+		{"certId":"GAP-7"}
+	]`
+
+	var report *models.BatchImportResult
+	mustSubmit(t, ctx, "DryRunBatchImport", func() (err error) {
+		report, err = s.DryRunBatchImport(ctx, "CreateGapCsv", batch)
+		return err
+	})
+
+	want := []struct {
+		id     string
+		status models.BatchRowStatus
+		field  string
+	}{
+		{"G3", models.BatchRowOk, ""},
+		{"G1", models.BatchRowDuplicate, "id"},
+		{"G3", models.BatchRowDuplicate, "id"},
+		{"G4", models.BatchRowInvalidField, "certId"},
+		{"G5", models.BatchRowInvalidField, "areaRai"},
+		{"G6", models.BatchRowInvalidField, "issueDate"},
+		{"", models.BatchRowInvalidField, "id"},
+	}
+	if !report.DryRun || report.Committed || report.Total != len(want) || report.Ok != 1 || report.Failed != len(want)-1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for i, w := range want {
+		row := report.Rows[i]
+		if row.Row != i+1 || row.Id != w.id || row.Status != w.status || row.Field != w.field {
+			t.Errorf("row %d: got %+v, want %+v", i+1, row, w)
+		}
+	}
+	if _, err := s.ReadGap(ctx, "G3"); err == nil {
+		t.Fatal("dry run should not write")
+	}
+
+	if err := submit(ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, batch)
+	}); err == nil {
+		t.Fatal("expected batch with invalid rows to be rejected")
+	}
+	if _, err := s.ReadGap(ctx, "G3"); err == nil {
+		t.Fatal("rejected batch should not store any row")
+	}
+
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G3","certId":"GAP-3"},{"id":"G4","certId":"GAP-4"}]`)
+	})
+	event := lastStateChanges(t, ctx)
+	if event.Function != "CreateGapCsv" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	assertChanges(t, event.Changes,
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Unique, Id: "gap:certId:GAP-3"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Gap, Id: "G3"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Unique, Id: "gap:certId:GAP-4"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Gap, Id: "G4"},
+	)
+	if event.Batch == nil || event.Batch.DryRun || !event.Batch.Committed || event.Batch.Total != 2 || event.Batch.Ok != 2 {
+		t.Fatalf("unexpected batch summary %+v", event.Batch)
+	}
+	if len(ctx.Stub.Events) != 3 {
+		t.Fatalf("expected one event per dry run and committed import, got %d", len(ctx.Stub.Events))
+	}
+
+	exporterCtx := ctx.As(exporterUser)
+	mustSubmit(t, exporterCtx, "DryRunBatchImport", func() (err error) {
+		report, err = s.DryRunBatchImport(exporterCtx, "CreatePackagingCsv",
+			`[{"id":"B1","gap":"GAP-1"},{"id":"B2","gap":"GAP-X"},{"id":"B3","gap":"GAP-OLD"},{"id":"B4","gmp":"PH-X"}]`)
+		return err
+	})
+	for i, w := range []struct {
+		status models.BatchRowStatus
+		field  string
+	}{
+		{models.BatchRowOk, ""},
+		{models.BatchRowUnknownReference, "gap"},
+		{models.BatchRowInvalidField, "gap"},
+		{models.BatchRowUnknownReference, "gmp"},
+	} {
+		if row := report.Rows[i]; row.Status != w.status || row.Field != w.field {
+			t.Errorf("packaging row %d: got %+v, want %+v", i+1, row, w)
+		}
+	}
+
+	if err := submit(exporterCtx, "DryRunBatchImport", func() error {
+		_, err := s.DryRunBatchImport(exporterCtx, "CreateGapCsv", batch)
+		return err
+	}); !isAccessDenied(err) {
+		t.Fatalf("exporter dry run of gap import: expected access denied, got %v", err)
+	}
+	if err := submit(ctx, "DryRunBatchImport", func() error {
+		_, err := s.DryRunBatchImport(ctx, "DeleteGap", batch)
+		return err
+	}); err == nil {
+		t.Fatal("expected dry run of a non-import function to be rejected")
+	}
+}
@@ -0,0 +1,102 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// Chaincode serves the contracts of a contractapi.ContractChaincode and
+// returns every failed transaction as a catalogued utils.Error, so errors
+// outside the catalogue reach the gateway as INTERNAL.
+type Chaincode struct {
+	*contractapi.ContractChaincode
+}
+
+// NewChaincode loads the collections config, see LoadCollections, and serves
+// contracts.
+func NewChaincode(contracts ...contractapi.ContractInterface) (*Chaincode, error) {
+	if err := LoadCollections(); err != nil {
+		return nil, err
+	}
+	cc, err := contractapi.NewChaincode(contracts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Chaincode{ContractChaincode: cc}, nil
+}
+
+// Start starts the chaincode in the fabric shim.
+func (cc *Chaincode) Start() error {
+	return shim.Start(cc)
+}
+
+func (cc *Chaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return catalogueResponse(cc.ContractChaincode.Init(stub))
+}
+
+func (cc *Chaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	response := catalogueResponse(cc.ContractChaincode.Invoke(stub))
+	if fn, _ := stub.GetFunctionAndParameters(); fn == getMetadataFunction && response.Status == shim.OK {
+		return withInputSchemas(response)
+	}
+	return response
+}
+
+const getMetadataFunction = "org.hyperledger.fabric:GetMetadata"
+
+// inputSchemas are the models transaction functions take as JSON arguments.
+// Their validation rules are published as component schemas of the contract
+// metadata so clients can check arguments before submitting them.
+var inputSchemas = []interface{}{
+	models.AttachDocumentInput{},
+	models.FormETransitionInput{},
+	models.GapYieldConfig{},
+	models.PackingTransitionInput{},
+	models.PlantTypeModel{},
+	models.SoftDeleteConfig{},
+	models.TransactionExporter{},
+	models.TransactionFarmer{},
+	models.TransactionFormE{},
+	models.TransactionGap{},
+	models.TransactionGmp{},
+	models.TransactionHscode{},
+	models.TransactionNectecStaff{},
+	models.TransactionPacker{},
+	models.TransactionPackaging{},
+	models.TransactionPacking{},
+	models.TransactionRegulator{},
+}
+
+func withInputSchemas(response peer.Response) peer.Response {
+	var contractMetadata metadata.ContractChaincodeMetadata
+	if err := json.Unmarshal(response.Payload, &contractMetadata); err != nil {
+		return response
+	}
+	if contractMetadata.Components.Schemas == nil {
+		contractMetadata.Components.Schemas = map[string]metadata.ObjectMetadata{}
+	}
+	for _, input := range inputSchemas {
+		schema := utils.ValidationSchema(input)
+		contractMetadata.Components.Schemas[schema.ID] = schema
+	}
+
+	payload, err := json.Marshal(contractMetadata)
+	if err != nil {
+		return response
+	}
+	response.Payload = payload
+	return response
+}
+
+func catalogueResponse(response peer.Response) peer.Response {
+	if response.Status >= shim.ERRORTHRESHOLD {
+		response.Message = utils.ParseError(response.Message).Error()
+	}
+	return response
+}
@@ -0,0 +1,215 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// errorCode decodes err the way the gateway does.
+func errorCode(err error) models.ErrorCode {
+	if err == nil {
+		return ""
+	}
+	return utils.ParseError(err.Error()).Code
+}
+
+func TestErrorCatalogue(t *testing.T) {
+	s, ctx := newTestContract()
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1"}`)
+	})
+
+	farmerCtx := ctx.As(farmerUser)
+	tests := []struct {
+		name string
+		fn   string
+		call func() error
+		want models.ErrorCode
+	}{
+		{"missing gmp", "ReadGmp", func() error {
+			_, err := s.ReadGmp(ctx, "M9")
+			return err
+		}, models.ErrNotFound},
+		{"update missing gap", "UpdateGap", func() error {
+			return s.UpdateGap(ctx, `{"id":"G9"}`)
+		}, models.ErrNotFound},
+		{"duplicate gmp", "CreateGMP", func() error {
+			return s.CreateGMP(ctx, `{"id":"M1"}`)
+		}, models.ErrAlreadyExists},
+		{"bad farmer json", "CreateFarmerProfile", func() error {
+			_, err := s.CreateFarmerProfile(farmerCtx, `{"id":`)
+			return err
+		}, models.ErrValidation},
+		{"bad farmer update json", "UpdateFarmerProfile", func() error {
+			return s.UpdateFarmerProfile(farmerCtx, `[]`)
+		}, models.ErrValidation},
+		{"restore live gmp", "RestoreGmp", func() error {
+			return s.RestoreGmp(ctx, "M1")
+		}, models.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := errorCode(submit(ctx, tt.fn, tt.call)); code != tt.want {
+				t.Fatalf("code = %q, want %q", code, tt.want)
+			}
+		})
+	}
+
+	err := submit(ctx.As(strangerUser), "CreateGMP", func() error { return nil })
+	if !isAccessDenied(err) || errorCode(err) != models.ErrUnauthorized {
+		t.Fatalf("expected an UNAUTHORIZED access error, got %v", err)
+	}
+}
+
+func TestCatalogueResponse(t *testing.T) {
+	ok := catalogueResponse(shim.Success([]byte("done")))
+	if ok.Status != shim.OK || ok.Message != "" {
+		t.Fatalf("success changed: %+v", ok)
+	}
+
+	internal := utils.ParseError(catalogueResponse(shim.Error("Function Nope not found in contract SmartContract")).Message)
+	if internal.Code != models.ErrInternal || internal.Message != "Function Nope not found in contract SmartContract" {
+		t.Fatalf("unexpected error %+v", internal)
+	}
+
+	var response peer.Response = shim.Error(utils.NotFound("the asset %s does not exist", "M9").Error())
+	notFound := utils.ParseError(catalogueResponse(response).Message)
+	if notFound.Code != models.ErrNotFound || notFound.Message != "the asset M9 does not exist" {
+		t.Fatalf("unexpected error %+v", notFound)
+	}
+
+	wrapped := fmt.Errorf("failed to update the copies held by %s: %w", "P1", utils.NotFound("the asset %s does not exist", "M9"))
+	want := "failed to update the copies held by P1: the asset M9 does not exist"
+	if e := utils.CatalogueError(wrapped); e.Code != models.ErrNotFound || e.Message != want {
+		t.Fatalf("unexpected wrapped error %+v", e)
+	}
+	if e := utils.ParseError(catalogueResponse(shim.Error(wrapped.Error())).Message); e.Code != models.ErrNotFound || e.Message != want {
+		t.Fatalf("unexpected wrapped response %+v", e)
+	}
+}
+
+// fieldErrors decodes the field level details of a validation error.
+func fieldErrors(t *testing.T, err error) []models.FieldError {
+	t.Helper()
+	catalogued := utils.ParseError(err.Error())
+	if catalogued.Code != models.ErrValidation {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	detailsJSON, _ := json.Marshal(catalogued.Details)
+	var fields []models.FieldError
+	if err := json.Unmarshal(detailsJSON, &fields); err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+func TestInputValidation(t *testing.T) {
+	s, ctx := newTestContract()
+	packerCtx := ctx.As(packerUser)
+
+	tests := []struct {
+		name  string
+		ctx   *mocks.MockContext
+		fn    string
+		call  func() error
+		field string
+		rule  string
+	}{
+		{"missing id", ctx, "CreateGAP", func() error {
+			return s.CreateGAP(ctx, `{"certId":"GAP-1"}`)
+		}, "id", "required"},
+		{"negative area", ctx, "CreateGAP", func() error {
+			return s.CreateGAP(ctx, `{"id":"G1","areaRai":-2}`)
+		}, "areaRai", "min"},
+		{"malformed date", ctx, "CreateGMP", func() error {
+			return s.CreateGMP(ctx, `{"id":"M1","issueDate":"31/12/2024"}`)
+		}, "issueDate", "date"},
+		{"unknown field", ctx, "CreateGMP", func() error {
+			return s.CreateGMP(ctx, `{"id":"M1","packingHouse":"Alpha"}`)
+		}, "packingHouse", "unknown"},
+		{"final above actual weight", packerCtx, "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, `{"id":"P1","actualWeight":10,"finalWeight":12}`)
+		}, "finalWeight", "lte"},
+		{"array element", ctx, "UpdateMultipleGmp", func() error {
+			return s.UpdateMultipleGmp(ctx, `[{"id":"M1"},{"id":" "}]`)
+		}, "[1].id", "required"},
+		{"negative skip", ctx, "GetAllGAP", func() error {
+			_, err := s.GetAllGAP(ctx, `{"skip":-1}`)
+			return err
+		}, "skip", "min"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := fieldErrors(t, submit(tt.ctx, tt.fn, tt.call))
+			if len(fields) != 1 || fields[0].Field != tt.field || fields[0].Rule != tt.rule {
+				t.Fatalf("unexpected field errors %+v", fields)
+			}
+		})
+	}
+
+	var report *models.BatchImportResult
+	mustSubmit(t, ctx, "DryRunBatchImport", func() (err error) {
+		report, err = s.DryRunBatchImport(ctx, "CreateGapCsv", `[{"id":"G1","certId":"GAP-1"},{"id":"G2","certId":"GAP-2","areaRai":-1}]`)
+		return err
+	})
+	if report.Ok != 1 || report.Rows[1].Status != models.BatchRowInvalidField || report.Rows[1].Field != "areaRai" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+// creatorlessStub lets the contract api read the caller of a transaction
+// that has none.
+type creatorlessStub struct {
+	*mocks.MockStub
+}
+
+func (s creatorlessStub) GetCreator() ([]byte, error) {
+	return nil, errors.New("no creator")
+}
+
+func TestMetadataPublishesInputSchemas(t *testing.T) {
+	cc, err := NewChaincode(&SmartContract{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := mocks.NewMockStub()
+	stub.Function = getMetadataFunction
+	response := cc.Invoke(creatorlessStub{stub})
+	if response.Status != shim.OK {
+		t.Fatalf("GetMetadata failed: %s", response.Message)
+	}
+
+	var contractMetadata metadata.ContractChaincodeMetadata
+	if err := json.Unmarshal(response.Payload, &contractMetadata); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := contractMetadata.Contracts["SmartContract"]; !ok {
+		t.Fatalf("contracts missing from metadata: %s", response.Payload)
+	}
+	gap, ok := contractMetadata.Components.Schemas["TransactionGap"]
+	if !ok || gap.AdditionalProperties || len(gap.Required) != 1 || gap.Required[0] != "id" {
+		t.Fatalf("unexpected gap schema %+v", gap)
+	}
+	if area := gap.Properties["areaRai"]; area.Minimum == nil || *area.Minimum != 0 {
+		t.Fatalf("unexpected areaRai schema %+v", area)
+	}
+	if gap.Properties["expireDate"].Pattern == "" {
+		t.Fatal("expireDate has no date pattern")
+	}
+	packing := contractMetadata.Components.Schemas["TransactionPacking"]
+	if packing.Properties["finalWeight"].Extensions["x-lte"] != "actualWeight" {
+		t.Fatalf("unexpected finalWeight schema %+v", packing.Properties["finalWeight"])
+	}
+}
@@ -0,0 +1,33 @@
+package chaincode
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// CollectionsConfigEnv names the environment variable holding the path of the
+// private data collections config the chaincode is deployed with, the file
+// given to the peer with --collections-config. It tells the chaincode which
+// organisations may read private details.
+const CollectionsConfigEnv = "COLLECTIONS_CONFIG"
+
+// defaultCollectionsConfig is used when CollectionsConfigEnv is not set.
+//
+//go:embed collections_config.json
+var defaultCollectionsConfig []byte
+
+// LoadCollections reads the collections config named by CollectionsConfigEnv,
+// or the one built into the chaincode.
+func LoadCollections() error {
+	config := defaultCollectionsConfig
+	if path := os.Getenv(CollectionsConfigEnv); path != "" {
+		var err error
+		if config, err = os.ReadFile(path); err != nil {
+			return fmt.Errorf("failed to read collections config %s: %w", path, err)
+		}
+	}
+	return utils.LoadCollections(config)
+}
@@ -0,0 +1,222 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func TestCollectionsConfig(t *testing.T) {
+	for collection, want := range map[string][]string{
+		utils.FarmerCollection:   {"NectecMSP", "RegulatorMSP", "FarmerMSP"},
+		utils.ExporterCollection: {"NectecMSP", "RegulatorMSP", "ExporterMSP"},
+	} {
+		assertIDs(t, utils.CollectionMembers[collection], want...)
+	}
+}
+
+func TestLoadCollectionsFromDeployment(t *testing.T) {
+	// Runs after t.Setenv restores the environment.
+	t.Cleanup(func() { LoadCollections() })
+
+	path := filepath.Join(t.TempDir(), "collections_config.json")
+	os.WriteFile(path, []byte(`[
+		{"name":"farmerPrivateDetails","policy":"OR('Org1MSP.member')"},
+		{"name":"exporterPrivateDetails","policy":"OR('Org1MSP.member', 'Org2MSP.member')"}
+	]`), 0o600)
+	t.Setenv(CollectionsConfigEnv, path)
+	if err := LoadCollections(); err != nil {
+		t.Fatal(err)
+	}
+	assertIDs(t, utils.CollectionMembers[utils.FarmerCollection], "Org1MSP")
+	assertIDs(t, utils.CollectionMembers[utils.ExporterCollection], "Org1MSP", "Org2MSP")
+
+	for _, config := range []string{`{"name":`, `[{"name":"farmerPrivateDetails","policy":"OR('Org1MSP.member')"}]`} {
+		os.WriteFile(path, []byte(config), 0o600)
+		if err := LoadCollections(); err == nil {
+			t.Errorf("expected collections config %s to be rejected", config)
+		}
+	}
+	t.Setenv(CollectionsConfigEnv, filepath.Join(t.TempDir(), "missing.json"))
+	if err := LoadCollections(); err == nil {
+		t.Error("expected a missing collections config to be rejected")
+	}
+}
+
+func TestPlantTypeContactIsPrivate(t *testing.T) {
+	s, ctx := newTestContract()
+	packerCtx := ctx.As(packerUser)
+
+	ctx.Stub.Transient = map[string][]byte{utils.TransientSalt: []byte("s3cret")}
+	mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[
+			{"id":"T1","plantType":"PT-1","province":"Chanthaburi","district":"Tha Mai","address":"1 Moo 2","postCode":"22120","email":"t1@example.com"},
+			{"id":"T2","plantType":"PT-2","province":"Chanthaburi","district":"Khlung"}
+		]`)
+	})
+	ctx.Stub.Transient = nil
+
+	public, _ := ctx.Stub.GetState("T1")
+	for _, field := range []string{"Tha Mai", "1 Moo 2", "22120", "t1@example.com"} {
+		if strings.Contains(string(public), field) {
+			t.Fatalf("public record leaks %q: %s", field, public)
+		}
+	}
+
+	var stored models.PlantTypeModel
+	if err := json.Unmarshal(public, &stored); err != nil {
+		t.Fatal(err)
+	}
+	details, _ := json.Marshal(models.ContactDetails{Address: "1 Moo 2", District: "Tha Mai", PostCode: "22120", Email: "t1@example.com"})
+	salt := sha256.Sum256([]byte("s3cret:T1"))
+	hash := sha256.Sum256(append([]byte(hex.EncodeToString(salt[:])), details...))
+	if stored.PrivateHash != hex.EncodeToString(hash[:]) || stored.Province != "Chanthaburi" {
+		t.Fatalf("unexpected public record %+v", stored)
+	}
+
+	member, err := s.ReadPlanType(ctx, "T1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Email != "t1@example.com" || member.District != "Tha Mai" || member.PostCode != "22120" || member.Address != "1 Moo 2" {
+		t.Fatalf("member did not get the contact details %+v", member)
+	}
+	outsider, err := s.ReadPlanType(packerCtx, "T1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outsider.Email != "" || outsider.District != "" || outsider.PrivateHash != stored.PrivateHash {
+		t.Fatalf("non-member got the contact details %+v", outsider)
+	}
+
+	mustSubmit(t, ctx, "UpdatePlantType", func() error {
+		return s.UpdatePlantType(ctx, `{"id":"T1","exporterId":"E1"}`)
+	})
+	if member, _ := s.ReadPlanType(ctx, "T1"); member.Email != "t1@example.com" || member.ExporterId != "E1" {
+		t.Fatalf("update lost the contact details %+v", member)
+	}
+
+	res, err := s.QueryPlanTypeWithPagination(ctx, `{"district":"Khlung"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 1 || res.Data[0].Id != "T2" {
+		t.Fatalf("unexpected district filter result %+v", res.Data)
+	}
+	if _, err := s.QueryPlanTypeWithPagination(packerCtx, `{"district":"Khlung"}`); err == nil {
+		t.Fatal("expected non-member district filter to be rejected")
+	}
+}
+
+func TestFarmerProfileIsPrivate(t *testing.T) {
+	s, ctx := newTestContract()
+	farmerCtx := ctx.As(farmerUser)
+	exporterCtx := ctx.As(exporterUser)
+
+	ctx.Stub.Transient = map[string][]byte{utils.TransientPrivateDetails: []byte(`{"F1":{"profileImg":"https://img.example.com/f1.png"}}`)}
+	if err := submit(farmerCtx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(farmerCtx, `{"id":"F1","certId":"C1"}`)
+		return err
+	}); errorCode(err) != models.ErrValidation {
+		t.Fatalf("expected private details without a salt to be rejected, got %v", err)
+	}
+	ctx.Stub.Transient[utils.TransientSalt] = []byte("s3cret")
+	mustSubmit(t, farmerCtx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(farmerCtx, `{"id":"F1","certId":"C1"}`)
+		return err
+	})
+	ctx.Stub.Transient = nil
+
+	if public, _ := ctx.Stub.GetState("F1"); strings.Contains(string(public), "f1.png") {
+		t.Fatalf("public record leaks the profile image: %s", public)
+	}
+	if farmer, _ := s.ReadFarmerProfile(farmerCtx, "F1"); farmer.ProfileImg != "https://img.example.com/f1.png" || farmer.PrivateHash == "" {
+		t.Fatalf("member did not get the profile image %+v", farmer)
+	}
+	if farmer, _ := s.ReadFarmerProfile(exporterCtx, "F1"); farmer.ProfileImg != "" {
+		t.Fatalf("non-member got the profile image %+v", farmer)
+	}
+
+	ctx.Stub.Transient = map[string][]byte{utils.TransientSalt: []byte("s3cret")}
+	mustSubmit(t, farmerCtx, "UpdateFarmerProfile", func() error {
+		return s.UpdateFarmerProfile(farmerCtx, `{"id":"F1","certId":"C1","profileImg":"https://img.example.com/f1-new.png"}`)
+	})
+	ctx.Stub.Transient = nil
+	if farmer, _ := s.ReadFarmerProfile(ctx, "F1"); farmer.ProfileImg != "https://img.example.com/f1-new.png" {
+		t.Fatalf("update not applied %+v", farmer)
+	}
+
+	mustSubmit(t, ctx, "DeleteFarmerProfile", func() error {
+		return s.DeleteFarmerProfile(ctx, "F1", "duplicate record")
+	})
+	mustSubmit(t, ctx, "RestoreFarmerProfile", func() error {
+		return s.RestoreFarmerProfile(ctx, "F1")
+	})
+	if farmer, _ := s.ReadFarmerProfile(ctx, "F1"); farmer.ProfileImg != "https://img.example.com/f1-new.png" {
+		t.Fatalf("restore lost the private details %+v", farmer)
+	}
+}
+
+func TestExporterContactIsPrivate(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	packerCtx := ctx.As(packerUser)
+
+	ctx.Stub.Transient = map[string][]byte{utils.TransientSalt: []byte("s3cret")}
+	mustSubmit(t, ctx, "CreateExporterCsv", func() error {
+		return s.CreateExporterCsv(ctx, `[
+			{"id":"E1","certId":"EC-1","plantTypeDetail":{"name":"Exporter One","district":"Tha Mai","email":"e1@example.com"}},
+			{"id":"E2","certId":"EC-2","plantTypeDetail":{"name":"Exporter Two","district":"Khlung","email":"e2@example.com"}}
+		]`)
+	})
+
+	if public, _ := ctx.Stub.GetState("E1"); strings.Contains(string(public), "e1@example.com") {
+		t.Fatalf("public record leaks the email: %s", public)
+	}
+
+	member, err := s.GetExporterByExporterId(exporterCtx, "E1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.PlantTypeDetail.Email != "e1@example.com" || member.PlantTypeDetail.Name != "Exporter One" {
+		t.Fatalf("member did not get the contact details %+v", member.PlantTypeDetail)
+	}
+	outsider, err := s.GetExporterByExporterId(packerCtx, "E1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outsider.PlantTypeDetail.Email != "" || outsider.PlantTypeDetail.PrivateHash == "" {
+		t.Fatalf("non-member got the contact details %+v", outsider.PlantTypeDetail)
+	}
+	if exporter, _ := s.ReadExporter(ctx, "E2"); exporter.PlantTypeDetail.District != "Khlung" {
+		t.Fatalf("member did not get the contact details %+v", exporter.PlantTypeDetail)
+	}
+
+	res, err := s.GetAllExporter(ctx, `{"district":"Tha"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 1 || res.Obj[0].Id != "E1" {
+		t.Fatalf("unexpected district filter result %+v", res.Obj)
+	}
+
+	mustSubmit(t, ctx, "DeleteExporterFromRegulator", func() error {
+		return s.DeleteExporterFromRegulator(ctx, "E1", "duplicate record", false)
+	})
+	if event := ctx.Stub.LastEvent(); strings.Contains(string(event.Payload), "e1@example.com") {
+		t.Fatalf("delete event leaks the email: %s", event.Payload)
+	}
+	mustSubmit(t, ctx, "RestoreExporter", func() error {
+		return s.RestoreExporter(ctx, "E1")
+	})
+	if exporter, _ := s.ReadExporter(ctx, "E1"); exporter.PlantTypeDetail.Email != "e1@example.com" {
+		t.Fatalf("restore lost the contact details %+v", exporter)
+	}
+}
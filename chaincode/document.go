@@ -0,0 +1,142 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+var documentRepository = utils.Repository[models.TransactionDocument]{
+	DocType: models.Document,
+	Id:      func(asset *models.TransactionDocument) string { return asset.Id },
+}
+
+// AttachDocument anchors an off-chain file to an asset by its SHA-256 digest.
+// The asset owner and staff may attach documents, and a file can only be
+// attached to the same asset once.
+func (s *SmartContract) AttachDocument(ctx contractapi.TransactionContextInterface, args string) (*models.TransactionDocument, error) {
+	var input models.AttachDocumentInput
+	if err := utils.DecodeInput(args, &input); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateAttachDocumentInput(&input); err != nil {
+		return nil, err
+	}
+
+	owner, err := utils.ReadAssetOwner(ctx, input.AssetId, input.AssetDocType)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.AssertStaffOrOwner(ctx, owner); err != nil {
+		return nil, err
+	}
+
+	key := utils.DocumentKey(input.AssetId, input.Sha256)
+	exists, err := documentRepository.Exists(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("error checking if asset exists: %w", err)
+	}
+	if exists {
+		return nil, utils.AlreadyExists("the document %s is already attached to %s", input.Sha256, input.AssetId)
+	}
+
+	clientID, err := utils.GetIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitting client's identity: %w", err)
+	}
+	orgName, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	timestamp, err := utils.GenerateTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &models.TransactionDocument{
+		Id:           key,
+		AssetId:      input.AssetId,
+		AssetDocType: input.AssetDocType,
+		Kind:         input.Kind,
+		Sha256:       input.Sha256,
+		MediaType:    input.MediaType,
+		Size:         input.Size,
+		StorageUri:   input.StorageUri,
+		UploadedBy:   clientID,
+		DocType:      models.Document,
+		Owner:        owner,
+		OrgName:      orgName,
+		CreatedAt:    timestamp,
+		UpdatedAt:    timestamp,
+	}
+
+	// The document belongs to the owner of the asset, not to the caller, so
+	// it is stored as it is rather than created
+	if err := documentRepository.Put(ctx, document); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// VerifyDocument checks a presented file hash against the documents attached
+// to assetId.
+func (s *SmartContract) VerifyDocument(ctx contractapi.TransactionContextInterface, assetId string, sha256 string) (*models.DocumentVerification, error) {
+	digest, err := utils.NormalizeSha256(sha256)
+	if err != nil {
+		return nil, err
+	}
+
+	documentJSON, err := ctx.GetStub().GetState(utils.DocumentKey(assetId, digest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if documentJSON == nil {
+		return &models.DocumentVerification{Verified: false}, nil
+	}
+
+	var document models.TransactionDocument
+	if err := json.Unmarshal(documentJSON, &document); err != nil {
+		return nil, err
+	}
+
+	return &models.DocumentVerification{Verified: true, Document: &document}, nil
+}
+
+func (s *SmartContract) QueryDocumentWithPagination(ctx contractapi.TransactionContextInterface, filterParams string) (*models.TransactionDocumentResponse, error) {
+	var filters models.DocumentFilterParams
+	err := utils.DecodeInput(filterParams, &filters)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := utils.NewSelector(models.Document)
+
+	if filters.AssetId != "" {
+		selector.Eq("assetId", filters.AssetId)
+	}
+
+	if filters.AssetDocType != "" {
+		selector.Eq("assetDocType", filters.AssetDocType)
+	}
+
+	if filters.Kind != "" {
+		selector.Eq("kind", filters.Kind)
+	}
+
+	page, err := utils.Paginate[models.TransactionDocument](ctx, selector.Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAt", "index-CreatedAt"), filters.Pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransactionDocumentResponse{
+		Data:     page.Items,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
+	}, nil
+}
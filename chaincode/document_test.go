@@ -0,0 +1,129 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestAttachDocument(t *testing.T) {
+	s, ctx := newTestContract()
+	farmerCtx := ctx.As(farmerUser)
+	regulatorCtx := ctx.As(regulatorUser)
+
+	mustSubmit(t, farmerCtx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(farmerCtx, `{"id":"F1"}`)
+		return err
+	})
+	mustSubmit(t, ctx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(ctx, `{"id":"F2"}`)
+		return err
+	})
+
+	photo := sha256.Sum256([]byte("profile photo"))
+	photoHash := hex.EncodeToString(photo[:])
+	cert := sha256.Sum256([]byte("gap certificate"))
+	certHash := hex.EncodeToString(cert[:])
+
+	attach := func(ctx *mocks.MockContext, args models.AttachDocumentInput) error {
+		return submit(ctx, "AttachDocument", func() error {
+			_, err := s.AttachDocument(ctx, toJSON(t, args))
+			return err
+		})
+	}
+	valid := models.AttachDocumentInput{
+		AssetId: "F1", AssetDocType: models.Farmer, Kind: "profileImg",
+		Sha256: strings.ToUpper(photoHash), MediaType: "image/png", Size: 1024,
+		StorageUri: "s3://nectec-documents/farmers/F1/profile.png",
+	}
+
+	if err := attach(farmerCtx, valid); err != nil {
+		t.Fatalf("owner attach: %v", err)
+	}
+	gapCert := valid
+	gapCert.Kind, gapCert.Sha256, gapCert.MediaType = "gapCertificate", certHash, "application/pdf"
+	if err := attach(regulatorCtx, gapCert); err != nil {
+		t.Fatalf("staff attach: %v", err)
+	}
+
+	if err := attach(ctx.As(otherFarmer), valid); !isAccessDenied(err) {
+		t.Fatalf("other farmer attach: expected access denied, got %v", err)
+	}
+
+	invalid := map[string]func(in *models.AttachDocumentInput){
+		"duplicate":       func(in *models.AttachDocumentInput) {},
+		"unknown asset":   func(in *models.AttachDocumentInput) { in.AssetId = "F9" },
+		"wrong doc type":  func(in *models.AttachDocumentInput) { in.AssetDocType = models.Gap },
+		"short hash":      func(in *models.AttachDocumentInput) { in.Sha256 = "abc123" },
+		"bad media type":  func(in *models.AttachDocumentInput) { in.MediaType = "png" },
+		"empty file":      func(in *models.AttachDocumentInput) { in.Size = 0 },
+		"relative uri":    func(in *models.AttachDocumentInput) { in.StorageUri = "profile.png" },
+		"missing assetId": func(in *models.AttachDocumentInput) { in.AssetId = "" },
+		"no media type":   func(in *models.AttachDocumentInput) { in.MediaType = "" },
+	}
+	for name, mutate := range invalid {
+		in := valid
+		mutate(&in)
+		err := attach(farmerCtx, in)
+		if err == nil || isAccessDenied(err) {
+			t.Errorf("%s: expected attach to fail, got %v", name, err)
+			continue
+		}
+		if in.AssetId == "" || in.MediaType == "" {
+			if fields := fieldErrors(t, err); len(fields) != 1 || fields[0].Rule != "required" {
+				t.Errorf("%s: unexpected field errors %+v", name, fields)
+			}
+		}
+	}
+
+	verification, err := s.VerifyDocument(ctx, "F1", photoHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Verified || verification.Document.Sha256 != photoHash || verification.Document.UploadedBy != farmerUser.ID || verification.Document.Owner != farmerUser.ID {
+		t.Fatalf("unexpected verification %+v", verification.Document)
+	}
+	for _, tc := range []struct{ assetId, hash string }{
+		{"F1", hex.EncodeToString(make([]byte, 32))},
+		{"F2", photoHash},
+	} {
+		verification, err := s.VerifyDocument(ctx, tc.assetId, tc.hash)
+		if err != nil || verification.Verified || verification.Document != nil {
+			t.Fatalf("expected %s on %s not to verify, got %+v, %v", tc.hash, tc.assetId, verification, err)
+		}
+	}
+	if _, err := s.VerifyDocument(ctx, "F1", "not a hash"); err == nil {
+		t.Fatal("expected malformed hash to be rejected")
+	}
+
+	tests := []struct {
+		name   string
+		filter models.DocumentFilterParams
+		kinds  []string
+	}{
+		{"all of an asset, newest first", models.DocumentFilterParams{AssetId: "F1"}, []string{"gapCertificate", "profileImg"}},
+		{"by kind", models.DocumentFilterParams{AssetId: "F1", Kind: "profileImg"}, []string{"profileImg"}},
+		{"by asset doc type", models.DocumentFilterParams{AssetDocType: models.Gap}, nil},
+		{"other asset", models.DocumentFilterParams{AssetId: "F2"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.QueryDocumentWithPagination(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kinds []string
+			for _, d := range res.Data {
+				kinds = append(kinds, d.Kind)
+			}
+			assertIDs(t, kinds, tt.kinds...)
+			if res.Total != len(tt.kinds) {
+				t.Errorf("total = %d, want %d", res.Total, len(tt.kinds))
+			}
+		})
+	}
+}
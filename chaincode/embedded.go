@@ -0,0 +1,122 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// farmerGapsCopy keeps farmerGaps, the GAP certificates of a farmer, in step
+// with the GAPs whose farmerId is the farmer. isCanDelete and totalSold are
+// worked out when the farmer is read and are not copied.
+var farmerGapsCopy = utils.Embedded[models.TransactionFarmer, models.TransactionGap]{
+	Parents:  farmerRepository,
+	Source:   models.Gap,
+	Link:     "farmerId",
+	SourceId: func(gap *models.TransactionGap) string { return gap.Id },
+	ParentId: func(gap *models.TransactionGap) string { return gap.FarmerID },
+	Set: func(farmer *models.TransactionFarmer, gaps []*models.TransactionGap) {
+		farmer.FarmerGaps = make([]models.FarmerGap, 0, len(gaps))
+		for _, gap := range gaps {
+			farmer.FarmerGaps = append(farmer.FarmerGaps, models.FarmerGap{
+				Id:            gap.Id,
+				CertID:        gap.CertID,
+				DisplayCertID: gap.DisplayCertID,
+				AreaCode:      gap.AreaCode,
+				AreaRai:       gap.AreaRai,
+				AreaStatus:    gap.AreaStatus,
+				OldAreaCode:   gap.OldAreaCode,
+				IssueDate:     gap.IssueDate,
+				ExpireDate:    gap.ExpireDate,
+				District:      gap.District,
+				Province:      gap.Province,
+				Source:        gap.Source,
+				FarmerID:      gap.FarmerID,
+				Owner:         gap.Owner,
+				OrgName:       gap.OrgName,
+				UpdatedAt:     gap.UpdatedAt,
+				CreatedAt:     gap.CreatedAt,
+			})
+		}
+	},
+}
+
+// packerGmpCopy keeps packerGmp in step with the GMP whose packerId is the
+// packer, the last one by id when there are several. The packer takes its
+// packing house name and register number from that GMP.
+var packerGmpCopy = utils.Embedded[models.TransactionPacker, models.TransactionGmp]{
+	Parents:  packerRepository,
+	Source:   models.Gmp,
+	Link:     "packerId",
+	SourceId: func(gmp *models.TransactionGmp) string { return gmp.Id },
+	ParentId: func(gmp *models.TransactionGmp) string { return gmp.PackerId },
+	Set: func(packer *models.TransactionPacker, gmps []*models.TransactionGmp) {
+		packer.PackerGmp = nil
+		if len(gmps) == 0 {
+			return
+		}
+		gmp := gmps[len(gmps)-1]
+		packer.PackerGmp = &models.PackerGmp{
+			Id:                         gmp.Id,
+			PackerId:                   gmp.PackerId,
+			PackingHouseRegisterNumber: gmp.PackingHouseRegisterNumber,
+			Address:                    gmp.Address,
+			PackingHouseName:           gmp.PackingHouseName,
+			UpdatedDate:                gmp.UpdatedDate,
+			Source:                     gmp.Source,
+			Owner:                      gmp.Owner,
+			OrgName:                    gmp.OrgName,
+			UpdatedAt:                  gmp.UpdatedAt,
+			CreatedAt:                  gmp.CreatedAt,
+		}
+		packer.PackingHouseName = gmp.PackingHouseName
+		packer.PackingHouseRegisterNumber = gmp.PackingHouseRegisterNumber
+	},
+}
+
+// exporterPlantTypeCopy keeps plantTypeDetail in step with the plant type
+// whose exporterId is the exporter, the last one by id when there are
+// several. Only the public fields are copied: the contact details stay in the
+// exporter collection under the exporter's id. A detail entered for an
+// exporter without a plant type has no id and is left alone.
+var exporterPlantTypeCopy = utils.Embedded[models.TransactionExporter, models.PlantTypeModel]{
+	Parents:  exporterRepository,
+	Source:   models.PlantType,
+	Link:     "exporterId",
+	SourceId: func(plantType *models.PlantTypeModel) string { return plantType.Id },
+	ParentId: func(plantType *models.PlantTypeModel) string { return plantType.ExporterId },
+	Set: func(exporter *models.TransactionExporter, plantTypes []*models.PlantTypeModel) {
+		privateHash := exporter.PlantTypeDetail.PrivateHash
+		if len(plantTypes) == 0 {
+			if exporter.PlantTypeDetail.Id != "" {
+				exporter.PlantTypeDetail = models.PlantTypeModel{PrivateHash: privateHash}
+			}
+			return
+		}
+		detail := *plantTypes[len(plantTypes)-1]
+		utils.ClearContact(&detail)
+		detail.PrivateHash = privateHash
+		detail.IsCanDelete = false
+		detail.Tombstone = models.Tombstone{}
+		exporter.PlantTypeDetail = detail
+	},
+}
+
+// embeddedCopies lists every copy one record keeps of others.
+var embeddedCopies = []utils.DriftReporter{farmerGapsCopy, packerGmpCopy, exporterPlantTypeCopy}
+
+// CheckEmbeddedCopies reports the farmers, packers and exporters whose
+// embedded copies differ from the GAPs, GMPs and plant types they copy, and
+// the records linked to one that does not exist. Copies written before they
+// were kept in step show up here.
+func (s *SmartContract) CheckEmbeddedCopies(ctx contractapi.TransactionContextInterface) ([]models.EmbeddedDrift, error) {
+	drift := []models.EmbeddedDrift{}
+	for _, copies := range embeddedCopies {
+		found, err := copies.Drift(ctx)
+		if err != nil {
+			return nil, err
+		}
+		drift = append(drift, found...)
+	}
+	return drift, nil
+}
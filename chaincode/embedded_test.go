@@ -0,0 +1,196 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// stored reads the record under id as it is in the world state, without
+// what the read functions add to it.
+func stored[T any](t *testing.T, ctx *mocks.MockContext, id string) *T {
+	t.Helper()
+	raw, err := ctx.Stub.GetState(id)
+	if err != nil || raw == nil {
+		t.Fatalf("no record %s: %v", id, err)
+	}
+	var asset T
+	if err := json.Unmarshal(raw, &asset); err != nil {
+		t.Fatal(err)
+	}
+	return &asset
+}
+
+func farmerGapIds(t *testing.T, ctx *mocks.MockContext, id string) []string {
+	t.Helper()
+	ids := []string{}
+	for _, gap := range stored[models.TransactionFarmer](t, ctx, id).FarmerGaps {
+		ids = append(ids, gap.Id+"="+gap.CertID)
+	}
+	return ids
+}
+
+func TestEmbeddedFarmerGaps(t *testing.T) {
+	s, ctx := newTestContract()
+
+	for _, id := range []string{"F1", "F2"} {
+		mustSubmit(t, ctx, "CreateFarmerProfile", func() error {
+			_, err := s.CreateFarmerProfile(ctx, `{"id":"`+id+`"}`)
+			return err
+		})
+	}
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G1","certId":"GAP-1","farmerId":"F1"},{"id":"G2","certId":"GAP-2","farmerId":"F1"}]`)
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F1"), "G1=GAP-1", "G2=GAP-2")
+
+	mustSubmit(t, ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G1","certId":"GAP-1A","farmerId":"F1"}`)
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F1"), "G1=GAP-1A", "G2=GAP-2")
+
+	// Both updates of one transaction reach the copies.
+	mustSubmit(t, ctx, "UpdateMultipleGap", func() error {
+		return s.UpdateMultipleGap(ctx, `[{"id":"G1","certId":"GAP-1B","farmerId":"F1"},{"id":"G2","certId":"GAP-2","farmerId":"F2"}]`)
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F1"), "G1=GAP-1B")
+	assertIDs(t, farmerGapIds(t, ctx, "F2"), "G2=GAP-2")
+
+	mustSubmit(t, ctx, "DeleteGap", func() error {
+		return s.DeleteGap(ctx, "G1", "entered twice")
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F1"))
+	mustSubmit(t, ctx, "RestoreGap", func() error {
+		return s.RestoreGap(ctx, "G1")
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F1"), "G1=GAP-1B")
+
+	// Farmer writes make the copy from the GAPs, not from what they are sent.
+	mustSubmit(t, ctx, "UpdateFarmerProfile", func() error {
+		return s.UpdateFarmerProfile(ctx, `{"id":"F1","farmerGaps":[{"id":"G9","certId":"GAP-9"}]}`)
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F1"), "G1=GAP-1B")
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G3","certId":"GAP-3","farmerId":"F3"}]`)
+	})
+	mustSubmit(t, ctx, "CreateFarmerFromCsv", func() error {
+		return s.CreateFarmerFromCsv(ctx, `[{"id":"F3","farmerGaps":[{"id":"G9","certId":"GAP-9"}]}]`)
+	})
+	assertIDs(t, farmerGapIds(t, ctx, "F3"), "G3=GAP-3")
+}
+
+func TestEmbeddedPackerGmp(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreatePacker", func() error {
+		_, err := s.CreatePacker(ctx, `{"id":"PK1","packingHouseName":"Alpha"}`)
+		return err
+	})
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Alpha House","packerId":"PK1"}`)
+	})
+	packer := stored[models.TransactionPacker](t, ctx, "PK1")
+	if packer.PackerGmp == nil || packer.PackerGmp.Id != "M1" || packer.PackingHouseRegisterNumber != "PH-1" {
+		t.Fatalf("unexpected packer %+v", packer)
+	}
+
+	mustSubmit(t, ctx, "UpdateMultipleGmp", func() error {
+		return s.UpdateMultipleGmp(ctx, `[{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Beta House","packerId":"PK1"}]`)
+	})
+	packer = stored[models.TransactionPacker](t, ctx, "PK1")
+	if packer.PackingHouseName != "Beta House" || packer.PackerGmp.PackingHouseName != "Beta House" {
+		t.Fatalf("packer copy not updated %+v", packer)
+	}
+
+	mustSubmit(t, ctx, "ClearGmpPacker", func() error {
+		return s.ClearGmpPacker(ctx, "PK1")
+	})
+	if packer := stored[models.TransactionPacker](t, ctx, "PK1"); packer.PackerGmp != nil {
+		t.Fatalf("released gmp still copied %+v", packer.PackerGmp)
+	}
+}
+
+func TestEmbeddedExporterPlantType(t *testing.T) {
+	s, ctx := newTestContract()
+
+	ctx.Stub.Transient = map[string][]byte{utils.TransientSalt: []byte("s3cret")}
+	mustSubmit(t, ctx, "CreateExporterCsv", func() error {
+		return s.CreateExporterCsv(ctx, `[
+			{"id":"E1","certId":"EC-1","plantTypeDetail":{"name":"Exporter One","email":"e1@example.com"}},
+			{"id":"E2","certId":"EC-2","plantTypeDetail":{"name":"Exporter Two"}}
+		]`)
+	})
+	privateHash := stored[models.TransactionExporter](t, ctx, "E1").PlantTypeDetail.PrivateHash
+
+	mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[{"id":"T1","plantType":"PT-1","name":"Durian Plant","email":"t1@example.com","exporterId":"E1"}]`)
+	})
+	detail := stored[models.TransactionExporter](t, ctx, "E1").PlantTypeDetail
+	if detail.Id != "T1" || detail.Name != "Durian Plant" || detail.Email != "" || detail.PrivateHash != privateHash {
+		t.Fatalf("unexpected copy %+v", detail)
+	}
+
+	mustSubmit(t, ctx, "UpdateMultiplePlantType", func() error {
+		return s.UpdateMultiplePlantType(ctx, `[{"id":"T1","plantType":"PT-1","name":"Mango Plant"}]`)
+	})
+	if detail := stored[models.TransactionExporter](t, ctx, "E1").PlantTypeDetail; detail.Name != "Mango Plant" {
+		t.Fatalf("copy not updated %+v", detail)
+	}
+
+	mustSubmit(t, ctx, "DeletePlantType", func() error {
+		return s.DeletePlantType(ctx, "T1", "entered twice")
+	})
+	if detail := stored[models.TransactionExporter](t, ctx, "E1").PlantTypeDetail; detail.Id != "" || detail.Name != "" {
+		t.Fatalf("deleted plant type still copied %+v", detail)
+	}
+	if detail := stored[models.TransactionExporter](t, ctx, "E2").PlantTypeDetail; detail.Name != "Exporter Two" {
+		t.Fatalf("unrelated exporter changed %+v", detail)
+	}
+}
+
+func TestCheckEmbeddedCopies(t *testing.T) {
+	s, ctx := newTestContract()
+
+	// Records written before the copies were kept in step.
+	ctx.Stub.PutState("F1", []byte(`{"id":"F1","docType":"farmer","farmerGaps":[{"id":"G1","certId":"OLD"}]}`))
+	ctx.Stub.PutState("G1", []byte(`{"id":"G1","docType":"gap","certId":"GAP-1","farmerId":"F1"}`))
+	ctx.Stub.PutState("G2", []byte(`{"id":"G2","docType":"gap","certId":"GAP-2","farmerId":"F9"}`))
+	ctx.Stub.PutState("PK1", []byte(`{"id":"PK1","docType":"packer"}`))
+
+	if err := submit(ctx.As(farmerUser), "CheckEmbeddedCopies", func() error {
+		_, err := s.CheckEmbeddedCopies(ctx.As(farmerUser))
+		return err
+	}); !isAccessDenied(err) {
+		t.Fatalf("farmer: expected access denied, got %v", err)
+	}
+
+	var drift []models.EmbeddedDrift
+	mustSubmit(t, ctx.As(regulatorUser), "CheckEmbeddedCopies", func() (err error) {
+		drift, err = s.CheckEmbeddedCopies(ctx.As(regulatorUser))
+		return err
+	})
+	if len(drift) != 2 {
+		t.Fatalf("unexpected drift %+v", drift)
+	}
+	if d := drift[0]; d.DocType != models.Farmer || d.Id != "F1" || len(d.Fields) != 1 || d.Fields[0] != "farmerGaps" {
+		t.Fatalf("unexpected farmer drift %+v", d)
+	}
+	if d := drift[1]; d.DocType != models.Gap || d.Id != "G2" || d.Fields[0] != "farmerId" || d.Message == "" {
+		t.Fatalf("unexpected gap drift %+v", d)
+	}
+
+	// The next write to the GAP brings the copy back in step.
+	mustSubmit(t, ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G1","certId":"GAP-1","farmerId":"F1"}`)
+	})
+	mustSubmit(t, ctx, "CheckEmbeddedCopies", func() (err error) {
+		drift, err = s.CheckEmbeddedCopies(ctx)
+		return err
+	})
+	if len(drift) != 1 || drift[0].Id != "G2" {
+		t.Fatalf("unexpected drift after update %+v", drift)
+	}
+}
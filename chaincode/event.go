@@ -0,0 +1,24 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// TransactionContext is the contract's transaction context. It records the
+// public state written by the transaction so AfterTransaction can report it,
+// and caches what the transaction reads.
+type TransactionContext struct {
+	contractapi.TransactionContext
+}
+
+func (ctx *TransactionContext) SetStub(stub shim.ChaincodeStubInterface) {
+	ctx.TransactionContext.SetStub(utils.NewRecordingStub(utils.NewCachingStub(stub)))
+}
+
+// AfterTransaction is registered as the contract's after hook. It emits one
+// utils.StateChangeEvent listing every record the transaction wrote.
+func AfterTransaction(ctx contractapi.TransactionContextInterface) error {
+	return utils.EmitStateChanges(ctx)
+}
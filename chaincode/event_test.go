@@ -0,0 +1,153 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// lastStateChanges decodes the event of the last committed transaction.
+func lastStateChanges(t *testing.T, ctx *mocks.MockContext) models.StateChangeEvent {
+	t.Helper()
+	ev := ctx.Stub.LastEvent()
+	var event models.StateChangeEvent
+	if ev == nil || ev.EventName != utils.StateChangeEvent || json.Unmarshal(ev.Payload, &event) != nil {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	return event
+}
+
+func assertChanges(t *testing.T, got []models.StateChange, want ...models.StateChange) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i].Type != want[i].Type || got[i].DocType != want[i].DocType || got[i].Id != want[i].Id {
+			t.Fatalf("changes = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func stateDigest(t *testing.T, ctx *mocks.MockContext, key string) string {
+	t.Helper()
+	value, _ := ctx.Stub.GetState(key)
+	if value == nil {
+		t.Fatalf("%s not found", key)
+	}
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestStateChangeEvent(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Alpha"}`)
+	})
+	event := lastStateChanges(t, ctx)
+	if event.Version != models.EventVersion || event.TxId != ctx.Stub.TxID || event.Function != "CreateGMP" ||
+		event.Actor != nectecUser.ID || event.MspId != "NectecMSP" || event.Timestamp != ctx.Stub.TxTimestamp.Format(time.RFC3339) {
+		t.Fatalf("unexpected envelope %+v", event)
+	}
+	assertChanges(t, event.Changes,
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Unique, Id: "gmp:packingHouseRegisterNumber:PH-1"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Gmp, Id: "M1"},
+	)
+	created := stateDigest(t, ctx, "M1")
+	if event.Changes[1].BeforeDigest != "" || event.Changes[1].AfterDigest != created {
+		t.Fatalf("unexpected digests %+v", event.Changes[1])
+	}
+
+	mustSubmit(t, ctx, "UpdateGmp", func() error {
+		return s.UpdateGmp(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Alpha 2"}`)
+	})
+	event = lastStateChanges(t, ctx)
+	assertChanges(t, event.Changes, models.StateChange{Type: models.ChangeUpdate, DocType: models.Gmp, Id: "M1"})
+	if event.Changes[0].BeforeDigest != created || event.Changes[0].AfterDigest != stateDigest(t, ctx, "M1") {
+		t.Fatalf("unexpected digests %+v", event.Changes[0])
+	}
+
+	mustSubmit(t, ctx, "DeleteGmp", func() error {
+		return s.DeleteGmp(ctx, "M1", "duplicate record")
+	})
+	assertChanges(t, lastStateChanges(t, ctx).Changes, models.StateChange{Type: models.ChangeDelete, DocType: models.Gmp, Id: "M1"})
+
+	mustSubmit(t, ctx, "RestoreGmp", func() error {
+		return s.RestoreGmp(ctx, "M1")
+	})
+	assertChanges(t, lastStateChanges(t, ctx).Changes, models.StateChange{Type: models.ChangeRestore, DocType: models.Gmp, Id: "M1"})
+
+	packerCtx := ctx.As(packerUser)
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1"}`)
+	})
+	mustSubmit(t, packerCtx, "TransferPacking", func() error {
+		return s.TransferPacking(packerCtx, "P1", otherPacker.ID)
+	})
+	event = lastStateChanges(t, ctx)
+	if event.Actor != packerUser.ID || event.MspId != "PackerMSP" {
+		t.Fatalf("unexpected actor %+v", event)
+	}
+	assertChanges(t, event.Changes, models.StateChange{Type: models.ChangeTransfer, DocType: models.Packing, Id: "P1"})
+
+	mustSubmit(t, ctx, "SetSoftDeleteConfig", func() error {
+		return s.SetSoftDeleteConfig(ctx, `{"restoreWindowDays":7}`)
+	})
+	assertChanges(t, lastStateChanges(t, ctx).Changes, models.StateChange{Type: models.ChangeCreate, DocType: models.Config, Id: utils.SoftDeleteConfig})
+
+	events := len(ctx.Stub.Events)
+	if err := submit(ctx, "UpdateGmp", func() error {
+		return s.UpdateGmp(ctx, `{"id":"M9","packingHouseName":"Nobody"}`)
+	}); err == nil {
+		t.Fatal("expected update of a missing gmp to fail")
+	}
+	if _, err := s.ReadGmp(ctx, "M1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.Stub.Events) != events {
+		t.Fatalf("failed or read-only transactions emitted %+v", ctx.Stub.Events[events:])
+	}
+}
+
+func TestStateChangeEventBatch(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[
+			{"id":"M1","packingHouseRegisterNumber":"PH-1"},
+			{"id":"M2","packingHouseRegisterNumber":"PH-2"},
+			{"id":"M3","packingHouseRegisterNumber":"PH-3"}
+		]`)
+	})
+	if len(ctx.Stub.Events) != 1 {
+		t.Fatalf("expected one event for the batch, got %d", len(ctx.Stub.Events))
+	}
+	assertChanges(t, lastStateChanges(t, ctx).Changes,
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Unique, Id: "gmp:packingHouseRegisterNumber:PH-1"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Gmp, Id: "M1"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Unique, Id: "gmp:packingHouseRegisterNumber:PH-2"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Gmp, Id: "M2"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Unique, Id: "gmp:packingHouseRegisterNumber:PH-3"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Gmp, Id: "M3"},
+	)
+
+	mustSubmit(t, ctx, "DryRunBatchImport", func() error {
+		_, err := s.DryRunBatchImport(ctx, "CreateGmpCsv", `[{"id":"M4","packingHouseRegisterNumber":"PH-4"}]`)
+		return err
+	})
+	if len(ctx.Stub.Events) != 2 {
+		t.Fatal("dry run should emit its summary")
+	}
+	event := lastStateChanges(t, ctx)
+	if len(event.Changes) != 0 || event.Batch == nil || !event.Batch.DryRun || event.Batch.Committed ||
+		event.Batch.Function != "CreateGmpCsv" || event.Batch.Total != 1 || event.Batch.Ok != 1 {
+		t.Fatalf("unexpected dry run event %+v", event)
+	}
+}
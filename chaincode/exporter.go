@@ -3,8 +3,6 @@ package chaincode
 import (
 	"encoding/json"
 	"fmt"
-	"sort"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
@@ -15,169 +13,86 @@ type SmartContract struct {
 	contractapi.Contract
 }
 
+var exporterRepository = utils.Repository[models.TransactionExporter]{
+	DocType: models.Exporter,
+	Id:      func(asset *models.TransactionExporter) string { return asset.Id },
+}
+
 func (s *SmartContract) CreateExporter(
 	ctx contractapi.TransactionContextInterface,
 	args string,
-) error {
+) (string, error) {
 	entityExporter := models.TransactionExporter{}
 	inputInterface, err := utils.Unmarshal(args, entityExporter)
 	if err != nil {
-		return err
+		return "", err
 	}
 	input := inputInterface.(*models.TransactionExporter)
 
-	// err := ctx.GetClientIdentity().AssertAttributeValue("exporter.creator", "true")
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have exporter.creator role")
-	}
-
-	existExporter, err := utils.AssetExists(ctx, input.Id)
-	if err != nil {
-		return err
-	}
-	if existExporter {
-		return fmt.Errorf("the asset %s already exists", input.Id)
+	if input.Id == "" {
+		input.Id, err = utils.NextId(ctx, utils.ExporterSequence, utils.SequenceVars{}, utils.AssetIdTaken(ctx))
+		if err != nil {
+			return "", err
+		}
 	}
 
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
-
-	timestamp := utils.GenerateTimestamp()
-
 	asset := models.TransactionExporter{
-		Id:        input.Id,
-		CertId:    input.CertId,
-		PlantType:    input.PlantType,
+		Id:              input.Id,
+		CertId:          input.CertId,
+		PlantType:       input.PlantType,
 		PlantTypeDetail: input.PlantTypeDetail,
-		Owner:     clientID,
-		OrgName:   orgName,
-		UpdatedAt: timestamp,
-		CreatedAt: timestamp,
-		DocType: models.Exporter,
 	}
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
-
-	fmt.Printf("[CreateExporter] assetJson %v \n", asset.PlantType)
-	return ctx.GetStub().PutState(input.Id, assetJSON)
-}
-
-func (s *SmartContract) GetLastIdExporter(ctx contractapi.TransactionContextInterface) string {
-	// Query to get all records sorted by ID in descending order
-	query := `{
-		"selector": {
-			"docType": "exporter"
-		},
-		"sort": [{"_id": "desc"}],
-		"limit": 1
-	}`
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
-	if err != nil {
-		return fmt.Sprintf("error querying CouchDB: %v", err)
+	if err := utils.SealContact(ctx, asset.Id, &asset.PlantTypeDetail); err != nil {
+		return "", err
 	}
-	defer resultsIterator.Close()
-
-	// Check if there is a result
-	if !resultsIterator.HasNext() {
-		return ""
+	if err := exporterPlantTypeCopy.Fill(ctx, []*models.TransactionExporter{&asset}); err != nil {
+		return "", err
 	}
 
-	// Get the first (and only) result
-	queryResponse, err := resultsIterator.Next()
-	if err != nil {
-		return "error iterating query results"
+	if err := exporterRepository.Create(ctx, &asset); err != nil {
+		return "", err
 	}
 
-	var result struct {
-		Id string `json:"id"`
-	}
+	return asset.Id, nil
+}
 
-	// Unmarshal the result into the result struct
-	if err := json.Unmarshal(queryResponse.Value, &result); err != nil {
-		return "error unmarshalling document"
-	}
+// GetLastIdExporter returns the highest exporter id.
+//
+// Deprecated: CreateFarmerProfile, CreatePacker and CreateExporter allocate
+// ids themselves when none is given.
+func (s *SmartContract) GetLastIdExporter(ctx contractapi.TransactionContextInterface) (string, error) {
+	return utils.LastId(ctx, utils.NewSelector(models.Exporter).Query().
+		Sort("_id", "desc"))
+}
 
-	return result.Id
+var exporterImport = utils.BatchImport[models.TransactionExporter]{
+	Function:   "CreateExporterCsv",
+	Repository: exporterRepository,
+	Build: func(input *models.TransactionExporter, meta utils.BatchMeta) *models.TransactionExporter {
+		return &models.TransactionExporter{
+			Id:              input.Id,
+			CertId:          input.CertId,
+			PlantType:       input.PlantType,
+			Owner:           meta.Owner,
+			OrgName:         meta.OrgName,
+			DocType:         models.Exporter,
+			PlantTypeDetail: input.PlantTypeDetail,
+			CreatedAt:       meta.Timestamp,
+			UpdatedAt:       meta.Timestamp,
+		}
+	},
+	Seal: func(ctx contractapi.TransactionContextInterface, exporter *models.TransactionExporter) error {
+		return utils.SealContact(ctx, exporter.Id, &exporter.PlantTypeDetail)
+	},
+	Fill: exporterPlantTypeCopy.Fill,
 }
 
 func (s *SmartContract) CreateExporterCsv(
 	ctx contractapi.TransactionContextInterface,
 	args string,
 ) error {
-	var inputs []models.TransactionExporter 
-	var eventPayloads []models.TransactionExporter
-
-	// Unmarshal input arguments
-	errInputExporter := json.Unmarshal([]byte(args), &inputs)
-	if errInputExporter != nil {
-		return fmt.Errorf("failed to unmarshal input arguments: %v", errInputExporter)
-	}
-
-	// Process each input
-	for _, input := range inputs {
-		// Get client's MSP ID
-		orgNameG, err := ctx.GetClientIdentity().GetMSPID()
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
-		}
-
-		// Check if the asset already exists
-		existExporter, err := utils.AssetExists(ctx, input.Id)
-		if err != nil {
-			return fmt.Errorf("error checking if asset exists: %v", err)
-		}
-		if existExporter {
-			return fmt.Errorf("the asset %s already exists", input.Id)
-		}
-
-		// Get client's identity
-		clientIDG, err := utils.GetIdentity(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's identity: %v", err)
-		}
-
-		// Create the asset
-		assetG := models.TransactionExporter {
-			Id:          input.Id,
-			CertId:      input.CertId,
-			PlantType:   input.PlantType,
-			Owner:       clientIDG,
-			OrgName:     orgNameG,
-			DocType:     models.Exporter,
-			PlantTypeDetail: input.PlantTypeDetail,
-			CreatedAt:   input.CreatedAt,
-			UpdatedAt:   input.UpdatedAt,
-		}
-
-		// Marshal the asset to JSON
-		assetJSON, err := json.Marshal(assetG)
-		if err != nil {
-			return fmt.Errorf("failed to marshal asset JSON: %v", err)
-		}
-
-		// Put the asset state
-		err = ctx.GetStub().PutState(input.Id, assetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put state for asset %s: %v", input.Id, err)
-		}
-
-		// Add the asset to the event payloads
-		eventPayloads = append(eventPayloads, assetG)
-		fmt.Printf("Asset %s created successfully\n", input.Id)
-	}
-
-	// Marshal event payloads to JSON
-	eventPayloadJSON, err := json.Marshal(eventPayloads)
-	if err != nil {
-		return fmt.Errorf("failed to marshal event payload JSON: %v", err)
-	}
-
-	// Set the event
-	ctx.GetStub().SetEvent("batchCreatedExporterEvent", eventPayloadJSON)
-
-	return nil
+	_, err := exporterImport.Run(ctx, args, false)
+	return err
 }
 
 func (s *SmartContract) UpdateExporter(ctx contractapi.TransactionContextInterface,
@@ -185,126 +100,129 @@ func (s *SmartContract) UpdateExporter(ctx contractapi.TransactionContextInterfa
 
 	entityExporter := models.TransactionExporter{}
 	inputInterface, err := utils.Unmarshal(args, entityExporter)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 	input := inputInterface.(*models.TransactionExporter)
 
 	asset, err := s.ReadExporter(ctx, input.Id)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
+	}
 
-	asset.UpdatedAt = input.UpdatedAt
 	asset.PlantType = input.PlantType
 	asset.PlantTypeDetail = input.PlantTypeDetail
+	if err := utils.SealContact(ctx, asset.Id, &asset.PlantTypeDetail); err != nil {
+		return err
+	}
+	if err := exporterPlantTypeCopy.Fill(ctx, []*models.TransactionExporter{asset}); err != nil {
+		return err
+	}
 
-	assetJSON, errE := json.Marshal(asset)
-	utils.HandleError(errE)
-
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return exporterRepository.Update(ctx, asset)
 }
 
-func (s *SmartContract) DeleteExporter(ctx contractapi.TransactionContextInterface, id string) error {
+// DeleteExporter deletes an exporter nothing refers to. With cascade set, the
+// exporter's plant types are released instead of blocking the delete.
+func (s *SmartContract) DeleteExporter(ctx contractapi.TransactionContextInterface, id string, reason string, cascade bool) error {
 
 	assetE, err := s.ReadExporter(ctx, id)
-	utils.HandleError(err)
-
-	// clientIDExporter, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	// if clientIDExporter != assetE.Owner {
-	// 	return fmt.Errorf(utils.UNAUTHORIZE)
-	// }
+	if err := utils.AssertStaffOrOwner(ctx, assetE.Owner); err != nil {
+		return err
+	}
+	if err := utils.AssertDeletable(ctx, assetE.Id, utils.ExporterDependencies(assetE.Id), cascade); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().DelState(assetE.Id)
+	// The private details are kept so a restored exporter gets them back
+	return exporterRepository.Delete(ctx, assetE.Id, reason)
 }
 
-func (s *SmartContract) DeleteExporterFromRegulator(ctx contractapi.TransactionContextInterface, id string) error {
+func (s *SmartContract) DeleteExporterFromRegulator(ctx contractapi.TransactionContextInterface, id string, reason string, cascade bool) error {
 
 	assetE, err := s.ReadExporter(ctx, id)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
+	if err := utils.AssertDeletable(ctx, assetE.Id, utils.ExporterDependencies(assetE.Id), cascade); err != nil {
+		return err
+	}
 
-	ctx.GetStub().DelState(assetE.Id)
+	return exporterRepository.Delete(ctx, assetE.Id, reason)
+}
 
-	// Marshal event payloads to JSON
-	eventPayloadJSON, err := json.Marshal(assetE)
+func (s *SmartContract) RestoreExporter(ctx contractapi.TransactionContextInterface, id string) error {
+	assetE, err := utils.ReadAssetHeader(ctx, id, models.Exporter)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event payload JSON: %v", err)
+		return err
 	}
 
-	ctx.GetStub().SetEvent("deleteExporterEvent", eventPayloadJSON)
+	if err := utils.AssertStaffOrOwner(ctx, assetE.Owner); err != nil {
+		return err
+	}
 
-	return nil
+	return exporterRepository.Restore(ctx, id)
 }
 
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
 
 	assetE, err := s.ReadExporter(ctx, id)
-	utils.HandleError(err)
-
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	if clientID != assetE.Owner {
-		return fmt.Errorf(utils.UNAUTHORIZE)
+	if err := utils.AssertStaffOrOwner(ctx, assetE.Owner); err != nil {
+		return err
 	}
 
 	assetE.Owner = newOwner
-	assetJSON, err := json.Marshal(assetE)
-	utils.HandleError(err)
-	return ctx.GetStub().PutState(id, assetJSON)
+	if err := utils.SealContact(ctx, assetE.Id, &assetE.PlantTypeDetail); err != nil {
+		return err
+	}
+	return exporterRepository.Update(ctx, assetE)
 }
 
 func (s *SmartContract) ReadExporter(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionExporter, error) {
 
-	assetJSON, err := ctx.GetStub().GetState(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
-	}
-
-	var asset models.TransactionExporter
-	err = json.Unmarshal(assetJSON, &asset)
+	asset, err := exporterRepository.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	asset.IsCanDelete = true
-
-	queryStr := fmt.Sprintf(`{
-		"selector": {
-			"docType": "formE",
-			"createdById": "%s"
-		},
-		"use_index": [
-			"_design/index-CreatedAt",
-			"index-CreatedAt"
-		]
-	}`, asset.Id)
-
-	salesResultsIterator, err := ctx.GetStub().GetQueryResult(queryStr)
+	asset.IsCanDelete, err = utils.CanDelete(ctx, utils.ExporterDependencies(asset.Id))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query related sales: %v", err)
+		return nil, err
 	}
-	defer salesResultsIterator.Close()
 
-	// If there are any related sales, set isCanDelete to false
-	if salesResultsIterator.HasNext() {
-		asset.IsCanDelete = false
+	if err := utils.UnsealContact(ctx, asset.Id, &asset.PlantTypeDetail); err != nil {
+		return nil, err
 	}
 
-	return &asset, nil
+	return asset, nil
 }
 
 func (s *SmartContract) GetExporterByExporterId(ctx contractapi.TransactionContextInterface, exporterId string) (*models.ExporterTransactionResponse, error) {
     // Define the query for the exporter
-    queryKeyExporter := fmt.Sprintf(`{
-        "selector": {"id": "%s", "docType": "exporter"},
-        "use_index": ["_design/index-DocTypeId", "index-DocTypeId"]
-    }`, exporterId)
+    queryKeyExporter, err := utils.NewSelector(models.Exporter).
+        Eq("id", exporterId).
+        Query().
+        UseIndex("_design/index-DocTypeId", "index-DocTypeId").
+        Build()
+    if err != nil {
+        return nil, err
+    }
 
     resultsIteratorExporter, err := ctx.GetStub().GetQueryResult(queryKeyExporter)
     if err != nil {
-        return nil, fmt.Errorf("error querying exporter: %v", err)
+        return nil, fmt.Errorf("error querying exporter: %w", err)
     }
     defer resultsIteratorExporter.Close()
 
@@ -312,29 +230,36 @@ func (s *SmartContract) GetExporterByExporterId(ctx contractapi.TransactionConte
     if resultsIteratorExporter.HasNext() {
         queryResponse, err := resultsIteratorExporter.Next()
         if err != nil {
-            return nil, fmt.Errorf("error getting next query result: %v", err)
+            return nil, fmt.Errorf("error getting next query result: %w", err)
         }
 
         err = json.Unmarshal(queryResponse.Value, &exporter)
         if err != nil {
-            return nil, fmt.Errorf("error unmarshalling exporter JSON: %v", err)
+            return nil, fmt.Errorf("error unmarshalling exporter JSON: %w", err)
         }
     } else {
         return &models.ExporterTransactionResponse{}, nil
     }
 
     // Query for plant types related to the exporter
-    queryPlantTypes := fmt.Sprintf(`{
-        "selector": {"docType": "plantType", "exporterId": "%s"},
-        "use_index": ["_design/index-DocTypeExporterId", "index-DocTypeExporterId"]
-    }`, exporterId)
+    queryPlantTypes, err := utils.NewSelector(models.PlantType).
+        Eq("exporterId", exporterId).
+        Query().
+        UseIndex("_design/index-DocTypeExporterId", "index-DocTypeExporterId").
+        Build()
+    if err != nil {
+        return nil, err
+    }
 
     resultsIteratorPlantTypes, err := ctx.GetStub().GetQueryResult(queryPlantTypes)
     if err != nil {
-        return nil, fmt.Errorf("failed to query plant types: %v", err)
+        return nil, fmt.Errorf("failed to query plant types: %w", err)
     }
     defer resultsIteratorPlantTypes.Close()
 
+    // The contact details are kept under the id of the record the detail
+    // comes from.
+    detailId := exporter.Id
     for resultsIteratorPlantTypes.HasNext() {
         queryResponse, err := resultsIteratorPlantTypes.Next()
         if err != nil {
@@ -348,21 +273,18 @@ func (s *SmartContract) GetExporterByExporterId(ctx contractapi.TransactionConte
         }
 
         exporter.PlantTypeDetail = plantType
+        detailId = plantType.Id
     }
 
-    // Check if the exporter can be deleted
-    queryFormE := fmt.Sprintf(`{
-        "selector": {"docType": "formE", "createdById": "%s"},
-        "use_index": ["_design/index-DocTypeCreatedById", "index-DocTypeCreatedById"]
-    }`, exporterId)
+    if err := utils.UnsealContact(ctx, detailId, &exporter.PlantTypeDetail); err != nil {
+        return nil, err
+    }
 
-    resultsIteratorFormE, err := ctx.GetStub().GetQueryResult(queryFormE)
+    // Check if the exporter can be deleted
+    exporter.IsCanDelete, err = utils.CanDelete(ctx, utils.ExporterDependencies(exporterId))
     if err != nil {
-        return nil, fmt.Errorf("failed to query formE documents: %v", err)
+        return nil, err
     }
-    defer resultsIteratorFormE.Close()
-
-    exporter.IsCanDelete = !resultsIteratorFormE.HasNext()
 
     return exporter, nil
 }
@@ -373,50 +295,38 @@ func (s *SmartContract) UpdateMultipleExporter(
 ) error {
 	var inputs []models.TransactionExporter
 
-	errInputGap := json.Unmarshal([]byte(args), &inputs)
-	utils.HandleError(errInputGap)
-	
+	if err := utils.DecodeInput(args, &inputs); err != nil {
+		return err
+	}
+
 	for _, input := range inputs {
-		assetJSON, err := ctx.GetStub().GetState(input.Id)
-		if err != nil {
-			return fmt.Errorf("failed to read from world state: %v", err)
-		}
-		if assetJSON == nil {
-			return fmt.Errorf("asset with ID %s does not exist", input.Id)
-		}
-		
-		var existingAsset models.TransactionExporter
-		err = json.Unmarshal(assetJSON, &existingAsset)
+		existingAsset, err := exporterRepository.Get(ctx, input.Id)
 		if err != nil {
-			return fmt.Errorf("failed to unmarshal existing asset: %v", err)
+			return err
 		}
 
 		existingAsset.PlantTypeDetail = input.PlantTypeDetail
-		existingAsset.PlantType =   input.PlantType
-		existingAsset.UpdatedAt = 	input.UpdatedAt
-		
-		updatedAssetJSON, err := json.Marshal(existingAsset)
-		if err != nil {
-			return fmt.Errorf("failed to marshal updated asset: %v", err)
+		existingAsset.PlantType = input.PlantType
+		if err := utils.SealContact(ctx, existingAsset.Id, &existingAsset.PlantTypeDetail); err != nil {
+			return err
 		}
-		
-		err = ctx.GetStub().PutState(input.Id, updatedAssetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to update asset in world state: %v", err)
+
+		if err := exporterRepository.Update(ctx, existingAsset); err != nil {
+			return err
 		}
-		
+
 		fmt.Printf("PlantType Asset %s updated successfully\n", input.Id)
 	}
-	
+
 	return nil
 }
 
 func (s *SmartContract) GetAllExporterImportData(ctx contractapi.TransactionContextInterface, args string) (*models.ExporterForImportResponse, error) {
 	var inputs []*models.PlantTypeModel 
 
-	errInputPlantType := json.Unmarshal([]byte(args), &inputs)
+	errInputPlantType := utils.DecodeStrict(args, &inputs)
 	if errInputPlantType != nil {
-		return nil, fmt.Errorf("failed to unmarshal input arguments: %v", errInputPlantType)
+		return nil, errInputPlantType
 	}
 
 	var duplicates []*models.PlantTypeModel
@@ -425,7 +335,7 @@ func (s *SmartContract) GetAllExporterImportData(ctx contractapi.TransactionCont
 	for _, input := range inputs {
 		exists, err := s.checkIfExists(ctx, input.PlantType)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check existence of input ID %s: %v", input.PlantType, err)
+			return nil, fmt.Errorf("failed to check existence of input ID %s: %w", input.PlantType, err)
 		}
 
 		if exists {
@@ -452,16 +362,14 @@ func (s *SmartContract) GetAllExporterImportData(ctx contractapi.TransactionCont
 }
 
 func (s *SmartContract) checkIfExists(ctx contractapi.TransactionContextInterface, plantType string) (bool, error) {
-	queryString := fmt.Sprintf(`{
-		"selector": {
-			"docType": "plantType",
-			"plantType": "%s"
-		}
-	}`, plantType)
+	queryString, err := utils.NewSelector(models.PlantType).Eq("plantType", plantType).Query().Build()
+	if err != nil {
+		return false, err
+	}
 
 	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
 	if err != nil {
-		return false, fmt.Errorf("failed to execute rich query: %v", err)
+		return false, fmt.Errorf("failed to execute rich query: %w", err)
 	}
 	defer resultsIterator.Close()
 
@@ -475,86 +383,61 @@ func (s *SmartContract) GetAllExporter(ctx contractapi.TransactionContextInterfa
 		return nil, err
 	}
 	inputExporter := interfaceE.(*models.ExporterFilterGetAll)
-	filterExporter := utils.ExporterSetFilter(inputExporter)
+	filterExporter, err := utils.ExporterSetFilter(ctx, inputExporter)
+	if err != nil {
+		return nil, err
+	}
 
-	arrExporter, total, err := utils.ExporterFetchResultsWithPagination(ctx, inputExporter, filterExporter)
+	page, err := utils.Paginate[models.ExporterTransactionResponse](ctx, filterExporter.Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAt", "index-CreatedAt"), inputExporter.Pagination)
 	if err != nil {
 		return nil, err
 	}
+	arrExporter := page.Items
 
-	if len(arrExporter) == 0 {
-		arrExporter = []*models.ExporterTransactionResponse{}
+	exporterIds := make([]string, 0, len(arrExporter))
+	for _, asset := range arrExporter {
+		exporterIds = append(exporterIds, asset.Id)
+	}
+	// An exporter with related Form Es cannot be deleted
+	sold, err := utils.ReferencedValues(ctx, models.FormE, "createdById", exporterIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related sales: %w", err)
 	}
 	for _, asset := range arrExporter {
-		asset.IsCanDelete = true
-
-		queryStr := fmt.Sprintf(`{
-			"selector": {
-				"docType": "formE",
-				"createdById": "%s"
-			},
-			"use_index": [
-				"_design/index-CreatedAt",
-				"index-CreatedAt"
-        	]
-		}`, asset.Id)
-
-		salesResultsIterator, err := ctx.GetStub().GetQueryResult(queryStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query related sales: %v", err)
-		}
-		defer salesResultsIterator.Close()
-
-		// If there are any related sales, set isCanDelete to false
-		if salesResultsIterator.HasNext() {
-			asset.IsCanDelete = false
-		}
+		asset.IsCanDelete = !sold[asset.Id]
 	}
 
 	return &models.ExporterGetAllResponse{
-		Data:  "All Exporter",
-		Obj:   arrExporter,
-		Total: total,
+		Data:     "All Exporter",
+		Obj:      arrExporter,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
 	}, nil
 }
 
 func (s *SmartContract) GetExporterList(ctx contractapi.TransactionContextInterface, args string) ([]models.TransactionExporter, error) {
 	var inputs []string
 
-	errInputPlantType := json.Unmarshal([]byte(args), &inputs)
+	errInputPlantType := utils.DecodeInput(args, &inputs)
 	if errInputPlantType != nil {
-		return nil, fmt.Errorf("failed to unmarshal input arguments: %v", errInputPlantType)
+		return nil, errInputPlantType
 	}
 
 	var exporters []models.TransactionExporter
 
-	for _, exporterId := range inputs {
-		queryString := fmt.Sprintf(`{
-			"selector": {
-				"docType": "exporter",
-				"certId": "%s"
-			}
-		}`, exporterId)
-	
-		resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if len(inputs) > 0 {
+		matches, err := utils.FetchAll[models.TransactionExporter](ctx, utils.NewSelector(models.Exporter).In("certId", inputs).Query())
 		if err != nil {
-			return nil, fmt.Errorf("failed to execute rich query: %v", err)
+			return nil, fmt.Errorf("failed to execute rich query: %w", err)
 		}
-		defer resultsIterator.Close()
-	
-		for resultsIterator.HasNext() {
-			queryResponse, err := resultsIterator.Next()
-			if err != nil {
-				return nil, fmt.Errorf("failed to iterate results: %v", err)
-			}
-	
-			var exporterModel models.TransactionExporter
-			err = json.Unmarshal(queryResponse.Value, &exporterModel)
-			if err != nil {
-				return nil, fmt.Errorf("failed to unmarshal query response: %v", err)
-			}
-	
-			exporters = append(exporters, exporterModel)
+		byCertId := map[string][]models.TransactionExporter{}
+		for _, exporterModel := range matches {
+			byCertId[exporterModel.CertId] = append(byCertId[exporterModel.CertId], *exporterModel)
+		}
+		for _, exporterId := range inputs {
+			exporters = append(exporters, byCertId[exporterId]...)
 		}
 	}
 
@@ -567,19 +450,12 @@ func (s *SmartContract) GetExporterList(ctx contractapi.TransactionContextInterf
 }
 
 func FetchFormEByExporterId(ctx contractapi.TransactionContextInterface, id string) ([]*models.TransactionFormE, error) {
-    filter := map[string]interface{}{
-        "selector": map[string]interface{}{
-            "docType": "formE",
-            "createdById": id,
-        },
-    }
-
-    getStringFormE, err := json.Marshal(filter)
+    getStringFormE, err := utils.NewSelector(models.FormE).Eq("createdById", id).Query().Build()
     if err != nil {
         return nil, err
     }
 
-    queryFormE, err := ctx.GetStub().GetQueryResult(string(getStringFormE))
+    queryFormE, err := ctx.GetStub().GetQueryResult(getStringFormE)
     if err != nil {
         return nil, err
     }
@@ -606,44 +482,5 @@ func FetchFormEByExporterId(ctx contractapi.TransactionContextInterface, id stri
 
 
 func (s *SmartContract) FilterExporter(ctx contractapi.TransactionContextInterface, key, value string) ([]*models.TransactionExporter, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
-	if err != nil {
-		return nil, err
-	}
-	defer resultsIterator.Close()
-
-	var assetExporter []*models.TransactionExporter
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var asset models.TransactionExporter
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, err
-		}
-
-		var m map[string]interface{}
-		if err := json.Unmarshal(queryResponse.Value, &m); err != nil {
-			return nil, err
-		}
-
-		if val, ok := m[key]; ok && fmt.Sprintf("%v", val) == value {
-			assetExporter = append(assetExporter, &asset)
-		}
-	}
-
-	sort.Slice(assetExporter, func(i, j int) bool {
-        t1, err1 := time.Parse(time.RFC3339, assetExporter[i].CreatedAt)
-        t2, err2 := time.Parse(time.RFC3339, assetExporter[j].CreatedAt)
-        if err1 != nil || err2 != nil {
-            fmt.Println("Error parsing time:", err1, err2)
-            return false
-        }
-        return t1.After(t2)
-    })
-
-	return assetExporter, nil
-}
\ No newline at end of file
+	return exporterRepository.Filter(ctx, key, value)
+}
@@ -0,0 +1,197 @@
+package chaincode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func seedExporters(t *testing.T, s *SmartContract, ctx *mocks.MockContext) {
+	t.Helper()
+	seedDaily(t, ctx, "CreateExporterCsv", func(args string) error {
+		return s.CreateExporterCsv(ctx, args)
+	},
+		`[{"id":"E1","certId":"EC-1","plantType":"PT-1","plantTypeDetail":{"name":"Durian Co","plantType":"PT-1","province":"Chanthaburi","createdAt":"2024-01-01T00:00:00Z"}}]`,
+		`[{"id":"E2","certId":"EC-2","plantType":"PT-2","plantTypeDetail":{"name":"Mango Co","plantType":"PT-2","province":"Rayong","createdAt":"2024-01-02T00:00:00Z"}}]`,
+		`[{"id":"E3","certId":"EC-3","plantType":"PT-3","plantTypeDetail":{"name":"Durian Export","plantType":"PT-3","province":"Chanthaburi","createdAt":"2024-01-03T00:00:00Z"}}]`,
+	)
+}
+
+func TestExporterLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateExporter", func() error {
+		_, err := s.CreateExporter(ctx, `{"id":"E1","certId":"EC-1","plantType":"PT-1"}`)
+		return err
+	})
+	if err := submit(ctx, "CreateExporter", func() error {
+		_, err := s.CreateExporter(ctx, `{"id":"E1"}`)
+		return err
+	}); err == nil {
+		t.Fatal("expected duplicate exporter to be rejected")
+	}
+
+	exporter, err := s.ReadExporter(ctx, "E1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exporter.DocType != models.Exporter || exporter.Owner != nectecUser.ID || !exporter.IsCanDelete {
+		t.Fatalf("unexpected exporter %+v", exporter)
+	}
+
+	exporterCtx := ctx.As(exporterUser)
+	if err := submit(exporterCtx, "UpdateExporter", func() error {
+		return s.UpdateExporter(exporterCtx, `{"id":"E1","plantType":"PT-X"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("non-owner update: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "TransferAsset", func() error {
+		return s.TransferAsset(ctx, "E1", exporterUser.ID)
+	})
+	mustSubmit(t, exporterCtx, "UpdateExporter", func() error {
+		return s.UpdateExporter(exporterCtx, `{"id":"E1","plantType":"PT-2","updatedAt":"2024-02-01T00:00:00Z"}`)
+	})
+	exporter, _ = s.ReadExporter(ctx, "E1")
+	if exporter.PlantType != "PT-2" || exporter.Owner != exporterUser.ID {
+		t.Fatalf("update not applied: %+v", exporter)
+	}
+
+	seedExporterPlantTypes(t, s, ctx, "E1")
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"referenceNo":"REF-1","createdById":"E1"}`)
+		return err
+	})
+	exporter, _ = s.ReadExporter(ctx, "E1")
+	if exporter.IsCanDelete {
+		t.Fatal("exporter with form E should not be deletable")
+	}
+
+	err = submit(ctx, "DeleteExporterFromRegulator", func() error {
+		return s.DeleteExporterFromRegulator(ctx, "E1", "duplicate record", true)
+	})
+	var refErr *utils.ReferenceError
+	if !errors.As(err, &refErr) || len(refErr.References) != 1 || refErr.References[0].Id != "FE1" {
+		t.Fatalf("expected delete to be blocked by FE1, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "CreateExporter", func() error {
+		_, err := s.CreateExporter(ctx, `{"id":"E2","certId":"EC-2","plantType":"PT-2"}`)
+		return err
+	})
+	mustSubmit(t, ctx, "DeleteExporterFromRegulator", func() error {
+		return s.DeleteExporterFromRegulator(ctx, "E2", "duplicate record", false)
+	})
+	assertChanges(t, lastStateChanges(t, ctx).Changes, models.StateChange{Type: models.ChangeDelete, DocType: models.Exporter, Id: "E2"})
+	if _, err := s.ReadExporter(ctx, "E2"); err == nil {
+		t.Fatal("expected exporter to be deleted")
+	}
+}
+
+func TestExporterLookups(t *testing.T) {
+	s, ctx := newTestContract()
+	seedExporters(t, s, ctx)
+
+	assertChanges(t, lastStateChanges(t, ctx).Changes, models.StateChange{Type: models.ChangeCreate, DocType: models.Exporter, Id: "E3"})
+	// The third daily batch runs as the fourth transaction.
+	exporter, err := s.ReadExporter(ctx, "E3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exporter.CreatedAt != "2024-01-03T00:00:03Z" || exporter.UpdatedAt != exporter.CreatedAt {
+		t.Fatalf("timestamps not taken from the transaction: %+v", exporter)
+	}
+
+	mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[{"id":"T1","plantType":"PT-1","name":"Durian Plant","exporterId":"E1"}]`)
+	})
+
+	byId, err := s.GetExporterByExporterId(ctx, "E1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byId.CertId != "EC-1" || byId.PlantTypeDetail.Id != "T1" || !byId.IsCanDelete {
+		t.Fatalf("unexpected exporter %+v", byId)
+	}
+	missing, err := s.GetExporterByExporterId(ctx, "nope")
+	if err != nil || missing.Id != "" {
+		t.Fatalf("unexpected missing exporter %+v, %v", missing, err)
+	}
+
+	list, err := s.GetExporterList(ctx, `["EC-1","EC-3","EC-9"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Id != "E1" || list[1].Id != "E3" {
+		t.Fatalf("unexpected exporter list %+v", list)
+	}
+
+	imported, err := s.GetAllExporterImportData(ctx, `[{"plantType":"PT-1"},{"plantType":"PT-9"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Duplicates) != 1 || imported.Duplicates[0].PlantType != "PT-1" ||
+		len(imported.NewEntries) != 1 || imported.NewEntries[0].PlantType != "PT-9" {
+		t.Fatalf("unexpected import data %+v", imported)
+	}
+
+	if id, err := s.GetLastIdExporter(ctx); err != nil || id != "E3" {
+		t.Fatalf("last id = %q, want E3, %v", id, err)
+	}
+
+	filtered, err := s.FilterExporter(ctx, "plantType", "PT-2")
+	if err != nil || len(filtered) != 1 || filtered[0].Id != "E2" {
+		t.Fatalf("unexpected filter result %+v, %v", filtered, err)
+	}
+
+	mustSubmit(t, ctx, "UpdateMultipleExporter", func() error {
+		return s.UpdateMultipleExporter(ctx, `[{"id":"E2","plantType":"PT-4","plantTypeDetail":{"name":"Mango Co","plantType":"PT-4","province":"Rayong"}}]`)
+	})
+	updated, _ := s.ReadExporter(ctx, "E2")
+	if updated.PlantType != "PT-4" {
+		t.Fatalf("multiple update not applied: %+v", updated)
+	}
+}
+
+func TestGetAllExporter(t *testing.T) {
+	s, ctx := newTestContract()
+	seedExporters(t, s, ctx)
+	ctx.Stub.PutState("FE1", []byte(`{"id":"FE1","docType":"formE","createdById":"E2"}`))
+
+	tests := []struct {
+		name    string
+		filter  models.ExporterFilterGetAll
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.ExporterFilterGetAll{}, []string{"E3", "E2", "E1"}, 3},
+		{"skip and limit", models.ExporterFilterGetAll{Pagination: models.Pagination{Skip: 1, Limit: 1}}, []string{"E2"}, 3},
+		{"province", models.ExporterFilterGetAll{Province: strPtr("Chanthaburi")}, []string{"E3", "E1"}, 2},
+		{"search name", models.ExporterFilterGetAll{Search: strPtr("Durian")}, []string{"E3", "E1"}, 2},
+		{"search plant type", models.ExporterFilterGetAll{Search: strPtr("PT-2")}, []string{"E2"}, 1},
+		{"created from", models.ExporterFilterGetAll{CreatedAtFrom: strPtr("02-01-2024")}, []string{"E3", "E2"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllExporter(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, e := range res.Obj {
+				ids = append(ids, e.Id)
+				if e.IsCanDelete != (e.Id != "E2") {
+					t.Errorf("%s: isCanDelete = %v", e.Id, e.IsCanDelete)
+				}
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
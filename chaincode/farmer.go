@@ -1,194 +1,146 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"fmt"
-	"sort"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var farmerRepository = utils.Repository[models.TransactionFarmer]{
+	DocType: models.Farmer,
+	Id:      func(asset *models.TransactionFarmer) string { return asset.Id },
+}
+
 func (s *SmartContract) CreateFarmerProfile(
 	ctx contractapi.TransactionContextInterface,
 	args string,
-) error {
+) (string, error) {
 	entityFarmer := models.TransactionFarmer{}
 	inputInterface, err := utils.Unmarshal(args, entityFarmer)
-
-	utils.HandleError(err)
-	input := inputInterface.(*models.TransactionFarmer)
-
-	// err := ctx.GetClientIdentity().AssertAttributeValue("farmer.creator", "true")
-	// if err != nil {
-	// 	return fmt.Errorf("submitting client not authorized to create asset, does not have abac.creator role")
-	// }
-
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have farmer.creator role")
+		return "", err
 	}
+	input := inputInterface.(*models.TransactionFarmer)
 
-	exists, err := utils.AssetExists(ctx, input.Id)
-	utils.HandleError(err)
-	if exists {
-		return fmt.Errorf("the asset %s already exists", input.Id)
+	if input.Id == "" {
+		input.Id, err = utils.NextId(ctx, utils.FarmerSequence, utils.SequenceVars{}, utils.AssetIdTaken(ctx))
+		if err != nil {
+			return "", err
+		}
 	}
 
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
-
 	asset := models.TransactionFarmer{
 		Id:        input.Id,
 		CertId:    input.CertId,
 		ProfileImg:    input.ProfileImg,
-		FarmerGaps: input.FarmerGaps,
-		Owner:     clientID,
-		OrgName:   orgName,
-		UpdatedAt: input.CreatedAt,
-		CreatedAt: input.CreatedAt,
-		DocType: models.Farmer,
 	}
-	assetJSON, err := json.Marshal(asset)
-
-	fmt.Printf("Farmer payload %v \n", assetJSON)
+	if err := utils.SealFarmer(ctx, &asset); err != nil {
+		return "", err
+	}
+	if err := farmerGapsCopy.Fill(ctx, []*models.TransactionFarmer{&asset}); err != nil {
+		return "", err
+	}
 
-	utils.HandleError(err)
+	if err := farmerRepository.Create(ctx, &asset); err != nil {
+		return "", err
+	}
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return asset.Id, nil
 }
 
 func (s *SmartContract) UpdateFarmerProfile(ctx contractapi.TransactionContextInterface,
 	args string) error {
 	entityType := models.TransactionFarmer{}
 	inputInterface, err := utils.Unmarshal(args, entityType)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 	input := inputInterface.(*models.TransactionFarmer)
 
 	asset, err := s.ReadFarmerProfile(ctx, input.Id)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
+	}
 
 	asset.Id = input.Id
 	asset.ProfileImg = input.ProfileImg
-	asset.FarmerGaps = input.FarmerGaps
 	asset.CertId = input.CertId
-	asset.UpdatedAt = input.UpdatedAt
-
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
+	if err := utils.SealFarmer(ctx, asset); err != nil {
+		return err
+	}
+	if err := farmerGapsCopy.Fill(ctx, []*models.TransactionFarmer{asset}); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return farmerRepository.Update(ctx, asset)
 }
 
-func (s *SmartContract) DeleteFarmerProfile(ctx contractapi.TransactionContextInterface, id string) error {
+func (s *SmartContract) DeleteFarmerProfile(ctx contractapi.TransactionContextInterface, id string, reason string) error {
 
 	asset, err := s.ReadFarmerProfile(ctx, id)
-	utils.HandleError(err)
-
-	// clientID, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	// if clientID != asset.Owner {
-	// 	return fmt.Errorf(utils.UNAUTHORIZE)
-	// }
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
+	}
+	if err := utils.AssertDeletable(ctx, asset.Id, utils.FarmerDependencies(asset.Id), false); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().DelState(asset.Id)
+	// The private details are kept so a restored profile gets them back
+	return farmerRepository.Delete(ctx, asset.Id, reason)
 }
 
-func (s *SmartContract) ReadFarmerProfile(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionFarmer, error) {
-
-	assetJSON, err := ctx.GetStub().GetState(id)
+func (s *SmartContract) RestoreFarmerProfile(ctx contractapi.TransactionContextInterface, id string) error {
+	asset, err := utils.ReadAssetHeader(ctx, id, models.Farmer)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return err
 	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
 	}
 
-	var asset models.TransactionFarmer
-	err = json.Unmarshal(assetJSON, &asset)
+	return farmerRepository.Restore(ctx, id)
+}
+
+func (s *SmartContract) ReadFarmerProfile(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionFarmer, error) {
+
+	asset, err := farmerRepository.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	
-   // Query CouchDB for related 'gap' documents
-    queryString := fmt.Sprintf(`{
-        "selector": {
-            "docType": "gap",
-            "farmerId": "%s"
-        }
-    }`, asset.Id)
 
-	fmt.Printf("queryString %v", queryString)
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	gaps, err := farmerGaps(ctx, []string{asset.Id})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query related gaps: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	asset.FarmerGaps = []models.FarmerGap{}
-    for resultsIterator.HasNext() {
-        queryResponse, err := resultsIterator.Next()
-        if err != nil {
-            return nil, err
-        }
-
-        var gap models.FarmerGap
-        err = json.Unmarshal(queryResponse.Value, &gap)
-        if err != nil {
-            return nil, err
-        }
-
-		packings, err := FetchPackingDocsByGap(ctx, gap.CertID)
+	asset.FarmerGaps = gaps[asset.Id]
+	for i := range asset.FarmerGaps {
+		total, err := utils.GetGapSoldTotal(ctx, asset.FarmerGaps[i].CertID)
 		if err != nil {
-			fmt.Printf("error %v", err)
-		}
-
-		totalSold := 0
-
-		for _, packing := range packings {
-			if (packing.ProcessStatus == 2 || packing.ProcessStatus == 3) {
-				totalSold += int(packing.ActualWeight)
-			}
+			return nil, err
 		}
+		asset.FarmerGaps[i].TotalSold = int(total.TotalSold)
+	}
 
-		gap.TotalSold = totalSold
-        gap.IsCanDelete = true
-
-        salesQueryString := fmt.Sprintf(`{
-            "selector": {
-                "docType": "packing",
-                "farmerId": "%s",
-                "gap": "%s"
-            }
-        }`, gap.FarmerID, gap.CertID)
-
-		fmt.Printf("salesQueryString %v", salesQueryString)
-
-        salesResultsIterator, err := ctx.GetStub().GetQueryResult(salesQueryString)
-        if err != nil {
-            return nil, fmt.Errorf("failed to query related sales: %v", err)
-        }
-        defer salesResultsIterator.Close()
-
-        if salesResultsIterator.HasNext() {
-            gap.IsCanDelete = false
-        }
-
-        asset.FarmerGaps = append(asset.FarmerGaps, gap)
-    }
+	if err := utils.UnsealFarmer(ctx, asset); err != nil {
+		return nil, err
+	}
 
-	return &asset, nil
+	return asset, nil
 }
 
 func (s *SmartContract) GetAllFarmerProfile(ctx contractapi.TransactionContextInterface, args string) (*models.FarmerGetAllResponse, error) {
 
-	var filter = map[string]interface{}{}
-	filter["docType"] = "farmer"
-
 	entityGetAll := models.FilterGetAllFarmer{}
 	inputInterface, err := utils.Unmarshal(args, entityGetAll)
 	if err != nil {
@@ -196,264 +148,131 @@ func (s *SmartContract) GetAllFarmerProfile(ctx contractapi.TransactionContextIn
 	}
 	input := inputInterface.(*models.FilterGetAllFarmer)
 
-	arrFarmer, total, err := utils.FarmerFetchResultsWithPagination(ctx, input)
+	page, err := utils.Paginate[models.FarmerTransactionResponse](ctx, utils.FarmerSetFilter(input).Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/indexCreatedAtId", "indexCreatedAtId"), input.Pagination)
 	if err != nil {
 		return nil, err
 	}
+	arrFarmer := page.Items
 
+	farmerIds := make([]string, 0, len(arrFarmer))
 	for _, farmer := range arrFarmer {
-		queryString := fmt.Sprintf(`{
-			"selector": {
-				"docType": "gap",
-				"farmerId": "%s"
-			}
-		}`, farmer.Id)
-
-		resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query related gaps: %v", err)
-		}
-		defer resultsIterator.Close()
-
-		farmer.FarmerGaps = []models.FarmerGap{}
-		for resultsIterator.HasNext() {
-			queryResponse, err := resultsIterator.Next()
-			if err != nil {
-				return nil, err
-			}
-
-			var gap models.FarmerGap
-			err = json.Unmarshal(queryResponse.Value, &gap)
-			if err != nil {
-				return nil, err
-			}
-
-			gap.IsCanDelete = true
-
-			salesQueryString := fmt.Sprintf(`{
-				"selector": {
-					"docType": "packing",
-					"farmerId": "%s",
-					"gap": "%s"
-				}
-			}`, gap.FarmerID, gap.CertID)
-
-			salesResultsIterator, err := ctx.GetStub().GetQueryResult(salesQueryString)
-			if err != nil {
-				return nil, fmt.Errorf("failed to query related sales: %v", err)
-			}
-			defer salesResultsIterator.Close()
-
-			if salesResultsIterator.HasNext() {
-				gap.IsCanDelete = false
-			}
-
-			farmer.FarmerGaps = append(farmer.FarmerGaps, gap)
-		}
+		farmerIds = append(farmerIds, farmer.Id)
 	}
-
-	if len(arrFarmer) == 0 {
-		arrFarmer = []*models.FarmerTransactionResponse{}
+	gaps, err := farmerGaps(ctx, farmerIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, farmer := range arrFarmer {
+		farmer.FarmerGaps = gaps[farmer.Id]
 	}
-	
+
 	return &models.FarmerGetAllResponse{
-		Data:  "All Farmer",
-		Obj:   arrFarmer,
-		Total: total,
+		Data:     "All Farmer",
+		Obj:      arrFarmer,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
 	}, nil
 }
 
-func (s *SmartContract) FilterFarmer(ctx contractapi.TransactionContextInterface, key, value string) ([]*models.TransactionFarmer, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
-	if err != nil {
-		return nil, err
+// farmerGaps returns the GAP certificates of each of farmerIds. A certificate
+// can be deleted while no packing order was packed from it. The certificates
+// of every farmer and the orders packed from them are read in two queries.
+func farmerGaps(ctx contractapi.TransactionContextInterface, farmerIds []string) (map[string][]models.FarmerGap, error) {
+	byFarmer := map[string][]models.FarmerGap{}
+	for _, id := range farmerIds {
+		byFarmer[id] = []models.FarmerGap{}
+	}
+	if len(farmerIds) == 0 {
+		return byFarmer, nil
 	}
-	defer resultsIterator.Close()
-
-	var assetFarmer []*models.TransactionFarmer
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var asset models.TransactionFarmer
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, err
-		}
 
-		var m map[string]interface{}
-		if err := json.Unmarshal(queryResponse.Value, &m); err != nil {
-			return nil, err
-		}
+	gaps, err := utils.FetchAll[models.FarmerGap](ctx, utils.NewSelector(models.Gap).In("farmerId", farmerIds).Query())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related gaps: %w", err)
+	}
+	certIds := make([]string, 0, len(gaps))
+	for _, gap := range gaps {
+		certIds = append(certIds, gap.CertID)
+	}
 
-		if val, ok := m[key]; ok && fmt.Sprintf("%v", val) == value {
-			assetFarmer = append(assetFarmer, &asset)
-		}
+	// A gap with related packing orders or packagings cannot be deleted,
+	// whoever they were packed for, as DeleteGap checks
+	packed, err := utils.ReferencedByAny(ctx, utils.CertificateReferrers, "gap", certIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related sales: %w", err)
 	}
 
-	sort.Slice(assetFarmer, func(i, j int) bool {
-        t1, err1 := time.Parse(time.RFC3339, assetFarmer[i].CreatedAt)
-        t2, err2 := time.Parse(time.RFC3339, assetFarmer[j].CreatedAt)
-        if err1 != nil || err2 != nil {
-            fmt.Println("Error parsing time:", err1, err2)
-            return false
-        }
-        return t1.After(t2)
-    })
+	for _, gap := range gaps {
+		gap.IsCanDelete = !packed[gap.CertID]
+		byFarmer[gap.FarmerID] = append(byFarmer[gap.FarmerID], *gap)
+	}
+	return byFarmer, nil
+}
 
-	return assetFarmer, nil
+func (s *SmartContract) FilterFarmer(ctx contractapi.TransactionContextInterface, key, value string) ([]*models.TransactionFarmer, error) {
+	return farmerRepository.Filter(ctx, key, value)
 }
 
 func (s *SmartContract) GetFarmerHistory(ctx contractapi.TransactionContextInterface, key string) ([]*models.FarmerTransactionHistory, error) {
-	// Get the history for the specified key
-	farmerResultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	entries, err := utils.ReadHistory[models.FarmerTransactionResponse](ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history for key %s: %v", key, err)
+		return nil, err
 	}
-	defer farmerResultsIterator.Close()
 
 	var farmerHistory []*models.FarmerTransactionHistory
 	var farmerAssets []*models.FarmerTransactionResponse
-	for farmerResultsIterator.HasNext() {
-		// Get the next history record
-		farmer, err := farmerResultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next history farmer for key %s: %v", key, err)
-		}
-
-		var asset models.FarmerTransactionResponse
-		if !farmer.IsDelete {
-			err = json.Unmarshal(farmer.Value, &asset)
-			if err != nil {
-				return nil, err
-			}
-			farmerAssets = append(farmerAssets, &asset)
-
+	for _, entry := range entries {
+		if entry.Value != nil {
+			farmerAssets = append(farmerAssets, entry.Value)
 		} else {
 			farmerAssets = []*models.FarmerTransactionResponse{}
 		}
-		// Convert the timestamp to string in the desired format
-		timestampStr := time.Unix(farmer.Timestamp.Seconds, int64(farmer.Timestamp.Nanos)).Format(utils.TIMEFORMAT)
 
-		historyFarmer := &models.FarmerTransactionHistory{
-			TxId:      farmer.TxId,
+		farmerHistory = append(farmerHistory, &models.FarmerTransactionHistory{
+			TxId:      entry.TxId,
 			Value:     farmerAssets,
-			Timestamp: timestampStr,
-			IsDelete:  farmer.IsDelete,
-		}
-
-		farmerHistory = append(farmerHistory, historyFarmer)
+			Timestamp: entry.Timestamp,
+			IsDelete:  entry.IsDelete,
+		})
 	}
 
 	return farmerHistory, nil
 }
 
-func (s *SmartContract) GetLastIdFarmer(ctx contractapi.TransactionContextInterface) string {
-	// Query to get all records sorted by ID in descending order
-	query := `{
-			"selector": {
-				"docType": "farmer"
-			},
-			"sort": [{"_id": "desc"}],
-			"limit": 1,
-			"use_index": ["_design/index-combined","index-combined"]
-	}`
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
-	if err != nil {
-		return "error querying CouchDB"
-	}
-	defer resultsIterator.Close()
-
-	// Check if there is a result
-	if !resultsIterator.HasNext() {
-		return ""
-	}
-
-	// Get the first (and only) result
-	queryResponse, err := resultsIterator.Next()
-	if err != nil {
-		return "error iterating query results"
-	}
-
-	var result struct {
-		Id string `json:"id"`
-	}
-
-	// Unmarshal the result into the result struct
-	if err := json.Unmarshal(queryResponse.Value, &result); err != nil {
-		return "error unmarshalling document"
-	}
+// GetLastIdFarmer returns the highest farmer id.
+//
+// Deprecated: CreateFarmerProfile, CreatePacker and CreateExporter allocate
+// ids themselves when none is given.
+func (s *SmartContract) GetLastIdFarmer(ctx contractapi.TransactionContextInterface) (string, error) {
+	return utils.LastId(ctx, utils.NewSelector(models.Farmer).Query().
+		Sort("_id", "desc").
+		UseIndex("_design/index-combined", "index-combined"))
+}
 
-	return result.Id
+var farmerImport = utils.BatchImport[models.TransactionFarmer]{
+	Function:   "CreateFarmerFromCsv",
+	Repository: farmerRepository,
+	Build: func(input *models.TransactionFarmer, meta utils.BatchMeta) *models.TransactionFarmer {
+		return &models.TransactionFarmer{
+			Id:         input.Id,
+			CertId:     input.CertId,
+			Owner:      meta.Owner,
+			OrgName:    meta.OrgName,
+			UpdatedAt:  meta.Timestamp,
+			CreatedAt:  meta.Timestamp,
+			DocType:    models.Farmer,
+		}
+	},
+	Seal: utils.SealFarmer,
+	Fill: farmerGapsCopy.Fill,
 }
 
 func (s *SmartContract) CreateFarmerFromCsv(
 	ctx contractapi.TransactionContextInterface,
 	args string,
 ) error {
-	var inputs []models.TransactionFarmer
-	var eventPayloads []models.TransactionFarmer
-
-	errInput := json.Unmarshal([]byte(args), &inputs)
-	if errInput != nil {
-		return fmt.Errorf("failed to unmarshal JSON array: %v", errInput)
-	}
-
-	for _, input := range inputs {
-		orgName, err := ctx.GetClientIdentity().GetMSPID()
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
-		}
-
-		existFarmer, err := utils.AssetExists(ctx, input.Id)
-		if err != nil {
-			return fmt.Errorf("error checking if asset exists: %v", err)
-		}
-		if existFarmer {
-			return fmt.Errorf("the asset %s already exists", input.Id)
-		}
-
-		clientID, err := utils.GetIdentity(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's identity: %v", err)
-		}
-
-		timestamp := utils.GenerateTimestamp()
-
-		asset := models.TransactionFarmer{
-			Id:        input.Id,
-			CertId:    input.CertId,
-			FarmerGaps: input.FarmerGaps,
-			Owner:     clientID,
-			OrgName:   orgName,
-			UpdatedAt: timestamp,
-			CreatedAt: timestamp,
-			DocType: models.Farmer,
-		}
-
-		assetJSON, err := json.Marshal(asset)
-		eventPayloads = append(eventPayloads, asset)
-		if err != nil {
-			return fmt.Errorf("failed to marshal asset JSON: %v", err)
-		}
-
-		err = ctx.GetStub().PutState(input.Id, assetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put state for asset %s: %v", input.Id, err)
-		}
-
-		fmt.Printf("Asset %s created successfully\n", input.Id)
-
-	}
-
-	eventPayloadJSON, err := json.Marshal(eventPayloads)
-	if err != nil {
-		return fmt.Errorf("failed to marshal asset JSON: %v", err)
-	}
-	ctx.GetStub().SetEvent("batchCreatedUserEvent", eventPayloadJSON)
-
-	return nil
+	_, err := farmerImport.Run(ctx, args, false)
+	return err
 }
\ No newline at end of file
@@ -0,0 +1,266 @@
+package chaincode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestFarmerProfileLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+	farmerCtx := ctx.As(farmerUser)
+
+	mustSubmit(t, farmerCtx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(farmerCtx, `{"id":"F1","certId":"C1","createdAt":"2024-01-01T00:00:00Z"}`)
+		return err
+	})
+
+	farmer, err := s.ReadFarmerProfile(ctx, "F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if farmer.Owner != farmerUser.ID || farmer.OrgName != "FarmerMSP" || farmer.DocType != models.Farmer {
+		t.Fatalf("unexpected farmer %+v", farmer)
+	}
+	if len(farmer.FarmerGaps) != 0 {
+		t.Fatalf("expected no gaps, got %v", farmer.FarmerGaps)
+	}
+
+	if err := submit(farmerCtx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(farmerCtx, `{"id":"F1"}`)
+		return err
+	}); err == nil {
+		t.Fatal("expected duplicate farmer to be rejected")
+	}
+
+	otherCtx := ctx.As(otherFarmer)
+	if err := submit(otherCtx, "UpdateFarmerProfile", func() error {
+		return s.UpdateFarmerProfile(otherCtx, `{"id":"F1","certId":"HACK"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("other farmer update: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, farmerCtx, "UpdateFarmerProfile", func() error {
+		return s.UpdateFarmerProfile(farmerCtx, `{"id":"F1","certId":"C2","updatedAt":"2024-02-01T00:00:00Z"}`)
+	})
+	farmer, _ = s.ReadFarmerProfile(ctx, "F1")
+	if farmer.CertId != "C2" || farmer.UpdatedAt != ctx.Stub.TxTimestamp.Format(time.RFC3339) || farmer.CreatedAt == farmer.UpdatedAt {
+		t.Fatalf("update not applied: %+v", farmer)
+	}
+
+	if err := submit(farmerCtx, "DeleteFarmerProfile", func() error {
+		return s.DeleteFarmerProfile(farmerCtx, "F1", "duplicate record")
+	}); !isAccessDenied(err) {
+		t.Fatalf("farmer delete: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "DeleteFarmerProfile", func() error {
+		return s.DeleteFarmerProfile(ctx, "F1", "duplicate record")
+	})
+	if _, err := s.ReadFarmerProfile(ctx, "F1"); err == nil {
+		t.Fatal("expected farmer to be deleted")
+	}
+
+	history, err := s.GetFarmerHistory(ctx, "F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || !history[2].IsDelete {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestReadFarmerProfileAttachesGaps(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateFarmerFromCsv", func() error {
+		return s.CreateFarmerFromCsv(ctx, `[{"id":"F1","certId":"C1"}]`)
+	})
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[
+			{"id":"G1","certId":"GAP-1","farmerId":"F1"},
+			{"id":"G2","certId":"GAP-2","farmerId":"F1"}
+		]`)
+	})
+	packerCtx := ctx.As(packerUser)
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","farmerId":"F1","gap":"GAP-1","actualWeight":40,"processStatus":1}`)
+	})
+	advancePacking(t, s, ctx, "P1", models.StatusApproved)
+
+	farmer, err := s.ReadFarmerProfile(ctx, "F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(farmer.FarmerGaps) != 2 {
+		t.Fatalf("expected 2 gaps, got %+v", farmer.FarmerGaps)
+	}
+	for _, gap := range farmer.FarmerGaps {
+		switch gap.CertID {
+		case "GAP-1":
+			if gap.IsCanDelete || gap.TotalSold != 40 {
+				t.Errorf("GAP-1: %+v", gap)
+			}
+		case "GAP-2":
+			if !gap.IsCanDelete || gap.TotalSold != 0 {
+				t.Errorf("GAP-2: %+v", gap)
+			}
+		}
+	}
+
+	event := lastStateChanges(t, ctx)
+	if event.Function != "ApprovePacking" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	assertChanges(t, event.Changes,
+		models.StateChange{Type: models.ChangeUpdate, DocType: models.Packing, Id: "P1"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.SoldTotal, Id: "GAP-1"},
+	)
+}
+
+func TestGetAllFarmerProfile(t *testing.T) {
+	s, ctx := newTestContract()
+
+	for _, args := range []string{
+		`{"id":"F1","certId":"C1"}`,
+		`{"id":"F2","certId":"C2"}`,
+		`{"id":"F3","certId":"C3","farmerGaps":[{"certId":"GAP-C"}]}`,
+	} {
+		args := args
+		mustSubmit(t, ctx, "CreateFarmerProfile", func() error {
+			_, err := s.CreateFarmerProfile(ctx, args)
+			return err
+		})
+	}
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[
+			{"id":"G1","certId":"GAP-A","farmerId":"F1"},
+			{"id":"G2","certId":"GAP-B","displayCertId":"DISP-B","farmerId":"F2"}
+		]`)
+	})
+
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllFarmer
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FilterGetAllFarmer{}, []string{"F3", "F2", "F1"}, 3},
+		{"limit", models.FilterGetAllFarmer{Pagination: models.Pagination{Limit: 2}}, []string{"F3", "F2"}, 3},
+		{"skip and limit", models.FilterGetAllFarmer{Pagination: models.Pagination{Skip: 2, Limit: 2}}, []string{"F1"}, 3},
+		{"search by gap cert", models.FilterGetAllFarmer{Search: "GAP-A"}, []string{"F1"}, 1},
+		{"search by display cert", models.FilterGetAllFarmer{Search: "DISP"}, []string{"F2"}, 1},
+		{"search by id", models.FilterGetAllFarmer{Search: "F3"}, []string{"F3"}, 1},
+		{"gaps sent by the client are not kept", models.FilterGetAllFarmer{Search: "GAP-C"}, []string{}, 0},
+		{"no match", models.FilterGetAllFarmer{Search: "nothing"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllFarmerProfile(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, f := range res.Obj {
+				ids = append(ids, f.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
+
+func TestFilterFarmerAndLastId(t *testing.T) {
+	s, ctx := newTestContract()
+
+	if id, err := s.GetLastIdFarmer(ctx); err != nil || id != "" {
+		t.Fatalf("expected empty last id, got %q, %v", id, err)
+	}
+
+	mustSubmit(t, ctx, "CreateFarmerFromCsv", func() error {
+		return s.CreateFarmerFromCsv(ctx, `[{"id":"F001","certId":"X"},{"id":"F002","certId":"Y"},{"id":"F010","certId":"X"}]`)
+	})
+
+	if id, err := s.GetLastIdFarmer(ctx); err != nil || id != "F010" {
+		t.Fatalf("last id = %q, want F010, %v", id, err)
+	}
+
+	farmers, err := s.FilterFarmer(ctx, "certId", "X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(farmers) != 2 {
+		t.Fatalf("expected 2 farmers, got %d", len(farmers))
+	}
+	for _, key := range []string{"docType", "deletedAt", "_id", "privateHash", "certId.$regex"} {
+		if _, err := s.FilterFarmer(ctx, key, "gap"); errorCode(err) != models.ErrValidation {
+			t.Fatalf("filter on %s: expected VALIDATION, got %v", key, err)
+		}
+	}
+
+	if err := submit(ctx, "CreateFarmerFromCsv", func() error {
+		return s.CreateFarmerFromCsv(ctx, `[{"id":"F020"},{"id":"F001"}]`)
+	}); err == nil {
+		t.Fatal("expected duplicate id in batch to fail")
+	}
+	if exists, _ := s.ReadFarmerProfile(ctx, "F020"); exists != nil {
+		t.Fatal("failed batch must not leave partial writes")
+	}
+}
+
+func TestGetAllFarmerProfileQueries(t *testing.T) {
+	s, ctx := newTestContract()
+	queries := countQueries(ctx)
+
+	seed := func(n int) {
+		for i := 1; i <= n; i++ {
+			id := fmt.Sprintf("F%02d", i)
+			ctx.Stub.PutState(id, []byte(fmt.Sprintf(`{"id":"%s","docType":"farmer","createdAt":"2024-01-%02dT00:00:00Z"}`, id, i)))
+			for _, gap := range []string{"A", "B"} {
+				gapId := fmt.Sprintf("G%02d%s", i, gap)
+				ctx.Stub.PutState(gapId, []byte(fmt.Sprintf(`{"id":"%s","docType":"gap","certId":"GAP-%s","farmerId":"%s"}`, gapId, gapId, id)))
+			}
+		}
+	}
+	list := func() *models.FarmerGetAllResponse {
+		t.Helper()
+		*queries = 0
+		var res *models.FarmerGetAllResponse
+		mustSubmit(t, ctx, "GetAllFarmerProfile", func() (err error) {
+			res, err = s.GetAllFarmerProfile(ctx, `{}`)
+			return err
+		})
+		return res
+	}
+
+	seed(2)
+	// Packed by its farmer, and packed for another farmer: DeleteGap refuses
+	// both.
+	ctx.Stub.PutState("P1", []byte(`{"id":"P1","docType":"packing","farmerId":"F01","gap":"GAP-G01A"}`))
+	ctx.Stub.PutState("P2", []byte(`{"id":"P2","docType":"packing","farmerId":"F09","gap":"GAP-G02A"}`))
+
+	res := list()
+	if *queries != 5 {
+		t.Errorf("2 farmers took %d queries, want 5", *queries)
+	}
+	for _, farmer := range res.Obj {
+		if len(farmer.FarmerGaps) != 2 {
+			t.Fatalf("%s: unexpected gaps %+v", farmer.Id, farmer.FarmerGaps)
+		}
+		for _, gap := range farmer.FarmerGaps {
+			if want := gap.CertID != "GAP-G01A" && gap.CertID != "GAP-G02A"; gap.IsCanDelete != want {
+				t.Errorf("%s: isCanDelete = %v, want %v", gap.CertID, gap.IsCanDelete, want)
+			}
+		}
+	}
+
+	seed(50)
+	if res := list(); len(res.Obj) != 50 || *queries != 5 {
+		t.Errorf("50 farmers: %d listed in %d queries, want 5", len(res.Obj), *queries)
+	}
+}
@@ -1,343 +1,424 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
-func (s *SmartContract) CreateFormE(ctx contractapi.TransactionContextInterface, id string, formEJSON string) error {
+var formEUniqueFields = []utils.UniqueField[models.TransactionFormE]{
+	{Index: utils.FormEReferenceNoIndex, Value: func(asset *models.TransactionFormE) string { return asset.ReferenceNo }},
+}
+
+var formERepository = utils.Repository[models.TransactionFormE]{
+	DocType: models.FormE,
+	Id:      func(asset *models.TransactionFormE) string { return asset.Id },
+	Unique:  formEUniqueFields,
+}
+
+func (s *SmartContract) CreateFormE(ctx contractapi.TransactionContextInterface, id string, formEJSON string) (string, error) {
 	var formE models.TransactionFormE
 
-	err := json.Unmarshal([]byte(formEJSON), &formE)
-	if err != nil {
-		return err
+	if err := utils.DecodeInput(formEJSON, &formE); err != nil {
+		return "", err
 	}
 
-    orgName, err := ctx.GetClientIdentity().GetMSPID()
-    if err != nil {
-        return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
-    }
-
-    existPacker, err := utils.AssetExists(ctx, id)
-    if err != nil {
-        return fmt.Errorf("error checking if asset exists: %v", err)
-    }
-    if existPacker {
-        return fmt.Errorf("the asset %s already exists", id)
-    }
+	if err := utils.AssertExporterPlantTypes(ctx, formE.CreatedById); err != nil {
+		return "", err
+	}
+	if err := assertExporterCaller(ctx, formE.CreatedById); err != nil {
+		return "", err
+	}
 
-    clientID, err := utils.GetIdentity(ctx)
-    if err != nil {
-        return fmt.Errorf("failed to get submitting client's identity: %v", err)
-    }
+	if formE.Status == "" {
+		formE.Status = models.FormEDraft
+	}
+	if err := utils.ValidateInitialFormEStatus(formE.Status); err != nil {
+		return "", err
+	}
+	if err := allocateFormEIds(ctx, &id, &formE); err != nil {
+		return "", err
+	}
+	if err := assertFormEContent(ctx, id, &formE); err != nil {
+		return "", err
+	}
+	formE.StatusHistory = nil
+	formE.IssuedAt = ""
+	formE.ReplacedByReferenceNumber = ""
 
 	formE.Id = id
-    formE.DocType = models.FormE
-    formE.Owner =   clientID
-    formE.OrgName =   orgName
 
-	formEAsBytes, err := json.Marshal(formE)
+	if err := formERepository.Create(ctx, &formE); err != nil {
+		return "", err
+	}
+
+	return formE.Id, nil
+}
+
+// assertExporterCaller checks that the caller owns the exporter record a Form E
+// is filed under. Staff may file for any exporter.
+func assertExporterCaller(ctx contractapi.TransactionContextInterface, exporterId string) error {
+	owner, err := utils.ReadAssetOwner(ctx, exporterId, models.Exporter)
 	if err != nil {
 		return err
 	}
+	return utils.AssertStaffOrOwner(ctx, owner)
+}
 
-	return ctx.GetStub().PutState(id, formEAsBytes)
+// allocateFormEIds allocates the id and the reference number of a new Form E
+// when they are not given. Reference numbers are counted per office and year.
+func allocateFormEIds(ctx contractapi.TransactionContextInterface, id *string, formE *models.TransactionFormE) error {
+	var err error
+	if *id == "" {
+		*id, err = utils.NextId(ctx, utils.FormESequence, utils.SequenceVars{}, utils.AssetIdTaken(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	if formE.ReferenceNo == "" {
+		vars := utils.SequenceVars{Office: formE.Office}
+		formE.ReferenceNo, err = utils.NextId(ctx, utils.FormEReferenceSequence, vars, func(referenceNo string) (bool, error) {
+			holder, err := utils.FormEReferenceNoIndex.Lookup(ctx, referenceNo)
+			return holder != "", err
+		})
+	}
+	return err
+}
+
+// ValidateFormE checks the content of a Form E without storing it and returns
+// every discrepancy found against the HS codes, packagings and packing orders
+// on the ledger.
+func (s *SmartContract) ValidateFormE(ctx contractapi.TransactionContextInterface, formEJSON string) (*models.FormEValidation, error) {
+	var formE models.TransactionFormE
+	if err := utils.DecodeInput(formEJSON, &formE); err != nil {
+		return nil, err
+	}
+
+	discrepancies, err := utils.ValidateFormEContent(ctx, &formE)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FormEValidation{
+		Valid:         len(discrepancies) == 0,
+		Discrepancies: discrepancies,
+	}, nil
 }
 
 func (s *SmartContract) QueryFormEWithPagination(ctx contractapi.TransactionContextInterface, filterParams string) (*models.TransactionFormEResponse, error) {
-    const offset = 7 // UTC+7
 	var filters models.FormEFilterParams
-	err := json.Unmarshal([]byte(filterParams), &filters)
+	err := utils.DecodeInput(filterParams, &filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal filter parameters: %v", err)
+		return nil, err
 	}
 
-	selector := map[string]interface{}{
-		"docType": "formE",
-	}
+	selector := utils.NewSelector(models.FormE)
 
 	if filters.CreatedById != "" {
-		selector["createdById"] = filters.CreatedById
+		selector.Eq("createdById", filters.CreatedById)
 	}
 
 	if filters.ReferenceNo != "" {
-		selector["referenceNo"] = filters.ReferenceNo
+		selector.Eq("referenceNo", filters.ReferenceNo)
 	}
 
 	if filters.ExportNumber != "" {
-		selector["invoice.exportNumber"] = filters.ExportNumber
+		selector.Eq("invoice.exportNumber", filters.ExportNumber)
 	}
 
 	if filters.Status != "" {
-		selector["status"] = filters.Status
+		selector.Eq("status", filters.Status)
 	}
 
 	if filters.RequestType != "" {
-		selector["requestType"] = filters.RequestType
+		selector.Eq("requestType", filters.RequestType)
 	}
 
-	if filters.StartDate != nil && filters.EndDate != nil {
-		fromDate, err1 := utils.FormatDate(*filters.StartDate, false, offset)
-		toDate, err2 := utils.FormatDate(*filters.EndDate, true, offset)
-
-		if err1 == nil && err2 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1, err2)
-		}
-	} else if (filters.StartDate != nil) {
-		fromDate, err1 := utils.FormatDate(*filters.StartDate, false, offset)
-
-		if err1 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1)
-		}
-	} else if (filters.EndDate != nil) {
-		toDate, err2 := utils.FormatDate(*filters.EndDate, true, offset)
-
-		if err2 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err2)
-		}
-	}
+	selector.DateRange("createdAt", filters.StartDate, filters.EndDate)
 
 	if filters.Search != "" {
-		elemMatch := map[string]interface{}{
-			"$or": []map[string]interface{}{
-				{"containerNumber": filters.Search},
-				{"palletNumber": filters.Search},
-			},
-		}
-		selector["$or"] = []map[string]interface{}{
-			{"invoice.productAndPackaging": map[string]interface{}{"$elemMatch": elemMatch}},
-			{"invoice.invoiceNumber": filters.Search},
-		}
-	}
-
-	countQueryString, err := json.Marshal(map[string]interface{}{
-		"selector": selector,
-		"use_index": []string{
-			"_design/index-CreatedAt",
-			"index-CreatedAt",
-		},
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal count query string: %v", err)
+		elemMatch := utils.NewSelector("").Or(
+			utils.NewSelector("").Eq("containerNumber", filters.Search),
+			utils.NewSelector("").Eq("palletNumber", filters.Search),
+		)
+		selector.Or(
+			utils.NewSelector("").ElemMatch("invoice.productAndPackaging", elemMatch),
+			utils.NewSelector("").Eq("invoice.invoiceNumber", filters.Search),
+		)
 	}
 
-	// Log the count query string
-	fmt.Printf("Count query string: %s\n", string(countQueryString))
-
-	// Execute the count query
-	total, err := utils.CountTotalResults(ctx, string(countQueryString))
+	page, err := utils.Paginate[models.TransactionFormE](ctx, selector.Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAt", "index-CreatedAt"), filters.Pagination)
 	if err != nil {
 		return nil, err
 	}
+	assets := page.Items
 
-	// Count the total number of records
-	if total == 0 {
-		return &models.TransactionFormEResponse{
-			Data:  []*models.TransactionFormE{},
-			Total: total,
-		}, nil
-	}
+	return &models.TransactionFormEResponse{
+		Data:     assets,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
+	}, nil
+}
 
-	// If no records are found, return an empty response
-	if total == 0 {
-		return &models.TransactionFormEResponse{
-			Data:  []*models.TransactionFormE{},
-			Total: total,
-		}, nil
-	}
+func (s *SmartContract) ReadFormE(ctx contractapi.TransactionContextInterface, referenceNo string) (*models.TransactionFormE, error) {
+	return formERepository.Get(ctx, referenceNo)
+}
 
-	query := map[string]interface{}{
-		"selector": selector,
-		"sort": []map[string]string{
-			{"createdAt": "desc"},
-		},
-		"use_index": []string{
-			"_design/index-CreatedAt",
-			"index-CreatedAt",
-		},
+func (s *SmartContract) GetFormEHistoryForKey(ctx contractapi.TransactionContextInterface, key string) ([]*models.FormETransactionHistory, error) {
+	history, err := formERepository.History(ctx, key)
+	if err != nil {
+		return nil, err
 	}
 
-	if filters.Skip > 0 {
-		query["skip"] = filters.Skip
-	}
-	if filters.Limit > 0 {
-		query["limit"] = filters.Limit
-	}
+	var formEHistory []*models.FormETransactionHistory
+	for _, entry := range history {
+		asset := entry.Value
+		if asset == nil {
+			asset = &models.TransactionFormE{}
+		}
 
-	// Create query string for paginated results
-	queryString, err := json.Marshal(query)
+		// Ensure productAndPackaging is always initialized as an array
+		if asset.Invoice.ProductAndPackaging == nil {
+			asset.Invoice.ProductAndPackaging = []models.ProductAndPackaging{}
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query string: %v", err)
+		formEHistory = append(formEHistory, &models.FormETransactionHistory{
+			TxId:      entry.TxId,
+			Value:     []*models.TransactionFormE{asset},
+			Timestamp: entry.Timestamp,
+			IsDelete:  entry.IsDelete,
+		})
 	}
 
-	// Log the paginated query string
-	fmt.Printf("Paginated query string: %s\n", string(queryString))
+	return formEHistory, nil
+}
 
-	// Execute the paginated query
-	resultsIterator, _, err := ctx.GetStub().GetQueryResultWithPagination(string(queryString), int32(filters.Limit), "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get query result with pagination: %v", err)
-	}
-	defer resultsIterator.Close()
+// SubmitFormE submits a draft or amended Form E for review.
+func (s *SmartContract) SubmitFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionFormE(ctx, args, models.FormESubmitted)
+}
 
-	var assets []*models.TransactionFormE
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next query result: %v", err)
-		}
+// ReviewFormE takes a submitted Form E under review.
+func (s *SmartContract) ReviewFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionFormE(ctx, args, models.FormEUnderReview)
+}
 
-		var asset models.TransactionFormE
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal query result: %v", err)
-		}
-		assets = append(assets, &asset)
-	}
+// IssueFormE issues a Form E under review.
+func (s *SmartContract) IssueFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionFormE(ctx, args, models.FormEIssued)
+}
 
-	return &models.TransactionFormEResponse{
-		Data:  assets,
-		Total: total,
-	}, nil
+// RejectFormE rejects a Form E under review. A reason is required.
+func (s *SmartContract) RejectFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionFormE(ctx, args, models.FormERejected)
 }
 
+// AmendFormE replaces the content of a submitted or rejected Form E with
+// input.FormE. It keeps its id, owner and reference number, and must be
+// submitted again. A reason is required.
+func (s *SmartContract) AmendFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionFormE(ctx, args, models.FormEAmended)
+}
 
+// CancelFormE cancels a Form E. A reason is required, given as reason or
+// cancelReason.
+func (s *SmartContract) CancelFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionFormE(ctx, args, models.FormECancelled)
+}
 
-func (s *SmartContract) ReadFormE(ctx contractapi.TransactionContextInterface, referenceNo string) (*models.TransactionFormE, error) {
-	formEAsBytes, err := ctx.GetStub().GetState(referenceNo)
+// ReplaceFormE replaces an issued Form E with the new certificate in
+// input.FormE, stored under input.NewId with a new reference number. Both are
+// allocated as for CreateFormE when not given. The two certificates are linked
+// both ways through their reference numbers. A reason is required.
+func (s *SmartContract) ReplaceFormE(ctx contractapi.TransactionContextInterface, args string) error {
+	input, asset, err := s.readFormETransition(ctx, args, models.FormEReplaced)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return err
 	}
-	if formEAsBytes == nil {
-		return nil, fmt.Errorf("the formE %s does not exist", referenceNo)
+	if input.FormE == nil {
+		return utils.Validation("formE is required to replace form E %s", input.Id)
 	}
 
-	var formE models.TransactionFormE
-	err = json.Unmarshal(formEAsBytes, &formE)
+	replacement := *input.FormE
+	replacement.CreatedById = asset.CreatedById
+	if replacement.Office == "" {
+		replacement.Office = asset.Office
+	}
+	if err := allocateFormEIds(ctx, &input.NewId, &replacement); err != nil {
+		return err
+	}
+	exists, err := utils.AssetExists(ctx, input.NewId)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("error checking if asset exists: %w", err)
+	}
+	if exists {
+		return utils.AlreadyExists("the asset %s already exists", input.NewId)
+	}
+	if err := utils.AssertExporterPlantTypes(ctx, replacement.CreatedById); err != nil {
+		return err
+	}
+	if err := assertFormEContent(ctx, input.NewId, &replacement); err != nil {
+		return err
 	}
 
-	return &formE, nil
+	if err := applyFormETransition(ctx, asset, models.FormEReplaced, input.Reason); err != nil {
+		return err
+	}
+	asset.ReplacedByReferenceNumber = replacement.ReferenceNo
+	if err := putFormE(ctx, asset); err != nil {
+		return err
+	}
+
+	replacement.Id = input.NewId
+	replacement.DocType = models.FormE
+	replacement.Owner = asset.Owner
+	replacement.OrgName = asset.OrgName
+	replacement.Status = models.FormESubmitted
+	replacement.PreviousReferenceNumber = asset.ReferenceNo
+	replacement.ReplacedByReferenceNumber = ""
+	replacement.IssuedAt = ""
+	replacement.CancelReason = ""
+	replacement.StatusHistory = nil
+	replacement.CreatedAt = asset.UpdatedAt
+	replacement.UpdatedAt = asset.UpdatedAt
+
+	return putFormE(ctx, &replacement)
 }
 
-func (s *SmartContract) GetFormEHistoryForKey(ctx contractapi.TransactionContextInterface, key string) ([]*models.FormETransactionHistory, error) {
-	formEResultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+func (s *SmartContract) transitionFormE(ctx contractapi.TransactionContextInterface, args string, to models.FormEStatus) error {
+	input, asset, err := s.readFormETransition(ctx, args, to)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history for key %s: %v", key, err)
+		return err
 	}
-	defer formEResultsIterator.Close()
-
-	var formEHistory []*models.FormETransactionHistory
 
-	for formEResultsIterator.HasNext() {
-		// Get the next history record
-		formE, err := formEResultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next history formE for key %s: %v", key, err)
+	if to == models.FormEAmended {
+		if input.FormE == nil {
+			return utils.Validation("formE is required to amend form E %s", input.Id)
 		}
-
-		var asset models.TransactionFormE
-		if !formE.IsDelete {
-			err = json.Unmarshal(formE.Value, &asset)
-			if err != nil {
-				return nil, err
-			}
-
-			// Ensure productAndPackaging is always initialized as an array
-			if asset.Invoice.ProductAndPackaging == nil {
-				asset.Invoice.ProductAndPackaging = []models.ProductAndPackaging{}
-			}
-		} else {
-			// If the record is deleted, initialize asset to an empty array
-			asset.Invoice.ProductAndPackaging = []models.ProductAndPackaging{}
+		amended := *input.FormE
+		amended.Id = asset.Id
+		amended.DocType = asset.DocType
+		amended.ReferenceNo = asset.ReferenceNo
+		amended.PreviousReferenceNumber = asset.PreviousReferenceNumber
+		amended.Owner = asset.Owner
+		amended.OrgName = asset.OrgName
+		amended.CreatedById = asset.CreatedById
+		amended.CreatedAt = asset.CreatedAt
+		amended.Status = asset.Status
+		amended.StatusHistory = asset.StatusHistory
+		amended.IssuedAt = ""
+		amended.ReplacedByReferenceNumber = ""
+		amended.CancelReason = ""
+		if err := assertFormEContent(ctx, asset.Id, &amended); err != nil {
+			return err
 		}
+		asset = &amended
+	}
 
-		var formEAssets []*models.TransactionFormE
-		formEAssets = append(formEAssets, &asset)
-
-		// Convert the timestamp to string in the desired format
-		timestampStr := time.Unix(formE.Timestamp.Seconds, int64(formE.Timestamp.Nanos)).Format(utils.TIMEFORMAT)
-
-		historyFormE := &models.FormETransactionHistory{
-			TxId:      formE.TxId,
-			Value:     formEAssets,
-			Timestamp: timestampStr,
-			IsDelete:  formE.IsDelete,
-		}
+	if err := applyFormETransition(ctx, asset, to, input.Reason); err != nil {
+		return err
+	}
 
-		formEHistory = append(formEHistory, historyFormE)
+	switch to {
+	case models.FormEIssued:
+		asset.IssuedAt = asset.UpdatedAt
+	case models.FormECancelled:
+		asset.CancelReason = input.Reason
 	}
 
-	return formEHistory, nil
+	return putFormE(ctx, asset)
 }
 
-func (s *SmartContract) CancelFormE(ctx contractapi.TransactionContextInterface,
-	args string) error {
-	entityType := models.TransactionFormE{}
-	inputInterface, err := utils.Unmarshal(args, entityType)
-	utils.HandleError(err)
-	input := inputInterface.(*models.TransactionFormE)
+// readFormETransition reads the transition input in args and the Form E it
+// moves, checking that a reason is given where to requires one.
+func (s *SmartContract) readFormETransition(ctx contractapi.TransactionContextInterface, args string, to models.FormEStatus) (*models.FormETransitionInput, *models.TransactionFormE, error) {
+	var input models.FormETransitionInput
+	if err := utils.DecodeInput(args, &input); err != nil {
+		if to != models.FormECancelled {
+			return nil, nil, err
+		}
+		// CancelFormE used to take the whole Form E, of which only the id
+		// and the cancel reason are read.
+		var legacy models.TransactionFormE
+		if utils.DecodeStrict(args, &legacy) != nil || legacy.Id == "" {
+			return nil, nil, err
+		}
+		input = models.FormETransitionInput{Id: legacy.Id, CancelReason: legacy.CancelReason}
+	}
+	if input.Reason == "" {
+		input.Reason = input.CancelReason
+	}
 
 	asset, err := s.ReadFormE(ctx, input.Id)
-	utils.HandleError(err)
-
-	asset.Id = input.Id
-	asset.CancelReason = input.CancelReason
-	asset.UpdatedAt = input.UpdatedAt
-	asset.Status = "2"
+	if err != nil {
+		return nil, nil, err
+	}
 
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
+	if utils.FormEReasonRequired[to] && input.Reason == "" {
+		return nil, nil, utils.Validation("a reason is required to move form E %s to %s", input.Id, to)
+	}
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return &input, asset, nil
 }
 
-func (s *SmartContract) GetFormEByReferenceId(ctx contractapi.TransactionContextInterface, referenceId string) (*models.TransactionFormE, error) {
-	queryFormE := fmt.Sprintf(`{"selector":{"referenceNo":"%s", "docType": "formE"}}`, referenceId)
+// applyFormETransition moves asset to status to on behalf of the caller and
+// records who made the change and when.
+func applyFormETransition(ctx contractapi.TransactionContextInterface, asset *models.TransactionFormE, to models.FormEStatus, reason string) error {
+	if err := utils.AssertFormETransition(ctx, asset, to); err != nil {
+		return err
+	}
 
-	resultsFormE, err := ctx.GetStub().GetQueryResult(queryFormE)
+	clientID, err := utils.GetIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	orgName, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return nil, fmt.Errorf("error querying chaincode: %v", err)
+		return fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	changedAt, err := utils.GenerateTimestamp(ctx)
+	if err != nil {
+		return err
 	}
-	defer resultsFormE.Close()
 
-	if !resultsFormE.HasNext() {
-		return &models.TransactionFormE{}, nil
-	} else {
-		queryResponse, err := resultsFormE.Next()
-		if err != nil {
-			return nil, fmt.Errorf("error getting next query result: %v", err)
-		}
-	
-		var asset models.TransactionFormE
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, fmt.Errorf("error unmarshalling asset JSON: %v", err)
-		}
+	asset.StatusHistory = append(asset.StatusHistory, models.FormEStatusChange{
+		From:      asset.Status,
+		To:        to,
+		ChangedBy: clientID,
+		OrgName:   orgName,
+		Reason:    reason,
+		ChangedAt: changedAt,
+	})
+	asset.Status = to
+	asset.UpdatedAt = changedAt
+
+	return nil
+}
 
-		return &asset, nil
+// assertFormEContent fails when the content of formE, to be stored under id,
+// does not match the ledger.
+func assertFormEContent(ctx contractapi.TransactionContextInterface, id string, formE *models.TransactionFormE) error {
+	discrepancies, err := utils.ValidateFormEContent(ctx, formE)
+	if err != nil {
+		return err
+	}
+	return utils.FormEContentError(id, discrepancies)
+}
 
+func putFormE(ctx contractapi.TransactionContextInterface, formE *models.TransactionFormE) error {
+	return formERepository.Put(ctx, formE)
+}
+
+func (s *SmartContract) GetFormEByReferenceId(ctx contractapi.TransactionContextInterface, referenceId string) (*models.TransactionFormE, error) {
+	var asset models.TransactionFormE
+	if _, err := utils.FormEReferenceNoIndex.Get(ctx, referenceId, &asset); err != nil {
+		return nil, err
 	}
+	return &asset, nil
 }
 
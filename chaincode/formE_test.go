@@ -0,0 +1,486 @@
+package chaincode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// seedOwnedExporters creates each of exporterIds not created yet and hands
+// it over to exporterUser, who files the form Es of the tests.
+func seedOwnedExporters(t *testing.T, s *SmartContract, ctx *mocks.MockContext, exporterIds ...string) {
+	t.Helper()
+	for _, id := range exporterIds {
+		id := id
+		if _, err := s.ReadExporter(ctx, id); err == nil {
+			continue
+		}
+		mustSubmit(t, ctx, "CreateExporter", func() error {
+			_, err := s.CreateExporter(ctx, `{"id":"`+id+`"}`)
+			return err
+		})
+		mustSubmit(t, ctx, "TransferAsset", func() error {
+			return s.TransferAsset(ctx, id, exporterUser.ID)
+		})
+	}
+}
+
+// seedExporterPlantTypes registers a plant type certificate without expiry
+// for each of exporterIds, as a form E of theirs needs one in force.
+func seedExporterPlantTypes(t *testing.T, s *SmartContract, ctx *mocks.MockContext, exporterIds ...string) {
+	t.Helper()
+	for _, id := range exporterIds {
+		id := id
+		seedOwnedExporters(t, s, ctx, id)
+		mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+			return s.CreatePlantTypeCsv(ctx, `[{"id":"T-`+id+`","exporterId":"`+id+`"}]`)
+		})
+	}
+}
+
+func TestFormELifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	seedExporterPlantTypes(t, s, ctx, "E1")
+
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"referenceNo":"REF-1","status":"1","createdById":"E1","invoice":{"invoiceNumber":"INV-1"}}`)
+		return err
+	})
+	if err := submit(exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{}`)
+		return err
+	}); err == nil {
+		t.Fatal("expected duplicate form E to be rejected")
+	}
+
+	formE, err := s.ReadFormE(ctx, "FE1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formE.Id != "FE1" || formE.DocType != models.FormE || formE.Owner != exporterUser.ID || formE.OrgName != "ExporterMSP" {
+		t.Fatalf("unexpected form E %+v", formE)
+	}
+
+	byRef, err := s.GetFormEByReferenceId(ctx, "REF-1")
+	if err != nil || byRef.Id != "FE1" {
+		t.Fatalf("unexpected form E by reference %+v, %v", byRef, err)
+	}
+	missing, err := s.GetFormEByReferenceId(ctx, "REF-9")
+	if err != nil || missing.Id != "" {
+		t.Fatalf("unexpected missing form E %+v, %v", missing, err)
+	}
+
+	packerCtx := ctx.As(packerUser)
+	if err := submit(packerCtx, "CancelFormE", func() error {
+		return s.CancelFormE(packerCtx, `{"id":"FE1","cancelReason":"not mine"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("non-owner cancel: expected access denied, got %v", err)
+	}
+
+	if err := submit(exporterCtx, "CancelFormE", func() error {
+		return s.CancelFormE(exporterCtx, `{"id":"FE1","cancelReason":"wrong invoice","bogus":true}`)
+	}); errorCode(err) != models.ErrValidation {
+		t.Fatalf("unknown field: expected a validation error, got %v", err)
+	}
+	mustSubmit(t, exporterCtx, "CancelFormE", func() error {
+		return s.CancelFormE(exporterCtx, `{"id":"FE1","referenceNo":"REF-1","cancelReason":"wrong invoice","updatedAt":"2030-01-01T00:00:00Z"}`)
+	})
+	formE, _ = s.ReadFormE(ctx, "FE1")
+	if formE.CancelReason != "wrong invoice" || formE.UpdatedAt != ctx.Stub.TxTimestamp.Format(time.RFC3339) {
+		t.Fatalf("cancel not applied: %+v", formE)
+	}
+
+	history, err := s.GetFormEHistoryForKey(ctx, "FE1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Value[0].CancelReason != "" || history[1].Value[0].CancelReason != "wrong invoice" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if history[0].Value[0].Invoice.ProductAndPackaging == nil {
+		t.Fatal("history should always carry a productAndPackaging array")
+	}
+}
+
+func TestQueryFormEWithPagination(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	seedExporterPlantTypes(t, s, ctx, "E1", "E2")
+
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[{"id":"H1","hscode":"0810.60"}]`)
+	})
+	mustSubmit(t, exporterCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(exporterCtx, `[
+			{"id":"B1","containerId":"CONT-1","palletNumber":"PAL-1","createdById":"E1"},
+			{"id":"B2","containerId":"CONT-2","palletNumber":"PAL-2","createdById":"E1"}
+		]`)
+	})
+
+	for _, tc := range []struct{ id, args string }{
+		{"FE1", `{"referenceNo":"REF-1","status":"1","requestType":"new","createdById":"E1",
+			"invoice":{"invoiceNumber":"INV-1","exportNumber":"EX-1","productAndPackaging":[{"hscode":"0810.60","containerNumber":"CONT-1","palletNumber":"PAL-1"}]}}`},
+		{"FE2", `{"referenceNo":"REF-2","status":"1","requestType":"renew","createdById":"E1",
+			"invoice":{"invoiceNumber":"INV-2","exportNumber":"EX-2","productAndPackaging":[{"hscode":"0810.60","containerNumber":"CONT-2","palletNumber":"PAL-2"}]}}`},
+		{"FE3", `{"referenceNo":"REF-3","status":"0","requestType":"new","createdById":"E2",
+			"invoice":{"invoiceNumber":"CONT-1","exportNumber":"EX-3"}}`},
+	} {
+		tc := tc
+		mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+			_, err := s.CreateFormE(exporterCtx, tc.id, tc.args)
+			return err
+		})
+		ctx.Stub.Advance(24 * time.Hour)
+	}
+
+	tests := []struct {
+		name    string
+		filter  models.FormEFilterParams
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FormEFilterParams{}, []string{"FE3", "FE2", "FE1"}, 3},
+		{"skip and limit", models.FormEFilterParams{Pagination: models.Pagination{Skip: 1, Limit: 1}}, []string{"FE2"}, 3},
+		{"creator", models.FormEFilterParams{CreatedById: "E1"}, []string{"FE2", "FE1"}, 2},
+		{"reference", models.FormEFilterParams{ReferenceNo: "REF-2"}, []string{"FE2"}, 1},
+		{"export number", models.FormEFilterParams{ExportNumber: "EX-3"}, []string{"FE3"}, 1},
+		{"status and type", models.FormEFilterParams{Status: "1", RequestType: "new"}, []string{"FE1"}, 1},
+		{"search container or invoice", models.FormEFilterParams{Search: "CONT-1"}, []string{"FE3", "FE1"}, 2},
+		{"search pallet", models.FormEFilterParams{Search: "PAL-2"}, []string{"FE2"}, 1},
+		{"date range", models.FormEFilterParams{StartDate: strPtr("02-01-2024"), EndDate: strPtr("03-01-2024")}, []string{"FE3", "FE2"}, 2},
+		{"no match", models.FormEFilterParams{Search: "nothing"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.QueryFormEWithPagination(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, f := range res.Data {
+				ids = append(ids, f.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+
+	_, err := s.QueryFormEWithPagination(ctx, toJSON(t, models.FormEFilterParams{StartDate: strPtr("2024/01/02")}))
+	if errorCode(err) != models.ErrValidation {
+		t.Fatalf("unparsable start date: expected VALIDATION, got %v", err)
+	}
+}
+
+func TestCreateFormEChecksExporterOwner(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	seedExporterPlantTypes(t, s, ctx, "E1")
+	mustSubmit(t, ctx, "CreateExporter", func() error {
+		_, err := s.CreateExporter(ctx, `{"id":"E2"}`)
+		return err
+	})
+	mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[{"id":"T-E2","exporterId":"E2"}]`)
+	})
+
+	if err := submit(exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"office":"BKK","createdById":"E2"}`)
+		return err
+	}); !isAccessDenied(err) {
+		t.Fatalf("form E of another exporter: expected access denied, got %v", err)
+	}
+	mustSubmit(t, ctx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(ctx, "FE1", `{"office":"BKK","createdById":"E2"}`)
+		return err
+	})
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE2", `{"office":"BKK","createdById":"E1"}`)
+		return err
+	})
+}
+
+func TestCreateFormEChecksPlantTypes(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	seedOwnedExporters(t, s, ctx, "E1", "E2", "E3")
+
+	mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[
+			{"id":"T1","exporterId":"E1","expiredDate":"2023-06-01T00:00:00Z"},
+			{"id":"T2","exporterId":"E2","expiredDate":"2023-06-01T00:00:00Z"},
+			{"id":"T3","exporterId":"E2","expiredDate":"2025-06-01T00:00:00Z"},
+			{"id":"T4","exporterId":"E3","issueDate":"2024-06-01T00:00:00Z"}
+		]`)
+	})
+
+	tests := []struct {
+		name        string
+		createdById string
+		ok          bool
+	}{
+		{"all plant types expired", "E1", false},
+		{"one plant type in force", "E2", true},
+		{"plant type not yet issued", "E3", false},
+		{"no plant types registered", "E9", false},
+		{"no exporter", "", false},
+	}
+	for i, tt := range tests {
+		id := fmt.Sprintf("FE%d", i)
+		t.Run(tt.name, func(t *testing.T) {
+			err := submit(exporterCtx, "CreateFormE", func() error {
+				_, err := s.CreateFormE(exporterCtx, id, `{"office":"BKK","createdById":"`+tt.createdById+`"}`)
+				return err
+			})
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected form E to be rejected")
+			}
+		})
+	}
+}
+
+func TestFormEWorkflow(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	packerCtx := ctx.As(packerUser)
+	regulatorCtx := ctx.As(regulatorUser)
+	seedExporterPlantTypes(t, s, ctx, "E1")
+
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"referenceNo":"REF-1","createdById":"E1","invoice":{"invoiceNumber":"INV-1"}}`)
+		return err
+	})
+	if err := submit(exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE2", `{"referenceNo":"REF-2","status":"4","createdById":"E1"}`)
+		return err
+	}); err == nil {
+		t.Fatal("expected form E created as issued to be rejected")
+	}
+
+	steps := []struct {
+		name   string
+		ctx    *mocks.MockContext
+		fn     string
+		args   string
+		status models.FormEStatus
+		denied bool
+		fails  bool
+	}{
+		{"review a draft", regulatorCtx, "ReviewFormE", `{"id":"FE1"}`, "", false, true},
+		{"packer submits exporter's form E", packerCtx, "SubmitFormE", `{"id":"FE1"}`, "", true, false},
+		{"exporter submits", exporterCtx, "SubmitFormE", `{"id":"FE1"}`, models.FormESubmitted, false, false},
+		{"exporter reviews", exporterCtx, "ReviewFormE", `{"id":"FE1"}`, "", true, false},
+		{"regulator reviews", regulatorCtx, "ReviewFormE", `{"id":"FE1"}`, models.FormEUnderReview, false, false},
+		{"reject without reason", regulatorCtx, "RejectFormE", `{"id":"FE1"}`, "", false, true},
+		{"regulator rejects", regulatorCtx, "RejectFormE", `{"id":"FE1","reason":"missing invoice date"}`, models.FormERejected, false, false},
+		{"amend without form E", exporterCtx, "AmendFormE", `{"id":"FE1","reason":"add date"}`, "", false, true},
+		{"exporter amends", exporterCtx, "AmendFormE", `{"id":"FE1","reason":"add date","formE":{"id":"X","referenceNo":"REF-X","owner":"x","invoice":{"invoiceNumber":"INV-1","invoiceDate":"2024-01-01"}}}`, models.FormEAmended, false, false},
+		{"exporter resubmits", exporterCtx, "SubmitFormE", `{"id":"FE1"}`, models.FormESubmitted, false, false},
+		{"regulator reviews again", regulatorCtx, "ReviewFormE", `{"id":"FE1"}`, models.FormEUnderReview, false, false},
+		{"regulator issues", regulatorCtx, "IssueFormE", `{"id":"FE1"}`, models.FormEIssued, false, false},
+		{"exporter cancels issued", exporterCtx, "CancelFormE", `{"id":"FE1","reason":"oops"}`, "", true, false},
+	}
+	for _, step := range steps {
+		err := submit(step.ctx, step.fn, func() error {
+			switch step.fn {
+			case "SubmitFormE":
+				return s.SubmitFormE(step.ctx, step.args)
+			case "ReviewFormE":
+				return s.ReviewFormE(step.ctx, step.args)
+			case "IssueFormE":
+				return s.IssueFormE(step.ctx, step.args)
+			case "RejectFormE":
+				return s.RejectFormE(step.ctx, step.args)
+			case "AmendFormE":
+				return s.AmendFormE(step.ctx, step.args)
+			case "CancelFormE":
+				return s.CancelFormE(step.ctx, step.args)
+			}
+			return fmt.Errorf("unknown function %s", step.fn)
+		})
+		switch {
+		case step.denied:
+			if !isAccessDenied(err) {
+				t.Fatalf("%s: expected access denied, got %v", step.name, err)
+			}
+		case step.fails:
+			if err == nil || isAccessDenied(err) {
+				t.Fatalf("%s: expected error, got %v", step.name, err)
+			}
+		case err != nil:
+			t.Fatalf("%s: %v", step.name, err)
+		default:
+			formE, _ := s.ReadFormE(ctx, "FE1")
+			if formE.Status != step.status {
+				t.Fatalf("%s: status = %s, want %s", step.name, formE.Status, step.status)
+			}
+		}
+	}
+
+	formE, _ := s.ReadFormE(ctx, "FE1")
+	if formE.Id != "FE1" || formE.ReferenceNo != "REF-1" || formE.Owner != exporterUser.ID || formE.Invoice.InvoiceDate != "2024-01-01" {
+		t.Fatalf("amendment changed identity or was lost: %+v", formE)
+	}
+	if formE.IssuedAt != formE.UpdatedAt || len(formE.StatusHistory) != 7 {
+		t.Fatalf("unexpected issued form E %+v", formE)
+	}
+	if last := formE.StatusHistory[len(formE.StatusHistory)-1]; last.From != models.FormEUnderReview || last.ChangedBy != regulatorUser.ID {
+		t.Fatalf("unexpected last status change %+v", last)
+	}
+	if formE.StatusHistory[2].Reason != "missing invoice date" {
+		t.Fatalf("rejection reason not recorded %+v", formE.StatusHistory[2])
+	}
+
+	for _, args := range []string{
+		`{"id":"FE1","newId":"FE2","formE":{"referenceNo":"REF-2"}}`,
+		`{"id":"FE1","reason":"wrong weight","newId":"FE2","formE":{"referenceNo":"REF-1"}}`,
+		`{"id":"FE1","reason":"wrong weight","newId":"FE1","formE":{"referenceNo":"REF-2"}}`,
+	} {
+		if err := submit(exporterCtx, "ReplaceFormE", func() error {
+			return s.ReplaceFormE(exporterCtx, args)
+		}); err == nil {
+			t.Fatalf("expected replacement %s to be rejected", args)
+		}
+	}
+	mustSubmit(t, exporterCtx, "ReplaceFormE", func() error {
+		return s.ReplaceFormE(exporterCtx, `{"id":"FE1","reason":"wrong weight","formE":{"office":"BKK","createdById":"E9","status":"4"}}`)
+	})
+
+	old, _ := s.ReadFormE(ctx, "FE1")
+	replacement, _ := s.GetFormEByReferenceId(ctx, old.ReplacedByReferenceNumber)
+	if old.Status != models.FormEReplaced || replacement.Id == "" || replacement.Id == "FE1" || replacement.ReferenceNo == "REF-1" {
+		t.Fatalf("unexpected replaced form E %+v", old)
+	}
+	if replacement.Status != models.FormESubmitted || replacement.PreviousReferenceNumber != "REF-1" || replacement.Owner != exporterUser.ID || replacement.DocType != models.FormE || replacement.CreatedById != "E1" {
+		t.Fatalf("unexpected replacement form E %+v", replacement)
+	}
+
+	if err := submit(regulatorCtx, "CancelFormE", func() error {
+		return s.CancelFormE(regulatorCtx, `{"id":"FE1","reason":"replaced"}`)
+	}); err == nil {
+		t.Fatal("expected replaced form E to be final")
+	}
+}
+
+func TestValidateFormE(t *testing.T) {
+	s, ctx := newTestContract()
+	packerCtx := ctx.As(packerUser)
+	exporterCtx := ctx.As(exporterUser)
+	seedExporterPlantTypes(t, s, ctx, "E1")
+
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[{"id":"H1","hscode":"0810.60"}]`)
+	})
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G1","certId":"GAP-1","farmerId":"F1","areaRai":4}]`)
+	})
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[{"id":"M1","packingHouseRegisterNumber":"PH-1"}]`)
+	})
+	for _, packing := range []string{
+		`{"id":"P1","gap":"GAP-1","gmp":"PH-1","farmerId":"F1","actualWeight":10,"processStatus":1}`,
+		`{"id":"P2","gap":"GAP-1","gmp":"PH-1","farmerId":"F1","actualWeight":5,"processStatus":1}`,
+		`{"id":"P3","gap":"GAP-1","gmp":"PH-1","farmerId":"F1","actualWeight":1,"processStatus":1}`,
+	} {
+		mustSubmit(t, packerCtx, "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, packing)
+		})
+	}
+	for _, id := range []string{"P1", "P3"} {
+		mustSubmit(t, ctx, "ApprovePacking", func() error {
+			return s.ApprovePacking(ctx, `{"id":"`+id+`"}`)
+		})
+	}
+	mustSubmit(t, ctx, "CompletePacking", func() error {
+		return s.CompletePacking(ctx, `{"id":"P1","finalWeight":8}`)
+	})
+	mustSubmit(t, ctx, "CompletePacking", func() error {
+		return s.CompletePacking(ctx, `{"id":"P3","finalWeight":1}`)
+	})
+	for _, row := range []string{
+		`{"id":"B9","containerId":"C3","gap":"GAP-1","gmp":"PH-1","packingId":"P9","createdById":"E1"}`,
+		`{"id":"B9","containerId":"C3","gap":"GAP-1","packingId":"P1","createdById":"E1"}`,
+	} {
+		if err := submit(exporterCtx, "CreatePackagingCsv", func() error {
+			return s.CreatePackagingCsv(exporterCtx, "["+row+"]")
+		}); err == nil {
+			t.Fatalf("expected packaging %s to be rejected", row)
+		}
+	}
+	// B1 and B2 predate the packing order link; B4 was packed from P1 only.
+	mustSubmit(t, exporterCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(exporterCtx, `[
+			{"id":"B1","containerId":"C1","palletNumber":"PL-1","gap":"GAP-1","gmp":"PH-1","createdById":"E1"},
+			{"id":"B2","containerId":"C1","palletNumber":"PL-2","gap":"GAP-1","gmp":"PH-1","createdById":"E1"},
+			{"id":"B3","containerId":"C2","palletNumber":"PL-1","createdById":"E2"},
+			{"id":"B4","containerId":"C3","gap":"GAP-1","gmp":"PH-1","packingId":"P1","createdById":"E1"}
+		]`)
+	})
+
+	tests := []struct {
+		name   string
+		formE  string
+		fields []string
+	}{
+		{"valid", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"9","productAndPackaging":[
+			{"hscode":"0810.60","containerNumber":"C1","palletNumber":"PL-1"},
+			{"hscode":"0810.60","containerNumber":"C1","palletNumber":"PL-2"}]}}`, nil},
+		{"whole container", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"7.5","productAndPackaging":[
+			{"hscode":"0810.60","containerNumber":"C1"}]}}`, nil},
+		{"no invoice", `{"office":"BKK","createdById":"E1"}`, nil},
+		{"every discrepancy", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"8.5","productAndPackaging":[
+			{"hscode":"9999.99","containerNumber":"C1","palletNumber":"PL-9"},
+			{"containerNumber":"C2"},
+			{"hscode":"0810.60"}]}}`, []string{
+			"invoice.productAndPackaging[0].hscode",
+			"invoice.productAndPackaging[0].palletNumber",
+			"invoice.productAndPackaging[1].hscode",
+			"invoice.productAndPackaging[1].containerNumber",
+			"invoice.productAndPackaging[2].containerNumber",
+			"invoice.totalWeight",
+		}},
+		{"over completed weight", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"9.5","productAndPackaging":[
+			{"hscode":"0810.60","containerNumber":"C1","palletNumber":"PL-1"}]}}`, []string{"invoice.totalWeight"}},
+		{"linked packing order", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"8","productAndPackaging":[
+			{"hscode":"0810.60","containerNumber":"C3"}]}}`, nil},
+		{"over linked packing order", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"8.5","productAndPackaging":[
+			{"hscode":"0810.60","containerNumber":"C3"}]}}`, []string{"invoice.totalWeight"}},
+		{"weight not a number", `{"office":"BKK","createdById":"E1","invoice":{"totalWeight":"lots"}}`, []string{"invoice.totalWeight"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.ValidateFormE(exporterCtx, tt.formE)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			for _, d := range res.Discrepancies {
+				fields = append(fields, d.Field)
+			}
+			assertIDs(t, fields, tt.fields...)
+			if res.Valid != (len(tt.fields) == 0) {
+				t.Errorf("valid = %v with discrepancies %+v", res.Valid, res.Discrepancies)
+			}
+
+			err = submit(exporterCtx, "CreateFormE", func() error {
+				_, err := s.CreateFormE(exporterCtx, fmt.Sprintf("FE%d", i), tt.formE)
+				return err
+			})
+			if res.Valid && err != nil {
+				t.Fatalf("valid form E rejected: %v", err)
+			}
+			if !res.Valid && err == nil {
+				t.Fatal("expected invalid form E to be rejected")
+			}
+		})
+	}
+}
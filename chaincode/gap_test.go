@@ -0,0 +1,389 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func seedGaps(t *testing.T, s *SmartContract, ctx *mocks.MockContext) {
+	t.Helper()
+	seedDaily(t, ctx, "CreateGapCsv", func(args string) error {
+		return s.CreateGapCsv(ctx, args)
+	},
+		`[{"id":"G1","certId":"GAP-001","displayCertId":"D-001","areaRai":5,"province":"Chiang Mai","farmerId":"F1","expireDate":"2025-01-01T00:00:00Z"}]`,
+		`[{"id":"G2","certId":"GAP-002","displayCertId":"D-002","areaRai":12,"province":"Chiang Mai","farmerId":"","expireDate":"2026-01-01T00:00:00Z"}]`,
+		`[{"id":"G3","certId":"GAP-003","displayCertId":"X-003","areaRai":20,"province":"Lampang","farmerId":"F2","expireDate":"2027-01-01T00:00:00Z"}]`,
+	)
+}
+
+func TestGapCrud(t *testing.T) {
+	s, ctx := newTestContract()
+	regulatorCtx := ctx.As(regulatorUser)
+
+	mustSubmit(t, regulatorCtx, "CreateGAP", func() error {
+		return s.CreateGAP(regulatorCtx, `{"id":"G1","certId":"GAP-001","areaRai":3.5}`)
+	})
+
+	gap, err := s.ReadGap(ctx, "G1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gap.Owner != regulatorUser.ID || gap.AreaRai != 3.5 || !gap.IsCanDelete {
+		t.Fatalf("unexpected gap %+v", gap)
+	}
+
+	farmerCtx := ctx.As(farmerUser)
+	if err := submit(farmerCtx, "UpdateGap", func() error {
+		return s.UpdateGap(farmerCtx, `{"id":"G1","certId":"GAP-001","areaRai":99}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("farmer update gap: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, regulatorCtx, "UpdateGap", func() error {
+		return s.UpdateGap(regulatorCtx, `{"id":"G1","certId":"GAP-001","areaRai":4,"province":"Nan"}`)
+	})
+	gap, _ = s.ReadGap(ctx, "G1")
+	if gap.AreaRai != 4 || gap.Province != "Nan" {
+		t.Fatalf("update not applied: %+v", gap)
+	}
+
+	mustSubmit(t, regulatorCtx, "UpdateMultipleGap", func() error {
+		return s.UpdateMultipleGap(regulatorCtx, `[{"id":"G1","certId":"GAP-001","areaRai":6}]`)
+	})
+	gap, _ = s.ReadGap(ctx, "G1")
+	if gap.AreaRai != 6 {
+		t.Fatalf("multiple update not applied: %+v", gap)
+	}
+
+	if err := submit(regulatorCtx, "UpdateMultipleGap", func() error {
+		return s.UpdateMultipleGap(regulatorCtx, `[{"id":"missing"}]`)
+	}); err == nil {
+		t.Fatal("expected update of missing gap to fail")
+	}
+}
+
+func TestDeleteGapDetachesFarmer(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(ctx, `{"id":"F1","farmerGaps":[{"certId":"GAP-001"},{"certId":"GAP-009"}]}`)
+		return err
+	})
+	seedGaps(t, s, ctx)
+	mustSubmit(t, ctx, "CreateGAP", func() error {
+		return s.CreateGAP(ctx, `{"id":"G9","certId":"GAP-009","farmerId":"F1"}`)
+	})
+
+	mustSubmit(t, ctx, "DeleteGap", func() error {
+		return s.DeleteGap(ctx, "G1", "duplicate record")
+	})
+
+	if _, err := s.ReadGap(ctx, "G1"); err == nil {
+		t.Fatal("expected gap to be deleted")
+	}
+
+	raw, _ := ctx.Stub.GetState("F1")
+	var farmer models.TransactionFarmer
+	if err := json.Unmarshal(raw, &farmer); err != nil {
+		t.Fatal(err)
+	}
+	if len(farmer.FarmerGaps) != 1 || farmer.FarmerGaps[0].CertID != "GAP-009" {
+		t.Fatalf("unexpected farmer gaps %+v", farmer.FarmerGaps)
+	}
+}
+
+func TestDeleteGapWithPackings(t *testing.T) {
+	s, ctx := newTestContract()
+	seedGaps(t, s, ctx)
+
+	packerCtx := ctx.As(packerUser)
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","gap":"GAP-001"}`)
+	})
+	if gap, _ := s.ReadGap(ctx, "G1"); gap.IsCanDelete {
+		t.Fatal("gap with packings should not be deletable")
+	}
+
+	var refErr *utils.ReferenceError
+	if err := submit(ctx, "DeleteGap", func() error {
+		return s.DeleteGap(ctx, "G1", "duplicate record")
+	}); !errors.As(err, &refErr) || refErr.References[0] != (models.Reference{DocType: models.Packing, Id: "P1", Field: "gap"}) {
+		t.Fatalf("expected delete to be blocked by P1, got %v", err)
+	}
+	if _, err := s.ReadGap(ctx, "G1"); err != nil {
+		t.Fatalf("refused delete removed the gap: %v", err)
+	}
+
+	if err := submit(ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G1","certId":"GAP-101","farmerId":"F1"}`)
+	}); !errors.As(err, &refErr) {
+		t.Fatalf("expected certId change to be blocked by P1, got %v", err)
+	}
+	mustSubmit(t, ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G1","certId":"GAP-001","areaRai":7,"farmerId":"F1"}`)
+	})
+
+	exporterCtx := ctx.As(exporterUser)
+	mustSubmit(t, exporterCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(exporterCtx, `[{"id":"B1","boxId":"BOX-1","gap":"GAP-002","createdById":"E1"}]`)
+	})
+	if gap, _ := s.ReadGap(ctx, "G2"); gap.IsCanDelete {
+		t.Fatal("gap with packagings should not be deletable")
+	}
+	if err := submit(ctx, "UpdateMultipleGap", func() error {
+		return s.UpdateMultipleGap(ctx, `[{"id":"G2","certId":"GAP-102"}]`)
+	}); !errors.As(err, &refErr) || refErr.References[0] != (models.Reference{DocType: models.Packaging, Id: "B1", Field: "gap"}) {
+		t.Fatalf("expected certId change to be blocked by B1, got %v", err)
+	}
+}
+
+func TestGapLookups(t *testing.T) {
+	s, ctx := newTestContract()
+	seedGaps(t, s, ctx)
+
+	byCert, err := s.GetGapByCertID(ctx, "GAP-002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byCert.Obj == nil || byCert.Obj.Id != "G2" {
+		t.Fatalf("unexpected gap by cert %+v", byCert)
+	}
+
+	missing, err := s.GetGapByCertID(ctx, "nope")
+	if err != nil || missing.Obj != nil || missing.Data != "Not found gap by certID" {
+		t.Fatalf("unexpected missing result %+v, %v", missing, err)
+	}
+
+	injected, err := s.GetGapByCertID(ctx, `nope","certId":{"$gt":""},"x":"`)
+	if err != nil || injected.Obj != nil {
+		t.Fatalf("cert id should be matched literally, got %+v, %v", injected, err)
+	}
+
+	byFarmer, err := s.GetGapByFarmerID(ctx, "F2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byFarmer.Obj == nil || byFarmer.Obj.Id != "G3" {
+		t.Fatalf("unexpected gap by farmer %+v", byFarmer)
+	}
+
+	filtered, err := s.FilterGap(ctx, "province", "Chiang Mai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 2 {
+		t.Fatalf("expected 2 gaps, got %d", len(filtered))
+	}
+}
+
+func TestGetAllGAP(t *testing.T) {
+	s, ctx := newTestContract()
+	seedGaps(t, s, ctx)
+
+	packerCtx := ctx.As(packerUser)
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","gap":"GAP-003","actualWeight":7,"processStatus":1}`)
+	})
+	advancePacking(t, s, ctx, "P1", models.StatusApproved)
+
+	from, to := float32(10), float32(15)
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllGap
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FilterGetAllGap{}, []string{"G3", "G2", "G1"}, 3},
+		{"limit", models.FilterGetAllGap{Pagination: models.Pagination{Limit: 1}}, []string{"G3"}, 3},
+		{"skip", models.FilterGetAllGap{Pagination: models.Pagination{Skip: 1, Limit: 5}}, []string{"G2", "G1"}, 3},
+		{"province", models.FilterGetAllGap{Province: strPtr("Lampang")}, []string{"G3"}, 1},
+		{"area range", models.FilterGetAllGap{AreaRaiFrom: &from, AreaRaiTo: &to}, []string{"G2"}, 1},
+		{"area from", models.FilterGetAllGap{AreaRaiFrom: &from}, []string{"G3", "G2"}, 2},
+		{"available", models.FilterGetAllGap{AvailableGap: strPtr("true")}, []string{"G2"}, 1},
+		{"cert search", models.FilterGetAllGap{CertID: strPtr("X-")}, []string{"G3"}, 1},
+		{"cert search is literal", models.FilterGetAllGap{CertID: strPtr("GAP-00.")}, nil, 0},
+		{"cert list", models.FilterGetAllGap{Gaps: []string{"GAP-001", "GAP-003"}}, []string{"G3", "G1"}, 2},
+		{"created range", models.FilterGetAllGap{CreatedAtFrom: strPtr("02-01-2024"), CreatedAtTo: strPtr("02-01-2024")}, []string{"G2"}, 1},
+		{"expire to", models.FilterGetAllGap{ExpireDateTo: strPtr("31-12-2025")}, []string{"G1"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllGAP(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, g := range res.Obj {
+				ids = append(ids, g.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+			for _, g := range res.Obj {
+				if g.Id == "G3" && (g.IsCanDelete || g.TotalSold != 7) {
+					t.Errorf("G3 should have sales: %+v", g)
+				}
+			}
+		})
+	}
+}
+
+func TestGapQuota(t *testing.T) {
+	s, ctx := newTestContract()
+	packerCtx := ctx.As(packerUser)
+
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[
+			{"id":"G1","certId":"GAP-001","areaRai":2,"plantType":"durian"},
+			{"id":"G2","certId":"GAP-002","areaRai":2,"plantType":"mango"}
+		]`)
+	})
+
+	regulatorCtx := ctx.As(regulatorUser)
+	if err := submit(regulatorCtx, "SetGapYieldConfig", func() error {
+		return s.SetGapYieldConfig(regulatorCtx, `{"defaultYieldPerRai":1}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator set yield: expected access denied, got %v", err)
+	}
+	if err := submit(ctx, "SetGapYieldConfig", func() error {
+		return s.SetGapYieldConfig(ctx, `{"yieldPerRai":{"durian":-1}}`)
+	}); err == nil {
+		t.Fatal("expected negative yield to be rejected")
+	}
+
+	// Without a yield the quota is reported but not enforced.
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P0","gap":"GAP-002","forecastWeight":5000}`)
+	})
+
+	mustSubmit(t, ctx, "SetGapYieldConfig", func() error {
+		return s.SetGapYieldConfig(ctx, `{"yieldPerRai":{"durian":100}}`)
+	})
+	config, err := s.GetGapYieldConfig(ctx)
+	if err != nil || config.YieldPerRai["durian"] != 100 || config.UpdatedBy != nectecUser.ID {
+		t.Fatalf("unexpected yield config %+v, %v", config, err)
+	}
+
+	for _, args := range []string{
+		`{"id":"P1","gap":"GAP-001","actualWeight":80,"processStatus":1}`,
+		`{"id":"P2","gap":"GAP-001","forecastWeight":50}`,
+		`{"id":"P3","gap":"GAP-001","forecastWeight":30}`,
+	} {
+		args := args
+		mustSubmit(t, packerCtx, "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, args)
+		})
+	}
+	advancePacking(t, s, ctx, "P1", models.StatusApproved)
+	mustSubmit(t, packerCtx, "CancelPacking", func() error {
+		return s.CancelPacking(packerCtx, `{"id":"P3","cancelReason":"no stock"}`)
+	})
+
+	quota, err := s.GetGapQuota(ctx, "GAP-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.GapQuota{CertID: "GAP-001", PlantType: "durian", AreaRai: 2, YieldPerRai: 100,
+		Limited: true, Capacity: 200, Sold: 80, Reserved: 50, Remaining: 70}
+	if *quota != want {
+		t.Fatalf("quota = %+v, want %+v", *quota, want)
+	}
+
+	tests := []struct {
+		name     string
+		function string
+		fn       func() error
+		ok       bool
+	}{
+		{"create over quota", "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, `{"id":"P4","gap":"GAP-001","forecastWeight":71}`)
+		}, false},
+		{"create within quota", "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, `{"id":"P4","gap":"GAP-001","forecastWeight":70}`)
+		}, true},
+		{"update over quota", "UpdatePacking", func() error {
+			return s.UpdatePacking(packerCtx, `{"id":"P2","gap":"GAP-001","forecastWeight":51}`)
+		}, false},
+		{"update down", "UpdatePacking", func() error {
+			return s.UpdatePacking(packerCtx, `{"id":"P2","gap":"GAP-001","forecastWeight":40}`)
+		}, true},
+		{"unlimited plant type", "UpdatePacking", func() error {
+			return s.UpdatePacking(packerCtx, `{"id":"P0","gap":"GAP-002","forecastWeight":9000}`)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := submit(packerCtx, tt.function, tt.fn)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected quota to be exceeded")
+			}
+		})
+	}
+
+	quota, _ = s.GetGapQuota(ctx, "GAP-001")
+	if quota.Reserved != 110 || quota.Remaining != 10 {
+		t.Fatalf("unexpected quota after updates %+v", quota)
+	}
+	if quota, _ = s.GetGapQuota(ctx, "GAP-002"); quota.Limited || quota.Reserved != 9000 {
+		t.Fatalf("unexpected unlimited quota %+v", quota)
+	}
+	if _, err := s.GetGapQuota(ctx, "GAP-404"); err == nil {
+		t.Fatal("expected unknown gap to fail")
+	}
+
+	// A completed order takes its final weight from the quota.
+	mustSubmit(t, ctx, "CompletePacking", func() error {
+		return s.CompletePacking(ctx, `{"id":"P1","finalWeight":60}`)
+	})
+	quota, _ = s.GetGapQuota(ctx, "GAP-001")
+	if quota.Sold != 60 || quota.Remaining != 30 {
+		t.Fatalf("unexpected quota after completion %+v", quota)
+	}
+}
+
+func TestGetAllGAPQueries(t *testing.T) {
+	s, ctx := newTestContract()
+	seedGaps(t, s, ctx)
+	ctx.Stub.PutState("P1", []byte(`{"id":"P1","docType":"packing","gap":"GAP-001"}`))
+	ctx.Stub.PutState("B1", []byte(`{"id":"B1","docType":"packaging","gap":"GAP-002"}`))
+	queries := countQueries(ctx)
+
+	list := func() *models.GetAllGapResponse {
+		t.Helper()
+		*queries = 0
+		var res *models.GetAllGapResponse
+		mustSubmit(t, ctx, "GetAllGAP", func() (err error) {
+			res, err = s.GetAllGAP(ctx, `{}`)
+			return err
+		})
+		return res
+	}
+
+	res := list()
+	if *queries != 4 {
+		t.Errorf("3 gaps took %d queries, want 4", *queries)
+	}
+	for _, g := range res.Obj {
+		if want := g.CertID != "GAP-001" && g.CertID != "GAP-002"; g.IsCanDelete != want {
+			t.Errorf("%s: isCanDelete = %v, want %v", g.CertID, g.IsCanDelete, want)
+		}
+	}
+
+	for i := 4; i <= 50; i++ {
+		ctx.Stub.PutState(fmt.Sprintf("G%d", i), []byte(fmt.Sprintf(`{"id":"G%d","docType":"gap","certId":"GAP-%03d","createdAt":"2024-02-01T00:00:00Z"}`, i, i)))
+	}
+	if res := list(); len(res.Obj) != 50 || *queries != 4 {
+		t.Errorf("50 gaps: %d listed in %d queries, want 4", len(res.Obj), *queries)
+	}
+}
@@ -1,192 +1,151 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"fmt"
-	"sort"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var gmpUniqueFields = []utils.UniqueField[models.TransactionGmp]{
+	{Index: utils.GmpRegisterNumberIndex, Value: func(asset *models.TransactionGmp) string { return asset.PackingHouseRegisterNumber }},
+}
+
+var gmpRepository = utils.Repository[models.TransactionGmp]{
+	DocType:  models.Gmp,
+	Id:       func(asset *models.TransactionGmp) string { return asset.Id },
+	Unique:   gmpUniqueFields,
+	Embedded: []utils.Embedding[models.TransactionGmp]{packerGmpCopy},
+}
+
 func (s *SmartContract) CreateGMP(
 	ctx contractapi.TransactionContextInterface,
 	args string,
 ) error {
 	entityGmp := models.TransactionGmp{}
 	inputInterface, err := utils.Unmarshal(args, entityGmp)
-	utils.HandleError(err)
-	input := inputInterface.(*models.TransactionGmp)
-
-	// err := ctx.GetClientIdentity().AssertAttributeValue("gmp.creator", "true")
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have gmp.creator role1")
-	}
-
-	existsGmp, err := utils.AssetExists(ctx, input.Id)
-	utils.HandleError(err)
-	if existsGmp {
-		return fmt.Errorf("the asset %s already exists", input.Id)
+		return err
 	}
-
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
-
-	// timestamp := utils.GenerateTimestamp()
+	input := inputInterface.(*models.TransactionGmp)
 
 	asset := models.TransactionGmp{
 		Id:                         input.Id,
-		PackerId: 									input.PackerId,		
+		PackerId:                   input.PackerId,
 		PackingHouseRegisterNumber: input.PackingHouseRegisterNumber,
 		Address:                    input.Address,
 		PackingHouseName:           input.PackingHouseName,
+		IssueDate:                  input.IssueDate,
+		ExpireDate:                 input.ExpireDate,
 		UpdatedDate:                input.UpdatedDate,
 		Source:                     input.Source,
-		Owner:                      clientID,
-		OrgName:                    orgName,
-		DocType: 					models.Gmp,
-		CreatedAt:  				input.CreatedAt,
-		UpdatedAt:  				input.UpdatedAt,
 	}
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return gmpRepository.Create(ctx, &asset)
 }
 
 func (s *SmartContract) ClearGmpPacker(ctx contractapi.TransactionContextInterface, packerId string) error {
-	queryGmp := fmt.Sprintf(`{
-		"selector": {
-			"docType": "gmp",
-			"packerId": "%s"
-		},
-		"sort": [
-			{"createdAt": "desc"}
-		],
-		"use_index": ["_design/index-packing-gmp-createdAt", "index-packing-gmp-createdAt"]
-	}`, packerId);
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryGmp)
-    if err != nil {
-        return fmt.Errorf("failed to execute query: %v", err)
-    }
-    defer resultsIterator.Close()
-
-	for resultsIterator.HasNext() {
-        queryResult, err := resultsIterator.Next()
-        if err != nil {
-            return fmt.Errorf("failed to get next result: %v", err)
-        }
-
-        var gmp models.TransactionGmp
-        err = json.Unmarshal(queryResult.Value, &gmp)
-        if err != nil {
-            return fmt.Errorf("failed to unmarshal GMP: %v", err)
-        }
+	gmps, err := gmpRepository.Find(ctx, gmpRepository.Selector().
+		Eq("packerId", packerId).
+		Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-packing-gmp-createdAt", "index-packing-gmp-createdAt"))
+	if err != nil {
+		return err
+	}
 
+	for _, gmp := range gmps {
 		gmp.PackerId = ""
+	}
 
-        updatedGmpJSON, err := json.Marshal(gmp)
-        if err != nil {
-            return fmt.Errorf("failed to marshal updated GMP: %v", err)
-        }
-
-        err = ctx.GetStub().PutState(gmp.Id, updatedGmpJSON)
-        if err != nil {
-            return fmt.Errorf("failed to update GMP in world state: %v", err)
-        }
-    }
-
-    return nil
+	return gmpRepository.UpdateAll(ctx, gmps)
 }
 
 func (s *SmartContract) UpdateGmp(ctx contractapi.TransactionContextInterface, args string) error {
 
 	entityGmp := models.TransactionGmp{}
 	inputInterface, err := utils.Unmarshal(args, entityGmp)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 	input := inputInterface.(*models.TransactionGmp)
 
 	asset, err := s.ReadGmp(ctx, input.Id)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	// clientID, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
-	// if clientID != asset.Owner {
-	// 	return utils.ReturnError(utils.UNAUTHORIZE)
-	// }
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
+	}
+
+	if err := setGmpFields(ctx, asset, input); err != nil {
+		return err
+	}
+
+	return gmpRepository.Update(ctx, asset)
+}
 
+// setGmpFields copies the fields a GMP update may change from input. The
+// register number may only change while nothing refers to the GMP by it.
+func setGmpFields(ctx contractapi.TransactionContextInterface, asset *models.TransactionGmp, input *models.TransactionGmp) error {
+	if err := utils.AssertKeyChange(ctx, asset.Id, asset.PackingHouseRegisterNumber, input.PackingHouseRegisterNumber, utils.GmpDependencies); err != nil {
+		return err
+	}
 	asset.PackerId = input.PackerId
 	asset.PackingHouseRegisterNumber = input.PackingHouseRegisterNumber
 	asset.Address = input.Address
 	asset.PackingHouseName = input.PackingHouseName
+	asset.IssueDate = input.IssueDate
+	asset.ExpireDate = input.ExpireDate
 	asset.UpdatedDate = input.UpdatedDate
 	asset.Source = input.Source
-	asset.UpdatedAt = input.UpdatedAt
-
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return fmt.Errorf("[UpdateGmp] failed to marshal asset JSON: %v", err)
-	}
-
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return nil
 }
 
-func (s *SmartContract) DeleteGmp(ctx contractapi.TransactionContextInterface, id string) error {
+func (s *SmartContract) DeleteGmp(ctx contractapi.TransactionContextInterface, id string, reason string) error {
 
 	assetGmp, err := s.ReadGmp(ctx, id)
-	utils.HandleError(err)
-
-	// clientIDGmp, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	// if clientIDGmp != assetGmp.Owner {
-	// 	return utils.ReturnError(utils.UNAUTHORIZE)
-	// }
+	if err := utils.AssertStaffOrOwner(ctx, assetGmp.Owner); err != nil {
+		return err
+	}
+	if err := utils.AssertDeletable(ctx, assetGmp.Id, utils.GmpDependencies(assetGmp.PackingHouseRegisterNumber), false); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().DelState(assetGmp.Id)
+	return gmpRepository.Delete(ctx, assetGmp.Id, reason)
 }
 
-func (s *SmartContract) ReadGmp(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionGmp, error) {
-	assetJSON, err := ctx.GetStub().GetState(id)
+func (s *SmartContract) RestoreGmp(ctx contractapi.TransactionContextInterface, id string) error {
+	assetGmp, err := utils.ReadAssetHeader(ctx, id, models.Gmp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return err
 	}
 
-	var asset models.TransactionGmp
-	err = json.Unmarshal(assetJSON, &asset)
-	if err != nil {
-		return nil, err
+	if err := utils.AssertStaffOrOwner(ctx, assetGmp.Owner); err != nil {
+		return err
 	}
 
-	asset.IsCanDelete = true
-
-	salesQueryString := fmt.Sprintf(`{
-		"selector": {
-			"docType": "packing",
-			"gmp": "%s"
-		}
-	}`, asset.PackingHouseRegisterNumber)
-
-	fmt.Printf("salesQueryString %v", salesQueryString)
+	return gmpRepository.Restore(ctx, id)
+}
 
-	salesResultsIterator, err := ctx.GetStub().GetQueryResult(salesQueryString)
+func (s *SmartContract) ReadGmp(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionGmp, error) {
+	asset, err := gmpRepository.Get(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query related sales: %v", err)
+		return nil, err
 	}
-	defer salesResultsIterator.Close()
 
-	if salesResultsIterator.HasNext() {
-		asset.IsCanDelete = false
+	asset.IsCanDelete, err = utils.CanDelete(ctx, utils.GmpDependencies(asset.PackingHouseRegisterNumber))
+	if err != nil {
+		return nil, err
 	}
 
-	return &asset, nil
+	return asset, nil
 }
 
 func (s *SmartContract) GetAllGMP(ctx contractapi.TransactionContextInterface, args string) (*models.GmpGetAllResponse, error) {
@@ -198,190 +157,95 @@ func (s *SmartContract) GetAllGMP(ctx contractapi.TransactionContextInterface, a
     inputGmp := interfaceGmp.(*models.FilterGetAllGmp)
     filterGmp := utils.GmpSetFilter(inputGmp)
 
-    assets, total, err := utils.GmpFetchResultsWithPagination(ctx, inputGmp, filterGmp)
+    page, err := utils.Paginate[models.GmpTransactionResponse](ctx, filterGmp.Query().
+        Sort("createdAt", "desc").
+        UseIndex("_design/index-CreatedAt", "index-CreatedAt"), inputGmp.Pagination)
     if err != nil {
         return nil, err
     }
+    assets := page.Items
 
+    registerNumbers := make([]string, 0, len(assets))
     for _, asset := range assets {
-        asset.IsCanDelete = true
-
-        salesQueryString := fmt.Sprintf(`{
-            "selector": {
-                "docType": "packing",
-                "gmp": "%s"
-            },
-			"sort": [
-				{"createdAt": "desc"}
-			],
-  			"use_index": ["_design/index-packing-gmp-createdAt", "index-packing-gmp-createdAt"]
-        }`, asset.PackingHouseRegisterNumber)
-
-        salesResultsIterator, err := ctx.GetStub().GetQueryResult(salesQueryString)
-        if err != nil {
-            return nil, fmt.Errorf("failed to query related sales: %v", err)
-        }
-        defer salesResultsIterator.Close()
-
-        if salesResultsIterator.HasNext() {
-            asset.IsCanDelete = false
-        }
+        registerNumbers = append(registerNumbers, asset.PackingHouseRegisterNumber)
     }
-
-    if len(assets) == 0 {
-        assets = []*models.GmpTransactionResponse{}
+    packed, err := utils.ReferencedByAny(ctx, utils.CertificateReferrers, "gmp", registerNumbers)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query related sales: %w", err)
+    }
+    for _, asset := range assets {
+        asset.IsCanDelete = !packed[asset.PackingHouseRegisterNumber]
     }
 
     return &models.GmpGetAllResponse{
-        Data:  "All Gmp",
-        Obj:   assets,
-        Total: total,
+        Data:     "All Gmp",
+        Obj:      assets,
+        Total:    page.Total,
+        PageInfo: page.PageInfo,
     }, nil
 }
 
 func (s *SmartContract) GetGmpByPackingHouseNumber(ctx contractapi.TransactionContextInterface, packingHouseRegisterNumber string) (*models.GetByRegisterNumberResponse, error) {
-	// Get the asset using CertID
-	queryKeyPackingHouse := fmt.Sprintf(`{"selector":{"packingHouseRegisterNumber":"%s"}}`, packingHouseRegisterNumber)
-
-	resultsIteratorPackingHouse, err := ctx.GetStub().GetQueryResult(queryKeyPackingHouse)
 	var asset *models.GmpTransactionResponse
-	resData := "Get gmp by packingHouseRegisterNumber"
+	found, err := utils.GmpRegisterNumberIndex.Get(ctx, packingHouseRegisterNumber, &asset)
 	if err != nil {
-		return nil, fmt.Errorf("error querying chaincode: %v", err)
+		return nil, err
 	}
-	defer resultsIteratorPackingHouse.Close()
 
-	if !resultsIteratorPackingHouse.HasNext() {
+	resData := "Get gmp by packingHouseRegisterNumber"
+	if !found {
 		resData = "Not found gmp by packingHouseRegisterNumber"
-
-		return &models.GetByRegisterNumberResponse{
-			Data: resData,
-			Obj:  asset,
-		}, nil
-	}
-
-	queryResponse, err := resultsIteratorPackingHouse.Next()
-	if err != nil {
-		return nil, fmt.Errorf("error getting next query result: %v", err)
-	}
-
-	err = json.Unmarshal(queryResponse.Value, &asset)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling asset JSON: %v", err)
 	}
 
 	return &models.GetByRegisterNumberResponse{
 		Data: resData,
 		Obj:  asset,
 	}, nil
-
 }
 
 func (s *SmartContract) FilterGmp(ctx contractapi.TransactionContextInterface, key, value string) ([]*models.TransactionGmp, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
-	if err != nil {
-		return nil, err
-	}
-	defer resultsIterator.Close()
-
-	var assetGmp []*models.TransactionGmp
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var asset models.TransactionGmp
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, err
-		}
-
-		var m map[string]interface{}
-		if err := json.Unmarshal(queryResponse.Value, &m); err != nil {
-			return nil, err
-		}
-
-		if val, ok := m[key]; ok && fmt.Sprintf("%v", val) == value {
-			assetGmp = append(assetGmp, &asset)
-		}
-	}
-
-
-	sort.Slice(assetGmp, func(i, j int) bool {
-        t1, err1 := time.Parse(time.RFC3339, assetGmp[i].CreatedAt)
-        t2, err2 := time.Parse(time.RFC3339, assetGmp[j].CreatedAt)
-        if err1 != nil || err2 != nil {
-            fmt.Println("Error parsing time:", err1, err2)
-            return false
-        }
-        return t1.After(t2)
-    })
-
-	return assetGmp, nil
+	return gmpRepository.Filter(ctx, key, value)
 }
 
-func (s *SmartContract) CreateGmpCsv(
-	ctx contractapi.TransactionContextInterface,
-	args string,
-) error {
-	var inputs []models.TransactionGmp
-
-	errInputGmp := json.Unmarshal([]byte(args), &inputs)
-	utils.HandleError(errInputGmp)
-
-	for _, input := range inputs {
-		// err := ctx.GetClientIdentity().AssertAttributeValue("gmp.creator", "true")
-
-		orgNameG, err := ctx.GetClientIdentity().GetMSPID()
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
-		}
-
-		existGmp, err := utils.AssetExists(ctx, input.Id)
-		if err != nil {
-			return fmt.Errorf("error checking if asset exists: %v", err)
-		}
-		if existGmp {
-			return fmt.Errorf("the asset %s already exists", input.Id)
+var gmpImport = utils.BatchImport[models.TransactionGmp]{
+	Function:   "CreateGmpCsv",
+	Repository: gmpRepository,
+	Validate: func(ctx contractapi.TransactionContextInterface, input *models.TransactionGmp) error {
+		if err := utils.RequireField("packingHouseRegisterNumber", input.PackingHouseRegisterNumber); err != nil {
+			return err
 		}
-
-		clientIDG, err := utils.GetIdentity(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's identity: %v", err)
+		if err := utils.CertificateDateField("issueDate", input.IssueDate); err != nil {
+			return err
 		}
-
-		// timestamp := utils.GenerateTimestamp()
-
-		assetG := models.TransactionGmp{
+		return utils.CertificateDateField("expireDate", input.ExpireDate)
+	},
+	Build: func(input *models.TransactionGmp, meta utils.BatchMeta) *models.TransactionGmp {
+		return &models.TransactionGmp{
 			Id:                         input.Id,
-			PackerId: 									input.PackerId,
+			PackerId:                   input.PackerId,
 			PackingHouseRegisterNumber: input.PackingHouseRegisterNumber,
 			Address:                    input.Address,
 			PackingHouseName:           input.PackingHouseName,
+			IssueDate:                  input.IssueDate,
+			ExpireDate:                 input.ExpireDate,
 			UpdatedDate:                input.UpdatedDate,
 			Source:                     input.Source,
-			Owner:                      clientIDG,
-			DocType: 					models.Gmp,
-			OrgName:                    orgNameG,
-			CreatedAt:  				input.CreatedAt,		
-			UpdatedAt:  				input.UpdatedAt,		
-			IsCanDelete: true,
-		}
-		assetJSON, err := json.Marshal(assetG)
-		if err != nil {
-			return fmt.Errorf("failed to marshal asset JSON: %v", err)
-		}
-
-		err = ctx.GetStub().PutState(input.Id, assetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put state for asset %s: %v", input.Id, err)
+			Owner:                      meta.Owner,
+			DocType:                    models.Gmp,
+			OrgName:                    meta.OrgName,
+			CreatedAt:                  meta.Timestamp,
+			UpdatedAt:                  meta.Timestamp,
+			IsCanDelete:                true,
 		}
+	},
+}
 
-		fmt.Printf("Asset %s created successfully\n", input.Id)
-	}
-
-	return nil
+func (s *SmartContract) CreateGmpCsv(
+	ctx contractapi.TransactionContextInterface,
+	args string,
+) error {
+	_, err := gmpImport.Run(ctx, args, false)
+	return err
 }
 
 
@@ -391,85 +255,27 @@ func (s *SmartContract) UpdateMultipleGmp(
 ) error {
 	var inputs []models.TransactionGmp
 
-	errInputGap := json.Unmarshal([]byte(args), &inputs)
-	utils.HandleError(errInputGap)
-	
-	for _, input := range inputs {
-		assetJSON, err := ctx.GetStub().GetState(input.Id)
-		if err != nil {
-			return fmt.Errorf("failed to read from world state: %v", err)
-		}
-		if assetJSON == nil {
-			return fmt.Errorf("asset with ID %s does not exist", input.Id)
-		}
-		
-		var existingAsset models.TransactionGmp
-		err = json.Unmarshal(assetJSON, &existingAsset)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal existing asset: %v", err)
-		}
-
-		existingAsset.Id = input.Id
-		existingAsset.PackerId = input.PackerId
-		existingAsset.PackingHouseRegisterNumber = input.PackingHouseRegisterNumber
-		existingAsset.Address = input.Address
-		existingAsset.PackingHouseName = input.PackingHouseName
-		existingAsset.UpdatedDate = input.UpdatedDate
-		existingAsset.Source = input.Source
-		existingAsset.UpdatedAt = input.UpdatedAt
+	if err := utils.DecodeInput(args, &inputs); err != nil {
+		return err
+	}
+	if err := gmpRepository.AssertDistinct(inputs); err != nil {
+		return err
+	}
 
-		updatedAssetJSON, err := json.Marshal(existingAsset)
+	assets := make([]*models.TransactionGmp, 0, len(inputs))
+	for i := range inputs {
+		input := &inputs[i]
+		existingAsset, err := gmpRepository.Get(ctx, input.Id)
 		if err != nil {
-			return fmt.Errorf("failed to marshal updated asset: %v", err)
+			return err
 		}
-		
-		err = ctx.GetStub().PutState(input.Id, updatedAssetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to update asset in world state: %v", err)
-		}
-		
-		fmt.Printf("Asset %s updated successfully\n", input.Id)
-
-		//Update packer gmp
-		queryPacker := fmt.Sprintf(`{
-			"selector": {
-				"docType": "packer",
-				"id": "%s"
-			}
-		}`, input.PackerId)
-
-		resultsIterator, err := ctx.GetStub().GetQueryResult(queryPacker)
-		if err != nil {
-			return fmt.Errorf("[UpdateMultipleGmp] failed to query related packer: %v", err)
-		}
-		defer resultsIterator.Close()
-
-		for resultsIterator.HasNext() {
-			queryResponse, err := resultsIterator.Next()
-			if err != nil {
-				return err
-			}
-
-			var packer models.TransactionPacker
-			err = json.Unmarshal(queryResponse.Value, &packer)
-			if err != nil {
-				return err
-			}
-
-			packer.PackingHouseName = input.PackingHouseName
-			packer.PackingHouseRegisterNumber = input.PackingHouseRegisterNumber
-
-			updatedPackerJSON, err := json.Marshal(packer)
-			if err != nil {
-				return fmt.Errorf("failed to marshal updated asset: %v", err)
-			}
-
-			err = ctx.GetStub().PutState(packer.Id, updatedPackerJSON)
-			if err != nil {
-				return fmt.Errorf("[UpdateMultipleGmp] failed to update packer asset in world state: %v", err)
-			}
+
+		if err := setGmpFields(ctx, existingAsset, input); err != nil {
+			return err
 		}
+		assets = append(assets, existingAsset)
 	}
-	
-	return nil
-}
\ No newline at end of file
+
+	// The packers take the new packing house names from packerGmpCopy
+	return gmpRepository.UpdateAll(ctx, assets)
+}
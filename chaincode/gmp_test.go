@@ -0,0 +1,133 @@
+package chaincode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func TestGmpLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"House One","packerId":"PK1"}`)
+	})
+
+	gmp, err := s.ReadGmp(ctx, "M1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gmp.DocType != models.Gmp || gmp.Owner != nectecUser.ID || !gmp.IsCanDelete {
+		t.Fatalf("unexpected gmp %+v", gmp)
+	}
+
+	packerCtx := ctx.As(packerUser)
+	if err := submit(packerCtx, "UpdateGmp", func() error {
+		return s.UpdateGmp(packerCtx, `{"id":"M1","packingHouseName":"Mine"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("packer update gmp: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "UpdateGmp", func() error {
+		return s.UpdateGmp(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"House 1","packerId":"PK1"}`)
+	})
+	gmp, _ = s.ReadGmp(ctx, "M1")
+	if gmp.PackingHouseName != "House 1" {
+		t.Fatalf("update not applied: %+v", gmp)
+	}
+
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","gmp":"PH-1"}`)
+	})
+	gmp, _ = s.ReadGmp(ctx, "M1")
+	if gmp.IsCanDelete {
+		t.Fatal("gmp with packings should not be deletable")
+	}
+
+	mustSubmit(t, ctx, "ClearGmpPacker", func() error {
+		return s.ClearGmpPacker(ctx, "PK1")
+	})
+	gmp, _ = s.ReadGmp(ctx, "M1")
+	if gmp.PackerId != "" {
+		t.Fatalf("packer not cleared: %+v", gmp)
+	}
+
+	var refErr *utils.ReferenceError
+	if err := submit(ctx, "DeleteGmp", func() error {
+		return s.DeleteGmp(ctx, "M1", "duplicate record")
+	}); !errors.As(err, &refErr) || refErr.References[0] != (models.Reference{DocType: models.Packing, Id: "P1", Field: "gmp"}) {
+		t.Fatalf("expected delete to be blocked by P1, got %v", err)
+	}
+
+	mustSubmit(t, packerCtx, "DeletePacking", func() error {
+		return s.DeletePacking(packerCtx, "P1", "entered by mistake")
+	})
+	mustSubmit(t, ctx, "DeleteGmp", func() error {
+		return s.DeleteGmp(ctx, "M1", "duplicate record")
+	})
+	if _, err := s.ReadGmp(ctx, "M1"); err == nil {
+		t.Fatal("expected gmp to be deleted")
+	}
+}
+
+func TestGetAllGMP(t *testing.T) {
+	s, ctx := newTestContract()
+
+	seedDaily(t, ctx, "CreateGmpCsv", func(args string) error {
+		return s.CreateGmpCsv(ctx, args)
+	},
+		`[{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Alpha","address":"Bangkok","packerId":"PK1"}]`,
+		`[{"id":"M2","packingHouseRegisterNumber":"PH-2","packingHouseName":"Beta","address":"Chiang Mai","packerId":""}]`,
+		`[{"id":"M3","packingHouseRegisterNumber":"PH-3","packingHouseName":"Gamma","address":"Bangkok","packerId":""}]`,
+	)
+
+	mustSubmit(t, ctx, "UpdateMultipleGmp", func() error {
+		return s.UpdateMultipleGmp(ctx, `[{"id":"M3","packingHouseRegisterNumber":"PH-3","packingHouseName":"Gamma 2","address":"Bangkok"}]`)
+	})
+	ctx.Stub.PutState("P1", []byte(`{"id":"P1","docType":"packing","gmp":"PH-1"}`))
+
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllGmp
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FilterGetAllGmp{}, []string{"M3", "M2", "M1"}, 3},
+		{"page", models.FilterGetAllGmp{Pagination: models.Pagination{Skip: 1, Limit: 1}}, []string{"M2"}, 3},
+		{"available", models.FilterGetAllGmp{AvailableGmp: strPtr("true")}, []string{"M3", "M2"}, 2},
+		{"search address", models.FilterGetAllGmp{Search: strPtr("Bangkok")}, []string{"M3", "M1"}, 2},
+		{"search updated name", models.FilterGetAllGmp{Search: strPtr("Gamma 2")}, []string{"M3"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllGMP(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, g := range res.Obj {
+				ids = append(ids, g.Id)
+				if g.IsCanDelete != (g.Id != "M1") {
+					t.Errorf("%s: isCanDelete = %v", g.Id, g.IsCanDelete)
+				}
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+
+	byNumber, err := s.GetGmpByPackingHouseNumber(ctx, "PH-2")
+	if err != nil || byNumber.Obj == nil || byNumber.Obj.Id != "M2" {
+		t.Fatalf("unexpected gmp by number %+v, %v", byNumber, err)
+	}
+
+	filtered, err := s.FilterGmp(ctx, "address", "Bangkok")
+	if err != nil || len(filtered) != 2 {
+		t.Fatalf("unexpected filter result %v, %v", filtered, err)
+	}
+}
@@ -0,0 +1,136 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// Test identities. The default access policy grants roles from the "role"
+// certificate attribute.
+var (
+	nectecUser    = mocks.NewMockClientIdentity("x509::CN=nectec-admin", "NectecMSP", map[string]string{"role": "nectec"})
+	regulatorUser = mocks.NewMockClientIdentity("x509::CN=regulator1", "RegulatorMSP", map[string]string{"role": "regulator"})
+	farmerUser    = mocks.NewMockClientIdentity("x509::CN=farmer1", "FarmerMSP", map[string]string{"role": "farmer"})
+	otherFarmer   = mocks.NewMockClientIdentity("x509::CN=farmer2", "FarmerMSP", map[string]string{"role": "farmer"})
+	packerUser    = mocks.NewMockClientIdentity("x509::CN=packer1", "PackerMSP", map[string]string{"role": "packer"})
+	otherPacker   = mocks.NewMockClientIdentity("x509::CN=packer2", "PackerMSP", map[string]string{"role": "packer"})
+	exporterUser  = mocks.NewMockClientIdentity("x509::CN=exporter1", "ExporterMSP", map[string]string{"role": "exporter"})
+	strangerUser  = mocks.NewMockClientIdentity("x509::CN=stranger", "UnknownMSP", nil)
+)
+
+func TestMain(m *testing.M) {
+	if err := LoadCollections(); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestContract() (*SmartContract, *mocks.MockContext) {
+	ctx := mocks.NewMockContext(nectecUser)
+	ctx.WrapStub = func(stub shim.ChaincodeStubInterface) shim.ChaincodeStubInterface {
+		return utils.NewRecordingStub(utils.NewCachingStub(stub))
+	}
+	return &SmartContract{}, ctx
+}
+
+// countQueries makes the transactions of ctx count the rich queries that get
+// past the read cache to the state database.
+func countQueries(ctx *mocks.MockContext) *int {
+	count := new(int)
+	ctx.WrapStub = func(stub shim.ChaincodeStubInterface) shim.ChaincodeStubInterface {
+		return utils.NewRecordingStub(utils.NewCachingStub(&queryCounter{stub, count}))
+	}
+	return count
+}
+
+type queryCounter struct {
+	shim.ChaincodeStubInterface
+	count *int
+}
+
+func (s *queryCounter) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	*s.count++
+	return s.ChaincodeStubInterface.GetQueryResult(query)
+}
+
+func (s *queryCounter) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	*s.count++
+	return s.ChaincodeStubInterface.GetQueryResultWithPagination(query, pageSize, bookmark)
+}
+
+// submit runs fn as one transaction named function between the before and
+// after hooks, like the contract router does. Writes are committed only when
+// all of them succeed.
+func submit(ctx *mocks.MockContext, function string, fn func() error) error {
+	return ctx.Stub.Invoke(function, func() error {
+		if err := BeforeTransaction(ctx); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		return AfterTransaction(ctx)
+	})
+}
+
+// seedDaily submits fn once for each of args, each in its own transaction a
+// day after the previous one, so that records get distinct createdAt values
+// from 2024-01-01 on.
+func seedDaily(t *testing.T, ctx *mocks.MockContext, function string, fn func(args string) error, args ...string) {
+	t.Helper()
+	for i, a := range args {
+		if i > 0 {
+			ctx.Stub.Advance(24 * time.Hour)
+		}
+		a := a
+		mustSubmit(t, ctx, function, func() error {
+			return fn(a)
+		})
+	}
+}
+
+func mustSubmit(t *testing.T, ctx *mocks.MockContext, function string, fn func() error) {
+	t.Helper()
+	if err := submit(ctx, function, fn); err != nil {
+		t.Fatalf("%s: unexpected error: %v", function, err)
+	}
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal %T: %v", v, err)
+	}
+	return string(b)
+}
+
+func isAccessDenied(err error) bool {
+	var denied *utils.AccessDeniedError
+	return errors.As(err, &denied)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func assertIDs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("ids = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("ids = %v, want %v", got, want)
+		}
+	}
+}
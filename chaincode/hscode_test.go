@@ -0,0 +1,144 @@
+package chaincode
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func TestHscodes(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[
+			{"id":"H3","hscode":"0810.60","description":"Durian","order":3},
+			{"id":"H1","hscode":"0804.50","description":"Mango","order":1},
+			{"id":"H2","hscode":"0810.90","description":"Longan","order":2}
+		]`)
+	})
+	mustSubmit(t, ctx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(ctx, `{"id":"F1"}`)
+		return err
+	})
+
+	if err := submit(ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[{"id":"H1"}]`)
+	}); err == nil {
+		t.Fatal("expected duplicate hscode to be rejected")
+	}
+
+	exporterCtx := ctx.As(exporterUser)
+	if err := submit(exporterCtx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(exporterCtx, `[{"id":"H9"}]`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("exporter create hscode: expected access denied, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  models.HscodeFilterParams
+		wantIds []string
+	}{
+		{"all, by order", models.HscodeFilterParams{}, []string{"H1", "H2", "H3"}},
+		{"limit", models.HscodeFilterParams{Pagination: models.Pagination{Limit: 2}}, []string{"H1", "H2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.QueryHscodeWithPagination(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != 3 {
+				t.Errorf("total = %d, want 3", res.Total)
+			}
+			var ids []string
+			for _, h := range res.Data {
+				ids = append(ids, h.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+
+	mustSubmit(t, ctx, "DeleteAllHscodes", func() error {
+		return s.DeleteAllHscodes(ctx, "duplicate record")
+	})
+	res, err := s.QueryHscodeWithPagination(ctx, `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 0 || len(res.Data) != 0 {
+		t.Fatalf("expected no hscodes, got %+v", res)
+	}
+	if _, err := s.ReadFarmerProfile(ctx, "F1"); err != nil {
+		t.Fatalf("DeleteAllHscodes removed other documents: %v", err)
+	}
+}
+
+func TestDeleteHscodesInUse(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[
+			{"id":"H1","hscode":"0804.50","order":1},
+			{"id":"H2","hscode":"0810.60","order":2}
+		]`)
+	})
+	// A Form E declaring durian, stored as is to skip its content checks.
+	ctx.Stub.PutState("FE1", []byte(`{"id":"FE1","docType":"formE","invoice":{"productAndPackaging":[{"hscode":"0810.60"}]}}`))
+
+	var refErr *utils.ReferenceError
+	if err := submit(ctx, "DeleteAllHscodes", func() error {
+		return s.DeleteAllHscodes(ctx, "new tariff schedule")
+	}); !errors.As(err, &refErr) || refErr.Id != "H2" || refErr.References[0].Id != "FE1" {
+		t.Fatalf("expected delete to be blocked by FE1, got %v", err)
+	}
+
+	res, err := s.QueryHscodeWithPagination(ctx, `{}`)
+	if err != nil || res.Total != 2 {
+		t.Fatalf("refused delete removed hscodes: %+v, %v", res, err)
+	}
+}
+
+func TestHscodeBookmarks(t *testing.T) {
+	s, ctx := newTestContract()
+
+	var rows []models.TransactionHscode
+	for i := 0; i <= utils.MaxTotal; i++ {
+		rows = append(rows, models.TransactionHscode{Id: fmt.Sprintf("H%04d", i), Hscode: fmt.Sprintf("0810.%04d", i), Order: i})
+	}
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, toJSON(t, rows))
+	})
+
+	first, err := s.QueryHscodeWithPagination(ctx, `{"limit":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Total != utils.MaxTotal || !first.TotalCapped || first.Bookmark == "" {
+		t.Fatalf("unexpected first page total %d, capped %v, bookmark %q", first.Total, first.TotalCapped, first.Bookmark)
+	}
+	assertIDs(t, []string{first.Data[0].Id, first.Data[1].Id}, "H0000", "H0001")
+
+	next, err := s.QueryHscodeWithPagination(ctx, toJSON(t, models.HscodeFilterParams{
+		Pagination: models.Pagination{Limit: 2, Skip: 7, Bookmark: first.Bookmark, SkipTotal: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Total != 0 || next.TotalCapped || next.Bookmark == first.Bookmark {
+		t.Fatalf("unexpected next page total %d, capped %v, bookmark %q", next.Total, next.TotalCapped, next.Bookmark)
+	}
+	assertIDs(t, []string{next.Data[0].Id, next.Data[1].Id}, "H0002", "H0003")
+
+	unlimited, err := s.QueryHscodeWithPagination(ctx, `{"skipTotal":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unlimited.Data) != utils.DefaultLimit {
+		t.Fatalf("page without a limit has %d rows, want %d", len(unlimited.Data), utils.DefaultLimit)
+	}
+}
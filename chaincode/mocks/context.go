@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// MockContext is a contractapi.TransactionContextInterface over a MockStub.
+type MockContext struct {
+	Stub     *MockStub
+	Identity *MockClientIdentity
+
+	// WrapStub, when set, wraps the stub once per transaction, the way a
+	// custom transaction context's SetStub would. Outside of a transaction
+	// GetStub returns the bare stub.
+	WrapStub func(stub shim.ChaincodeStubInterface) shim.ChaincodeStubInterface
+}
+
+func NewMockContext(identity *MockClientIdentity) *MockContext {
+	return &MockContext{Stub: NewMockStub(), Identity: identity}
+}
+
+func (c *MockContext) GetStub() shim.ChaincodeStubInterface {
+	if c.WrapStub == nil || !c.Stub.inTx {
+		return c.Stub
+	}
+	if c.Stub.txStub == nil {
+		c.Stub.txStub = c.WrapStub(c.Stub)
+	}
+	return c.Stub.txStub
+}
+
+func (c *MockContext) GetClientIdentity() cid.ClientIdentity {
+	return c.Identity
+}
+
+// As returns a context sharing the same ledger but submitted by identity.
+func (c *MockContext) As(identity *MockClientIdentity) *MockContext {
+	return &MockContext{Stub: c.Stub, Identity: identity, WrapStub: c.WrapStub}
+}
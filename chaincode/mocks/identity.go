@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+)
+
+// MockClientIdentity is a cid.ClientIdentity backed by plain fields.
+type MockClientIdentity struct {
+	// ID is the decoded identity; GetID returns it base64 encoded as the
+	// real client identity library does.
+	ID         string
+	MspID      string
+	Attributes map[string]string
+}
+
+func NewMockClientIdentity(id, mspID string, attrs map[string]string) *MockClientIdentity {
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	return &MockClientIdentity{ID: id, MspID: mspID, Attributes: attrs}
+}
+
+func (c *MockClientIdentity) GetID() (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(c.ID)), nil
+}
+
+func (c *MockClientIdentity) GetMSPID() (string, error) {
+	return c.MspID, nil
+}
+
+func (c *MockClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := c.Attributes[attrName]
+	return value, found, nil
+}
+
+func (c *MockClientIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	value, found := c.Attributes[attrName]
+	if !found || value != attrValue {
+		return &attributeError{name: attrName, value: attrValue}
+	}
+	return nil
+}
+
+func (c *MockClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
+
+type attributeError struct {
+	name, value string
+}
+
+func (e *attributeError) Error() string {
+	return "attribute '" + e.name + "' does not equal '" + e.value + "'"
+}
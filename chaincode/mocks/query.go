@@ -0,0 +1,458 @@
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// Query is a parsed CouchDB Mango query. Only the parts of the language the
+// chaincode relies on are supported; use_index and fields are ignored.
+type Query struct {
+	Selector map[string]interface{}
+	Sort     []SortField
+	Skip     int
+	Limit    int
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+func ParseQuery(query string) (*Query, error) {
+	var raw struct {
+		Selector map[string]interface{} `json:"selector"`
+		Sort     []interface{}          `json:"sort"`
+		Skip     int                    `json:"skip"`
+		Limit    int                    `json:"limit"`
+	}
+	if err := json.Unmarshal([]byte(query), &raw); err != nil {
+		return nil, fmt.Errorf("invalid query %s: %w", query, err)
+	}
+	if raw.Selector == nil {
+		return nil, fmt.Errorf("query %s has no selector", query)
+	}
+
+	q := &Query{Selector: raw.Selector, Skip: raw.Skip, Limit: raw.Limit}
+	for _, s := range raw.Sort {
+		switch s := s.(type) {
+		case string:
+			q.Sort = append(q.Sort, SortField{Field: s})
+		case map[string]interface{}:
+			for field, dir := range s {
+				q.Sort = append(q.Sort, SortField{Field: field, Desc: dir == "desc"})
+			}
+		default:
+			return nil, fmt.Errorf("invalid sort %v", s)
+		}
+	}
+	return q, nil
+}
+
+// Run returns every non-composite entry of state matching the selector, in
+// sort order. Documents without a sort field are skipped, as CouchDB would
+// refuse to sort them with the index.
+func (q *Query) Run(state map[string][]byte) ([]*queryresult.KV, error) {
+	type doc struct {
+		kv   *queryresult.KV
+		body map[string]interface{}
+	}
+
+	var docs []doc
+	for key, value := range state {
+		if strings.HasPrefix(key, compositeKeyNamespace) {
+			continue
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(value, &body); err != nil {
+			continue
+		}
+		body["_id"] = key
+
+		ok, err := Match(q.Selector, body)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		missing := false
+		for _, s := range q.Sort {
+			if _, found := lookup(body, s.Field); !found {
+				missing = true
+			}
+		}
+		if missing {
+			continue
+		}
+		docs = append(docs, doc{kv: &queryresult.KV{Key: key, Value: value}, body: body})
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, s := range q.Sort {
+			a, _ := lookup(docs[i].body, s.Field)
+			b, _ := lookup(docs[j].body, s.Field)
+			c := collate(a, b)
+			if c == 0 {
+				continue
+			}
+			if s.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return docs[i].kv.Key < docs[j].kv.Key
+	})
+
+	kvs := make([]*queryresult.KV, len(docs))
+	for i, d := range docs {
+		kvs[i] = d.kv
+	}
+	return kvs, nil
+}
+
+// Page returns at most limit results starting at offset. A limit of zero or
+// less means no limit.
+func (q *Query) Page(kvs []*queryresult.KV, offset, limit int) []*queryresult.KV {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(kvs) {
+		return nil
+	}
+	kvs = kvs[offset:]
+	if limit > 0 && limit < len(kvs) {
+		kvs = kvs[:limit]
+	}
+	return kvs
+}
+
+// Match reports whether doc satisfies a Mango selector.
+func Match(selector map[string]interface{}, doc map[string]interface{}) (bool, error) {
+	for field, cond := range selector {
+		var ok bool
+		var err error
+		switch field {
+		case "$and", "$or", "$nor":
+			ok, err = matchCombination(field, cond, doc)
+		case "$not":
+			sub, isMap := cond.(map[string]interface{})
+			if !isMap {
+				return false, fmt.Errorf("$not requires an object")
+			}
+			ok, err = Match(sub, doc)
+			ok = !ok
+		default:
+			value, found := lookup(doc, field)
+			ok, err = matchField(cond, value, found)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchCombination(op string, cond interface{}, doc map[string]interface{}) (bool, error) {
+	list, ok := cond.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("%s requires an array", op)
+	}
+	matched := 0
+	for _, item := range list {
+		sub, ok := item.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s requires an array of objects", op)
+		}
+		m, err := Match(sub, doc)
+		if err != nil {
+			return false, err
+		}
+		if m {
+			matched++
+		}
+	}
+	switch op {
+	case "$and":
+		return matched == len(list), nil
+	case "$or":
+		return matched > 0, nil
+	default:
+		return matched == 0, nil
+	}
+}
+
+func matchField(cond interface{}, value interface{}, found bool) (bool, error) {
+	ops, ok := cond.(map[string]interface{})
+	if !ok || !isOperatorObject(ops) {
+		if ok {
+			// Nested selector on a sub-object.
+			sub, isMap := value.(map[string]interface{})
+			if !found || !isMap {
+				return false, nil
+			}
+			return Match(ops, sub)
+		}
+		return found && collate(value, cond) == 0, nil
+	}
+
+	for op, arg := range ops {
+		ok, err := matchOperator(op, arg, value, found)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func isOperatorObject(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func matchOperator(op string, arg interface{}, value interface{}, found bool) (bool, error) {
+	if op == "$exists" {
+		want, ok := arg.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists requires a boolean")
+		}
+		return found == want, nil
+	}
+	if !found {
+		return false, nil
+	}
+
+	switch op {
+	case "$eq":
+		return collate(value, arg) == 0, nil
+	case "$ne":
+		return collate(value, arg) != 0, nil
+	case "$gt":
+		return sameKind(value, arg) && collate(value, arg) > 0, nil
+	case "$gte":
+		return sameKind(value, arg) && collate(value, arg) >= 0, nil
+	case "$lt":
+		return sameKind(value, arg) && collate(value, arg) < 0, nil
+	case "$lte":
+		return sameKind(value, arg) && collate(value, arg) <= 0, nil
+	case "$in", "$nin":
+		list, ok := arg.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s requires an array", op)
+		}
+		in := false
+		for _, item := range list {
+			if collate(value, item) == 0 {
+				in = true
+				break
+			}
+		}
+		return in == (op == "$in"), nil
+	case "$regex":
+		pattern, ok := arg.(string)
+		if !ok {
+			return false, fmt.Errorf("$regex requires a string")
+		}
+		s, ok := value.(string)
+		if !ok {
+			return false, nil
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid $regex %q: %w", pattern, err)
+		}
+		return re.MatchString(s), nil
+	case "$size":
+		n, ok := arg.(float64)
+		if !ok {
+			return false, fmt.Errorf("$size requires a number")
+		}
+		list, ok := value.([]interface{})
+		return ok && len(list) == int(n), nil
+	case "$all":
+		want, ok := arg.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("$all requires an array")
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		for _, w := range want {
+			has := false
+			for _, item := range list {
+				if collate(item, w) == 0 {
+					has = true
+					break
+				}
+			}
+			if !has {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$elemMatch", "$allMatch":
+		list, ok := value.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		matched := 0
+		for _, item := range list {
+			m, err := matchElement(arg, item)
+			if err != nil {
+				return false, err
+			}
+			if m {
+				matched++
+			}
+		}
+		if op == "$elemMatch" {
+			return matched > 0, nil
+		}
+		return len(list) > 0 && matched == len(list), nil
+	case "$not":
+		m, err := matchField(arg, value, found)
+		return !m, err
+	case "$and", "$or", "$nor":
+		// Inside a field condition the combinators apply to the field
+		// value itself, or to its fields when it is a sub-document.
+		if doc, ok := value.(map[string]interface{}); ok {
+			return matchCombination(op, arg, doc)
+		}
+		list, ok := arg.([]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s requires an array", op)
+		}
+		matched := 0
+		for _, cond := range list {
+			m, err := matchField(cond, value, found)
+			if err != nil {
+				return false, err
+			}
+			if m {
+				matched++
+			}
+		}
+		switch op {
+		case "$and":
+			return matched == len(list), nil
+		case "$or":
+			return matched > 0, nil
+		default:
+			return matched == 0, nil
+		}
+	}
+	return false, fmt.Errorf("unsupported operator %s", op)
+}
+
+// matchElement applies an $elemMatch condition to one array element, which
+// may be either a document or a scalar.
+func matchElement(cond interface{}, item interface{}) (bool, error) {
+	ops, ok := cond.(map[string]interface{})
+	if ok && isOperatorObject(ops) {
+		return matchField(ops, item, true)
+	}
+	doc, isMap := item.(map[string]interface{})
+	if ok && isMap {
+		return Match(ops, doc)
+	}
+	return matchField(cond, item, true)
+}
+
+// lookup resolves a dotted field path in doc.
+func lookup(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func kind(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 2
+		}
+		return 1
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// sameKind reports whether range operators can compare a and b. Booleans are
+// one kind for this purpose.
+func sameKind(a, b interface{}) bool {
+	ka, kb := kind(a), kind(b)
+	if ka <= 2 && kb <= 2 && ka > 0 && kb > 0 {
+		return true
+	}
+	return ka == kb
+}
+
+// collate orders JSON values the way CouchDB does:
+// null < false < true < numbers < strings < arrays < objects.
+func collate(a, b interface{}) int {
+	ka, kb := kind(a), kind(b)
+	if ka != kb {
+		if ka < kb {
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	case []interface{}:
+		b := b.([]interface{})
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := collate(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(b)
+	case map[string]interface{}:
+		aj, _ := json.Marshal(a)
+		bj, _ := json.Marshal(b)
+		return strings.Compare(string(aj), string(bj))
+	}
+	return 0
+}
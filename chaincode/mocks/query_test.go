@@ -0,0 +1,134 @@
+package mocks
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{
+		"docType": "gap",
+		"certId": "GAP-001",
+		"areaRai": 12.5,
+		"farmerId": "",
+		"active": true,
+		"tags": ["a", "b"],
+		"detail": {"province": "Nan", "district": "Pua"},
+		"items": [{"no": "C-1", "qty": 3}, {"no": "C-2", "qty": 9}]
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		want     bool
+	}{
+		{"implicit eq", `{"docType":"gap"}`, true},
+		{"implicit eq mismatch", `{"docType":"gmp"}`, false},
+		{"empty string", `{"farmerId":""}`, true},
+		{"missing field", `{"packerId":""}`, false},
+		{"exists true", `{"certId":{"$exists":true}}`, true},
+		{"exists false", `{"packerId":{"$exists":false}}`, true},
+		{"ne", `{"docType":{"$ne":"gmp"}}`, true},
+		{"gte lte", `{"areaRai":{"$gte":10,"$lte":15}}`, true},
+		{"gt out of range", `{"areaRai":{"$gt":12.5}}`, false},
+		{"range needs same kind", `{"areaRai":{"$gte":"10"}}`, false},
+		{"string range", `{"certId":{"$gte":"GAP-000","$lt":"GAP-002"}}`, true},
+		{"in", `{"certId":{"$in":["GAP-009","GAP-001"]}}`, true},
+		{"in missing", `{"packerId":{"$in":[""]}}`, false},
+		{"nin", `{"certId":{"$nin":["GAP-001"]}}`, false},
+		{"regex", `{"certId":{"$regex":"^GAP-0"}}`, true},
+		{"regex non string", `{"areaRai":{"$regex":"12"}}`, false},
+		{"nested path", `{"detail.province":"Nan"}`, true},
+		{"nested object", `{"detail":{"district":{"$regex":"P"}}}`, true},
+		{"array contains", `{"tags":"b"}`, false},
+		{"all", `{"tags":{"$all":["a","b"]}}`, true},
+		{"size", `{"tags":{"$size":2}}`, true},
+		{"elemMatch", `{"items":{"$elemMatch":{"no":"C-2","qty":{"$gt":5}}}}`, true},
+		{"elemMatch or", `{"items":{"$elemMatch":{"$or":[{"no":"C-9"},{"qty":3}]}}}`, true},
+		{"allMatch", `{"items":{"$allMatch":{"qty":{"$gt":5}}}}`, false},
+		{"and", `{"$and":[{"docType":"gap"},{"active":true}]}`, true},
+		{"or", `{"$or":[{"docType":"gmp"},{"certId":{"$regex":"001"}}]}`, true},
+		{"nor", `{"$nor":[{"docType":"gmp"},{"active":false}]}`, true},
+		{"not", `{"$not":{"docType":"gap"}}`, false},
+		{"field not", `{"certId":{"$not":{"$regex":"^X"}}}`, true},
+		{"field or", `{"areaRai":{"$or":[{"$lt":1},{"$gt":10}]}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var selector map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.selector), &selector); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Match(selector, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%s) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Match(map[string]interface{}{"certId": map[string]interface{}{"$bogus": 1}}, doc); err == nil {
+		t.Error("expected unsupported operator to fail")
+	}
+}
+
+func TestQueryRun(t *testing.T) {
+	state := map[string][]byte{
+		"A":           []byte(`{"docType":"x","n":3,"createdAt":"2024-01-02"}`),
+		"B":           []byte(`{"docType":"x","n":1,"createdAt":"2024-01-03"}`),
+		"C":           []byte(`{"docType":"x","n":2,"createdAt":"2024-01-01"}`),
+		"D":           []byte(`{"docType":"y","n":4}`),
+		"E":           []byte(`{"docType":"x"}`),
+		"raw":         []byte(`not json`),
+		"\x00idx\x00": []byte(`{"docType":"x","n":0}`),
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"key order", `{"selector":{"docType":"x"}}`, []string{"A", "B", "C", "E"}},
+		{"sort asc", `{"selector":{"docType":"x"},"sort":["n"]}`, []string{"B", "C", "A"}},
+		{"sort desc", `{"selector":{"docType":"x"},"sort":[{"createdAt":"desc"}]}`, []string{"B", "A", "C"}},
+		{"skip limit", `{"selector":{"docType":"x"},"sort":[{"n":"asc"}],"skip":1,"limit":1}`, []string{"C"}},
+		{"by _id", `{"selector":{"_id":{"$gt":"C"}}}`, []string{"D", "E"}},
+		{"use_index ignored", `{"selector":{"docType":"y"},"use_index":["_design/x","x"]}`, []string{"D"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kvs, err := q.Run(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kvs = q.Page(kvs, q.Skip, q.Limit)
+			var keys []string
+			for _, kv := range kvs {
+				keys = append(keys, kv.Key)
+			}
+			if len(keys) != len(tt.want) {
+				t.Fatalf("keys = %v, want %v", keys, tt.want)
+			}
+			for i := range keys {
+				if keys[i] != tt.want[i] {
+					t.Fatalf("keys = %v, want %v", keys, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := ParseQuery(`{"selector":`); err == nil {
+		t.Error("expected malformed query to fail")
+	}
+}
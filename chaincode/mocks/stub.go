@@ -0,0 +1,475 @@
+// Package mocks provides in-memory fakes of the Fabric chaincode stub, client
+// identity and transaction context so contract functions can be exercised
+// without a peer.
+package mocks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const compositeKeyNamespace = "\x00"
+
+// Epoch is the timestamp of the first transaction run on a MockStub. Every
+// later transaction is one second after the previous one.
+var Epoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// MockStub is an in-memory shim.ChaincodeStubInterface.
+//
+// Outside of a transaction every write is committed immediately. Between
+// BeginTx and Commit/Rollback writes are buffered and, as on a real peer,
+// reads only see committed state.
+type MockStub struct {
+	// Methods that are not implemented panic when called.
+	shim.ChaincodeStubInterface
+
+	Function    string
+	Args        []string
+	TxID        string
+	TxTimestamp time.Time
+	Transient   map[string][]byte
+	ChannelID   string
+
+	// Events holds every committed chaincode event, oldest first.
+	Events []*peer.ChaincodeEvent
+
+	state   map[string][]byte
+	history map[string][]*queryresult.KeyModification
+
+	// private holds the committed private data of every collection.
+	private        map[string]map[string][]byte
+	pendingPrivate []privateWrite
+
+	inTx         bool
+	txCount      int
+	clockOffset  time.Duration
+	pending      map[string][]byte
+	pendingOrder []string
+	pendingEvent *peer.ChaincodeEvent
+
+	// txStub is the stub of the current transaction as wrapped by
+	// MockContext.WrapStub.
+	txStub shim.ChaincodeStubInterface
+}
+
+func NewMockStub() *MockStub {
+	stub := &MockStub{
+		ChannelID: "mychannel",
+		state:     map[string][]byte{},
+		history:   map[string][]*queryresult.KeyModification{},
+		private:   map[string]map[string][]byte{},
+	}
+	stub.nextTx()
+	return stub
+}
+
+func (s *MockStub) nextTx() {
+	s.txCount++
+	s.TxID = fmt.Sprintf("tx%d", s.txCount)
+	s.TxTimestamp = Epoch.Add(time.Duration(s.txCount-1)*time.Second + s.clockOffset)
+}
+
+// Advance moves the clock of later transactions forward by d.
+func (s *MockStub) Advance(d time.Duration) {
+	s.clockOffset += d
+}
+
+// BeginTx starts a new transaction invoking function with args. Writes are
+// buffered until Commit.
+func (s *MockStub) BeginTx(function string, args ...string) {
+	s.nextTx()
+	s.Function = function
+	s.Args = args
+	s.inTx = true
+	s.pending = map[string][]byte{}
+	s.pendingOrder = nil
+	s.pendingEvent = nil
+	s.pendingPrivate = nil
+	s.txStub = nil
+}
+
+// Commit applies the buffered writes and event of the current transaction.
+func (s *MockStub) Commit() {
+	for _, key := range s.pendingOrder {
+		s.apply(key, s.pending[key])
+	}
+	for _, w := range s.pendingPrivate {
+		s.applyPrivate(w)
+	}
+	if s.pendingEvent != nil {
+		s.Events = append(s.Events, s.pendingEvent)
+	}
+	s.inTx = false
+	s.pending = nil
+	s.pendingOrder = nil
+	s.pendingEvent = nil
+	s.pendingPrivate = nil
+	s.txStub = nil
+}
+
+// Rollback discards the buffered writes and event of the current transaction.
+func (s *MockStub) Rollback() {
+	s.inTx = false
+	s.pending = nil
+	s.pendingOrder = nil
+	s.pendingEvent = nil
+	s.pendingPrivate = nil
+	s.txStub = nil
+}
+
+// Invoke runs fn as a single transaction named function. Its writes are
+// committed only when fn succeeds, as they would be after endorsement.
+func (s *MockStub) Invoke(function string, fn func() error) error {
+	s.BeginTx(function)
+	if err := fn(); err != nil {
+		s.Rollback()
+		return err
+	}
+	s.Commit()
+	return nil
+}
+
+// LastEvent returns the most recently committed event, or nil.
+func (s *MockStub) LastEvent() *peer.ChaincodeEvent {
+	if len(s.Events) == 0 {
+		return nil
+	}
+	return s.Events[len(s.Events)-1]
+}
+
+// Keys returns the committed keys in order, composite keys included.
+func (s *MockStub) Keys() []string {
+	keys := make([]string, 0, len(s.state))
+	for key := range s.state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *MockStub) apply(key string, value []byte) {
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{
+		TxId:      s.TxID,
+		Value:     value,
+		Timestamp: timestamppb.New(s.TxTimestamp),
+		IsDelete:  value == nil,
+	})
+	if value == nil {
+		delete(s.state, key)
+		return
+	}
+	s.state[key] = value
+}
+
+func (s *MockStub) write(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if !s.inTx {
+		s.apply(key, value)
+		return nil
+	}
+	if _, ok := s.pending[key]; !ok {
+		s.pendingOrder = append(s.pendingOrder, key)
+	}
+	s.pending[key] = value
+	return nil
+}
+
+func (s *MockStub) GetFunctionAndParameters() (string, []string) {
+	return s.Function, s.Args
+}
+
+func (s *MockStub) GetStringArgs() []string {
+	return append([]string{s.Function}, s.Args...)
+}
+
+func (s *MockStub) GetTxID() string {
+	return s.TxID
+}
+
+func (s *MockStub) GetChannelID() string {
+	return s.ChannelID
+}
+
+func (s *MockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.TxTimestamp), nil
+}
+
+func (s *MockStub) GetTransient() (map[string][]byte, error) {
+	if s.Transient == nil {
+		return map[string][]byte{}, nil
+	}
+	return s.Transient, nil
+}
+
+func (s *MockStub) GetState(key string) ([]byte, error) {
+	value, ok := s.state[key]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (s *MockStub) PutState(key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	return s.write(key, append([]byte(nil), value...))
+}
+
+func (s *MockStub) DelState(key string) error {
+	return s.write(key, nil)
+}
+
+func (s *MockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	event := &peer.ChaincodeEvent{TxId: s.TxID, EventName: name, Payload: payload}
+	if s.inTx {
+		s.pendingEvent = event
+		return nil
+	}
+	s.Events = append(s.Events, event)
+	return nil
+}
+
+func (s *MockStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	var kvs []*queryresult.KV
+	for _, key := range s.Keys() {
+		if strings.HasPrefix(key, compositeKeyNamespace) {
+			continue
+		}
+		if startKey != "" && key < startKey {
+			continue
+		}
+		if endKey != "" && key >= endKey {
+			continue
+		}
+		kvs = append(kvs, &queryresult.KV{Key: key, Value: s.state[key]})
+	}
+	return &StateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	parts := strings.Split(compositeKey[1:], "\x00")
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	return parts[0], parts[1 : len(parts)-1], nil
+}
+
+func (s *MockStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var kvs []*queryresult.KV
+	for _, key := range s.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, &queryresult.KV{Key: key, Value: s.state[key]})
+		}
+	}
+	return &StateIterator{kvs: kvs}, nil
+}
+
+func (s *MockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := q.Run(s.state)
+	if err != nil {
+		return nil, err
+	}
+	return &StateIterator{kvs: q.Page(kvs, q.Skip, q.Limit)}, nil
+}
+
+// GetQueryResultWithPagination pages through the query results. Bookmarks
+// are opaque offsets into the sorted result set; a pageSize of 0 returns
+// every remaining result.
+func (s *MockStub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvs, err := q.Run(s.state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offset := q.Skip
+	if bookmark != "" {
+		offset, err = decodeBookmark(bookmark)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	limit := int(pageSize)
+	page := q.Page(kvs, offset, limit)
+
+	next := offset + len(page)
+	return &StateIterator{kvs: page}, &peer.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(page)),
+		Bookmark:            encodeBookmark(next),
+	}, nil
+}
+
+// privateWrite is a buffered write to a private data collection. A nil value
+// deletes the key.
+type privateWrite struct {
+	collection string
+	key        string
+	value      []byte
+}
+
+func (s *MockStub) applyPrivate(w privateWrite) {
+	data := s.private[w.collection]
+	if data == nil {
+		data = map[string][]byte{}
+		s.private[w.collection] = data
+	}
+	if w.value == nil {
+		delete(data, w.key)
+		return
+	}
+	data[w.key] = w.value
+}
+
+func (s *MockStub) writePrivate(collection, key string, value []byte) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	w := privateWrite{collection: collection, key: key, value: value}
+	if !s.inTx {
+		s.applyPrivate(w)
+		return nil
+	}
+	s.pendingPrivate = append(s.pendingPrivate, w)
+	return nil
+}
+
+func (s *MockStub) GetPrivateData(collection, key string) ([]byte, error) {
+	value, ok := s.private[collection][key]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (s *MockStub) PutPrivateData(collection, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	return s.writePrivate(collection, key, append([]byte(nil), value...))
+}
+
+func (s *MockStub) DelPrivateData(collection, key string) error {
+	return s.writePrivate(collection, key, nil)
+}
+
+// GetPrivateDataQueryResult runs a rich query against the committed private
+// data of collection.
+func (s *MockStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	data := s.private[collection]
+	if data == nil {
+		data = map[string][]byte{}
+	}
+	kvs, err := q.Run(data)
+	if err != nil {
+		return nil, err
+	}
+	return &StateIterator{kvs: q.Page(kvs, q.Skip, q.Limit)}, nil
+}
+
+func (s *MockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &HistoryIterator{mods: append([]*queryresult.KeyModification(nil), s.history[key]...)}, nil
+}
+
+func encodeBookmark(offset int) string {
+	return fmt.Sprintf("mock-%d", offset)
+}
+
+func decodeBookmark(bookmark string) (int, error) {
+	var offset int
+	if _, err := fmt.Sscanf(bookmark, "mock-%d", &offset); err != nil {
+		return 0, fmt.Errorf("invalid bookmark %q", bookmark)
+	}
+	return offset, nil
+}
+
+// StateIterator iterates over a fixed slice of key/value pairs.
+type StateIterator struct {
+	kvs    []*queryresult.KV
+	index  int
+	Closed bool
+}
+
+func (it *StateIterator) HasNext() bool {
+	return it.index < len(it.kvs)
+}
+
+func (it *StateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	kv := it.kvs[it.index]
+	it.index++
+	return kv, nil
+}
+
+func (it *StateIterator) Close() error {
+	it.Closed = true
+	return nil
+}
+
+// HistoryIterator iterates over the modifications of a key, oldest first.
+type HistoryIterator struct {
+	mods  []*queryresult.KeyModification
+	index int
+}
+
+func (it *HistoryIterator) HasNext() bool {
+	return it.index < len(it.mods)
+}
+
+func (it *HistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	mod := it.mods[it.index]
+	it.index++
+	return mod, nil
+}
+
+func (it *HistoryIterator) Close() error {
+	return nil
+}
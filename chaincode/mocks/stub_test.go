@@ -0,0 +1,204 @@
+package mocks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMockStubTransactions(t *testing.T) {
+	stub := NewMockStub()
+
+	if err := stub.PutState("a", []byte(`{"v":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := stub.Invoke("Update", func() error {
+		if err := stub.PutState("a", []byte(`{"v":2}`)); err != nil {
+			return err
+		}
+		if err := stub.PutState("b", []byte(`{"v":1}`)); err != nil {
+			return err
+		}
+		got, _ := stub.GetState("a")
+		if string(got) != `{"v":1}` {
+			t.Errorf("read inside tx = %s, want committed value", got)
+		}
+		return stub.SetEvent("updated", []byte("a"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := stub.GetState("a")
+	if string(got) != `{"v":2}` {
+		t.Fatalf("a = %s after commit", got)
+	}
+	if ev := stub.LastEvent(); ev == nil || ev.EventName != "updated" || ev.TxId != stub.TxID {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	err = stub.Invoke("Fail", func() error {
+		_ = stub.DelState("a")
+		_ = stub.PutState("c", []byte(`{}`))
+		_ = stub.SetEvent("failed", nil)
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if keys := stub.Keys(); len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("rolled back tx leaked writes: %v", keys)
+	}
+	if len(stub.Events) != 1 {
+		t.Fatalf("rolled back tx leaked events: %+v", stub.Events)
+	}
+
+	if err := stub.PutState("", []byte("x")); err == nil {
+		t.Error("expected empty key to be rejected")
+	}
+
+	if err := stub.Invoke("Delete", func() error { return stub.DelState("a") }); err != nil {
+		t.Fatal(err)
+	}
+	it, _ := stub.GetHistoryForKey("a")
+	var mods []bool
+	var lastTime int64
+	for it.HasNext() {
+		mod, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mod.Timestamp.Seconds < lastTime {
+			t.Error("history timestamps must not go backwards")
+		}
+		lastTime = mod.Timestamp.Seconds
+		mods = append(mods, mod.IsDelete)
+	}
+	if len(mods) != 3 || mods[0] || mods[1] || !mods[2] {
+		t.Fatalf("unexpected history %v", mods)
+	}
+
+	before := stub.TxTimestamp
+	stub.Advance(time.Hour)
+	stub.BeginTx("Later")
+	stub.Rollback()
+	if got := stub.TxTimestamp.Sub(before); got != time.Hour+time.Second {
+		t.Fatalf("advanced clock moved by %v", got)
+	}
+}
+
+func TestMockStubRangeAndPagination(t *testing.T) {
+	stub := NewMockStub()
+	for _, key := range []string{"k1", "k2", "k3", "k4", "k5"} {
+		_ = stub.PutState(key, []byte(`{"docType":"x"}`))
+	}
+	ck, err := stub.CreateCompositeKey("idx", []string{"k1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = stub.PutState(ck, []byte{0})
+
+	rng, _ := stub.GetStateByRange("k2", "k4")
+	var keys []string
+	for rng.HasNext() {
+		kv, _ := rng.Next()
+		keys = append(keys, kv.Key)
+	}
+	if len(keys) != 2 || keys[0] != "k2" || keys[1] != "k3" {
+		t.Fatalf("range keys = %v", keys)
+	}
+
+	all, _ := stub.GetStateByRange("", "")
+	count := 0
+	for all.HasNext() {
+		_, _ = all.Next()
+		count++
+	}
+	if count != 5 {
+		t.Fatalf("full range returned %d keys, composite keys must be skipped", count)
+	}
+
+	partial, _ := stub.GetStateByPartialCompositeKey("idx", nil)
+	if !partial.HasNext() {
+		t.Fatal("expected composite key in partial range")
+	}
+	kv, _ := partial.Next()
+	if _, attrs, _ := stub.SplitCompositeKey(kv.Key); len(attrs) != 1 || attrs[0] != "k1" {
+		t.Fatalf("unexpected composite attributes %v", attrs)
+	}
+
+	query := `{"selector":{"docType":"x"}}`
+	bookmark := ""
+	var pages [][]string
+	for i := 0; i < 4; i++ {
+		it, meta, err := stub.GetQueryResultWithPagination(query, 2, bookmark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []string
+		for it.HasNext() {
+			kv, _ := it.Next()
+			page = append(page, kv.Key)
+		}
+		if len(page) == 0 {
+			break
+		}
+		if int(meta.FetchedRecordsCount) != len(page) {
+			t.Fatalf("fetched count %d for page %v", meta.FetchedRecordsCount, page)
+		}
+		pages = append(pages, page)
+		bookmark = meta.Bookmark
+	}
+	if len(pages) != 3 || pages[0][0] != "k1" || pages[1][0] != "k3" || len(pages[2]) != 1 || pages[2][0] != "k5" {
+		t.Fatalf("unexpected pages %v", pages)
+	}
+
+	if _, _, err := stub.GetQueryResultWithPagination(query, 2, "bogus"); err == nil {
+		t.Error("expected invalid bookmark to fail")
+	}
+}
+
+func TestMockStubPrivateData(t *testing.T) {
+	stub := NewMockStub()
+
+	err := stub.Invoke("Put", func() error {
+		if err := stub.PutPrivateData("c1", "a", []byte(`{"district":"Mueang"}`)); err != nil {
+			return err
+		}
+		if got, _ := stub.GetPrivateData("c1", "a"); got != nil {
+			t.Errorf("read inside tx = %s, want nothing", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := stub.GetPrivateData("c1", "a"); string(got) != `{"district":"Mueang"}` {
+		t.Fatalf("a = %s after commit", got)
+	}
+	if got, _ := stub.GetPrivateData("c2", "a"); got != nil {
+		t.Fatalf("collections leaked into each other: %s", got)
+	}
+	if got, _ := stub.GetState("a"); got != nil {
+		t.Fatalf("private data leaked into the world state: %s", got)
+	}
+
+	_ = stub.Invoke("Fail", func() error {
+		_ = stub.DelPrivateData("c1", "a")
+		return errors.New("boom")
+	})
+	it, err := stub.GetPrivateDataQueryResult("c1", `{"selector":{"district":{"$regex":"Mue"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv, err := it.Next(); err != nil || kv.Key != "a" {
+		t.Fatalf("unexpected query result %v, %v", kv, err)
+	}
+
+	if err := stub.DelPrivateData("c1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := stub.GetPrivateData("c1", "a"); got != nil {
+		t.Fatalf("a = %s after delete", got)
+	}
+}
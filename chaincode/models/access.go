@@ -0,0 +1,49 @@
+package models
+
+type Role string
+
+// Roles
+const (
+	RoleNectec    Role = "nectec"
+	RoleRegulator Role = "regulator"
+	RoleFarmer    Role = "farmer"
+	RolePacker    Role = "packer"
+	RoleExporter  Role = "exporter"
+)
+
+var AllRoles = []Role{RoleNectec, RoleRegulator, RoleFarmer, RolePacker, RoleExporter}
+
+func (r Role) IsValid() bool {
+	for _, role := range AllRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeRole grants Role to identities whose certificate attribute
+// Attribute equals Value.
+type AttributeRole struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Role      Role   `json:"role"`
+}
+
+// AccessPolicy maps client identities to roles and transaction functions to
+// the roles allowed to invoke them. Functions missing from Functions fall
+// back to the chaincode defaults.
+type AccessPolicy struct {
+	MspRoles       map[string][]Role `json:"mspRoles"`
+	AttributeRoles []AttributeRole   `json:"attributeRoles"`
+	Functions      map[string][]Role `json:"functions"`
+	UpdatedBy      string            `json:"updatedBy"`
+	UpdatedAt      string            `json:"updatedAt"`
+	DocType        DocType           `json:"docType"`
+}
+
+type CallerAccess struct {
+	Id    string `json:"id"`
+	MspId string `json:"mspId"`
+	Roles []Role `json:"roles"`
+}
@@ -0,0 +1,40 @@
+package models
+
+type BatchRowStatus string
+
+const (
+	BatchRowOk               BatchRowStatus = "ok"
+	BatchRowDuplicate        BatchRowStatus = "duplicate"
+	BatchRowInvalidField     BatchRowStatus = "invalidField"
+	BatchRowUnknownReference BatchRowStatus = "unknownReference"
+)
+
+// BatchRowResult reports the outcome of one row of a batch import. Row
+// counts from 1 in the order the rows were sent.
+type BatchRowResult struct {
+	Row     int            `json:"row"`
+	Id      string         `json:"id"`
+	Status  BatchRowStatus `json:"status"`
+	Field   string         `json:"field,omitempty"`
+	Message string         `json:"message,omitempty"`
+}
+
+// BatchSummary counts the rows of a batch import. Dry runs and committed
+// imports both report it in the batch field of their stateChange event, next
+// to the records a committed import wrote.
+type BatchSummary struct {
+	Function  string  `json:"function"`
+	DocType   DocType `json:"docType"`
+	DryRun    bool    `json:"dryRun"`
+	Committed bool    `json:"committed"`
+	Total     int     `json:"total"`
+	Ok        int     `json:"ok"`
+	Failed    int     `json:"failed"`
+}
+
+// BatchImportResult is the summary of a batch import returned to the caller,
+// with the status of every row.
+type BatchImportResult struct {
+	BatchSummary
+	Rows []BatchRowResult `json:"rows"`
+}
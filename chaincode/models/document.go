@@ -0,0 +1,52 @@
+package models
+
+// TransactionDocument anchors a file stored off-chain, such as a GAP or GMP
+// certificate, an invoice or a phytosanitary certificate, to the asset it
+// belongs to. Only the SHA-256 digest of the file is kept on the ledger.
+type TransactionDocument struct {
+	Id           string  `json:"id"`
+	AssetId      string  `json:"assetId"`
+	AssetDocType DocType `json:"assetDocType"`
+	Kind         string  `json:"kind"`
+	Sha256       string  `json:"sha256"`
+	MediaType    string  `json:"mediaType"`
+	Size         int64   `json:"size"`
+	StorageUri   string  `json:"storageUri"`
+	UploadedBy   string  `json:"uploadedBy"`
+	DocType      DocType `json:"docType"`
+	Owner        string  `json:"owner"`
+	OrgName      string  `json:"orgName"`
+	CreatedAt    string  `json:"createdAt"`
+	UpdatedAt    string  `json:"updatedAt"`
+}
+
+// AttachDocumentInput is the argument of AttachDocument.
+type AttachDocumentInput struct {
+	AssetId      string  `json:"assetId" validate:"required"`
+	AssetDocType DocType `json:"assetDocType" validate:"required"`
+	Kind         string  `json:"kind"`
+	Sha256       string  `json:"sha256" validate:"required"`
+	MediaType    string  `json:"mediaType" validate:"required"`
+	Size         int64   `json:"size" validate:"min=1"`
+	StorageUri   string  `json:"storageUri" validate:"required"`
+}
+
+// DocumentVerification is the result of VerifyDocument. Document is the
+// matching attachment when the presented hash is anchored on the ledger.
+type DocumentVerification struct {
+	Verified bool                 `json:"verified"`
+	Document *TransactionDocument `json:"document"`
+}
+
+type DocumentFilterParams struct {
+	AssetId      string  `json:"assetId"`
+	AssetDocType DocType `json:"assetDocType"`
+	Kind         string  `json:"kind"`
+	Pagination
+}
+
+type TransactionDocumentResponse struct {
+	Data  []*TransactionDocument `json:"obj"`
+	Total int                    `json:"total"`
+	PageInfo
+}
@@ -0,0 +1,11 @@
+package models
+
+// EmbeddedDrift is a record whose embedded copy no longer matches the records
+// it copies. Fields lists the JSON fields that differ; Message explains drift
+// that is not in the copy itself, such as a link to a missing record.
+type EmbeddedDrift struct {
+	DocType DocType  `json:"docType"`
+	Id      string   `json:"id"`
+	Fields  []string `json:"fields"`
+	Message string   `json:"message,omitempty"`
+}
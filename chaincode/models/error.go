@@ -0,0 +1,14 @@
+package models
+
+// ErrorCode classifies a failed transaction. Codes are stable so the gateway
+// can map them to HTTP statuses.
+type ErrorCode string
+
+const (
+	ErrNotFound      ErrorCode = "NOT_FOUND"
+	ErrAlreadyExists ErrorCode = "ALREADY_EXISTS"
+	ErrUnauthorized  ErrorCode = "UNAUTHORIZED"
+	ErrValidation    ErrorCode = "VALIDATION"
+	ErrConflict      ErrorCode = "CONFLICT"
+	ErrInternal      ErrorCode = "INTERNAL"
+)
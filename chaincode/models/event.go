@@ -0,0 +1,39 @@
+package models
+
+// EventVersion is the version of the StateChangeEvent envelope. It changes
+// whenever a field is removed or changes meaning.
+const EventVersion = 1
+
+type ChangeType string
+
+const (
+	ChangeCreate   ChangeType = "create"
+	ChangeUpdate   ChangeType = "update"
+	ChangeDelete   ChangeType = "delete"
+	ChangeRestore  ChangeType = "restore"
+	ChangeTransfer ChangeType = "transfer"
+)
+
+// StateChange describes one record written by a transaction. The digests are
+// the SHA-256 of the stored record before and after the transaction; a
+// created record has no before digest and a removed one no after digest.
+type StateChange struct {
+	Type         ChangeType `json:"type"`
+	DocType      DocType    `json:"docType"`
+	Id           string     `json:"id"`
+	BeforeDigest string     `json:"beforeDigest,omitempty"`
+	AfterDigest  string     `json:"afterDigest,omitempty"`
+}
+
+// StateChangeEvent is the one event a transaction emits, listing every
+// record it wrote. Batch imports add their summary in Batch.
+type StateChangeEvent struct {
+	Version   int           `json:"version"`
+	TxId      string        `json:"txId"`
+	Function  string        `json:"function"`
+	Actor     string        `json:"actor"`
+	MspId     string        `json:"mspId"`
+	Timestamp string        `json:"timestamp"`
+	Changes   []StateChange `json:"changes"`
+	Batch     *BatchSummary `json:"batch,omitempty"`
+}
@@ -6,16 +6,16 @@ type TransactionExporter struct {
 	Owner     string    `json:"owner"`
 	PlantType     string    `json:"plantType"`
 	OrgName   string    `json:"orgName"`
-	PlantTypeDetail PlantTypeModel `json:"plantTypeDetail"`
+	PlantTypeDetail PlantTypeModel `json:"plantTypeDetail" validate:"-"`
 	IsCanDelete bool       `json:"isCanDelete"`
 	DocType   DocType   `json:"docType"`
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
+	Tombstone
 }
 
 type ExporterFilterGetAll struct {
-	Skip  int `json:"skip"`
-	Limit int `json:"limit"`
+	Pagination
 	Search             *string  `json:"search"`
 	Province             *string  `json:"province"`
 	District             *string  `json:"district"`
@@ -40,12 +40,14 @@ type ExporterTransactionResponse struct {
 	CreatedAt string `json:"createdAt"`
 	IsCanDelete bool       `json:"isCanDelete"`
 	PlantTypeDetail PlantTypeModel `json:"plantTypeDetail"`
+	Tombstone
 }
 
 type ExporterGetAllResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*ExporterTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type ExporterForImportResponse struct {
@@ -9,13 +9,14 @@ type TransactionFarmer struct {
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
 	FarmerGaps []FarmerGap `json:"farmerGaps"`
+	PrivateHash string `json:"privateHash"`
 	DocType DocType `json:"docType"`
+	Tombstone
 }
 
 type FilterGetAllFarmer struct {
 	Gap   string `json:"gap"`
-	Skip  int `json:"skip"`
-	Limit int `json:"limit"`
+	Pagination
 	FarmerGap string `json:"farmerGap"`
 	Search string `json:"search"`
 }
@@ -25,11 +26,11 @@ type FarmerGap struct {
 	CertID      string    `json:"certId"`
 	DisplayCertID      string    `json:"displayCertId"`
 	AreaCode    string    `json:"areaCode"`
-	AreaRai     float32   `json:"areaRai"`
+	AreaRai     float32   `json:"areaRai" validate:"min=0"`
 	AreaStatus  string    `json:"areaStatus"`
 	OldAreaCode string    `json:"oldAreaCode"`
-	IssueDate   string    `json:"issueDate"`
-	ExpireDate  string    `json:"expireDate"`
+	IssueDate   string    `json:"issueDate" validate:"date"`
+	ExpireDate  string    `json:"expireDate" validate:"date"`
 	District    string    `json:"district"`
 	Province    string    `json:"province"`
 	UpdatedDate string    `json:"updatedDate"`
@@ -50,12 +51,14 @@ type FarmerTransactionResponse struct {
 	FarmerGaps []FarmerGap `json:"farmerGaps"`
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
+	Tombstone
 }
 
 type FarmerGetAllResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*FarmerTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type FarmerTransactionHistory struct {
@@ -1,5 +1,79 @@
 package models
 
+import "fmt"
+
+// FormEStatus is the state of a Form E certificate of origin in the ACFTA
+// workflow. The codes of submitted and cancelled match Form Es stored before
+// the workflow existed.
+type FormEStatus string
+
+const (
+	FormEDraft       FormEStatus = "0"
+	FormESubmitted   FormEStatus = "1"
+	FormECancelled   FormEStatus = "2"
+	FormEUnderReview FormEStatus = "3"
+	FormEIssued      FormEStatus = "4"
+	FormERejected    FormEStatus = "5"
+	FormEAmended     FormEStatus = "6"
+	FormEReplaced    FormEStatus = "7"
+)
+
+var formEStatusNames = map[FormEStatus]string{
+	FormEDraft:       "draft",
+	FormESubmitted:   "submitted",
+	FormECancelled:   "cancelled",
+	FormEUnderReview: "underReview",
+	FormEIssued:      "issued",
+	FormERejected:    "rejected",
+	FormEAmended:     "amended",
+	FormEReplaced:    "replaced",
+}
+
+func (f FormEStatus) String() string {
+	if name, ok := formEStatusNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%s)", string(f))
+}
+
+// FormEStatusChange records one transition of a Form E.
+type FormEStatusChange struct {
+	From      FormEStatus `json:"from"`
+	To        FormEStatus `json:"to"`
+	ChangedBy string      `json:"changedBy"`
+	OrgName   string      `json:"orgName"`
+	Reason    string      `json:"reason"`
+	ChangedAt string      `json:"changedAt"`
+}
+
+// FormETransitionInput is the argument of the Form E workflow functions.
+// Amendments carry the corrected certificate in FormE, and replacements the
+// new certificate along with the id to store it under in NewId, which is
+// allocated when empty. CancelReason
+// is accepted in place of Reason when cancelling, as is the whole Form E that
+// CancelFormE used to take.
+type FormETransitionInput struct {
+	Id           string            `json:"id" validate:"required"`
+	Reason       string            `json:"reason"`
+	CancelReason string            `json:"cancelReason"`
+	NewId        string            `json:"newId"`
+	FormE        *TransactionFormE `json:"formE"`
+}
+
+// FormEDiscrepancy is one problem found validating the content of a Form E.
+// Field is the JSON path of the offending value.
+type FormEDiscrepancy struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+// FormEValidation is the result of the ValidateFormE dry run.
+type FormEValidation struct {
+	Valid         bool               `json:"valid"`
+	Discrepancies []FormEDiscrepancy `json:"discrepancies"`
+}
+
 type Shipper struct {
 	Name       string `json:"name"`
 	Address    string `json:"address"`
@@ -41,9 +115,10 @@ type Invoice struct {
 type TransactionFormE struct {
 	Id                      string  `json:"id"`
 	ReferenceNo       		string  `json:"referenceNo"`
+	Office                  string  `json:"office"`
 	CountryOfIssuance       string  `json:"countryOfIssuance"`
 	RequestType             string  `json:"requestType"`
-	Status                  string  `json:"status"`
+	Status                  FormEStatus `json:"status"`
 	CarrierName             string  `json:"carrierName"`
 	DepartureCity           string  `json:"departureCity"`
 	DestinationCity         string  `json:"destinationCity"`
@@ -52,11 +127,14 @@ type TransactionFormE struct {
 	ExportDate              string  `json:"exportDate"`
 	ImportSpecialCondition  string  `json:"importSpecialCondition"`
 	PreviousReferenceNumber string  `json:"previousReferenceNumber"`
+	ReplacedByReferenceNumber string `json:"replacedByReferenceNumber"`
+	IssuedAt                string  `json:"issuedAt"`
 	CreatedAt               string  `json:"createdAt"`
 	UpdatedAt               string  `json:"updatedAt"`
 	Owner                   string  `json:"owner"`
 	OrgName                 string  `json:"orgName"`
 	CancelReason            string  `json:"cancelReason"`
+	StatusHistory           []FormEStatusChange `json:"statusHistory"`
 	CreatedById             string  `json:"createdById"`
 	Shipper                 *Shipper `json:"shipper"`
 	Receiver                *Receiver `json:"receiver"`
@@ -74,13 +152,13 @@ type FormEFilterParams struct {
 	StartDate   			   *string  `json:"startDate"`
 	EndDate                    *string  `json:"endDate"`
 	Status                     string  `json:"status"`
-	Skip                       int     `json:"skip"`
-	Limit                      int     `json:"limit"`
+	Pagination
 }
 
 type TransactionFormEResponse struct {
 	Data   []*TransactionFormE `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type FormETransactionHistory struct {
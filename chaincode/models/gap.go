@@ -1,15 +1,16 @@
 package models
 
 type TransactionGap struct {
-	Id          string    `json:"id"`
+	Id          string    `json:"id" validate:"required"`
 	CertID      string    `json:"certId"`
 	DisplayCertID      string    `json:"displayCertId"`
 	AreaCode    string    `json:"areaCode"`
-	AreaRai     float32   `json:"areaRai"`
+	AreaRai     float32   `json:"areaRai" validate:"min=0"`
 	AreaStatus  string    `json:"areaStatus"`
+	PlantType   string    `json:"plantType"`
 	OldAreaCode string    `json:"oldAreaCode"`
-	IssueDate   string    `json:"issueDate"`
-	ExpireDate  string    `json:"expireDate"`
+	IssueDate   string    `json:"issueDate" validate:"date"`
+	ExpireDate  string    `json:"expireDate" validate:"date"`
 	District    string    `json:"district"`
 	Province    string    `json:"province"`
 	Source      string    `json:"source"`
@@ -20,11 +21,11 @@ type TransactionGap struct {
 	DocType     DocType   `json:"docType"`
 	UpdatedAt   string `json:"updatedAt"`
 	CreatedAt   string `json:"createdAt"`
+	Tombstone
 }
 
 type FilterGetAllGap struct {
-	Skip         int      `json:"skip"`
-	Limit        int      `json:"limit"`
+	Pagination
 	CertID       *string  `json:"certId"`
 	FarmerID       *string  `json:"farmerId"`
 	AreaCode     *string  `json:"areaCode"`
@@ -48,6 +49,7 @@ type GapTransactionResponse struct {
 	AreaCode    string    `json:"areaCode"`
 	AreaRai     float32   `json:"areaRai"`
 	AreaStatus  string    `json:"areaStatus"`
+	PlantType   string    `json:"plantType"`
 	OldAreaCode string    `json:"oldAreaCode"`
 	IssueDate   string    `json:"issueDate"`
 	ExpireDate  string    `json:"expireDate"`
@@ -60,15 +62,43 @@ type GapTransactionResponse struct {
 	CreatedAt   string `json:"createdAt"`
 	TotalSold   float32   `json:"totalSold"`
 	IsCanDelete bool       `json:"isCanDelete"`
+	Tombstone
 }
 
 type GetAllGapResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*GapTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type GetGapByCertIdResponse struct {
 	Data string              `json:"data"`
 	Obj  *GapTransactionResponse `json:"obj"`
-}
\ No newline at end of file
+}
+
+// GapYieldConfig sets how many kilograms a rai of GAP certified area may
+// yield, per plant type. DefaultYieldPerRai applies to plant types without an
+// entry; a zero yield leaves the GAP quota unlimited.
+type GapYieldConfig struct {
+	YieldPerRai        map[string]float32 `json:"yieldPerRai"`
+	DefaultYieldPerRai float32            `json:"defaultYieldPerRai" validate:"min=0"`
+	UpdatedBy          string             `json:"updatedBy"`
+	UpdatedAt          string             `json:"updatedAt"`
+	DocType            DocType            `json:"docType"`
+}
+
+// GapQuota reports how much weight may still be sold from a GAP
+// certificate. Sold counts approved and completed packings, reserved counts
+// draft and saved ones.
+type GapQuota struct {
+	CertID      string  `json:"certId"`
+	PlantType   string  `json:"plantType"`
+	AreaRai     float32 `json:"areaRai"`
+	YieldPerRai float32 `json:"yieldPerRai"`
+	Limited     bool    `json:"limited"`
+	Capacity    float32 `json:"capacity"`
+	Sold        float32 `json:"sold"`
+	Reserved    float32 `json:"reserved"`
+	Remaining   float32 `json:"remaining"`
+}
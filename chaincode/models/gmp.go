@@ -1,11 +1,13 @@
 package models
 
 type TransactionGmp struct {
-	Id                         string    `json:"id"`
+	Id                         string    `json:"id" validate:"required"`
 	PackerId 									 string    `json:"packerId"`
 	PackingHouseRegisterNumber string    `json:"packingHouseRegisterNumber"`
 	Address                    string    `json:"address"`
 	PackingHouseName           string    `json:"packingHouseName"`
+	IssueDate                  string    `json:"issueDate" validate:"date"`
+	ExpireDate                 string    `json:"expireDate" validate:"date"`
 	UpdatedDate                string    `json:"updatedDate"`
 	Source                     string    `json:"source"`
 	DocType                    DocType   `json:"docType"`
@@ -14,12 +16,12 @@ type TransactionGmp struct {
 	UpdatedAt                  string `json:"updatedAt"`
 	CreatedAt                  string `json:"createdAt"`
 	IsCanDelete 			   bool       `json:"isCanDelete"`
+	Tombstone
 }
 
 type FilterGetAllGmp struct {
-	Skip                       int     `json:"skip"`
+	Pagination
 	PackerId 									 string  `json:"packerId"`
-	Limit                      int     `json:"limit"`
 	PackingHouseRegisterNumber *string `json:"packingHouseRegisterNumber"`
 	PackingHouseName 		   *string `json:"packingHouseName"`
 	Address                    *string `json:"address"`
@@ -33,17 +35,21 @@ type GmpTransactionResponse struct {
 	PackingHouseRegisterNumber string    `json:"packingHouseRegisterNumber"`
 	Address                    string    `json:"address"`
 	PackingHouseName           string    `json:"packingHouseName"`
+	IssueDate                  string    `json:"issueDate"`
+	ExpireDate                 string    `json:"expireDate"`
 	UpdatedDate                string    `json:"updatedDate"`
 	IsCanDelete 			   bool       `json:"isCanDelete"`
 	Source                     string    `json:"source"`
 	UpdatedAt                  string `json:"updatedAt"`
 	CreatedAt                  string `json:"createdAt"`
+	Tombstone
 }
 
 type GmpGetAllResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*GmpTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type GetByRegisterNumberResponse struct {
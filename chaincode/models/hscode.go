@@ -1,15 +1,16 @@
 package models
 
 type TransactionHscode struct {
-	Id                         string    `json:"id"`
+	Id                         string    `json:"id" validate:"required"`
 	Hscode 					   string    `json:"hscode"`
 	Description 			   string    `json:"description"`
 	DocType                    DocType   `json:"docType"`
 	Owner                      string    `json:"owner"`
 	OrgName                    string    `json:"orgName"`
-	Order                      int    	 `json:"order"`
+	Order                      int    	 `json:"order" validate:"min=0"`
 	UpdatedAt                  string    `json:"updatedAt"`
 	CreatedAt                  string    `json:"createdAt"`
+	Tombstone
 }
 
 type HscodeTransactionResponse struct {
@@ -21,11 +22,11 @@ type HscodeTransactionResponse struct {
 }
 
 type HscodeFilterParams struct {
-	Skip                       int    	 `json:"skip"`
-	Limit                      int    	 `json:"limit"`
+	Pagination
 }
 
 type TransactionHscodeResponse struct {
 	Data   []*TransactionHscode `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
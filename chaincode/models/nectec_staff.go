@@ -1,7 +1,7 @@
 package models
 
 type TransactionNectecStaff struct {
-	Id        string    `json:"id"`
+	Id        string    `json:"id" validate:"required"`
 	CertId    string    `json:"certId"`
 	ProfileImg    string    `json:"profileImg"`
 	Owner     string    `json:"owner"`
@@ -9,11 +9,11 @@ type TransactionNectecStaff struct {
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
 	DocType DocType `json:"docType"`
+	Tombstone
 }
 
 type FilterGetAllNectecStaff struct {
-	Skip  int `json:"skip"`
-	Limit int `json:"limit"`
+	Pagination
 	Search *string `json:"search"`
 }
 
@@ -23,11 +23,13 @@ type NectecStaffTransactionResponse struct {
 	ProfileImg    string    `json:"profileImg"`
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
+	Tombstone
 }
 
 type GetAllNectecStaffResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*NectecStaffTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
@@ -1,14 +1,16 @@
 package models
 
 type TransactionPackaging struct {
-	Id                         string    `json:"id"`
+	Id                         string    `json:"id" validate:"required"`
 	ContainerId 			   string    `json:"containerId"`
 	ExportId 				   string    `json:"exportId"`
 	LotNumber 				   string    `json:"lotNumber"`
+	PalletNumber 			   string    `json:"palletNumber"`
 	CreatedById 			   string    `json:"createdById"`
 	BoxId 				       string    `json:"boxId"`
 	Gap 				       string    `json:"gap"`
 	Gmp 				       string    `json:"gmp"`
+	PackingId 				   string    `json:"packingId"`
 	GradeName 				   string    `json:"gradeName"`
 	Gtin14 				       string    `json:"gtin14"`
 	Gtin13 				       string    `json:"gtin13"`
@@ -25,9 +27,11 @@ type ReadPackaging struct {
 	ContainerId 			   string    `json:"containerId"`
 	ExportId 				   string    `json:"exportId"`
 	LotNumber 				   string    `json:"lotNumber"`
+	PalletNumber 			   string    `json:"palletNumber"`
 	BoxId 				       string    `json:"boxId"`
 	Gap 				       string    `json:"gap"`
 	Gmp 				       string    `json:"gmp"`
+	PackingId 				   string    `json:"packingId"`
 	GradeName 				   string    `json:"gradeName"`
 	Gtin14 				       string    `json:"gtin14"`
 	Gtin13 				       string    `json:"gtin13"`
@@ -48,11 +52,11 @@ type PackagingFilterParams struct {
 	CreatedById   			   string 	 `json:"createdById"`
 	StartDate   			   *string 	 `json:"startDate"`
 	EndDate                    *string 	 `json:"endDate"`
-	Skip                       int    	 `json:"skip"`
-	Limit                      int    	 `json:"limit"`
+	Pagination
 }
 
 type TransactionPackagingResponse struct {
 	Data   []*TransactionPackaging `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
\ No newline at end of file
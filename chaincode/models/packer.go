@@ -14,11 +14,11 @@ type TransactionPacker struct {
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
 	DocType	  DocType	`json:"docType"`
+	Tombstone
 }
 
 type FilterGetAllPacker struct {
-	Skip  int `json:"skip"`
-	Limit int `json:"limit"`
+	Pagination
 	Search                     *string `json:"search"`
 	PackerGmp string `json:"packerGmp"`
 	PackingHouseName           string    `json:"packingHouseName"`
@@ -51,12 +51,14 @@ type PackerTransactionResponse struct {
 	CreatedAt string `json:"createdAt"`
 	PackingHouseName           string    `json:"packingHouseName"`
 	PackingHouseRegisterNumber string    `json:"packingHouseRegisterNumber"`
+	Tombstone
 }
 
 type PackerGetAllResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*PackerTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type PackerByIdResponse struct {
@@ -1,15 +1,85 @@
 package models
 
+import "fmt"
+
+type ProcessStatus int
+
+// Packing process status. Approved and completed orders count towards the
+// quota sold from a GAP certificate.
+const (
+	StatusDraft       ProcessStatus = 0
+	StatusPackerSaved ProcessStatus = 1
+	StatusApproved    ProcessStatus = 2
+	StatusCompleted   ProcessStatus = 3
+	StatusCancelled   ProcessStatus = 4
+)
+
+var processStatusNames = map[ProcessStatus]string{
+	StatusDraft:       "draft",
+	StatusPackerSaved: "packerSaved",
+	StatusApproved:    "approved",
+	StatusCompleted:   "completed",
+	StatusCancelled:   "cancelled",
+}
+
+// SoldStatuses are the statuses whose packed weight is counted as sold.
+var SoldStatuses = []ProcessStatus{StatusApproved, StatusCompleted}
+
+// OpenStatuses are the statuses of orders that reserve quota from a GAP
+// certificate without having sold from it yet.
+var OpenStatuses = []ProcessStatus{StatusDraft, StatusPackerSaved}
+
+func (p ProcessStatus) String() string {
+	if name, ok := processStatusNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(p))
+}
+
+func (p ProcessStatus) IsValid() bool {
+	_, ok := processStatusNames[p]
+	return ok
+}
+
+func (p ProcessStatus) IsSold() bool {
+	for _, status := range SoldStatuses {
+		if status == p {
+			return true
+		}
+	}
+	return false
+}
+
+// PackingStatusChange records one transition of a packing order.
+type PackingStatusChange struct {
+	From      ProcessStatus `json:"from"`
+	To        ProcessStatus `json:"to"`
+	ChangedBy string        `json:"changedBy"`
+	OrgName   string        `json:"orgName"`
+	Reason    string        `json:"reason"`
+	ChangedAt string        `json:"changedAt"`
+}
+
+// PackingTransitionInput is the argument of the packing status transition
+// functions.
+type PackingTransitionInput struct {
+	Id           string   `json:"id" validate:"required"`
+	ApprovedType string   `json:"approvedType"`
+	FinalWeight  *float32 `json:"finalWeight" validate:"min=0"`
+	CancelReason string   `json:"cancelReason"`
+	Remark       string   `json:"remark"`
+}
+
 type TransactionPacking struct {
-	Id             string    `json:"id"`
+	Id             string    `json:"id" validate:"required"`
 	OrderID        string    `json:"orderId"`
 	FarmerID       string    `json:"farmerId"`
-	ForecastWeight float32   `json:"forecastWeight"`
-	ActualWeight   float32   `json:"actualWeight"`
+	ForecastWeight float32   `json:"forecastWeight" validate:"min=0"`
+	ActualWeight   float32   `json:"actualWeight" validate:"min=0"`
 	SavedTime      string    `json:"savedTime"`
 	ApprovedDate   string    `json:"approvedDate"`
 	ApprovedType   string    `json:"approvedType"`
-	FinalWeight    float32   `json:"finalWeight"`
+	FinalWeight    float32   `json:"finalWeight" validate:"min=0,lte=ActualWeight"`
 	Remark         string    `json:"remark"`
 	CancelReason   string    `json:"cancelReason"`
 	PackerId       string    `json:"packerId"`
@@ -17,8 +87,9 @@ type TransactionPacking struct {
 	PackingHouseName            string    `json:"packingHouseName"`
 	Gap            string    `json:"gap"` // รหัสซื้อขาย
 	DisplayCertId     string    `json:"displayCertId"`
-	ProcessStatus  int       `json:"processStatus"`
-	SellingStep    int    `json:"sellingStep"`
+	ProcessStatus  ProcessStatus `json:"processStatus"`
+	SellingStep    int    `json:"sellingStep" validate:"min=0"`
+	StatusHistory  []PackingStatusChange `json:"statusHistory"`
 	Owner          string    `json:"owner"`
 	Province          string    `json:"province"`
 	District          string    `json:"district"`
@@ -28,11 +99,11 @@ type TransactionPacking struct {
 	UpdatedAt      string `json:"updatedAt"`
 	CreatedAt      string `json:"createdAt"`
 	DocType        DocType   `json:"docType"`
+	Tombstone
 }
 
 type FilterGetAllPacking struct {
-	Skip               int      `json:"skip"`
-	Limit              int      `json:"limit"`
+	Pagination
 	Search             *string  `json:"search"`
 	PackerId           *string  `json:"packerId"`
 	FarmerID					 *string  `json:"farmerId"` 
@@ -67,20 +138,23 @@ type PackingTransactionResponse struct {
 	Gap           string    `json:"gap"`
 	DisplayCertId     string    `json:"displayCertId"`
 	CancelReason           string    `json:"cancelReason"`
-	ProcessStatus int       `json:"processStatus"`
+	ProcessStatus ProcessStatus `json:"processStatus"`
 	TotalSold        float32   `json:"totalSold"`
 	SellingStep       int    `json:"sellingStep"`
+	StatusHistory []PackingStatusChange `json:"statusHistory"`
 	UpdatedAt     string `json:"updatedAt"`
 	TotalSoldSnapShot        float32   `json:"totalSoldSnapShot"`
 	CreatedAt     string `json:"createdAt"`
 	Province          string    `json:"province"`
 	District          string    `json:"district"`
+	Tombstone
 }
 
 type PackingGetAllResponse struct {
 	Data  string                `json:"data"`
 	Obj   []*PackingTransactionResponse `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type PackingTransactionHistory struct {
@@ -1,30 +1,31 @@
 package models
 
 type PlantTypeModel struct {
-	Id          string      `json:"id"`
+	Id          string      `json:"id" validate:"required"`
 	Name        string      `json:"name"`
 	Address     string      `json:"address"`
 	Province    string      `json:"province"`
 	District    string      `json:"district"`
 	PostCode    string      `json:"postCode"`
 	Email    	string      `json:"email"`
-	IssueDate   string      `json:"issueDate"`
-	ExpiredDate string      `json:"expiredDate"`
+	IssueDate   string      `json:"issueDate" validate:"date"`
+	ExpiredDate string      `json:"expiredDate" validate:"date"`
 	PlantType   string      `json:"plantType"`
 	ExporterId  string      `json:"exporterId"`
+	PrivateHash string      `json:"privateHash"`
     Owner       string      `json:"owner"`
 	OrgName     string      `json:"orgName"`
 	DocType     DocType     `json:"docType"`
 	IsCanDelete bool       `json:"isCanDelete"`
 	CreatedAt   string      `json:"createdAt"`
     UpdatedAt   string      `json:"updatedAt"`
+	Tombstone
 }
 
 type PlanTypeFilterParams struct {
 	AvailablePlanType	string    	 `json:"availablePlantType"`
 	PlantType			string    	 `json:"plantType"`
-	Skip				int    	 	 `json:"skip"`
-	Limit   			int    	 	 `json:"limit"`
+	Pagination
 	Search             *string  `json:"search"`
 	Province             *string  `json:"province"`
 	District             *string  `json:"district"`
@@ -37,4 +38,5 @@ type PlanTypeFilterParams struct {
 type PlantTypeResponse struct {
 	Data   []*PlantTypeModel `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
\ No newline at end of file
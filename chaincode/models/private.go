@@ -0,0 +1,17 @@
+package models
+
+// FarmerPrivateDetails are the personal fields of a farmer record, kept in the
+// farmer private data collection under the farmer id.
+type FarmerPrivateDetails struct {
+	ProfileImg string `json:"profileImg"`
+}
+
+// ContactDetails are the personal fields of plant type records and of the
+// plant type detail embedded in exporter records. They are kept in the
+// exporter private data collection under the id of the record.
+type ContactDetails struct {
+	Address  string `json:"address"`
+	District string `json:"district"`
+	PostCode string `json:"postCode"`
+	Email    string `json:"email"`
+}
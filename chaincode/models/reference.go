@@ -0,0 +1,9 @@
+package models
+
+// Reference is a live record that points at another one through Field.
+// References block deleting the record they point at.
+type Reference struct {
+	DocType DocType `json:"docType"`
+	Id      string  `json:"id"`
+	Field   string  `json:"field"`
+}
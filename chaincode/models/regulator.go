@@ -1,7 +1,7 @@
 package models
 
 type TransactionRegulator struct {
-	Id        string    `json:"id"`
+	Id        string    `json:"id" validate:"required"`
 	CertId    string    `json:"certId"`
 	ProfileImg    string    `json:"profileImg"`
 	UserId    string    `json:"userId"`
@@ -10,12 +10,12 @@ type TransactionRegulator struct {
 	DocType   DocType `json:"docType"`
 	UpdatedAt string `json:"updatedAt"`
 	CreatedAt string `json:"createdAt"`
+	Tombstone
 }
 
 type FilterGetAllRegulator struct {
 	UserId string `json:"userId"`
-	Skip   int 	  `json:"skip"`
-	Limit  int     `json:"limit"`
+	Pagination
 }
 
 type RegulatorTransactionResponse struct {
@@ -28,6 +28,7 @@ type RegulatorTransactionResponse struct {
 type RegulatorGetAllResponse struct {
 	Obj   []*TransactionRegulator `json:"obj"`
 	Total int                   `json:"total"`
+	PageInfo
 }
 
 type RegulatorByIdResponse struct {
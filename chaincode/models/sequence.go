@@ -0,0 +1,21 @@
+package models
+
+// SequenceConfig holds the id format of every sequence, by sequence name.
+// Formats are templates such as "FE{yyyy}{seq:6}"; see utils.FormatSequence.
+type SequenceConfig struct {
+	Formats   map[string]string `json:"formats"`
+	UpdatedBy string            `json:"updatedBy"`
+	UpdatedAt string            `json:"updatedAt"`
+	DocType   DocType           `json:"docType"`
+}
+
+// SequenceCounter is the counter behind one scope of a sequence. Formats with a
+// year or office count separately for every year or office, each scope
+// being the format with everything but the counter filled in.
+type SequenceCounter struct {
+	Name      string  `json:"name"`
+	Scope     string  `json:"scope"`
+	Value     int64   `json:"value"`
+	UpdatedAt string  `json:"updatedAt"`
+	DocType   DocType `json:"docType"`
+}
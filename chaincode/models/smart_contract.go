@@ -1,8 +1,22 @@
 package models
 
+// Pagination selects a page of a listing. Pass the bookmark of the previous
+// page to fetch the next one; skip only applies to the first page. SkipTotal
+// saves counting the matches when the caller already knows the total.
+// IncludeDeleted lists deleted records along with the live ones.
 type Pagination struct {
-	Skip  int `json:"skip"`
-	Limit int `json:"limit"`
+	Skip           int    `json:"skip" validate:"min=0"`
+	Limit          int    `json:"limit" validate:"min=0"`
+	Bookmark       string `json:"bookmark"`
+	SkipTotal      bool   `json:"skipTotal"`
+	IncludeDeleted bool   `json:"includeDeleted"`
+}
+
+// PageInfo is returned with every page of a listing. TotalCapped is set when
+// there are more matches than the total counts.
+type PageInfo struct {
+	Bookmark    string `json:"bookmark"`
+	TotalCapped bool   `json:"totalCapped"`
 }
 
 type DocType string
@@ -21,4 +35,9 @@ const (
 	Hscode DocType = "hscode"
 	FormE DocType = "formE"
 	PlantType DocType = "plantType"
+	Config DocType = "config"
+	Sequence DocType = "sequence"
+	Unique DocType = "unique"
+	SoldTotal DocType = "soldTotal"
+	Document DocType = "document"
 )
\ No newline at end of file
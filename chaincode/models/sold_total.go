@@ -0,0 +1,14 @@
+package models
+
+// GapSoldTotal is the weight sold from a GAP certificate: the final weight of
+// its live packing orders in a sold status, or their actual weight while no
+// final weight is set. It is stored under the composite key (soldTotal,
+// [gap]), one per certificate, so that packing orders of different
+// certificates never write the same key. The total is kept in float64 so that
+// adding and taking off order weights over many writes does not drift.
+type GapSoldTotal struct {
+	Gap       string  `json:"gap"`
+	TotalSold float64 `json:"totalSold"`
+	UpdatedAt string  `json:"updatedAt"`
+	DocType   DocType `json:"docType"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// Tombstone marks a record as deleted. Deleted records stay in the world
+// state for the audit trail and are left out of listings unless
+// includeDeleted is set.
+type Tombstone struct {
+	DeletedAt    string `json:"deletedAt,omitempty"`
+	DeletedBy    string `json:"deletedBy,omitempty"`
+	DeleteReason string `json:"deleteReason,omitempty"`
+}
+
+func (t Tombstone) IsDeleted() bool {
+	return t.DeletedAt != ""
+}
+
+// SoftDeleteConfig sets how many days after deletion a record may still be
+// restored. A zero window falls back to the default.
+type SoftDeleteConfig struct {
+	RestoreWindowDays int     `json:"restoreWindowDays" validate:"min=0"`
+	UpdatedBy         string  `json:"updatedBy"`
+	UpdatedAt         string  `json:"updatedAt"`
+	DocType           DocType `json:"docType"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// ProductTrace is the provenance of an exported product, from its export
+// boxes back to the farms it was grown on. MatchedBy names the field the
+// traced key was found in.
+type ProductTrace struct {
+	Key        string                  `json:"key"`
+	MatchedBy  string                  `json:"matchedBy"`
+	Packagings []*TransactionPackaging `json:"packagings"`
+	FormEs     []*TransactionFormE     `json:"formEs"`
+	Exporters  []*TransactionExporter  `json:"exporters"`
+	PlantTypes []*PlantTypeModel       `json:"plantTypes"`
+	Gmps       []*TransactionGmp       `json:"gmps"`
+	Packings   []*TransactionPacking   `json:"packings"`
+	Gaps       []*TransactionGap       `json:"gaps"`
+	Farmers    []*TransactionFarmer    `json:"farmers"`
+}
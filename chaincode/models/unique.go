@@ -0,0 +1,21 @@
+package models
+
+// UniqueEntry records which record holds a business key. Entries are stored
+// under the composite key (unique, [of, field, value]).
+type UniqueEntry struct {
+	Of      DocType `json:"of"`
+	Field   string  `json:"field"`
+	Value   string  `json:"value"`
+	Id      string  `json:"id"`
+	DocType DocType `json:"docType"`
+}
+
+// UniqueConflict is a record that could not be indexed because another
+// record already holds its business key.
+type UniqueConflict struct {
+	DocType DocType `json:"docType"`
+	Field   string  `json:"field"`
+	Value   string  `json:"value"`
+	Id      string  `json:"id"`
+	HeldBy  string  `json:"heldBy"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// FieldError is one validation rule a transaction argument breaks. Field is
+// the JSON path of the value, such as "invoice.productAndPackaging[0].hscode".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
@@ -1,16 +1,18 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"fmt"
-	"sort"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var nectecStaffRepository = utils.Repository[models.TransactionNectecStaff]{
+	DocType: models.Nectec,
+	Id:      func(asset *models.TransactionNectecStaff) string { return asset.Id },
+}
+
 func (s *SmartContract) CreateNectecStaff(
 	ctx contractapi.TransactionContextInterface,
 	args string,
@@ -22,37 +24,13 @@ func (s *SmartContract) CreateNectecStaff(
 	}
 	input := inputInterface.(*models.TransactionNectecStaff)
 
-	// err := ctx.GetClientIdentity().AssertAttributeValue("nstdaStaff.creator", "true")
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have nstdaStaff.creator role")
-	}
-
-	existNstda, err := utils.AssetExists(ctx, input.Id)
-	utils.HandleError(err)
-	if existNstda {
-		return fmt.Errorf("the asset %s already exists", input.Id)
-	}
-
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
-
-	timestamp := utils.GenerateTimestamp()
-
 	asset := models.TransactionNectecStaff{
-		Id:        input.Id,
-		CertId:    input.CertId,
-		ProfileImg:    input.ProfileImg,
-		Owner:     clientID,
-		OrgName:   orgName,
-		UpdatedAt: timestamp,
-		CreatedAt: timestamp,
-		DocType: models.Nectec,
+		Id:         input.Id,
+		CertId:     input.CertId,
+		ProfileImg: input.ProfileImg,
 	}
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return nectecStaffRepository.Create(ctx, &asset)
 }
 
 func (s *SmartContract) UpdateNectecStaff(ctx contractapi.TransactionContextInterface,
@@ -60,124 +38,81 @@ func (s *SmartContract) UpdateNectecStaff(ctx contractapi.TransactionContextInte
 
 	entityNstda := models.TransactionNectecStaff{}
 	inputInterface, err := utils.Unmarshal(args, entityNstda)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 	input := inputInterface.(*models.TransactionNectecStaff)
 
 	asset, err := s.ReadNectecStaff(ctx, input.Id)
-	utils.HandleError(err)
-
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf(utils.UNAUTHORIZE)
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
 	}
 
-	timestamp := utils.GenerateTimestamp()
-	asset.Id = input.Id
 	asset.CertId = input.CertId
-	asset.UpdatedAt = timestamp
 
-	assetJSON, errN := json.Marshal(asset)
-	utils.HandleError(errN)
-
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return nectecStaffRepository.Update(ctx, asset)
 }
 
-func (s *SmartContract) DeleteNectecStaff(ctx contractapi.TransactionContextInterface, id string) error {
+func (s *SmartContract) DeleteNectecStaff(ctx contractapi.TransactionContextInterface, id string, reason string) error {
 
 	assetNstda, err := s.ReadNectecStaff(ctx, id)
-	utils.HandleError(err)
-
-	// clientIDNstda, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	// if clientIDNstda != assetNstda.Owner {
-	// 	return fmt.Errorf(utils.UNAUTHORIZE)
-	// }
+	if err := utils.AssertStaffOrOwner(ctx, assetNstda.Owner); err != nil {
+		return err
+	}
 
-	return ctx.GetStub().DelState(assetNstda.Id)
+	return nectecStaffRepository.Delete(ctx, assetNstda.Id, reason)
 }
 
-func (s *SmartContract) DeleteNectecStaffFromCertId(ctx contractapi.TransactionContextInterface, certId string) error {
-	queryString := fmt.Sprintf(`{"selector":{"certId":"%s"}}`, certId)
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+func (s *SmartContract) RestoreNectecStaff(ctx contractapi.TransactionContextInterface, id string) error {
+	assetNstda, err := utils.ReadAssetHeader(ctx, id, models.Nectec)
 	if err != nil {
-		return fmt.Errorf("failed to query certId: %v", err)
+		return err
 	}
-	defer resultsIterator.Close()
 
-	if !resultsIterator.HasNext() {
-		return fmt.Errorf("no asset found with certId: %s", certId)
+	if err := utils.AssertStaffOrOwner(ctx, assetNstda.Owner); err != nil {
+		return err
 	}
 
-	// Assuming there is only one asset per certId. If there could be multiple, you'd need to handle that case.
-	queryResponse, err := resultsIterator.Next()
-	if err != nil {
-		return fmt.Errorf("failed to get query response: %v", err)
-	}
+	return nectecStaffRepository.Restore(ctx, id)
+}
 
-	// Convert queryResponse to asset structure if needed, e.g., NectecStaff
-	var assetNstda models.TransactionNectecStaff
-	err = json.Unmarshal(queryResponse.Value, &assetNstda)
+func (s *SmartContract) DeleteNectecStaffFromCertId(ctx contractapi.TransactionContextInterface, certId string, reason string) error {
+	assets, err := s.QueryNectecStaffByCertId(ctx, certId)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal query response: %v", err)
+		return err
+	}
+	if len(assets) == 0 {
+		return utils.NotFound("no asset found with certId: %s", certId)
 	}
 
-	return ctx.GetStub().DelState(assetNstda.Id)
+	// Assuming there is only one asset per certId
+	return nectecStaffRepository.Delete(ctx, assets[0].Id, reason)
 }
 
 func (s *SmartContract) QueryNectecStaffByCertId(ctx contractapi.TransactionContextInterface, certId string) ([]*models.TransactionNectecStaff, error) {
-	queryString := fmt.Sprintf(`{"selector":{"certId":"%s","docType": "nectec}}`, certId)
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	assets, err := nectecStaffRepository.Find(ctx, nectecStaffRepository.Selector().Eq("certId", certId).Query())
 	if err != nil {
-		return nil, fmt.Errorf("failed to query by certId: %v", err)
-	}
-	defer resultsIterator.Close()
-
-	var assets []*models.TransactionNectecStaff
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to iterate results: %v", err)
-		}
-
-		var asset models.TransactionNectecStaff
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal asset: %v", err)
-		}
-		assets = append(assets, &asset)
+		return nil, fmt.Errorf("failed to query by certId: %w", err)
 	}
 
 	return assets, nil
 }
 
 func (s *SmartContract) ReadNectecStaff(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionNectecStaff, error) {
-
-	assetJSON, err := ctx.GetStub().GetState(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
-	}
-
-	var asset models.TransactionNectecStaff
-	err = json.Unmarshal(assetJSON, &asset)
-	if err != nil {
-		return nil, err
-	}
-
-	return &asset, nil
+	return nectecStaffRepository.Get(ctx, id)
 }
 
 
 func (s *SmartContract) GetAllNectecStaff(ctx contractapi.TransactionContextInterface, args string) (*models.GetAllNectecStaffResponse, error) {
 
-	var filterNstda = map[string]interface{}{}
-
 	entityGetAll := models.FilterGetAllNectecStaff{}
 	interfaceNstda, err := utils.Unmarshal(args, entityGetAll)
 	if err != nil {
@@ -185,61 +120,21 @@ func (s *SmartContract) GetAllNectecStaff(ctx contractapi.TransactionContextInte
 	}
 	input := interfaceNstda.(*models.FilterGetAllNectecStaff)
 
-	arrNstda, total, err := utils.NectecStaffFetchResultsWithPagination(ctx, input, filterNstda)
+	page, err := utils.Paginate[models.NectecStaffTransactionResponse](ctx, utils.NectecStaffSetFilter(input).Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/indexCreatedAtId", "indexCreatedAtId"), input.Pagination)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(arrNstda) == 0 {
-		arrNstda = []*models.NectecStaffTransactionResponse{}
-	}
-
 	return &models.GetAllNectecStaffResponse{
-		Data:  "All NstdaStaff",
-		Obj:   arrNstda,
-		Total: total,
+		Data:     "All NstdaStaff",
+		Obj:      page.Items,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
 	}, nil
 }
 
 func (s *SmartContract) FilterNstdaStaff(ctx contractapi.TransactionContextInterface, key, value string) ([]*models.TransactionNectecStaff, error) {
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
-	if err != nil {
-		return nil, err
-	}
-	defer resultsIterator.Close()
-
-	var assetNstda []*models.TransactionNectecStaff
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var asset models.TransactionNectecStaff
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, err
-		}
-
-		var m map[string]interface{}
-		if err := json.Unmarshal(queryResponse.Value, &m); err != nil {
-			return nil, err
-		}
-
-		if val, ok := m[key]; ok && fmt.Sprintf("%v", val) == value {
-			assetNstda = append(assetNstda, &asset)
-		}
-	}
-
-	sort.Slice(assetNstda, func(i, j int) bool {
-        t1, err1 := time.Parse(time.RFC3339, assetNstda[i].CreatedAt)
-        t2, err2 := time.Parse(time.RFC3339, assetNstda[j].CreatedAt)
-        if err1 != nil || err2 != nil {
-            fmt.Println("Error parsing time:", err1, err2)
-            return false
-        }
-        return t1.After(t2)
-    })
-
-	return assetNstda, nil
-}
\ No newline at end of file
+	return nectecStaffRepository.Filter(ctx, key, value)
+}
@@ -0,0 +1,129 @@
+package chaincode
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestNectecStaffLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+
+	regulatorCtx := ctx.As(regulatorUser)
+	if err := submit(regulatorCtx, "CreateNectecStaff", func() error {
+		return s.CreateNectecStaff(regulatorCtx, `{"id":"N1"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator create: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "CreateNectecStaff", func() error {
+		return s.CreateNectecStaff(ctx, `{"id":"N1","certId":"NC-1","profileImg":"a.png"}`)
+	})
+	mustSubmit(t, ctx, "CreateNectecStaff", func() error {
+		return s.CreateNectecStaff(ctx, `{"id":"N2","certId":"NC-2"}`)
+	})
+	if err := submit(ctx, "CreateNectecStaff", func() error {
+		return s.CreateNectecStaff(ctx, `{"id":"N1"}`)
+	}); err == nil {
+		t.Fatal("expected duplicate staff to be rejected")
+	}
+
+	staff, err := s.ReadNectecStaff(ctx, "N1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staff.DocType != models.Nectec || staff.Owner != nectecUser.ID || staff.CreatedAt == "" {
+		t.Fatalf("unexpected staff %+v", staff)
+	}
+
+	mustSubmit(t, ctx, "UpdateNectecStaff", func() error {
+		return s.UpdateNectecStaff(ctx, `{"id":"N1","certId":"NC-9"}`)
+	})
+	staff, _ = s.ReadNectecStaff(ctx, "N1")
+	if staff.CertId != "NC-9" || staff.ProfileImg != "a.png" {
+		t.Fatalf("update not applied: %+v", staff)
+	}
+
+	filtered, err := s.FilterNstdaStaff(ctx, "certId", "NC-2")
+	if err != nil || len(filtered) != 1 || filtered[0].Id != "N2" {
+		t.Fatalf("unexpected filter result %+v, %v", filtered, err)
+	}
+
+	mustSubmit(t, ctx, "DeleteNectecStaffFromCertId", func() error {
+		return s.DeleteNectecStaffFromCertId(ctx, "NC-2", "duplicate record")
+	})
+	if _, err := s.ReadNectecStaff(ctx, "N2"); err == nil {
+		t.Fatal("expected staff to be deleted by cert id")
+	}
+	if err := submit(ctx, "DeleteNectecStaffFromCertId", func() error {
+		return s.DeleteNectecStaffFromCertId(ctx, "NC-2", "duplicate record")
+	}); err == nil {
+		t.Fatal("expected delete of unknown cert id to fail")
+	}
+
+	mustSubmit(t, ctx, "DeleteNectecStaff", func() error {
+		return s.DeleteNectecStaff(ctx, "N1", "duplicate record")
+	})
+	if _, err := s.ReadNectecStaff(ctx, "N1"); err == nil {
+		t.Fatal("expected staff to be deleted")
+	}
+}
+
+func TestGetAllNectecStaff(t *testing.T) {
+	s, ctx := newTestContract()
+
+	for _, args := range []string{
+		`{"id":"N1","certId":"NC-100"}`,
+		`{"id":"N2","certId":"NC-200"}`,
+		`{"id":"N3","certId":"NC-101"}`,
+	} {
+		args := args
+		mustSubmit(t, ctx, "CreateNectecStaff", func() error {
+			return s.CreateNectecStaff(ctx, args)
+		})
+	}
+	mustSubmit(t, ctx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(ctx, `{"id":"F1","certId":"NC-100"}`)
+		return err
+	})
+
+	// Staff timestamps come from the wall clock, so only membership is
+	// compared here.
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllNectecStaff
+		wantIds []string
+		total   int
+		count   int
+	}{
+		{"all", models.FilterGetAllNectecStaff{}, []string{"N1", "N2", "N3"}, 3, 3},
+		{"limit", models.FilterGetAllNectecStaff{Pagination: models.Pagination{Limit: 2}}, nil, 3, 2},
+		{"search", models.FilterGetAllNectecStaff{Search: strPtr("NC-10")}, []string{"N1", "N3"}, 2, 2},
+		{"no match", models.FilterGetAllNectecStaff{Search: strPtr("XX")}, []string{}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllNectecStaff(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			if len(res.Obj) != tt.count {
+				t.Fatalf("got %d staff, want %d", len(res.Obj), tt.count)
+			}
+			if tt.wantIds == nil {
+				return
+			}
+			var ids []string
+			for _, n := range res.Obj {
+				ids = append(ids, n.Id)
+			}
+			sort.Strings(ids)
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
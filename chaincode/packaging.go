@@ -9,135 +9,113 @@ import (
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var packagingImport = utils.BatchImport[models.TransactionPackaging]{
+	Function:   "CreatePackagingCsv",
+	Repository: packagingRepository,
+	Validate: func(ctx contractapi.TransactionContextInterface, input *models.TransactionPackaging) error {
+		if input.Gap != "" {
+			if err := utils.CertificateReference("gap", utils.AssertGapCertificate(ctx, input.Gap)); err != nil {
+				return err
+			}
+		}
+		if input.Gmp != "" {
+			if err := utils.CertificateReference("gmp", utils.AssertGmpCertificate(ctx, input.Gmp)); err != nil {
+				return err
+			}
+		}
+		if input.PackingId == "" {
+			return nil
+		}
+		packing, err := packingRepository.Get(ctx, input.PackingId)
+		if err != nil {
+			if utils.CatalogueError(err).Code == models.ErrNotFound {
+				return utils.UnknownReference("packingId", "%s", utils.ErrorMessage(err))
+			}
+			return err
+		}
+		if packing.DocType != models.Packing {
+			return utils.UnknownReference("packingId", "the asset %s is not a packing order", input.PackingId)
+		}
+		if packing.Gap != input.Gap || packing.Gmp != input.Gmp {
+			return utils.InvalidField("packingId", "packing order %s was packed from gap %q and gmp %q", input.PackingId, packing.Gap, packing.Gmp)
+		}
+		return nil
+	},
+	Build: func(input *models.TransactionPackaging, meta utils.BatchMeta) *models.TransactionPackaging {
+		return &models.TransactionPackaging{
+			Id:           input.Id,
+			ContainerId:  input.ContainerId,
+			ExportId:     input.ExportId,
+			LotNumber:    input.LotNumber,
+			PalletNumber: input.PalletNumber,
+			BoxId:        input.BoxId,
+			Gap:          input.Gap,
+			Gmp:          input.Gmp,
+			PackingId:    input.PackingId,
+			GradeName:    input.GradeName,
+			Gtin14:       input.Gtin14,
+			Gtin13:       input.Gtin13,
+			VarietyName:  input.VarietyName,
+			CreatedById:  input.CreatedById,
+			DocType:      models.Packaging,
+			Owner:        meta.Owner,
+			OrgName:      meta.OrgName,
+			CreatedAt:    meta.Timestamp,
+			UpdatedAt:    meta.Timestamp,
+		}
+	},
+}
+
 func (s *SmartContract) CreatePackagingCsv(
-    ctx contractapi.TransactionContextInterface,
-    args string,
+	ctx contractapi.TransactionContextInterface,
+	args string,
 ) error {
-    var inputs []models.TransactionPackaging
-
-    errInputPackaging := json.Unmarshal([]byte(args), &inputs)
-    if errInputPackaging != nil {
-        return fmt.Errorf("error unmarshaling input: %v", errInputPackaging)
-    }
-
-    var batchErrors []error
-
-    for _, input := range inputs {
-        if err := s.processSinglePackaging(ctx, input); err != nil {
-            batchErrors = append(batchErrors, err)
-            fmt.Printf("Error processing asset %s: %v\n", input.Id, err)
-        } else {
-            fmt.Printf("Packaging Asset %s created successfully\n", input.Id)
-        }
-    }
-
-    if len(batchErrors) > 0 {
-        return fmt.Errorf("encountered errors during processing: %v", batchErrors)
-    }
-
-    return nil
+	_, err := packagingImport.Run(ctx, args, false)
+	return err
 }
 
-func (s *SmartContract) processSinglePackaging(ctx contractapi.TransactionContextInterface, input models.TransactionPackaging) error {
-    orgNamePackaging, err := ctx.GetClientIdentity().GetMSPID()
-    if err != nil {
-        return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
-    }
-
-    existPackaging, err := utils.AssetExists(ctx, input.Id)
-    if err != nil {
-        return fmt.Errorf("error checking if asset exists: %v", err)
-    }
-    if existPackaging {
-        return fmt.Errorf("the asset %s already exists", input.Id)
-    }
-
-    clientIDPackaging, err := utils.GetIdentity(ctx)
-    if err != nil {
-        return fmt.Errorf("failed to get submitting client's identity: %v", err)
-    }
-
-    // timestamp := utils.GenerateTimestamp()
-
-    assetPackaging := models.TransactionPackaging{
-        Id:          input.Id,
-        ContainerId: input.ContainerId,
-        ExportId:    input.ExportId,
-        LotNumber:   input.LotNumber,
-        BoxId:       input.BoxId,
-        Gap:         input.Gap,
-        Gmp:         input.Gmp,
-        GradeName:   input.GradeName,
-        Gtin14:      input.Gtin14,
-        Gtin13:      input.Gtin13,
-        VarietyName: input.VarietyName,
-		CreatedById: input.CreatedById,
-        DocType:     models.Packaging,
-        Owner:       clientIDPackaging,
-        OrgName:     orgNamePackaging,
-        CreatedAt:   input.CreatedAt,
-        UpdatedAt:   input.UpdatedAt,
-    }
-
-    assetJSON, err := json.Marshal(assetPackaging)
-    if err != nil {
-        return fmt.Errorf("failed to marshal asset JSON for asset %s: %v", input.Id, err)
-    }
-
-    err = ctx.GetStub().PutState(input.Id, assetJSON)
-    if err != nil {
-        return fmt.Errorf("failed to put state for asset %s: %v", input.Id, err)
-    }
+// Packagings are only written by the batch import. packagingReads reads them
+// in the shape returned to clients.
+var packagingRepository = utils.Repository[models.TransactionPackaging]{
+	DocType: models.Packaging,
+	Id:      func(asset *models.TransactionPackaging) string { return asset.Id },
+}
 
-    return nil
+var packagingReads = utils.Repository[models.ReadPackaging]{
+	DocType: models.Packaging,
+	Id:      func(asset *models.ReadPackaging) string { return asset.Id },
 }
 
 func (s *SmartContract) ReadPackaging(ctx contractapi.TransactionContextInterface, id string) (*models.ReadPackaging, error) {
-	assetJSON, err := ctx.GetStub().GetState(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
-	}
-
-	var asset models.ReadPackaging
-	err = json.Unmarshal(assetJSON, &asset)
+	asset, err := packagingReads.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	gmp, err := s.GetPackagingGmp(ctx, asset.Gmp) 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get gmp for asset %s: %v", id, err)
+		return nil, fmt.Errorf("failed to get gmp for asset %s: %w", id, err)
 	}
 	asset.GmpDetail = gmp
 
-	return &asset, nil
+	return asset, nil
 }
 
 func (s *SmartContract) GetPackagingGmp(ctx contractapi.TransactionContextInterface, id string) (models.TransactionGmp, error) {
-    selector := map[string]interface{}{
-        "docType": "gmp",
-        "packingHouseRegisterNumber": id,
-    }
-
-    queryString, err := json.Marshal(map[string]interface{}{
-        "selector": selector,
-        "use_index": []string{
-            "_design/index-docType",
-            "index-docType",
-        },
-    })
+    queryString, err := utils.NewSelector(models.Gmp).
+        Eq("packingHouseRegisterNumber", id).
+        Query().
+        UseIndex("_design/index-docType", "index-docType").
+        Build()
     if err != nil {
-        return models.TransactionGmp{}, fmt.Errorf("failed to marshal query string: %v", err)
+        return models.TransactionGmp{}, fmt.Errorf("failed to marshal query string: %w", err)
     }
 
     fmt.Printf("GMP query: %s\n", queryString)
 
-    resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryString))
+    resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
     if err != nil {
-        return models.TransactionGmp{}, fmt.Errorf("failed to get GMP query result: %v", err)
+        return models.TransactionGmp{}, fmt.Errorf("failed to get GMP query result: %w", err)
     }
     defer resultsIterator.Close()
 
@@ -147,160 +125,58 @@ func (s *SmartContract) GetPackagingGmp(ctx contractapi.TransactionContextInterf
 
     queryResponse, err := resultsIterator.Next()
     if err != nil {
-        return models.TransactionGmp{}, fmt.Errorf("failed to get next GMP query result: %v", err)
+        return models.TransactionGmp{}, fmt.Errorf("failed to get next GMP query result: %w", err)
     }
 
     var gmpDocument models.TransactionGmp
     err = json.Unmarshal(queryResponse.Value, &gmpDocument)
     if err != nil {
-        return models.TransactionGmp{}, fmt.Errorf("failed to unmarshal GMP query result: %v", err)
+        return models.TransactionGmp{}, fmt.Errorf("failed to unmarshal GMP query result: %w", err)
     }
 
     return gmpDocument, nil
 }
 
 func (s *SmartContract) QueryPackagingWithPagination(ctx contractapi.TransactionContextInterface, filterParams string) (*models.TransactionPackagingResponse, error) {
-    const offset = 7 // UTC+7
 	var filters models.PackagingFilterParams
-	err := json.Unmarshal([]byte(filterParams), &filters)
+	err := utils.DecodeInput(filterParams, &filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal filter parameters: %v", err)
+		return nil, err
 	}
 
-	selector := map[string]interface{}{
-		"docType": "packaging",
-	}
+	selector := utils.NewSelector(models.Packaging)
 
 	if filters.CreatedById != "" {
-		selector["createdById"] = filters.CreatedById
+		selector.Eq("createdById", filters.CreatedById)
 	}
 
 	if filters.ContainerId != "" {
-		selector["containerId"] = filters.ContainerId
+		selector.Eq("containerId", filters.ContainerId)
 	}
 	if filters.BoxId != "" {
-		selector["boxId"] = filters.BoxId
+		selector.Eq("boxId", filters.BoxId)
 	}
 	if filters.Gtin13 != "" {
-		selector["gtin13"] = filters.Gtin13
+		selector.Eq("gtin13", filters.Gtin13)
 	}
 	if filters.Gap != "" {
-		selector["gap"] = filters.Gap
+		selector.Eq("gap", filters.Gap)
 	}
 
-	if filters.StartDate != nil && filters.EndDate != nil {
-		fromDate, err1 := utils.FormatDate(*filters.StartDate, false, offset)
-		toDate, err2 := utils.FormatDate(*filters.EndDate, true, offset)
-
-		if err1 == nil && err2 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1, err2)
-		}
-	} else if (filters.StartDate != nil) {
-		fromDate, err1 := utils.FormatDate(*filters.StartDate, false, offset)
-
-		if err1 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1)
-		}
-	} else if (filters.EndDate != nil) {
-		toDate, err2 := utils.FormatDate(*filters.EndDate, true, offset)
-
-		if err2 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err2)
-		}
-	}
-
-	// Create query string for counting total records
-	countQueryString, err := json.Marshal(map[string]interface{}{
-		"selector": selector,
-		"use_index": []string{
-            "_design/index-CreatedAt",
-            "index-CreatedAt",
-        },
-	})
+	selector.DateRange("createdAt", filters.StartDate, filters.EndDate)
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal count query string: %v", err)
-	}
-
-	// Execute the count query
-	total, err := utils.CountTotalResults(ctx, string(countQueryString))
+	page, err := utils.Paginate[models.TransactionPackaging](ctx, selector.Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAt", "index-CreatedAt"), filters.Pagination)
 	if err != nil {
 		return nil, err
 	}
-
-	// If no records are found, return an empty response
-	if total == 0 {
-		return &models.TransactionPackagingResponse{
-			Data:  []*models.TransactionPackaging{},
-			Total: total,
-		}, nil
-	}
-
-	query := map[string]interface{}{
-		"selector": selector,
-		"sort": []map[string]string{
-			{"createdAt": "desc"},
-		},
-        "use_index": []string{
-            "_design/index-CreatedAt",
-            "index-CreatedAt",
-        },
-	}
-
-	if filters.Skip > 0 {
-		query["skip"] = filters.Skip
-	}
-	if filters.Limit > 0 {
-		query["limit"] = filters.Limit
-	}
-
-	// Create query string for paginated results
-	queryString, err := json.Marshal(query)
-
-	fmt.Printf("Packaging query %v", queryString)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query string: %v", err)
-	}
-
-	// Execute the paginated query
-	resultsIterator, _, err := ctx.GetStub().GetQueryResultWithPagination(string(queryString), int32(filters.Limit), "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get query result with pagination: %v", err)
-	}
-	defer resultsIterator.Close()
-
-	var assets []*models.TransactionPackaging
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next query result: %v", err)
-		}
-
-		var asset models.TransactionPackaging
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal query result: %v", err)
-		}
-		assets = append(assets, &asset)
-	}
+	assets := page.Items
 
 	return &models.TransactionPackagingResponse{
-		Data:  assets,
-		Total: total,
+		Data:     assets,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
 	}, nil
 }
 
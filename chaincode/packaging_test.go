@@ -0,0 +1,88 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestPackaging(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Alpha"}`)
+	})
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G1","certId":"GAP-1"},{"id":"G2","certId":"GAP-2"}]`)
+	})
+
+	exporterCtx := ctx.As(exporterUser)
+	seedDaily(t, exporterCtx, "CreatePackagingCsv", func(args string) error {
+		return s.CreatePackagingCsv(exporterCtx, args)
+	},
+		`[{"id":"B1","containerId":"C1","boxId":"BOX-1","gap":"GAP-1","gmp":"PH-1","gtin13":"885","createdById":"E1"}]`,
+		`[{"id":"B2","containerId":"C1","boxId":"BOX-2","gap":"GAP-2","gmp":"PH-1","gtin13":"885","createdById":"E1"}]`,
+		`[{"id":"B3","containerId":"C2","boxId":"BOX-3","gap":"GAP-1","gmp":"","gtin13":"886","createdById":"E2"}]`,
+	)
+
+	if err := submit(exporterCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(exporterCtx, `[{"id":"B4"},{"id":"B1"}]`)
+	}); err == nil {
+		t.Fatal("expected duplicate packaging to be rejected")
+	}
+
+	farmerCtx := ctx.As(farmerUser)
+	if err := submit(farmerCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(farmerCtx, `[{"id":"B5"}]`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("farmer create packaging: expected access denied, got %v", err)
+	}
+
+	packaging, err := s.ReadPackaging(ctx, "B1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packaging.DocType != models.Packaging || packaging.Owner != exporterUser.ID || packaging.GmpDetail.Id != "M1" {
+		t.Fatalf("unexpected packaging %+v", packaging)
+	}
+
+	orphan, err := s.ReadPackaging(ctx, "B3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orphan.GmpDetail.Id != "" {
+		t.Fatalf("expected empty gmp detail, got %+v", orphan.GmpDetail)
+	}
+
+	tests := []struct {
+		name    string
+		filter  models.PackagingFilterParams
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.PackagingFilterParams{}, []string{"B3", "B2", "B1"}, 3},
+		{"skip and limit", models.PackagingFilterParams{Pagination: models.Pagination{Skip: 1, Limit: 1}}, []string{"B2"}, 3},
+		{"container", models.PackagingFilterParams{ContainerId: "C1"}, []string{"B2", "B1"}, 2},
+		{"gap and creator", models.PackagingFilterParams{Gap: "GAP-1", CreatedById: "E1"}, []string{"B1"}, 1},
+		{"gtin", models.PackagingFilterParams{Gtin13: "886"}, []string{"B3"}, 1},
+		{"start date", models.PackagingFilterParams{StartDate: strPtr("02-01-2024")}, []string{"B3", "B2"}, 2},
+		{"no match", models.PackagingFilterParams{BoxId: "none"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.QueryPackagingWithPagination(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, p := range res.Data {
+				ids = append(ids, p.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
@@ -9,53 +9,47 @@ import (
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var packerRepository = utils.Repository[models.TransactionPacker]{
+	DocType: models.Packer,
+	Id:      func(asset *models.TransactionPacker) string { return asset.Id },
+}
 
 func (s *SmartContract) CreatePacker(
 	ctx contractapi.TransactionContextInterface,
 	args string,
-) error {
+) (string, error) {
 	entityPacker := models.TransactionPacker{}
 	inputInterface, err := utils.Unmarshal(args, entityPacker)
 	if err != nil {
-		return err
+		return "", err
 	}
 	input := inputInterface.(*models.TransactionPacker)
 
-	// err := ctx.GetClientIdentity().AssertAttributeValue("packer.creator", "true")
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have packer.creator role")
-	}
-
-	existPacker, err := utils.AssetExists(ctx, input.Id)
-	utils.HandleError(err)
-	if existPacker {
-		return fmt.Errorf("the asset %s already exists", input.Id)
+	if input.Id == "" {
+		input.Id, err = utils.NextId(ctx, utils.PackerSequence, utils.SequenceVars{}, utils.AssetIdTaken(ctx))
+		if err != nil {
+			return "", err
+		}
 	}
 
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
-
-	// timestamp := utils.GenerateTimestamp()
-
 	asset := models.TransactionPacker{
-		Id:        input.UserId,
-		CertId:    input.CertId,
-		UserId:    input.UserId,
-		IsCanExport: input.IsCanExport,
-		IsCanDelete: true,
-		PackingHouseName: input.PackingHouseName,
+		Id:                         input.Id,
+		CertId:                     input.CertId,
+		UserId:                     input.UserId,
+		IsCanExport:                input.IsCanExport,
+		IsCanDelete:                true,
+		PackingHouseName:           input.PackingHouseName,
 		PackingHouseRegisterNumber: input.PackingHouseRegisterNumber,
-		Owner:     clientID,
-		OrgName:   orgName,
-		DocType:   models.Packer,
-		CreatedAt: input.CreatedAt,
-		UpdatedAt: input.UpdatedAt,
 	}
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
+	if err := packerGmpCopy.Fill(ctx, []*models.TransactionPacker{&asset}); err != nil {
+		return "", err
+	}
+
+	if err := packerRepository.Create(ctx, &asset); err != nil {
+		return "", err
+	}
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return asset.Id, nil
 }
 
 func (s *SmartContract) UpdatePacker(ctx contractapi.TransactionContextInterface, args string) error {
@@ -71,229 +65,137 @@ func (s *SmartContract) UpdatePacker(ctx contractapi.TransactionContextInterface
         return err
     }
 	
-    // clientID, err := utils.GetIdentity(ctx)
-    // if err != nil {
-    //     return err
-    // }
-
-    // if clientID != asset.Owner {
-    //     return fmt.Errorf(utils.UNAUTHORIZE)
-    // }
+    if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+        return err
+    }
 
 	asset.CertId = input.CertId
 	asset.UserId = input.UserId
-	asset.PackerGmp = nil
 	asset.PackingHouseName = input.PackingHouseName
 	asset.PackingHouseRegisterNumber = input.PackingHouseRegisterNumber
 	asset.IsCanExport = input.IsCanExport
-	asset.UpdatedAt = input.UpdatedAt
-
-    assetJSON, errP := json.Marshal(asset)
-    if errP != nil {
-        return errP
-    }
+	if err := packerGmpCopy.Fill(ctx, []*models.TransactionPacker{asset}); err != nil {
+		return err
+	}
 
-    return ctx.GetStub().PutState(input.Id, assetJSON)
+    return packerRepository.Update(ctx, asset)
 }
 
 
-func (s *SmartContract) DeletePacker(ctx contractapi.TransactionContextInterface, id string) error {
-	assetJSON, err := ctx.GetStub().GetState(id)
+// DeletePacker deletes a packer nothing refers to. With cascade set, the
+// packer's GMPs are released instead of blocking the delete.
+func (s *SmartContract) DeletePacker(ctx contractapi.TransactionContextInterface, id string, reason string, cascade bool) error {
+	asset, err := packerRepository.Get(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to read from world state or packerId: %v", err)
-	}  
-	if assetJSON == nil {
-		return fmt.Errorf("the asset %s does not exist", id)
+		return err
 	}
 
-	var asset models.TransactionPacker
-	err = json.Unmarshal(assetJSON, &asset)
-	if err != nil {
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
+	}
+	if err := utils.AssertDeletable(ctx, asset.Id, utils.PackerDependencies(asset.Id), cascade); err != nil {
 		return err
 	}
 
-	fmt.Printf("[DeletePacker] assetPacker %v \n", asset)
-
-	return ctx.GetStub().DelState(asset.Id)
+	return packerRepository.Delete(ctx, asset.Id, reason)
 }
 
-func (s *SmartContract) GetPackerByPackerId(ctx contractapi.TransactionContextInterface, packerId string) (*models.PackerByIdResponse, error) {
-	queryKeyFarmer := fmt.Sprintf(`{"selector":{"userId":"%s", "docType": "packer"}}`, packerId)
-
-	resultsIteratorFarmer, err := ctx.GetStub().GetQueryResult(queryKeyFarmer)
-	var asset *models.PackerTransactionResponse
-	resData := "Get packer by packerId"
+func (s *SmartContract) RestorePacker(ctx contractapi.TransactionContextInterface, id string) error {
+	asset, err := utils.ReadAssetHeader(ctx, id, models.Packer)
 	if err != nil {
-		return nil, fmt.Errorf("error querying chaincode: %v", err)
+		return err
 	}
-	defer resultsIteratorFarmer.Close()
 
-	if !resultsIteratorFarmer.HasNext() {
-		resData = "Not found packer by packerId"
-
-		return &models.PackerByIdResponse{
-			Data: resData,
-			Obj:  asset,
-		}, nil
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
 	}
 
-	queryResponse, err := resultsIteratorFarmer.Next()
-	if err != nil {
-		return nil, fmt.Errorf("error getting next query result: %v", err)
-	}
+	return packerRepository.Restore(ctx, id)
+}
 
-	err = json.Unmarshal(queryResponse.Value, &asset)
+func (s *SmartContract) GetPackerByPackerId(ctx contractapi.TransactionContextInterface, packerId string) (*models.PackerByIdResponse, error) {
+	packers, err := utils.FetchAll[models.PackerTransactionResponse](ctx, utils.NewSelector(models.Packer).Eq("userId", packerId).Query())
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling asset JSON: %v", err)
+		return nil, fmt.Errorf("error querying chaincode: %w", err)
 	}
+	if len(packers) == 0 {
+		return &models.PackerByIdResponse{
+			Data: "Not found packer by packerId",
+		}, nil
+	}
+	asset := packers[0]
 
-	// Attach related GMP documents
-	queryString := fmt.Sprintf(`{
-		"selector": {
-			"docType": "gmp",
-			"packerId": "%s"
-		}
-	}`, asset.Id)
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	// Attach related GMP documents. The query is the one of the packer's GMP
+	// dependency, so the delete check below reads it from the cache.
+	gmps, err := packerGmps(ctx, gmpRepository.Selector().Eq("packerId", asset.Id).Query())
 	if err != nil {
-		return nil, fmt.Errorf("failed to query related gmp documents: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var gmpDoc models.PackerGmp
-		err = json.Unmarshal(queryResponse.Value, &gmpDoc)
-		if err != nil {
-			return nil, err
-		}
-
-		asset.PackerGmp = gmpDoc
+	if gmpDoc, ok := gmps[asset.Id]; ok {
+		asset.PackerGmp = *gmpDoc
 	}
 
-	asset.IsCanDelete = true
-
-	salesQueryString := fmt.Sprintf(`{
-        "selector": {
-            "$or": [
-                {
-                    "docType": "packing",
-                    "packerId": "%s"
-                },
-                {
-                    "docType": "formE",
-                    "createdById": "%s"
-                },
-                {
-                    "docType": "packaging",
-                    "createdById": "%s"
-                }
-            ]
-        }
-    }`, asset.Id, asset.Id, asset.Id)
-
-	fmt.Printf("salesQueryString %v", salesQueryString)
-
-	salesResultsIterator, err := ctx.GetStub().GetQueryResult(salesQueryString)
+	asset.IsCanDelete, err = utils.CanDelete(ctx, utils.PackerDependencies(asset.Id))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query related sales: %v", err)
-	}
-	defer salesResultsIterator.Close()
-
-	if salesResultsIterator.HasNext() {
-		asset.IsCanDelete = false
+		return nil, err
 	}
 
 	return &models.PackerByIdResponse{
-		Data: resData,
+		Data: "Get packer by packerId",
 		Obj:  asset,
 	}, nil
 }
 
 func (s *SmartContract) ReadPacker(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionPacker, error) {
 
-	assetJSON, err := ctx.GetStub().GetState(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
-	}
-
-	var asset models.TransactionPacker
-	err = json.Unmarshal(assetJSON, &asset)
+	asset, err := packerRepository.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	// Attach related GMP documents
-	queryString := fmt.Sprintf(`{
-		"selector": {
-			"docType": "gmp",
-			"packerId": "%s"
-		}
-	}`, asset.Id)
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	gmps, err := packerGmps(ctx, gmpRepository.Selector().Eq("packerId", asset.Id).Query())
 	if err != nil {
-		return nil, fmt.Errorf("failed to query related gmp documents: %v", err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var gmpDoc *models.PackerGmp
-		err = json.Unmarshal(queryResponse.Value, &gmpDoc)
-		if err != nil {
-			return nil, err
-		}
-
+	if gmpDoc, ok := gmps[asset.Id]; ok {
 		asset.PackerGmp = gmpDoc
 	}
 
-	return &asset, nil
+	return asset, nil
 }
 
 func (s *SmartContract) GetPackerById(ctx contractapi.TransactionContextInterface, id string) (*models.PackerTransactionResponse, error) {
-	queryPacker := fmt.Sprintf(`{"selector":{"id":"%s"}}`, id)
+	queryPacker, err := utils.NewSelector(models.Packer).Eq("id", id).Query().Build()
+	if err != nil {
+		return nil, err
+	}
 
 	resultsPacker, err := ctx.GetStub().GetQueryResult(queryPacker)
 	if err != nil {
-		return nil, fmt.Errorf("error querying chaincode: %v", err)
+		return nil, fmt.Errorf("error querying chaincode: %w", err)
 	}
 	defer resultsPacker.Close()
 
 	if !resultsPacker.HasNext() {
-		return nil, fmt.Errorf("the asset with id %s does not exist", id)
+		return nil, utils.NotFound("the asset with id %s does not exist", id)
 	}
 
 	queryResponse, err := resultsPacker.Next()
 	if err != nil {
-		return nil, fmt.Errorf("error getting next query result: %v", err)
+		return nil, fmt.Errorf("error getting next query result: %w", err)
 	}
 
 	var asset models.PackerTransactionResponse
 	err = json.Unmarshal(queryResponse.Value, &asset)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling asset JSON: %v", err)
+		return nil, fmt.Errorf("error unmarshalling asset JSON: %w", err)
 	}
 
 	return &asset, nil
 }
 
 func (s *SmartContract) GetAllPacker(ctx contractapi.TransactionContextInterface, args string) (*models.PackerGetAllResponse, error) {
-    var filterPacker = map[string]interface{}{}
-    filterPacker["docType"] = "packer"
-
     entityGetAll := models.FilterGetAllPacker{}
     interfacePacker, err := utils.Unmarshal(args, entityGetAll)
     if err != nil {
@@ -301,205 +203,93 @@ func (s *SmartContract) GetAllPacker(ctx contractapi.TransactionContextInterface
     }
     input := interfacePacker.(*models.FilterGetAllPacker)
 
-    arrPacker, total, err := utils.PackerFetchResultsWithPagination(ctx, input, filterPacker)
+    page, err := utils.Paginate[models.PackerTransactionResponse](ctx, utils.PackerSetFilter(input).Query().
+        Sort("createdAt", "desc").
+        UseIndex("_design/index-DocType", "index-DocType"), input.Pagination)
     if err != nil {
         return nil, err
     }
+    arrPacker := page.Items
 
-    // Attach related GMP documents and check packer can delete
-    for _, packer := range arrPacker {
-		packer.IsCanDelete = true
-
-        queryString := fmt.Sprintf(`{
-            "selector": {
-                "docType": "gmp",
-                "packerId": "%s"
-            },
-			"use_index": ["_design/index-DocTypePackerId", "index-DocTypePackerId"]
-        }`, packer.Id)
-
-        resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+    // Attach related GMP documents
+    if len(arrPacker) > 0 {
+        packerIds := make([]string, 0, len(arrPacker))
+        for _, packer := range arrPacker {
+            packerIds = append(packerIds, packer.Id)
+        }
+        gmps, err := packerGmps(ctx, gmpRepository.Selector().
+            In("packerId", packerIds).
+            Query().
+            UseIndex("_design/index-DocTypePackerId", "index-DocTypePackerId"))
         if err != nil {
-            return nil, fmt.Errorf("failed to query related gmp documents: %v", err)
+            return nil, err
         }
-        defer resultsIterator.Close()
-
-        for resultsIterator.HasNext() {
-            queryResponse, err := resultsIterator.Next()
-            if err != nil {
-                return nil, err
-            }
-
-            var gmpDoc models.PackerGmp
-            err = json.Unmarshal(queryResponse.Value, &gmpDoc)
-            if err != nil {
-                return nil, err
+        for _, packer := range arrPacker {
+            packer.IsCanDelete = true
+            if gmpDoc, ok := gmps[packer.Id]; ok {
+                packer.PackerGmp = *gmpDoc
             }
-
-            packer.PackerGmp = gmpDoc
         }
     }
 
-    // for _, packer := range arrPacker {
-	// 	packer.IsCanDelete = true
-
-    	// Check is this packer can delete
-		// packerSellingQuery := fmt.Sprintf(`{
-		// 	"selector": {
-		// 		"docType": "packing",
-		// 		"packerId": "%s"
-		// 	},
-		// 	"use_index": ["_design/index-DocTypePackerId", "index-DocTypePackerId"],
-		// 	"limit": 1
-		// }`, packer.Id)
-		
-		// packerSellingIterator, err := ctx.GetStub().GetQueryResult(packerSellingQuery)
-		// if err != nil {
-		// 	return nil, fmt.Errorf("failed to query related packer selling documents: %v", err)
-		// }
-		// defer packerSellingIterator.Close()
-
-		// for packerSellingIterator.HasNext() {
-		// 	packer.IsCanDelete = false
-        // }
-		// packerFormEQuery := fmt.Sprintf(`{
-        //     "selector": {
-        //         "docType": "formE",
-        //         "createdById": "%s"
-        //     },
-		// 	"use_index": ["_design/index-DocTypeCreatedById", "index-DocTypeCreatedById"]
-        // }`, packer.Id)
-		// packerFormEIterator, err := ctx.GetStub().GetQueryResult(packerFormEQuery)
-		// if err != nil {
-        //     return nil, fmt.Errorf("failed to query related packer selling documents: %v", err)
-        // }
-		// defer packerFormEIterator.Close()
-
-		// for packerFormEIterator.HasNext() {
-		// 	packer.IsCanDelete = false
-        // }
-	// }
-
-    if len(arrPacker) == 0 {
-        arrPacker = []*models.PackerTransactionResponse{}
-    }
-
     return &models.PackerGetAllResponse{
-        Data:  "All Packer",
-        Obj:   arrPacker,
-        Total: total,
+        Data:     "All Packer",
+        Obj:      arrPacker,
+        Total:    page.Total,
+        PageInfo: page.PageInfo,
     }, nil
 }
 
-func (s *SmartContract) GetLastIdPacker(ctx contractapi.TransactionContextInterface) string {
-	// Query to get all records sorted by ID in descending order
-	query := `{
-		"selector": {
-			"docType": "packer"
-		},
-		"sort": [{"_id": "desc"}],
-		"use_index": ["_design/index-combined","index-combined"],
-		"limit": 1
-	}`
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
+// packerGmps returns the GMP of every packer that query finds GMPs of, the
+// last one found when a packer has several.
+func packerGmps(ctx contractapi.TransactionContextInterface, query *utils.Query) (map[string]*models.PackerGmp, error) {
+	gmps, err := utils.FetchAll[models.PackerGmp](ctx, query)
 	if err != nil {
-		return fmt.Sprintf("error querying CouchDB: %v", err)
+		return nil, fmt.Errorf("failed to query related gmp documents: %w", err)
 	}
-	defer resultsIterator.Close()
-
-	// Check if there is a result
-	if !resultsIterator.HasNext() {
-		return ""
-	}
-
-	// Get the first (and only) result
-	queryResponse, err := resultsIterator.Next()
-	if err != nil {
-		return "error iterating query results"
-	}
-
-	var result struct {
-		Id string `json:"id"`
+	byPacker := map[string]*models.PackerGmp{}
+	for _, gmpDoc := range gmps {
+		byPacker[gmpDoc.PackerId] = gmpDoc
 	}
+	return byPacker, nil
+}
 
-	// Unmarshal the result into the result struct
-	if err := json.Unmarshal(queryResponse.Value, &result); err != nil {
-		return "error unmarshalling document"
-	}
+// GetLastIdPacker returns the highest packer id.
+//
+// Deprecated: CreateFarmerProfile, CreatePacker and CreateExporter allocate
+// ids themselves when none is given.
+func (s *SmartContract) GetLastIdPacker(ctx contractapi.TransactionContextInterface) (string, error) {
+	return utils.LastId(ctx, utils.NewSelector(models.Packer).Query().
+		Sort("_id", "desc").
+		UseIndex("_design/index-combined", "index-combined"))
+}
 
-	return result.Id
+var packerImport = utils.BatchImport[models.TransactionPacker]{
+	Function:   "CreatePackerCsv",
+	Repository: packerRepository,
+	Build: func(input *models.TransactionPacker, meta utils.BatchMeta) *models.TransactionPacker {
+		return &models.TransactionPacker{
+			Id:                         input.Id,
+			CertId:                     input.CertId,
+			UserId:                     input.UserId,
+			IsCanExport:                input.IsCanExport,
+			IsCanDelete:                true,
+			PackingHouseName:           input.PackingHouseName,
+			PackingHouseRegisterNumber: input.PackingHouseRegisterNumber,
+			Owner:                      meta.Owner,
+			OrgName:                    meta.OrgName,
+			UpdatedAt:                  meta.Timestamp,
+			CreatedAt:                  meta.Timestamp,
+			DocType:                    models.Packer,
+		}
+	},
+	Fill: packerGmpCopy.Fill,
 }
 
 func (s *SmartContract) CreatePackerCsv(
 	ctx contractapi.TransactionContextInterface,
 	args string,
 ) error {
-	var inputs []models.TransactionPacker
-	var eventPayloads []models.TransactionPacker
-
-	errPackerInput := json.Unmarshal([]byte(args), &inputs)
-	if errPackerInput != nil {
-		return fmt.Errorf("failed to unmarshal JSON array: %v", errPackerInput)
-	}
-
-	for _, input := range inputs {
-		fmt.Printf("create packer csv input %v", input)
-		orgName, err := ctx.GetClientIdentity().GetMSPID()
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
-		}
-
-		existPacker, err := utils.AssetExists(ctx, input.Id)
-		if err != nil {
-			return fmt.Errorf("error checking if asset exists: %v", err)
-		}
-		if existPacker {
-			return fmt.Errorf("the asset %s already exists", input.Id)
-		}
-
-		clientID, err := utils.GetIdentity(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's identity: %v", err)
-		}
-
-		// timestamp := utils.GenerateTimestamp()
-
-		asset := models.TransactionPacker{
-			Id:        input.Id,
-			CertId:    input.CertId,
-			UserId:    input.UserId,
-			IsCanExport: input.IsCanExport,
-			IsCanDelete: true,
-			PackingHouseName: input.PackingHouseName,
-			PackingHouseRegisterNumber: input.PackingHouseRegisterNumber,
-			Owner:     clientID,
-			OrgName:   orgName,
-			UpdatedAt: input.UpdatedAt,
-			CreatedAt: input.CreatedAt,
-			DocType:   models.Packer,
-		}
-
-		packerAssetJSON, packerErr := json.Marshal(asset)
-		eventPayloads = append(eventPayloads, asset)
-		if packerErr != nil {
-			return fmt.Errorf("failed to marshal asset JSON: %v", err)
-		}
-
-		err = ctx.GetStub().PutState(input.Id, packerAssetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put state for asset %s: %v", input.Id, err)
-		}
-
-		fmt.Printf("Asset %s created successfully\n", input.Id)
-	}
-
-	eventPayloadJSON, err := json.Marshal(eventPayloads)
-	if err != nil {
-		return fmt.Errorf("failed to marshal asset JSON: %v", err)
-	}
-
-	ctx.GetStub().SetEvent("batchCreatedPackerEvent", eventPayloadJSON)
-
-	return nil
+	_, err := packerImport.Run(ctx, args, false)
+	return err
 }
\ No newline at end of file
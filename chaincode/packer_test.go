@@ -0,0 +1,220 @@
+package chaincode
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func TestPackerLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreatePackerCsv", func() error {
+		return s.CreatePackerCsv(ctx, `[
+			{"id":"PK1","userId":"PK1","certId":"C-1","packingHouseName":"Alpha","createdAt":"2024-01-01T00:00:00Z"},
+			{"id":"PK2","userId":"PK2","certId":"C-2","packingHouseName":"Beta","createdAt":"2024-01-02T00:00:00Z"}
+		]`)
+	})
+	assertChanges(t, lastStateChanges(t, ctx).Changes,
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Packer, Id: "PK1"},
+		models.StateChange{Type: models.ChangeCreate, DocType: models.Packer, Id: "PK2"},
+	)
+
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packerId":"PK1"}`)
+	})
+
+	packer, err := s.ReadPacker(ctx, "PK1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packer.DocType != models.Packer || packer.PackerGmp == nil || packer.PackerGmp.Id != "M1" {
+		t.Fatalf("unexpected packer %+v", packer)
+	}
+
+	byPackerId, err := s.GetPackerByPackerId(ctx, "PK1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byPackerId.Obj == nil || byPackerId.Obj.PackerGmp.Id != "M1" || !byPackerId.Obj.IsCanDelete {
+		t.Fatalf("unexpected packer by id %+v", byPackerId.Obj)
+	}
+
+	packerCtx := ctx.As(packerUser)
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","packerId":"PK1"}`)
+	})
+	byPackerId, _ = s.GetPackerByPackerId(ctx, "PK1")
+	if byPackerId.Obj.IsCanDelete {
+		t.Fatal("packer with packings should not be deletable")
+	}
+
+	byId, err := s.GetPackerById(ctx, "PK2")
+	if err != nil || byId.CertId != "C-2" {
+		t.Fatalf("unexpected packer %+v, %v", byId, err)
+	}
+
+	if id, err := s.GetLastIdPacker(ctx); err != nil || id != "PK2" {
+		t.Fatalf("last id = %q, want PK2, %v", id, err)
+	}
+
+	// The packer record is owned by the staff member who imported it.
+	if err := submit(packerCtx, "UpdatePacker", func() error {
+		return s.UpdatePacker(packerCtx, `{"id":"PK2","certId":"C-X"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("packer update: expected access denied, got %v", err)
+	}
+	mustSubmit(t, ctx, "UpdatePacker", func() error {
+		return s.UpdatePacker(ctx, `{"id":"PK2","userId":"PK2","certId":"C-3","isCanExport":true}`)
+	})
+	updated, _ := s.ReadPacker(ctx, "PK2")
+	if updated.CertId != "C-3" || !updated.IsCanExport {
+		t.Fatalf("update not applied: %+v", updated)
+	}
+
+	mustSubmit(t, ctx, "DeletePacker", func() error {
+		return s.DeletePacker(ctx, "PK2", "duplicate record", false)
+	})
+	if err := submit(ctx, "DeletePacker", func() error {
+		return s.DeletePacker(ctx, "PK2", "duplicate record", false)
+	}); err == nil {
+		t.Fatal("expected delete of missing packer to fail")
+	}
+}
+
+func TestDeletePackerReferences(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreatePackerCsv", func() error {
+		return s.CreatePackerCsv(ctx, `[{"id":"PK1","userId":"PK1","certId":"C-1"}]`)
+	})
+	mustSubmit(t, ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1","packerId":"PK1"}`)
+	})
+
+	var refErr *utils.ReferenceError
+	if err := submit(ctx, "DeletePacker", func() error {
+		return s.DeletePacker(ctx, "PK1", "left the scheme", false)
+	}); !errors.As(err, &refErr) || len(refErr.References) != 1 || refErr.References[0] != (models.Reference{DocType: models.Gmp, Id: "M1", Field: "packerId"}) {
+		t.Fatalf("expected delete to be blocked by M1, got %v", err)
+	}
+
+	packerCtx := ctx.As(packerUser)
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","packerId":"PK1"}`)
+	})
+	if err := submit(ctx, "DeletePacker", func() error {
+		return s.DeletePacker(ctx, "PK1", "left the scheme", true)
+	}); !errors.As(err, &refErr) || len(refErr.References) != 1 || refErr.References[0].Id != "P1" {
+		t.Fatalf("expected cascade to be blocked by P1, got %v", err)
+	}
+	if gmp, _ := s.ReadGmp(ctx, "M1"); gmp.PackerId != "PK1" {
+		t.Fatalf("refused cascade released the gmp %+v", gmp)
+	}
+
+	mustSubmit(t, packerCtx, "DeletePacking", func() error {
+		return s.DeletePacking(packerCtx, "P1", "entered by mistake")
+	})
+	mustSubmit(t, ctx, "DeletePacker", func() error {
+		return s.DeletePacker(ctx, "PK1", "left the scheme", true)
+	})
+	gmp, err := s.ReadGmp(ctx, "M1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gmp.PackerId != "" || gmp.UpdatedAt != ctx.Stub.TxTimestamp.Format(time.RFC3339) {
+		t.Fatalf("cascade did not release the gmp %+v", gmp)
+	}
+}
+
+func TestGetAllPacker(t *testing.T) {
+	s, ctx := newTestContract()
+
+	seedDaily(t, ctx, "CreatePackerCsv", func(args string) error {
+		return s.CreatePackerCsv(ctx, args)
+	},
+		`[{"id":"PK1","userId":"PK1","certId":"C-1","packingHouseName":"Alpha","packingHouseRegisterNumber":"PH-1"}]`,
+		`[{"id":"PK2","userId":"PK2","certId":"C-2","packingHouseName":"Beta","packingHouseRegisterNumber":"PH-2"}]`,
+		`[{"id":"PK3","userId":"PK3","certId":"C-3","packingHouseName":"Alphabet","packingHouseRegisterNumber":"PH-3"}]`,
+	)
+
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllPacker
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FilterGetAllPacker{}, []string{"PK3", "PK2", "PK1"}, 3},
+		{"limit", models.FilterGetAllPacker{Pagination: models.Pagination{Limit: 2}}, []string{"PK3", "PK2"}, 3},
+		{"search name", models.FilterGetAllPacker{Search: strPtr("Alpha")}, []string{"PK3", "PK1"}, 2},
+		{"search cert", models.FilterGetAllPacker{Search: strPtr("C-2")}, []string{"PK2"}, 1},
+		{"search is literal", models.FilterGetAllPacker{Search: strPtr(".*")}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllPacker(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, p := range res.Obj {
+				ids = append(ids, p.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
+
+func TestPackerQueries(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreatePackerCsv", func() error {
+		return s.CreatePackerCsv(ctx, `[
+			{"id":"PK1","userId":"PK1","createdAt":"2024-01-01T00:00:00Z"},
+			{"id":"PK2","userId":"PK2","createdAt":"2024-01-02T00:00:00Z"},
+			{"id":"PK3","userId":"PK3","createdAt":"2024-01-03T00:00:00Z"}
+		]`)
+	})
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[
+			{"id":"M1","packingHouseRegisterNumber":"PH-1","packerId":"PK1"},
+			{"id":"M2","packingHouseRegisterNumber":"PH-2","packerId":"PK2"}
+		]`)
+	})
+	queries := countQueries(ctx)
+
+	var byPackerId *models.PackerByIdResponse
+	mustSubmit(t, ctx, "GetPackerByPackerId", func() (err error) {
+		byPackerId, err = s.GetPackerByPackerId(ctx, "PK1")
+		return err
+	})
+	// The packer, then its GMPs once for the listing and the delete check,
+	// and the orders, Form Es and packagings that would block a delete.
+	if byPackerId.Obj.PackerGmp.Id != "M1" || !byPackerId.Obj.IsCanDelete || *queries != 5 {
+		t.Errorf("packer by id %+v in %d queries, want 5", byPackerId.Obj, *queries)
+	}
+
+	*queries = 0
+	var all *models.PackerGetAllResponse
+	mustSubmit(t, ctx, "GetAllPacker", func() (err error) {
+		all, err = s.GetAllPacker(ctx, `{}`)
+		return err
+	})
+	if *queries != 3 {
+		t.Errorf("3 packers took %d queries, want 3", *queries)
+	}
+	gmps := []string{}
+	for _, packer := range all.Obj {
+		gmps = append(gmps, packer.Id+"="+packer.PackerGmp.Id)
+	}
+	sort.Strings(gmps)
+	assertIDs(t, gmps, "PK1=M1", "PK2=M2", "PK3=")
+}
@@ -1,77 +1,72 @@
 package chaincode
 
 import (
-	"encoding/json"
 	"fmt"
 	"sort"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var packingRepository = utils.Repository[models.TransactionPacking]{
+	DocType:  models.Packing,
+	Id:       func(asset *models.TransactionPacking) string { return asset.Id },
+	Embedded: []utils.Embedding[models.TransactionPacking]{utils.GapSoldTotals{}},
+}
+
 func (s *SmartContract) CreatePacking(
 	ctx contractapi.TransactionContextInterface,
 	args string,
 ) error {
 	entityPacking := models.TransactionPacking{}
 	inputInterface, err := utils.Unmarshal(args, entityPacking)
-	utils.HandleError(err)
-	input := inputInterface.(*models.TransactionPacking)
-
-	// err := ctx.GetClientIdentity().AssertAttributeValue("packing.creator", "true")
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have packing.creator role")
+		return err
 	}
+	input := inputInterface.(*models.TransactionPacking)
 
-	existsPacking, err := utils.AssetExists(ctx, input.Id)
-	utils.HandleError(err)
-	if existsPacking {
-		return fmt.Errorf("the asset %s already exists", input.Id)
+	if err := utils.ValidateInitialPackingStatus(input.ProcessStatus); err != nil {
+		return err
 	}
 
-	clientIDPacking, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
+	if err := utils.AssertCertificates(ctx, input.Gap, input.Gmp); err != nil {
+		return err
+	}
 
-	totalSoldSnapShot, err := utils.GetTotalSoldSnapShot(ctx, input.Gap, input.ActualWeight, input.ProcessStatus)
+	totalSoldSnapShot, err := utils.GetTotalSoldSnapShot(ctx, input)
 	if err != nil {
 		return err
 	}
 
 	asset := models.TransactionPacking{
-		Id:             input.Id,
-		OrderID:        input.OrderID,
-		FarmerID:       input.FarmerID,
-		PackingHouseName: input.PackingHouseName,
-		ForecastWeight: input.ForecastWeight,
-		ActualWeight:   input.ActualWeight,
-		SavedTime:      input.SavedTime,
-		ApprovedDate:   input.ApprovedDate,
-		ApprovedType:   input.ApprovedType,
-		FinalWeight:    input.FinalWeight,
-		Remark:         input.Remark,
-		CancelReason:   input.CancelReason,
-		PackerId:       input.PackerId,
+		Id:                input.Id,
+		OrderID:           input.OrderID,
+		FarmerID:          input.FarmerID,
+		PackingHouseName:  input.PackingHouseName,
+		ForecastWeight:    input.ForecastWeight,
+		ActualWeight:      input.ActualWeight,
+		SavedTime:         input.SavedTime,
+		ApprovedDate:      input.ApprovedDate,
+		ApprovedType:      input.ApprovedType,
+		FinalWeight:       input.FinalWeight,
+		Remark:            input.Remark,
+		CancelReason:      input.CancelReason,
+		PackerId:          input.PackerId,
 		TotalSoldSnapShot: float32(totalSoldSnapShot),
-		Province:       input.Province,
-		District:       input.District,
-		Gmp:            input.Gmp,
-		Gap:            input.Gap,
-		DisplayCertId:  input.DisplayCertId,
-		ProcessStatus:  input.ProcessStatus,
-		SellingStep:  	input.SellingStep,
-		Owner:          clientIDPacking,
-		OrgName:        orgName,
-		UpdatedAt:      input.UpdatedAt,
-		CreatedAt:      input.CreatedAt,
-		DocType: 		models.Packing,
-	}
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
-
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+		Province:          input.Province,
+		District:          input.District,
+		Gmp:               input.Gmp,
+		Gap:               input.Gap,
+		DisplayCertId:     input.DisplayCertId,
+		ProcessStatus:     input.ProcessStatus,
+	}
+
+	if err := utils.AssertGapQuota(ctx, &asset); err != nil {
+		return err
+	}
+
+	return packingRepository.Create(ctx, &asset)
 }
 
 func (s *SmartContract) UpdatePacking(ctx contractapi.TransactionContextInterface, args string) error {
@@ -79,23 +74,31 @@ func (s *SmartContract) UpdatePacking(ctx contractapi.TransactionContextInterfac
 
 	// Initialize entityPacking and unmarshal the input args into it
 	var entityPacking models.TransactionPacking
-	err := json.Unmarshal([]byte(args), &entityPacking)
+	err := utils.DecodeInput(args, &entityPacking)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal input: %v", err)
+		return err
 	}
 
 	fmt.Println("Start read packing")
 	asset, err := s.ReadPacking(ctx, entityPacking.Id)
 	if err != nil {
-		return fmt.Errorf("failed to read asset: %v", err)
+		return err
+	}
+	if err := utils.AssertPackingEditor(ctx, asset); err != nil {
+		return err
 	}
 
-	totalSoldSnapShot, err := utils.GetTotalSoldSnapShot(ctx, entityPacking.Gap, entityPacking.ActualWeight, entityPacking.ProcessStatus)
-	if err != nil {
+	if err := utils.AssertPackingFields(asset, &entityPacking); err != nil {
 		return err
 	}
 
 	fmt.Println("Modify packing model")
+	quotaWeight, quotaGap := utils.PackingQuotaWeight(asset), asset.Gap
+	if entityPacking.Gap != asset.Gap || entityPacking.Gmp != asset.Gmp {
+		if err := utils.AssertCertificates(ctx, entityPacking.Gap, entityPacking.Gmp); err != nil {
+			return err
+		}
+	}
 	asset.ForecastWeight = entityPacking.ForecastWeight
 	asset.ActualWeight = entityPacking.ActualWeight
 	asset.SavedTime = entityPacking.SavedTime
@@ -108,147 +111,211 @@ func (s *SmartContract) UpdatePacking(ctx contractapi.TransactionContextInterfac
 	asset.CancelReason = entityPacking.CancelReason
 	asset.Gmp = entityPacking.Gmp
 	asset.Gap = entityPacking.Gap
-	asset.TotalSoldSnapShot = totalSoldSnapShot
-	asset.ProcessStatus = entityPacking.ProcessStatus
-	asset.SellingStep = entityPacking.SellingStep
-	asset.UpdatedAt = entityPacking.UpdatedAt
 
-	fmt.Println("Modify packing done")
-	assetJSON, errPacking := json.Marshal(asset)
-	if errPacking != nil {
-		return fmt.Errorf("failed to marshal updated asset: %v", errPacking)
+	// Status changes go through the same checks as the transition functions.
+	if entityPacking.ProcessStatus != asset.ProcessStatus {
+		reason := entityPacking.Remark
+		if entityPacking.ProcessStatus == models.StatusCancelled {
+			reason = entityPacking.CancelReason
+		}
+		if err := applyPackingTransition(ctx, asset, entityPacking.ProcessStatus, reason); err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("Send UpdateAsset event")
-	err = ctx.GetStub().SetEvent("UpdateAsset", assetJSON)
-	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+	// Only re-check the quota when the order takes more of it, so orders left
+	// over quota by a lowered yield can still be edited down.
+	if asset.Gap != quotaGap || utils.PackingQuotaWeight(asset) > quotaWeight {
+		if err := utils.AssertGapQuota(ctx, asset); err != nil {
+			return err
+		}
 	}
 
-	fmt.Printf("Packing %s updated successfully\n", entityPacking.Id)
-	return ctx.GetStub().PutState(entityPacking.Id, assetJSON)
+	fmt.Println("Modify packing done")
+	return putPacking(ctx, asset)
 }
 
+// SavePacking marks a draft packing order as saved by the packer.
+func (s *SmartContract) SavePacking(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionPacking(ctx, args, models.StatusPackerSaved)
+}
 
-func (s *SmartContract) DeletePacking(ctx contractapi.TransactionContextInterface, id string) error {
+// ApprovePacking approves a saved packing order, counting its weight as sold.
+func (s *SmartContract) ApprovePacking(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionPacking(ctx, args, models.StatusApproved)
+}
 
-	assetPacking, err := s.ReadPacking(ctx, id)
+// CompletePacking completes an approved packing order.
+func (s *SmartContract) CompletePacking(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionPacking(ctx, args, models.StatusCompleted)
+}
+
+// CancelPacking cancels a packing order that is not yet completed. A cancel
+// reason is required.
+func (s *SmartContract) CancelPacking(ctx contractapi.TransactionContextInterface, args string) error {
+	return s.transitionPacking(ctx, args, models.StatusCancelled)
+}
+
+func (s *SmartContract) transitionPacking(ctx contractapi.TransactionContextInterface, args string, to models.ProcessStatus) error {
+	var input models.PackingTransitionInput
+	if err := utils.DecodeInput(args, &input); err != nil {
+		return err
+	}
+
+	asset, err := s.ReadPacking(ctx, input.Id)
 	if err != nil {
 		return err
 	}
 
-	// clientIDPacking, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
+	reason := input.Remark
+	if to == models.StatusCancelled {
+		if input.CancelReason == "" {
+			return utils.Validation("cancelReason is required to cancel packing %s", input.Id)
+		}
+		reason = input.CancelReason
+	}
 
-	// if clientIDPacking != assetPacking.Owner {
-	// 	return utils.ReturnError(utils.UNAUTHORIZE)
-	// }
+	if err := applyPackingTransition(ctx, asset, to, reason); err != nil {
+		return err
+	}
+	changedAt := asset.StatusHistory[len(asset.StatusHistory)-1].ChangedAt
+
+	switch to {
+	case models.StatusPackerSaved:
+		asset.SavedTime = changedAt
+	case models.StatusApproved:
+		asset.ApprovedDate = changedAt
+		asset.ApprovedType = input.ApprovedType
+	case models.StatusCompleted:
+		if input.FinalWeight != nil {
+			asset.FinalWeight = *input.FinalWeight
+		}
+	case models.StatusCancelled:
+		asset.CancelReason = input.CancelReason
+	}
+	if input.Remark != "" {
+		asset.Remark = input.Remark
+	}
 
-	return ctx.GetStub().DelState(assetPacking.Id)
+	return putPacking(ctx, asset)
 }
 
-func (s *SmartContract) TransferPacking(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
-
-	assetPacking, err := s.ReadPacking(ctx, id)
-	utils.HandleError(err)
+// applyPackingTransition moves asset to status to on behalf of the caller and
+// records who made the change and when.
+func applyPackingTransition(ctx contractapi.TransactionContextInterface, asset *models.TransactionPacking, to models.ProcessStatus, reason string) error {
+	if err := utils.AssertPackingTransition(ctx, asset, to); err != nil {
+		return err
+	}
 
 	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
+	orgName, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	changedAt, err := utils.GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
 
-	if clientID != assetPacking.Owner {
-		return utils.ReturnError(utils.UNAUTHORIZE)
+	asset.StatusHistory = append(asset.StatusHistory, models.PackingStatusChange{
+		From:      asset.ProcessStatus,
+		To:        to,
+		ChangedBy: clientID,
+		OrgName:   orgName,
+		Reason:    reason,
+		ChangedAt: changedAt,
+	})
+	asset.ProcessStatus = to
+	asset.SellingStep++
+
+	return nil
+}
+
+// putPacking refreshes the sold snapshot of asset and stores it.
+func putPacking(ctx contractapi.TransactionContextInterface, asset *models.TransactionPacking) error {
+	totalSoldSnapShot, err := utils.GetTotalSoldSnapShot(ctx, asset)
+	if err != nil {
+		return err
 	}
+	asset.TotalSoldSnapShot = totalSoldSnapShot
 
-	assetPacking.Owner = newOwner
-	assetJSON, err := json.Marshal(assetPacking)
-	utils.HandleError(err)
-	return ctx.GetStub().PutState(id, assetJSON)
+	return packingRepository.Update(ctx, asset)
 }
 
-func (s *SmartContract) ReadPacking(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionPacking, error) {
 
-	assetJSON, err := ctx.GetStub().GetState(id)
+func (s *SmartContract) DeletePacking(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+
+	assetPacking, err := s.ReadPacking(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return err
 	}
 
-	var asset models.TransactionPacking
-	err = json.Unmarshal(assetJSON, &asset)
-	if err != nil {
-		return nil, err
+	if err := utils.AssertStaffOrOwner(ctx, assetPacking.Owner); err != nil {
+		return err
 	}
 
-	return &asset, nil
+	return packingRepository.Delete(ctx, assetPacking.Id, reason)
 }
 
-func (s *SmartContract) GetAllPacking(ctx contractapi.TransactionContextInterface, args string) (*models.PackingGetAllResponse, error) {
-    entityGetAllPacking := models.FilterGetAllPacking{}
-    interfacePacking, err := utils.Unmarshal(args, entityGetAllPacking)
-    if err != nil {
-        return nil, err
-    }
-    inputPacking := interfacePacking.(*models.FilterGetAllPacking)
-    filterPacking := utils.PackingSetFilter(inputPacking)
+func (s *SmartContract) RestorePacking(ctx contractapi.TransactionContextInterface, id string) error {
+	assetPacking, err := utils.ReadAssetHeader(ctx, id, models.Packing)
+	if err != nil {
+		return err
+	}
 
-	// Build query string
-	selector := map[string]interface{}{
-		"selector": filterPacking,
+	if err := utils.AssertStaffOrOwner(ctx, assetPacking.Owner); err != nil {
+		return err
 	}
 
-	if inputPacking.Search != nil && *inputPacking.Search != "" {
-		searchTerm := *inputPacking.Search
+	return packingRepository.Restore(ctx, id)
+}
 
-		_, searchExists := filterPacking["search"]
-		if searchExists {
-			delete(filterPacking, "search")
-		}
+func (s *SmartContract) TransferPacking(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
 
-		selector["selector"] = map[string]interface{}{
-			"$and": []map[string]interface{}{
-				filterPacking,
-				{
-					"$or": []map[string]interface{}{
-						{"gmp": map[string]interface{}{"$regex": searchTerm}},
-						{"packingHouseName": map[string]interface{}{"$regex": searchTerm}},
-						{"gap": map[string]interface{}{"$regex": searchTerm}},
-						{"displayCertId": map[string]interface{}{"$regex": searchTerm}},
-					},
-				},
-			},
-		}
+	assetPacking, err := s.ReadPacking(ctx, id)
+	if err != nil {
+		return err
 	}
 
-	queryString, err := json.Marshal(selector)
-	if err != nil {
-		return nil, err
+	if err := utils.AssertStaffOrOwner(ctx, assetPacking.Owner); err != nil {
+		return err
 	}
 
-	// Debugging: print the query string to ensure it matches the expected filter criteria
-	fmt.Printf("Query String for Fetching and Counting: %s\n", queryString)
+	assetPacking.Owner = newOwner
+	return packingRepository.Update(ctx, assetPacking)
+}
+
+func (s *SmartContract) ReadPacking(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionPacking, error) {
+	return packingRepository.Get(ctx, id)
+}
 
-    total, err := utils.CountTotalResults(ctx, string(queryString))
+func (s *SmartContract) GetAllPacking(ctx contractapi.TransactionContextInterface, args string) (*models.PackingGetAllResponse, error) {
+    entityGetAllPacking := models.FilterGetAllPacking{}
+    interfacePacking, err := utils.Unmarshal(args, entityGetAllPacking)
     if err != nil {
         return nil, err
     }
+    inputPacking := interfacePacking.(*models.FilterGetAllPacking)
+    filterPacking := utils.PackingSetFilter(inputPacking)
 
-    arrPacking, _, err := utils.PackingFetchResultsWithPagination(ctx, inputPacking, selector)
+    page, err := utils.Paginate[models.PackingTransactionResponse](ctx, filterPacking.Query().
+        Sort("createdAt", "desc").
+        UseIndex("_design/indexFarmerCreatedAt", "indexFarmerCreatedAt"), inputPacking.Pagination)
     if err != nil {
         return nil, err
     }
-
-    if len(arrPacking) == 0 {
-        arrPacking = []*models.PackingTransactionResponse{}
-    }
+    arrPacking := page.Items
 
     // CalculateTotalSold(arrPacking)
 
     return &models.PackingGetAllResponse{
-        Data:  "All Packing",
-        Obj:   arrPacking,
-        Total: total,
+        Data:     "All Packing",
+        Obj:      arrPacking,
+        Total:    page.Total,
+        PageInfo: page.PageInfo,
     }, nil
 }
 
@@ -275,7 +342,7 @@ func CalculateTotalPackingSold(documents []*models.TransactionPacking) {
 	packingTotals := make(map[string]float32)
 	for _, doc := range documents {
 		if doc.Gap != "" {
-			packingTotals[doc.Gap] += doc.FinalWeight
+			packingTotals[doc.Gap] += utils.SoldWeight(doc)
 		}
 	}
 	for _, doc := range documents {
@@ -285,165 +352,74 @@ func CalculateTotalPackingSold(documents []*models.TransactionPacking) {
 	}
 }
 
+// CalculateTotalSold returns the weight sold from the GAP certificate gapId,
+// in whole kg.
 func (s *SmartContract) CalculateTotalSold(ctx contractapi.TransactionContextInterface, gapId string) (int, error) {
-	packings, err := FetchPackingDocsByGap(ctx, gapId)
+	total, err := utils.GetGapSoldTotal(ctx, gapId)
 	if err != nil {
 		return 0, err
 	}
 
-	totalSold := 0
-
-	for _, packing := range packings {
-		if (packing.ProcessStatus == 2 || packing.ProcessStatus == 3) {
-			totalSold += int(packing.ActualWeight)
-		}
-	}
+	return int(total.TotalSold), nil
+}
 
-	return totalSold, nil
+// RebuildGapSoldTotals recomputes the totals sold from the GAP certificates
+// from the packing orders and returns them.
+func (s *SmartContract) RebuildGapSoldTotals(ctx contractapi.TransactionContextInterface) ([]models.GapSoldTotal, error) {
+	return utils.RebuildGapSoldTotals(ctx)
 }
 
 func (s *SmartContract) FilterPacking(ctx contractapi.TransactionContextInterface, key, value string) ([]*models.TransactionPacking, error) {
-	resultsIteratorP, err := ctx.GetStub().GetStateByRange("", "")
+	assetPacking, err := packingRepository.Filter(ctx, key, value)
 	if err != nil {
 		return nil, err
 	}
-	defer resultsIteratorP.Close()
-
-	var assetPacking []*models.TransactionPacking
-	for resultsIteratorP.HasNext() {
-		queryResponse, err := resultsIteratorP.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var asset models.TransactionPacking
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, err
-		}
-
-		var m map[string]interface{}
-		if err := json.Unmarshal(queryResponse.Value, &m); err != nil {
-			return nil, err
-		}
-
-		// Check if docType is "packing"
-		if docType, ok := m["docType"]; ok && docType == "packing" {
-			// Apply the key-value filter
-			if val, ok := m[key]; ok && fmt.Sprintf("%v", val) == value {
-				assetPacking = append(assetPacking, &asset)
-			}
-		}
-	}
 
 	CalculateTotalPackingSold(assetPacking)
 
-	sort.Slice(assetPacking, func(i, j int) bool {
-        t1, err1 := time.Parse(time.RFC3339, assetPacking[i].CreatedAt)
-        t2, err2 := time.Parse(time.RFC3339, assetPacking[j].CreatedAt)
-        if err1 != nil || err2 != nil {
-            fmt.Println("Error parsing time:", err1, err2)
-            return false
-        }
-        return t1.After(t2)
-    })
-
 	return assetPacking, nil
 }
 
 func (s *SmartContract) GetLatestHistoryForKey(ctx contractapi.TransactionContextInterface, key string) (*models.PackingTransactionHistory, error) {
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	history, err := s.GetHistoryForKey(ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history for key %s: %v", key, err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
-
-	var latestHistory *models.PackingTransactionHistory
-	var packingAsset []*models.PackingTransactionResponse
-
-	for resultsIterator.HasNext() {
-		// Get the next history record
-		record, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next history record for key %s: %v", key, err)
-		}
-
-		var asset models.PackingTransactionResponse
-		if !record.IsDelete {
-			err = json.Unmarshal(record.Value, &asset)
-			if err != nil {
-				return nil, err
-			}
-			packingAsset = append(packingAsset, &asset)
-		} else {
-			packingAsset = []*models.PackingTransactionResponse{}
-		}
-
-		// Sort packingAsset by SellingStep
-		sort.SliceStable(packingAsset, func(i, j int) bool {
-			return packingAsset[i].SellingStep > packingAsset[j].SellingStep
-		})
-
-		// Convert the timestamp to string in the desired format
-		timestampStr := time.Unix(record.Timestamp.Seconds, int64(record.Timestamp.Nanos)).Format(utils.TIMEFORMAT)
-
-		historyRecord := &models.PackingTransactionHistory{
-			TxId:      record.TxId,
-			Value:     packingAsset,
-			Timestamp: timestampStr,
-			IsDelete:  record.IsDelete,
-		}
-
-		latestHistory = historyRecord
+	if len(history) == 0 {
+		return nil, utils.NotFound("no history found for key %s", key)
 	}
 
-	if latestHistory == nil {
-		return nil, fmt.Errorf("no history found for key %s", key)
-	}
+	// Sort packingAsset by SellingStep
+	latestHistory := history[len(history)-1]
+	sort.SliceStable(latestHistory.Value, func(i, j int) bool {
+		return latestHistory.Value[i].SellingStep > latestHistory.Value[j].SellingStep
+	})
 
 	return latestHistory, nil
 }
 
 func (s *SmartContract) GetHistoryForKey(ctx contractapi.TransactionContextInterface, key string) ([]*models.PackingTransactionHistory, error) {
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	entries, err := utils.ReadHistory[models.PackingTransactionResponse](ctx, key)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get history for key %s: %v", key, err)
+		return nil, err
 	}
-	defer resultsIterator.Close()
 
 	var history []*models.PackingTransactionHistory
 	var assetsValue []*models.PackingTransactionResponse
-
-	for resultsIterator.HasNext() {
-		// Get the next history record
-		record, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next history record for key %s: %v", key, err)
-		}
-
-		var asset models.PackingTransactionResponse
-		if !record.IsDelete {
-			err = json.Unmarshal(record.Value, &asset)
-			if err != nil {
-				return nil, err
-			}
-			assetsValue = append(assetsValue, &asset)
-
+	for _, entry := range entries {
+		if entry.Value != nil {
+			assetsValue = append(assetsValue, entry.Value)
 		} else {
 			assetsValue = []*models.PackingTransactionResponse{}
 		}
-		// Convert the timestamp to string in the desired format
-		timestampStr := time.Unix(record.Timestamp.Seconds, int64(record.Timestamp.Nanos)).Format(utils.TIMEFORMAT)
 
-		historyRecord := &models.PackingTransactionHistory{
-			TxId:      record.TxId,
+		history = append(history, &models.PackingTransactionHistory{
+			TxId:      entry.TxId,
 			Value:     assetsValue,
-			Timestamp: timestampStr,
-			IsDelete:  record.IsDelete,
-		}
-
-		history = append(history, historyRecord)
+			Timestamp: entry.Timestamp,
+			IsDelete:  entry.IsDelete,
+		})
 	}
 
 	return history, nil
-}
\ No newline at end of file
+}
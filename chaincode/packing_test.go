@@ -0,0 +1,406 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// seedCertificates registers the GAP and GMP certificates the packing tests
+// refer to.
+func seedCertificates(t *testing.T, s *SmartContract, ctx *mocks.MockContext) {
+	t.Helper()
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G1","certId":"GAP-1"},{"id":"G2","certId":"GAP-2"}]`)
+	})
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[{"id":"M1","packingHouseRegisterNumber":"PH-1"},{"id":"M2","packingHouseRegisterNumber":"PH-2"}]`)
+	})
+}
+
+func TestPackingLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+	seedCertificates(t, s, ctx)
+	packerCtx := ctx.As(packerUser)
+
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","gap":"GAP-1","actualWeight":10,"processStatus":1}`)
+	})
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P2","gap":"GAP-1","actualWeight":5,"processStatus":1}`)
+	})
+	advancePacking(t, s, ctx, "P1", models.StatusApproved)
+	advancePacking(t, s, ctx, "P2", models.StatusApproved)
+
+	packing, err := s.ReadPacking(ctx, "P2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packing.Owner != packerUser.ID || packing.OrgName != "PackerMSP" || packing.DocType != models.Packing {
+		t.Fatalf("unexpected packing %+v", packing)
+	}
+	if packing.TotalSoldSnapShot != 15 {
+		t.Fatalf("snapshot = %v, want 15", packing.TotalSoldSnapShot)
+	}
+
+	if err := submit(packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1"}`)
+	}); err == nil {
+		t.Fatal("expected duplicate packing to be rejected")
+	}
+
+	// An approved order takes no new weight or certificates, only the final
+	// weight it is completed with.
+	if err := submit(packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, `{"id":"P2","gap":"GAP-1","actualWeight":8,"finalWeight":8,"processStatus":3,"sellingStep":1}`)
+	}); errorCode(err) != models.ErrConflict {
+		t.Fatalf("weight change of an approved order: expected a conflict, got %v", err)
+	}
+	packing.FinalWeight = 4
+	packing.ProcessStatus = models.StatusCompleted
+	packing.SellingStep = 1
+	mustSubmit(t, packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, toJSON(t, packing))
+	})
+	event := lastStateChanges(t, ctx)
+	if event.Function != "UpdatePacking" || event.Actor != packerUser.ID {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	assertChanges(t, event.Changes,
+		models.StateChange{Type: models.ChangeUpdate, DocType: models.Packing, Id: "P2"},
+		models.StateChange{Type: models.ChangeUpdate, DocType: models.SoldTotal, Id: "GAP-1"},
+	)
+
+	total, err := s.CalculateTotalSold(ctx, "GAP-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 14 {
+		t.Fatalf("total sold = %d, want 14", total)
+	}
+	packing.Remark = "weighed twice"
+	packing.Province = "Nan"
+	if err := submit(packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, toJSON(t, packing))
+	}); errorCode(err) != models.ErrConflict {
+		t.Fatalf("province change of a completed order: expected a conflict, got %v", err)
+	}
+
+	otherCtx := ctx.As(otherPacker)
+	if err := submit(otherCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(otherCtx, `{"id":"P1","gap":"GAP-1","actualWeight":1,"processStatus":2,"remark":"mine now"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("update by non-owner: expected access denied, got %v", err)
+	}
+	if packing, _ := s.ReadPacking(ctx, "P1"); packing.ActualWeight != 10 || packing.Remark != "" {
+		t.Fatalf("non-owner update changed the order %+v", packing)
+	}
+	if err := submit(otherCtx, "TransferPacking", func() error {
+		return s.TransferPacking(otherCtx, "P1", otherPacker.ID)
+	}); !isAccessDenied(err) {
+		t.Fatalf("transfer by non-owner: expected access denied, got %v", err)
+	}
+	if err := submit(otherCtx, "DeletePacking", func() error {
+		return s.DeletePacking(otherCtx, "P1", "duplicate record")
+	}); !isAccessDenied(err) {
+		t.Fatalf("delete by non-owner: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, packerCtx, "TransferPacking", func() error {
+		return s.TransferPacking(packerCtx, "P1", "x509::CN=packer2")
+	})
+	packing, _ = s.ReadPacking(ctx, "P1")
+	if packing.Owner != "x509::CN=packer2" {
+		t.Fatalf("owner = %q", packing.Owner)
+	}
+
+	mustSubmit(t, ctx, "DeletePacking", func() error {
+		return s.DeletePacking(ctx, "P2", "duplicate record")
+	})
+	if err := submit(ctx, "DeletePacking", func() error {
+		return s.DeletePacking(ctx, "P2", "duplicate record")
+	}); err == nil {
+		t.Fatal("expected delete of missing packing to fail")
+	}
+
+	history, err := s.GetHistoryForKey(ctx, "P2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 || history[0].TxId == "" || !history[3].IsDelete {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if len(history[2].Value) != 3 || history[2].Value[2].ProcessStatus != models.StatusCompleted || history[2].Value[2].SellingStep != 2 {
+		t.Fatalf("unexpected history values %+v", history[2].Value)
+	}
+
+	latest, err := s.GetLatestHistoryForKey(ctx, "P1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.IsDelete || len(latest.Value) != 3 {
+		t.Fatalf("unexpected latest history %+v", latest)
+	}
+	if _, err := s.GetLatestHistoryForKey(ctx, "missing"); err == nil {
+		t.Fatal("expected error for key without history")
+	}
+}
+
+func TestGetAllPacking(t *testing.T) {
+	s, ctx := newTestContract()
+	seedCertificates(t, s, ctx)
+	packerCtx := ctx.As(packerUser)
+
+	seedDaily(t, packerCtx, "CreatePacking", func(args string) error {
+		return s.CreatePacking(packerCtx, args)
+	},
+		`{"id":"P1","packerId":"PK1","farmerId":"F1","gap":"GAP-1","gmp":"PH-1","province":"Nan","forecastWeight":10,"processStatus":1}`,
+		`{"id":"P2","packerId":"PK1","farmerId":"F2","gap":"GAP-2","gmp":"PH-1","province":"Nan","forecastWeight":50,"processStatus":1}`,
+		`{"id":"P3","packerId":"PK2","farmerId":"F1","gap":"GAP-1","gmp":"PH-2","packingHouseName":"Beta","province":"Lampang","forecastWeight":100,"processStatus":1}`,
+	)
+	advancePacking(t, s, ctx, "P2", models.StatusApproved)
+	advancePacking(t, s, ctx, "P3", models.StatusCompleted)
+
+	from, to := float32(20), float32(60)
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllPacking
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FilterGetAllPacking{}, []string{"P3", "P2", "P1"}, 3},
+		{"skip and limit", models.FilterGetAllPacking{Pagination: models.Pagination{Skip: 1, Limit: 1}}, []string{"P2"}, 3},
+		{"packer", models.FilterGetAllPacking{PackerId: strPtr("PK1")}, []string{"P2", "P1"}, 2},
+		{"farmer", models.FilterGetAllPacking{FarmerID: strPtr("F1")}, []string{"P3", "P1"}, 2},
+		{"province", models.FilterGetAllPacking{Province: strPtr("Lampang")}, []string{"P3"}, 1},
+		{"process status list", models.FilterGetAllPacking{ProcessStatus: strPtr("1, 3")}, []string{"P3", "P1"}, 2},
+		{"forecast range", models.FilterGetAllPacking{ForecastWeightFrom: &from, ForecastWeightTo: &to}, []string{"P2"}, 1},
+		{"forecast to", models.FilterGetAllPacking{ForecastWeightTo: &to}, []string{"P2", "P1"}, 2},
+		{"date range", models.FilterGetAllPacking{StartDate: strPtr("02-01-2024"), EndDate: strPtr("02-01-2024")}, []string{"P2"}, 1},
+		{"search gmp", models.FilterGetAllPacking{Search: strPtr("PH-2")}, []string{"P3"}, 1},
+		{"search name with filter", models.FilterGetAllPacking{Search: strPtr("Beta"), PackerId: strPtr("PK1")}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllPacking(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, p := range res.Obj {
+				ids = append(ids, p.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+
+	filtered, err := s.FilterPacking(ctx, "gap", "GAP-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 2 || filtered[0].Id != "P3" {
+		t.Fatalf("unexpected filter result %+v", filtered)
+	}
+}
+
+// advancePacking moves packing id forward as staff through the transition
+// functions until it reaches status to.
+func advancePacking(t *testing.T, s *SmartContract, ctx *mocks.MockContext, id string, to models.ProcessStatus) {
+	t.Helper()
+	steps := []struct {
+		status models.ProcessStatus
+		name   string
+		fn     func(contractapi.TransactionContextInterface, string) error
+	}{
+		{models.StatusPackerSaved, "SavePacking", s.SavePacking},
+		{models.StatusApproved, "ApprovePacking", s.ApprovePacking},
+		{models.StatusCompleted, "CompletePacking", s.CompletePacking},
+	}
+	packing, err := s.ReadPacking(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range steps {
+		if step.status <= packing.ProcessStatus || step.status > to {
+			continue
+		}
+		step := step
+		mustSubmit(t, ctx, step.name, func() error {
+			return step.fn(ctx, `{"id":"`+id+`"}`)
+		})
+	}
+}
+
+func TestPackingTransitions(t *testing.T) {
+	s, ctx := newTestContract()
+	seedCertificates(t, s, ctx)
+	packerCtx := ctx.As(packerUser)
+	farmerCtx := ctx.As(farmerUser)
+
+	mustSubmit(t, farmerCtx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(farmerCtx, `{"id":"F1"}`)
+		return err
+	})
+	if err := submit(packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P0","processStatus":2}`)
+	}); err == nil {
+		t.Fatal("expected packing created as approved to be rejected")
+	}
+	for _, id := range []string{"P1", "P2", "P3"} {
+		id := id
+		mustSubmit(t, packerCtx, "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, `{"id":"`+id+`","farmerId":"F1","gap":"GAP-1","actualWeight":10}`)
+		})
+	}
+
+	tests := []struct {
+		name     string
+		ctx      *mocks.MockContext
+		function string
+		fn       func(contractapi.TransactionContextInterface, string) error
+		args     string
+		want     models.ProcessStatus
+		denied   bool
+		fails    bool
+	}{
+		{"draft cannot skip to approved", ctx, "ApprovePacking", s.ApprovePacking, `{"id":"P1"}`, models.StatusDraft, false, true},
+		{"other packer cannot save", ctx.As(otherPacker), "SavePacking", s.SavePacking, `{"id":"P1"}`, models.StatusDraft, true, false},
+		{"owning packer saves", packerCtx, "SavePacking", s.SavePacking, `{"id":"P1"}`, models.StatusPackerSaved, false, false},
+		{"packer cannot approve", packerCtx, "ApprovePacking", s.ApprovePacking, `{"id":"P1"}`, models.StatusPackerSaved, true, false},
+		{"other farmer cannot approve", ctx.As(otherFarmer), "ApprovePacking", s.ApprovePacking, `{"id":"P1"}`, models.StatusPackerSaved, true, false},
+		{"owning farmer approves", farmerCtx, "ApprovePacking", s.ApprovePacking, `{"id":"P1","approvedType":"manual"}`, models.StatusApproved, false, false},
+		{"cancel needs a reason", packerCtx, "CancelPacking", s.CancelPacking, `{"id":"P1"}`, models.StatusApproved, false, true},
+		{"owning packer completes", packerCtx, "CompletePacking", s.CompletePacking, `{"id":"P1","finalWeight":9}`, models.StatusCompleted, false, false},
+		{"completed is final", ctx, "CancelPacking", s.CancelPacking, `{"id":"P1","cancelReason":"late"}`, models.StatusCompleted, false, true},
+		{"packer cancels draft", packerCtx, "CancelPacking", s.CancelPacking, `{"id":"P2","cancelReason":"duplicate"}`, models.StatusCancelled, false, false},
+		{"cancelled is final", ctx, "SavePacking", s.SavePacking, `{"id":"P2"}`, models.StatusCancelled, false, true},
+		{"missing packing", ctx, "SavePacking", s.SavePacking, `{"id":"P9"}`, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := submit(tt.ctx, tt.function, func() error {
+				return tt.fn(tt.ctx, tt.args)
+			})
+			switch {
+			case tt.denied && !isAccessDenied(err):
+				t.Fatalf("expected access denied, got %v", err)
+			case tt.fails && (err == nil || isAccessDenied(err)):
+				t.Fatalf("expected transition error, got %v", err)
+			case !tt.denied && !tt.fails && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var id struct{ Id string }
+			_ = json.Unmarshal([]byte(tt.args), &id)
+			if packing, err := s.ReadPacking(ctx, id.Id); err == nil && packing.ProcessStatus != tt.want {
+				t.Fatalf("status = %s, want %s", packing.ProcessStatus, tt.want)
+			}
+		})
+	}
+
+	packing, _ := s.ReadPacking(ctx, "P1")
+	if len(packing.StatusHistory) != 3 || packing.SellingStep != 3 {
+		t.Fatalf("unexpected status history %+v", packing.StatusHistory)
+	}
+	approval := packing.StatusHistory[1]
+	if approval.From != models.StatusPackerSaved || approval.To != models.StatusApproved ||
+		approval.ChangedBy != farmerUser.ID || approval.OrgName != "FarmerMSP" || approval.ChangedAt == "" {
+		t.Fatalf("unexpected approval record %+v", approval)
+	}
+	if packing.ApprovedType != "manual" || packing.ApprovedDate != approval.ChangedAt || packing.FinalWeight != 9 {
+		t.Fatalf("transition fields not applied: %+v", packing)
+	}
+	assertChanges(t, lastStateChanges(t, ctx).Changes, models.StateChange{Type: models.ChangeUpdate, DocType: models.Packing, Id: "P2"})
+	packing, _ = s.ReadPacking(ctx, "P2")
+	if packing.CancelReason != "duplicate" || packing.StatusHistory[0].Reason != "duplicate" {
+		t.Fatalf("cancel not recorded: %+v", packing)
+	}
+
+	// UpdatePacking cannot be used to sidestep the transition table.
+	if err := submit(packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, `{"id":"P3","gap":"GAP-1","actualWeight":10,"processStatus":3}`)
+	}); err == nil {
+		t.Fatal("expected update from draft to completed to be rejected")
+	}
+	// P1 counts with its final weight.
+	if total, _ := s.CalculateTotalSold(ctx, "GAP-1"); total != 9 {
+		t.Fatalf("total sold = %d, want 9", total)
+	}
+}
+
+func TestPackingCertificateValidation(t *testing.T) {
+	s, ctx := newTestContract()
+	packerCtx := ctx.As(packerUser)
+
+	// Transactions in the mock are timestamped from 2024-01-01.
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[
+			{"id":"G1","certId":"GAP-OK","issueDate":"2023-01-01T00:00:00Z","expireDate":"2025-01-01T00:00:00Z"},
+			{"id":"G2","certId":"GAP-OLD","expireDate":"2023-12-31T00:00:00Z"},
+			{"id":"G3","certId":"GAP-TODAY","expireDate":"01-01-2024"}
+		]`)
+	})
+	// Single creates reject unparseable dates like batch imports do.
+	if err := submit(ctx, "CreateGAP", func() error {
+		return s.CreateGAP(ctx, `{"id":"G4","certId":"GAP-BAD","expireDate":"someday"}`)
+	}); errorCode(err) != models.ErrValidation {
+		t.Fatalf("unparseable expiry: expected a validation error, got %v", err)
+	}
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[
+			{"id":"M1","packingHouseRegisterNumber":"PH-OK","expireDate":"2025-01-01"},
+			{"id":"M2","packingHouseRegisterNumber":"PH-NEW","issueDate":"2024-06-01"}
+		]`)
+	})
+
+	tests := []struct {
+		name string
+		args string
+		ok   bool
+	}{
+		{"valid certificates", `{"id":"P1","gap":"GAP-OK","gmp":"PH-OK"}`, true},
+		{"no certificates yet", `{"id":"P2"}`, true},
+		{"expiring today", `{"id":"P3","gap":"GAP-TODAY"}`, true},
+		{"unknown gap", `{"id":"P4","gap":"GAP-404","gmp":"PH-OK"}`, false},
+		{"expired gap", `{"id":"P4","gap":"GAP-OLD"}`, false},
+		{"gap rejected for its expiry", `{"id":"P4","gap":"GAP-BAD"}`, false},
+		{"unknown gmp", `{"id":"P4","gap":"GAP-OK","gmp":"PH-404"}`, false},
+		{"gmp not yet issued", `{"id":"P4","gmp":"PH-NEW"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := submit(packerCtx, "CreatePacking", func() error {
+				return s.CreatePacking(packerCtx, tt.args)
+			})
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected certificate to be rejected")
+			}
+		})
+	}
+
+	if err := submit(packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, `{"id":"P1","gap":"GAP-OLD","gmp":"PH-OK"}`)
+	}); err == nil {
+		t.Fatal("expected update to an expired gap to be rejected")
+	}
+
+	exporterCtx := ctx.As(exporterUser)
+	if err := submit(exporterCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(exporterCtx, `[{"id":"B1","gap":"GAP-OK","gmp":"PH-OK"},{"id":"B2","gap":"GAP-OLD"}]`)
+	}); err == nil {
+		t.Fatal("expected packaging with an expired gap to be rejected")
+	}
+	if _, err := s.ReadPackaging(ctx, "B1"); err == nil {
+		t.Fatal("rejected batch should not store any packaging")
+	}
+}
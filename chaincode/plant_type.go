@@ -9,125 +9,70 @@ import (
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
-func (s *SmartContract) CreatePlantTypeCsv(
-	ctx contractapi.TransactionContextInterface,
-	args string,
-) error {
-	var inputs []models.PlantTypeModel 
-	var eventPayloads []models.PlantTypeModel
-
-	// Unmarshal input arguments
-	errInputPlantType := json.Unmarshal([]byte(args), &inputs)
-	if errInputPlantType != nil {
-		return fmt.Errorf("failed to unmarshal input arguments: %v", errInputPlantType)
-	}
-
-	// Process each input
-	for _, input := range inputs {
-		// Get client's MSP ID
-		orgNameG, err := ctx.GetClientIdentity().GetMSPID()
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's MSP ID: %v", err)
+var plantTypeImport = utils.BatchImport[models.PlantTypeModel]{
+	Function:   "CreatePlantTypeCsv",
+	Repository: plantTypeRepository,
+	Validate: func(ctx contractapi.TransactionContextInterface, input *models.PlantTypeModel) error {
+		if err := utils.CertificateDateField("issueDate", input.IssueDate); err != nil {
+			return err
 		}
-
-		// Check if the asset already exists
-		existPlantType, err := utils.AssetExists(ctx, input.Id)
-		if err != nil {
-			return fmt.Errorf("error checking if asset exists: %v", err)
-		}
-		if existPlantType {
-			return fmt.Errorf("the asset %s already exists", input.Id)
-		}
-
-		// Get client's identity
-		clientIDG, err := utils.GetIdentity(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to get submitting client's identity: %v", err)
-		}
-
-		// Create the asset
-		assetG := models.PlantTypeModel{
+		return utils.CertificateDateField("expiredDate", input.ExpiredDate)
+	},
+	Build: func(input *models.PlantTypeModel, meta utils.BatchMeta) *models.PlantTypeModel {
+		return &models.PlantTypeModel{
 			Id:          input.Id,
-			Name:   	 input.Name,
-			Address:   	 input.Address,
-			Province:   	 input.Province,
-			District:   	 input.District,
-			PostCode:   	 input.PostCode,
-			Email:   	 input.Email,
-			IssueDate:   	 input.IssueDate,
-			ExpiredDate:   	 input.ExpiredDate,
+			Name:        input.Name,
+			Address:     input.Address,
+			Province:    input.Province,
+			District:    input.District,
+			PostCode:    input.PostCode,
+			Email:       input.Email,
+			IssueDate:   input.IssueDate,
+			ExpiredDate: input.ExpiredDate,
 			PlantType:   input.PlantType,
 			ExporterId:  input.ExporterId,
-			Owner:       clientIDG,
-			OrgName:     orgNameG,
-			CreatedAt:   input.CreatedAt,
-			DocType: 	 models.PlantType,
-			UpdatedAt:   input.UpdatedAt,
-		}
-
-		// Marshal the asset to JSON
-		assetJSON, err := json.Marshal(assetG)
-		if err != nil {
-			return fmt.Errorf("failed to marshal asset JSON: %v", err)
-		}
-
-		// Put the asset state
-		err = ctx.GetStub().PutState(input.Id, assetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to put state for asset %s: %v", input.Id, err)
+			Owner:       meta.Owner,
+			OrgName:     meta.OrgName,
+			CreatedAt:   meta.Timestamp,
+			DocType:     models.PlantType,
+			UpdatedAt:   meta.Timestamp,
 		}
+	},
+	Seal: func(ctx contractapi.TransactionContextInterface, plantType *models.PlantTypeModel) error {
+		return utils.SealContact(ctx, plantType.Id, plantType)
+	},
+}
 
-		// Add the asset to the event payloads
-		eventPayloads = append(eventPayloads, assetG)
-		fmt.Printf("Asset %s created successfully\n", input.Id)
-	}
+func (s *SmartContract) CreatePlantTypeCsv(
+	ctx contractapi.TransactionContextInterface,
+	args string,
+) error {
+	_, err := plantTypeImport.Run(ctx, args, false)
+	return err
+}
 
-	return nil
+var plantTypeRepository = utils.Repository[models.PlantTypeModel]{
+	DocType:  models.PlantType,
+	Id:       func(asset *models.PlantTypeModel) string { return asset.Id },
+	Embedded: []utils.Embedding[models.PlantTypeModel]{exporterPlantTypeCopy},
 }
 
 func (s *SmartContract) ReadPlanType(ctx contractapi.TransactionContextInterface, id string) (*models.PlantTypeModel, error) {
-
-	assetJSON, err := ctx.GetStub().GetState(id)
+	asset, err := plantTypeRepository.Get(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, err
 	}
 
-	var asset models.PlantTypeModel
-	err = json.Unmarshal(assetJSON, &asset)
+	asset.IsCanDelete, err = utils.CanDelete(ctx, utils.PlantTypeDependencies(asset.ExporterId))
 	if err != nil {
 		return nil, err
 	}
 
-	asset.IsCanDelete = true
-
-	if (asset.ExporterId != "") {
-		queryStr := fmt.Sprintf(`{
-			"selector": {
-				"docType": "formE",
-				"createdById": "%s"
-			},
-			"use_index": [
-				"_design/index-CreatedAt",
-				"index-CreatedAt"
-			]
-		}`, asset.ExporterId)
-
-		salesResultsIterator, err := ctx.GetStub().GetQueryResult(queryStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query related sales: %v", err)
-		}
-		defer salesResultsIterator.Close()
-
-		// If there are any related sales, set isCanDelete to false
-		if salesResultsIterator.HasNext() {
-			asset.IsCanDelete = false
-		}
+	if err := utils.UnsealContact(ctx, asset.Id, asset); err != nil {
+		return nil, err
 	}
 
-	return &asset, nil
+	return asset, nil
 }
 
 func (s *SmartContract) UpdatePlantType(ctx contractapi.TransactionContextInterface,
@@ -135,247 +80,101 @@ func (s *SmartContract) UpdatePlantType(ctx contractapi.TransactionContextInterf
 
 	entityPlanType := models.PlantTypeModel{}
 	inputInterface, err := utils.Unmarshal(args, entityPlanType)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 	input := inputInterface.(*models.PlantTypeModel)
 
 	asset, err := s.ReadPlanType(ctx, input.Id)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	asset.UpdatedAt = input.UpdatedAt
+	// The contact details stay as they are in the collection
 	asset.ExporterId = input.ExporterId
+	utils.ClearContact(asset)
 
-	assetJSON, errE := json.Marshal(asset)
-	utils.HandleError(errE)
-
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return plantTypeRepository.Update(ctx, asset)
 }
 
 func (s *SmartContract) QueryPlanTypeWithPagination(ctx contractapi.TransactionContextInterface, filterParams string) (*models.PlantTypeResponse, error) {
 	var filters models.PlanTypeFilterParams
-    const offset = 7 // UTC+7
 
-	err := json.Unmarshal([]byte(filterParams), &filters)
+	err := utils.DecodeInput(filterParams, &filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal filter parameters: %v", err)
+		return nil, err
 	}
 
-	selector := map[string]interface{}{
-		"docType": "plantType",
-	}
+	selector := utils.NewSelector(models.PlantType)
 
 	if filters.AvailablePlanType != "" {
-		selector["exporterId"] = ""
+		selector.Eq("exporterId", "")
 	}
 
 	if filters.PlantType != "" {
-		selector["plantType"] = filters.PlantType
+		selector.Eq("plantType", filters.PlantType)
 	}
 
 	if filters.Province != nil {
-        selector["province"] = map[string]interface{}{
-            "$regex": filters.Province,
-        }
-    }
-
-    if filters.District != nil {
-        selector["district"] = map[string]interface{}{
-            "$regex": filters.District,
-        }
-    }
-
-	if filters.Search != nil {
-        selector["plantType"] = map[string]interface{}{
-            "$regex": filters.Search,
-        }
-    }
-
-	if filters.CreatedAtFrom != nil && filters.CreatedAtTo != nil {
-		fromDate, err1 := utils.FormatDate(*filters.CreatedAtFrom, false, offset)
-		toDate, err2 := utils.FormatDate(*filters.CreatedAtTo, true, offset)
-
-		if err1 == nil && err2 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1, err2)
-		}
-	} else if (filters.CreatedAtFrom != nil) {
-		fromDate, err1 := utils.FormatDate(*filters.CreatedAtFrom, false, offset)
-
-		if err1 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1)
-		}
-	} else if (filters.CreatedAtTo != nil) {
-		toDate, err2 := utils.FormatDate(*filters.CreatedAtTo, true, offset)
-
-		if err2 == nil {
-			selector["createdAt"] = map[string]interface{}{
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err2)
-		}
+		selector.Contains("province", *filters.Province)
 	}
 
-	if filters.ExpireDateFrom != nil && filters.ExpireDateTo != nil {
-		fromDate, err1 := utils.FormatDate(*filters.ExpireDateFrom, false, offset)
-		toDate, err2 := utils.FormatDate(*filters.ExpireDateTo, true, offset)
-
-		if err1 == nil && err2 == nil {
-			selector["expiredDate"] = map[string]interface{}{
-				"$gte": fromDate,
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1, err2)
-		}
-	} else if (filters.ExpireDateFrom != nil) {
-		fromDate, err1 := utils.FormatDate(*filters.ExpireDateFrom, false, offset)
-
-		if err1 == nil {
-			selector["expiredDate"] = map[string]interface{}{
-				"$gte": fromDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1)
-		}
-	} else if (filters.ExpireDateTo != nil) {
-		toDate, err2 := utils.FormatDate(*filters.ExpireDateTo, true, offset)
-
-		if err2 == nil {
-			selector["expiredDate"] = map[string]interface{}{
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err2)
+	if filters.District != nil {
+		ids, err := utils.PrivateIds(ctx, utils.ExporterCollection, utils.NewSelector("").Contains("district", *filters.District))
+		if err != nil {
+			return nil, err
 		}
+		selector.In("id", ids)
 	}
 
-	// Create query string for counting total records
-	countQueryString, err := json.Marshal(map[string]interface{}{
-		"selector": selector,
-		"use_index": []string{
-            "_design/index-CreatedAt",
-            "index-CreatedAt",
-        },
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal count query string: %v", err)
+	if filters.Search != nil {
+		selector.Contains("plantType", *filters.Search)
 	}
 
-	// Execute the count query
-	total, err := utils.CountTotalResults(ctx, string(countQueryString))
+	selector.DateRange("createdAt", filters.CreatedAtFrom, filters.CreatedAtTo)
+	selector.DateRange("expiredDate", filters.ExpireDateFrom, filters.ExpireDateTo)
+
+	page, err := utils.Paginate[models.PlantTypeModel](ctx, selector.Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAt", "index-CreatedAt"), filters.Pagination)
 	if err != nil {
 		return nil, err
 	}
+	assets := page.Items
 
-	// If no records are found, return an empty response
-	if total == 0 {
-		return &models.PlantTypeResponse{
-			Data:  []*models.PlantTypeModel{},
-			Total: total,
-		}, nil
-	}
-
-
-
-	query := map[string]interface{}{
-		"selector": selector,
-		"sort": []map[string]string{
-			{"createdAt": "desc"},
-		},
-        "use_index": []string{
-            "_design/index-CreatedAt",
-            "index-CreatedAt",
-        },
-	}
-
-	if filters.Skip > 0 {
-		query["skip"] = filters.Skip
-	}
-	if filters.Limit > 0 {
-		query["limit"] = filters.Limit
-	}
-
-	// Create query string for paginated results
-	queryString, err := json.Marshal(query)
-
-	fmt.Printf("PlantType query %v", queryString)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query string: %v", err)
+	exporterIds := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		if asset.ExporterId != "" {
+			exporterIds = append(exporterIds, asset.ExporterId)
+		}
 	}
-
-	// Execute the paginated query
-	resultsIterator, _, err := ctx.GetStub().GetQueryResultWithPagination(string(queryString), int32(filters.Limit), "")
+	// A plant type whose exporter has related Form Es cannot be deleted
+	sold, err := utils.ReferencedValues(ctx, models.FormE, "createdById", exporterIds)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get query result with pagination: %v", err)
+		return nil, fmt.Errorf("failed to query related sales: %w", err)
 	}
-	defer resultsIterator.Close()
-
-	var assets []*models.PlantTypeModel
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next query result: %v", err)
-		}
-
-		var asset models.PlantTypeModel
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal query result: %v", err)
-		}
-		assets = append(assets, &asset)
-	}
-
 	for _, asset := range assets {
-		asset.IsCanDelete = true
-
-		if (asset.ExporterId != "") {
-			queryStr := fmt.Sprintf(`{
-				"selector": {
-					"docType": "formE",
-					"createdById": "%s"
-				},
-				"use_index": [
-					"_design/index-CreatedAt",
-					"index-CreatedAt"
-				]
-			}`, asset.ExporterId)
-	
-			salesResultsIterator, err := ctx.GetStub().GetQueryResult(queryStr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to query related sales: %v", err)
-			}
-			defer salesResultsIterator.Close()
-	
-			// If there are any related sales, set isCanDelete to false
-			if salesResultsIterator.HasNext() {
-				asset.IsCanDelete = false
-			}
-		}
+		asset.IsCanDelete = asset.ExporterId == "" || !sold[asset.ExporterId]
 	}
 
 	return &models.PlantTypeResponse{
-		Data:  assets,
-		Total: total,
+		Data:     assets,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
 	}, nil
 }
 
 func (s *SmartContract) GetPlantTypeByPlantType(ctx contractapi.TransactionContextInterface, plantType string) (*models.PlantTypeModel, error) {
 	// Get the asset using CertID
-	queryKeyPlantType := fmt.Sprintf(`{"selector":{"plantType":"%s", "docType":"plantType"}}`, plantType)
+	queryKeyPlantType, err := utils.NewSelector(models.PlantType).Eq("plantType", plantType).Query().Build()
+	if err != nil {
+		return nil, err
+	}
 
 	resultsIteratorPlantType, err := ctx.GetStub().GetQueryResult(queryKeyPlantType)
 	var asset *models.PlantTypeModel
 	if err != nil {
-		return nil, fmt.Errorf("error querying chaincode: %v", err)
+		return nil, fmt.Errorf("error querying chaincode: %w", err)
 	}
 	defer resultsIteratorPlantType.Close()
 
@@ -385,22 +184,32 @@ func (s *SmartContract) GetPlantTypeByPlantType(ctx contractapi.TransactionConte
 
 	queryResponse, err := resultsIteratorPlantType.Next()
 	if err != nil {
-		return nil, fmt.Errorf("error getting next query result: %v", err)
+		return nil, fmt.Errorf("error getting next query result: %w", err)
 	}
 
 	err = json.Unmarshal(queryResponse.Value, &asset)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling asset JSON: %v", err)
+		return nil, fmt.Errorf("error unmarshalling asset JSON: %w", err)
 	}
 
 	return asset, nil
 }
 
-func (s *SmartContract) DeletePlantType(ctx contractapi.TransactionContextInterface, id string) error {
+func (s *SmartContract) DeletePlantType(ctx contractapi.TransactionContextInterface, id string, reason string) error {
 	plantType, err := s.ReadPlanType(ctx, id)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
+	if err := utils.AssertDeletable(ctx, plantType.Id, utils.PlantTypeDependencies(plantType.ExporterId), false); err != nil {
+		return err
+	}
+
+	// The contact details are kept so a restored plant type gets them back
+	return plantTypeRepository.Delete(ctx, plantType.Id, reason)
+}
 
-	return ctx.GetStub().DelState(plantType.Id)
+func (s *SmartContract) RestorePlantType(ctx contractapi.TransactionContextInterface, id string) error {
+	return plantTypeRepository.Restore(ctx, id)
 }
 
 func (s *SmartContract) UpdateMultiplePlantType(
@@ -409,89 +218,57 @@ func (s *SmartContract) UpdateMultiplePlantType(
 ) error {
 	var inputs []models.PlantTypeModel
 
-	errInputGap := json.Unmarshal([]byte(args), &inputs)
-	utils.HandleError(errInputGap)
-	
-	for _, input := range inputs {
-		assetJSON, err := ctx.GetStub().GetState(input.Id)
-		if err != nil {
-			return fmt.Errorf("failed to read from world state: %v", err)
-		}
-		if assetJSON == nil {
-			return fmt.Errorf("asset with ID %s does not exist", input.Id)
-		}
-		
-		var existingAsset models.PlantTypeModel
-		err = json.Unmarshal(assetJSON, &existingAsset)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal existing asset: %v", err)
-		}
+	if err := utils.DecodeInput(args, &inputs); err != nil {
+		return err
+	}
 
-		existingAsset.Name =   input.Name
-		existingAsset.Address =   input.Address
-		existingAsset.Province =   input.Province
-		existingAsset.District =    input.District
-		existingAsset.PostCode =    input.PostCode
-		existingAsset.Email =    input.Email
-		existingAsset.IssueDate =   input.IssueDate
-		existingAsset.ExpiredDate =   input.ExpiredDate
-		existingAsset.PlantType =   input.PlantType
-		existingAsset.Province =    input.Province
-		existingAsset.UpdatedAt = 	input.UpdatedAt
-		
-		updatedAssetJSON, err := json.Marshal(existingAsset)
+	assets := make([]*models.PlantTypeModel, 0, len(inputs))
+	for i := range inputs {
+		input := &inputs[i]
+		existingAsset, err := plantTypeRepository.Get(ctx, input.Id)
 		if err != nil {
-			return fmt.Errorf("failed to marshal updated asset: %v", err)
+			return err
 		}
-		
-		err = ctx.GetStub().PutState(input.Id, updatedAssetJSON)
-		if err != nil {
-			return fmt.Errorf("failed to update asset in world state: %v", err)
+
+		existingAsset.Name = input.Name
+		existingAsset.Address = input.Address
+		existingAsset.Province = input.Province
+		existingAsset.District = input.District
+		existingAsset.PostCode = input.PostCode
+		existingAsset.Email = input.Email
+		existingAsset.IssueDate = input.IssueDate
+		existingAsset.ExpiredDate = input.ExpiredDate
+		existingAsset.PlantType = input.PlantType
+		if err := utils.SealContact(ctx, existingAsset.Id, existingAsset); err != nil {
+			return err
 		}
-		
-		fmt.Printf("PlantType Asset %s updated successfully\n", input.Id)
+		assets = append(assets, existingAsset)
 	}
-	
-	return nil
+
+	return plantTypeRepository.UpdateAll(ctx, assets)
 }
 
 func (s *SmartContract) GetPlantTypeList(ctx contractapi.TransactionContextInterface, args string) ([]models.PlantTypeModel, error) {
 	var inputs []string
 
-	errInputPlantType := json.Unmarshal([]byte(args), &inputs)
+	errInputPlantType := utils.DecodeInput(args, &inputs)
 	if errInputPlantType != nil {
-		return nil, fmt.Errorf("failed to unmarshal input arguments: %v", errInputPlantType)
+		return nil, errInputPlantType
 	}
 
 	var plantTypes []models.PlantTypeModel
 
-	for _, plantType := range inputs {
-		queryString := fmt.Sprintf(`{
-			"selector": {
-				"docType": "plantType",
-				"plantType": "%s"
-			}
-		}`, plantType)
-	
-		resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if len(inputs) > 0 {
+		matches, err := utils.FetchAll[models.PlantTypeModel](ctx, utils.NewSelector(models.PlantType).In("plantType", inputs).Query())
 		if err != nil {
-			return nil, fmt.Errorf("failed to execute rich query: %v", err)
+			return nil, fmt.Errorf("failed to execute rich query: %w", err)
 		}
-		defer resultsIterator.Close()
-	
-		for resultsIterator.HasNext() {
-			queryResponse, err := resultsIterator.Next()
-			if err != nil {
-				return nil, fmt.Errorf("failed to iterate results: %v", err)
-			}
-	
-			var plantTypeModel models.PlantTypeModel
-			err = json.Unmarshal(queryResponse.Value, &plantTypeModel)
-			if err != nil {
-				return nil, fmt.Errorf("failed to unmarshal query response: %v", err)
-			}
-	
-			plantTypes = append(plantTypes, plantTypeModel)
+		byPlantType := map[string][]models.PlantTypeModel{}
+		for _, plantTypeModel := range matches {
+			byPlantType[plantTypeModel.PlantType] = append(byPlantType[plantTypeModel.PlantType], *plantTypeModel)
+		}
+		for _, plantType := range inputs {
+			plantTypes = append(plantTypes, byPlantType[plantType]...)
 		}
 	}
 
@@ -0,0 +1,125 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func seedPlantTypes(t *testing.T, s *SmartContract, ctx *mocks.MockContext) {
+	t.Helper()
+	seedDaily(t, ctx, "CreatePlantTypeCsv", func(args string) error {
+		return s.CreatePlantTypeCsv(ctx, args)
+	},
+		`[{"id":"T1","plantType":"PT-001","name":"Durian Plant","province":"Chanthaburi","exporterId":"E1","expiredDate":"2025-01-01T00:00:00Z"}]`,
+		`[{"id":"T2","plantType":"PT-002","name":"Mango Plant","province":"Rayong","exporterId":"","expiredDate":"2026-01-01T00:00:00Z"}]`,
+		`[{"id":"T3","plantType":"PT-103","name":"Longan Plant","province":"Chanthaburi","exporterId":"","expiredDate":"2027-01-01T00:00:00Z"}]`,
+	)
+}
+
+func TestPlantTypeLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+	seedPlantTypes(t, s, ctx)
+
+	if err := submit(ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[{"id":"T1"}]`)
+	}); err == nil {
+		t.Fatal("expected duplicate plant type to be rejected")
+	}
+
+	plantType, err := s.ReadPlanType(ctx, "T1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plantType.DocType != models.PlantType || !plantType.IsCanDelete {
+		t.Fatalf("unexpected plant type %+v", plantType)
+	}
+
+	exporterCtx := ctx.As(exporterUser)
+	seedOwnedExporters(t, s, ctx, "E1")
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"office":"BKK","createdById":"E1"}`)
+		return err
+	})
+	plantType, _ = s.ReadPlanType(ctx, "T1")
+	if plantType.IsCanDelete {
+		t.Fatal("plant type whose exporter has form E should not be deletable")
+	}
+
+	mustSubmit(t, ctx, "UpdatePlantType", func() error {
+		return s.UpdatePlantType(ctx, `{"id":"T2","exporterId":"E2"}`)
+	})
+	plantType, _ = s.ReadPlanType(ctx, "T2")
+	if plantType.ExporterId != "E2" {
+		t.Fatalf("update not applied: %+v", plantType)
+	}
+
+	mustSubmit(t, ctx, "UpdateMultiplePlantType", func() error {
+		return s.UpdateMultiplePlantType(ctx, `[{"id":"T3","plantType":"PT-103","name":"Longan Co","province":"Nan"}]`)
+	})
+	plantType, _ = s.ReadPlanType(ctx, "T3")
+	if plantType.Name != "Longan Co" || plantType.Province != "Nan" {
+		t.Fatalf("multiple update not applied: %+v", plantType)
+	}
+
+	byType, err := s.GetPlantTypeByPlantType(ctx, "PT-002")
+	if err != nil || byType.Id != "T2" {
+		t.Fatalf("unexpected plant type %+v, %v", byType, err)
+	}
+
+	list, err := s.GetPlantTypeList(ctx, `["PT-001","PT-103","PT-999"]`)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("unexpected plant type list %+v, %v", list, err)
+	}
+
+	if err := submit(exporterCtx, "DeletePlantType", func() error {
+		return s.DeletePlantType(exporterCtx, "T3", "duplicate record")
+	}); !isAccessDenied(err) {
+		t.Fatalf("exporter delete: expected access denied, got %v", err)
+	}
+	mustSubmit(t, ctx, "DeletePlantType", func() error {
+		return s.DeletePlantType(ctx, "T3", "duplicate record")
+	})
+	if _, err := s.ReadPlanType(ctx, "T3"); err == nil {
+		t.Fatal("expected plant type to be deleted")
+	}
+}
+
+func TestQueryPlanTypeWithPagination(t *testing.T) {
+	s, ctx := newTestContract()
+	seedPlantTypes(t, s, ctx)
+
+	tests := []struct {
+		name    string
+		filter  models.PlanTypeFilterParams
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.PlanTypeFilterParams{}, []string{"T3", "T2", "T1"}, 3},
+		{"skip and limit", models.PlanTypeFilterParams{Pagination: models.Pagination{Skip: 2, Limit: 2}}, []string{"T1"}, 3},
+		{"available", models.PlanTypeFilterParams{AvailablePlanType: "true"}, []string{"T3", "T2"}, 2},
+		{"plant type", models.PlanTypeFilterParams{PlantType: "PT-002"}, []string{"T2"}, 1},
+		{"search", models.PlanTypeFilterParams{Search: strPtr("PT-00")}, []string{"T2", "T1"}, 2},
+		{"province", models.PlanTypeFilterParams{Province: strPtr("Chanthaburi")}, []string{"T3", "T1"}, 2},
+		{"expire range", models.PlanTypeFilterParams{ExpireDateFrom: strPtr("01-06-2025"), ExpireDateTo: strPtr("01-06-2026")}, []string{"T2"}, 1},
+		{"no match", models.PlanTypeFilterParams{PlantType: "none"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.QueryPlanTypeWithPagination(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, p := range res.Data {
+				ids = append(ids, p.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
@@ -9,6 +9,11 @@ import (
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
 )
 
+var regulatorRepository = utils.Repository[models.TransactionRegulator]{
+	DocType: models.Regulator,
+	Id:      func(asset *models.TransactionRegulator) string { return asset.Id },
+}
+
 func (s SmartContract) CreateRegulatorProfile(
 	ctx contractapi.TransactionContextInterface,
 	args string,
@@ -19,37 +24,16 @@ func (s SmartContract) CreateRegulatorProfile(
 	if err != nil {
 		return err
 	}
-
 	input := inputInterface.(*models.TransactionRegulator)
-	orgName, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return utils.ReturnError(utils.UNAUTHORIZE)
-	}
-
-	existRegulator, err := utils.AssetExists(ctx, input.Id)
-	utils.HandleError(err)
-	if existRegulator {
-		return fmt.Errorf("the asset %s already exists", input.Id)
-	}
-
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
 
 	asset := models.TransactionRegulator{
-		Id:        input.Id,
-		CertId:    input.CertId,
-		UserId:    input.UserId,
-		Owner:     clientID,
-		OrgName:   orgName,
-		DocType:   models.Regulator,
+		Id:         input.Id,
+		CertId:     input.CertId,
+		UserId:     input.UserId,
 		ProfileImg: input.ProfileImg,
-		UpdatedAt: input.CreatedAt,
-		CreatedAt: input.UpdatedAt,
 	}
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
 
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return regulatorRepository.Create(ctx, &asset)
 }
 
 func (s SmartContract) UpdateRegulatorProfile(ctx contractapi.TransactionContextInterface,
@@ -57,182 +41,97 @@ func (s SmartContract) UpdateRegulatorProfile(ctx contractapi.TransactionContext
 
 	entityRegulator := models.TransactionRegulator{}
 	inputInterface, err := utils.Unmarshal(args, entityRegulator)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 	input := inputInterface.(*models.TransactionRegulator)
 
 	asset, err := s.ReadRegulatorProfile(ctx, input.Id)
-	utils.HandleError(err)
-
-	clientID, err := utils.GetIdentity(ctx)
-	utils.HandleError(err)
+	if err != nil {
+		return err
+	}
 
-	if clientID != asset.Owner {
-		return utils.ReturnError(utils.UNAUTHORIZE)
+	if err := utils.AssertStaffOrOwner(ctx, asset.Owner); err != nil {
+		return err
 	}
 
-	asset.Id = input.Id
 	asset.CertId = input.CertId
-	asset.UpdatedAt = input.UpdatedAt
 	asset.ProfileImg = input.ProfileImg
 	asset.UserId = input.UserId
 
-	assetJSON, err := json.Marshal(asset)
-	utils.HandleError(err)
-
-	return ctx.GetStub().PutState(input.Id, assetJSON)
+	return regulatorRepository.Update(ctx, asset)
 }
 
 func (s *SmartContract) ReadRegulatorProfile(ctx contractapi.TransactionContextInterface, id string) (*models.TransactionRegulator, error) {
-
-	assetJSON, err := ctx.GetStub().GetState(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
-	}
-
-	var asset models.TransactionRegulator
-	err = json.Unmarshal(assetJSON, &asset)
-	if err != nil {
-		return nil, err
-	}
-
-	return &asset, nil
+	return regulatorRepository.Get(ctx, id)
 }
 
 func (s *SmartContract) QueryRegulatorWithPagination(ctx contractapi.TransactionContextInterface, filterParams string) (*models.RegulatorGetAllResponse, error) {
 	var filters models.FilterGetAllRegulator
-	err := json.Unmarshal([]byte(filterParams), &filters)
+	err := utils.DecodeInput(filterParams, &filters)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal filter parameters: %v", err)
+		return nil, err
 	}
 
-	selector := map[string]interface{}{
-		"docType": "regulator",
-	}
+	selector := utils.NewSelector(models.Regulator)
 
 	if filters.UserId != "" {
-		selector["userId"] = filters.UserId
+		selector.Eq("userId", filters.UserId)
 	}
 
-	// Build the count query
-	countQuery := map[string]interface{}{
-		"selector": selector,
-		"use_index": []string{
-			"_design/index-CreatedAtId",
-			"index-CreatedAtId",
-		},
-	}
-
-	countQueryString, err := json.Marshal(countQuery)
+	page, err := utils.Paginate[models.TransactionRegulator](ctx, selector.Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAtId", "index-CreatedAtId"), filters.Pagination)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal count query string: %v", err)
+		return nil, err
 	}
+	assets := page.Items
 
-	fmt.Printf("Count Query String: %s\n", countQueryString) // Debugging
+	return &models.RegulatorGetAllResponse{
+		Obj:      assets,
+		Total:    page.Total,
+		PageInfo: page.PageInfo,
+	}, nil
+}
 
-	// Execute the count query
-	countResultsIterator, err := ctx.GetStub().GetQueryResult(string(countQueryString))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get count query result: %v", err)
-	}
-	defer countResultsIterator.Close()
-
-	// Count the total number of records
-	var totalCount int
-	for countResultsIterator.HasNext() {
-		_, err := countResultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next count query result: %v", err)
-		}
-		totalCount++
-	}
 
-	// If no records are found, return an empty response
-	if totalCount == 0 {
-		return &models.RegulatorGetAllResponse{
-			Obj:  []*models.TransactionRegulator{},
-			Total: totalCount,
-		}, nil
-	}
-
-	// Create the query string for paginated results
-	paginationQuery := map[string]interface{}{
-		"selector": selector,
-		"sort": []map[string]string{
-			{"createdAt": "desc"},
-		},
-		"use_index": []string{
-			"_design/index-CreatedAtId",
-			"index-CreatedAtId",
-		},
-		"limit": filters.Limit,
-		"skip":  filters.Skip,
+func (s *SmartContract) DeleteRegulator(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	assetRegulator, err := s.ReadRegulatorProfile(ctx, id)
+	if err != nil {
+		return err
 	}
 
-	queryString, err := json.Marshal(paginationQuery)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query string: %v", err)
+	if err := utils.AssertStaffOrOwner(ctx, assetRegulator.Owner); err != nil {
+		return err
 	}
 
-	fmt.Printf("Pagination Query String: %s\n", queryString) // Debugging
+	return regulatorRepository.Delete(ctx, assetRegulator.Id, reason)
+}
 
-	// Execute the paginated query
-	resultsIterator, _, err := ctx.GetStub().GetQueryResultWithPagination(string(queryString), int32(filters.Limit), "")
+func (s *SmartContract) RestoreRegulator(ctx contractapi.TransactionContextInterface, id string) error {
+	assetRegulator, err := utils.ReadAssetHeader(ctx, id, models.Regulator)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get query result with pagination: %v", err)
-	}
-	defer resultsIterator.Close()
-
-	var assets []*models.TransactionRegulator
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get next query result: %v", err)
-		}
-
-		var asset models.TransactionRegulator
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal query result: %v", err)
-		}
-		assets = append(assets, &asset)
+		return err
 	}
 
-	if assets == nil {
-		assets = []*models.TransactionRegulator{}
+	if err := utils.AssertStaffOrOwner(ctx, assetRegulator.Owner); err != nil {
+		return err
 	}
 
-	return &models.RegulatorGetAllResponse{
-		Obj:  assets,
-		Total: totalCount,
-	}, nil
-}
-
-
-func (s *SmartContract) DeleteRegulator(ctx contractapi.TransactionContextInterface, id string) error {
-	assetRegulator, err := s.ReadRegulatorProfile(ctx, id)
-	utils.HandleError(err)
-
-	// clientIDGmp, err := utils.GetIdentity(ctx)
-	// utils.HandleError(err)
-
-	// if clientIDGmp != assetRegulator.Owner {
-	// 	return utils.ReturnError(utils.UNAUTHORIZE)
-	// }
-
-	return ctx.GetStub().DelState(assetRegulator.Id)
+	return regulatorRepository.Restore(ctx, id)
 }
 
 func (s *SmartContract) GetRegulatorByUserId(ctx contractapi.TransactionContextInterface, regulatorId string) (*models.RegulatorByIdResponse, error) {
-	queryKeyFarmer := fmt.Sprintf(`{"selector":{"userId":"%s", "docType": "regulator"}}`, regulatorId)
+	queryKeyFarmer, err := utils.NewSelector(models.Regulator).Eq("userId", regulatorId).Query().Build()
+	if err != nil {
+		return nil, err
+	}
 
 	resultsIteratorFarmer, err := ctx.GetStub().GetQueryResult(queryKeyFarmer)
 	var asset *models.TransactionRegulator
 	resData := "Get regulator by regulatorId"
 	if err != nil {
-		return nil, fmt.Errorf("error querying chaincode: %v", err)
+		return nil, fmt.Errorf("error querying chaincode: %w", err)
 	}
 	defer resultsIteratorFarmer.Close()
 
@@ -247,12 +146,12 @@ func (s *SmartContract) GetRegulatorByUserId(ctx contractapi.TransactionContextI
 
 	queryResponse, err := resultsIteratorFarmer.Next()
 	if err != nil {
-		return nil, fmt.Errorf("error getting next query result: %v", err)
+		return nil, fmt.Errorf("error getting next query result: %w", err)
 	}
 
 	err = json.Unmarshal(queryResponse.Value, &asset)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling asset JSON: %v", err)
+		return nil, fmt.Errorf("error unmarshalling asset JSON: %w", err)
 	}
 
 	return &models.RegulatorByIdResponse{
@@ -0,0 +1,108 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestRegulatorLifecycle(t *testing.T) {
+	s, ctx := newTestContract()
+
+	regulatorCtx := ctx.As(regulatorUser)
+	if err := submit(regulatorCtx, "CreateRegulatorProfile", func() error {
+		return s.CreateRegulatorProfile(regulatorCtx, `{"id":"R1"}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator create: expected access denied, got %v", err)
+	}
+
+	mustSubmit(t, ctx, "CreateRegulatorProfile", func() error {
+		return s.CreateRegulatorProfile(ctx, `{"id":"R1","certId":"RC-1","userId":"U1"}`)
+	})
+	if err := submit(ctx, "CreateRegulatorProfile", func() error {
+		return s.CreateRegulatorProfile(ctx, `{"id":"R1"}`)
+	}); err == nil {
+		t.Fatal("expected duplicate regulator to be rejected")
+	}
+
+	regulator, err := s.ReadRegulatorProfile(ctx, "R1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if regulator.DocType != models.Regulator || regulator.Owner != nectecUser.ID || regulator.CertId != "RC-1" {
+		t.Fatalf("unexpected regulator %+v", regulator)
+	}
+
+	mustSubmit(t, regulatorCtx, "UpdateRegulatorProfile", func() error {
+		return s.UpdateRegulatorProfile(regulatorCtx, `{"id":"R1","certId":"RC-2","userId":"U1","profileImg":"img.png"}`)
+	})
+	regulator, _ = s.ReadRegulatorProfile(ctx, "R1")
+	if regulator.CertId != "RC-2" || regulator.ProfileImg != "img.png" {
+		t.Fatalf("update not applied: %+v", regulator)
+	}
+
+	byUser, err := s.GetRegulatorByUserId(ctx, "U1")
+	if err != nil || byUser.Obj == nil || byUser.Obj.Id != "R1" {
+		t.Fatalf("unexpected regulator by user %+v, %v", byUser, err)
+	}
+	missing, err := s.GetRegulatorByUserId(ctx, "U9")
+	if err != nil || missing.Obj != nil || missing.Data != "Not found regulator by regulatorId" {
+		t.Fatalf("unexpected missing regulator %+v, %v", missing, err)
+	}
+
+	if err := submit(regulatorCtx, "DeleteRegulator", func() error {
+		return s.DeleteRegulator(regulatorCtx, "R1", "duplicate record")
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator delete: expected access denied, got %v", err)
+	}
+	mustSubmit(t, ctx, "DeleteRegulator", func() error {
+		return s.DeleteRegulator(ctx, "R1", "duplicate record")
+	})
+	if _, err := s.ReadRegulatorProfile(ctx, "R1"); err == nil {
+		t.Fatal("expected regulator to be deleted")
+	}
+}
+
+func TestQueryRegulatorWithPagination(t *testing.T) {
+	s, ctx := newTestContract()
+
+	for _, args := range []string{
+		`{"id":"R1","userId":"U1"}`,
+		`{"id":"R2","userId":"U2"}`,
+		`{"id":"R3","userId":"U2"}`,
+	} {
+		args := args
+		mustSubmit(t, ctx, "CreateRegulatorProfile", func() error {
+			return s.CreateRegulatorProfile(ctx, args)
+		})
+	}
+
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllRegulator
+		wantIds []string
+		total   int
+	}{
+		{"all, newest first", models.FilterGetAllRegulator{}, []string{"R3", "R2", "R1"}, 3},
+		{"skip and limit", models.FilterGetAllRegulator{Pagination: models.Pagination{Skip: 1, Limit: 1}}, []string{"R2"}, 3},
+		{"user", models.FilterGetAllRegulator{UserId: "U2"}, []string{"R3", "R2"}, 2},
+		{"no match", models.FilterGetAllRegulator{UserId: "U9"}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.QueryRegulatorWithPagination(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total {
+				t.Errorf("total = %d, want %d", res.Total, tt.total)
+			}
+			var ids []string
+			for _, r := range res.Obj {
+				ids = append(ids, r.Id)
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// SetSequenceConfig sets the formats ids are allocated with. Sequences it
+// leaves out keep their default format.
+func (s *SmartContract) SetSequenceConfig(ctx contractapi.TransactionContextInterface, args string) error {
+	entityConfig := models.SequenceConfig{}
+	inputInterface, err := utils.Unmarshal(args, entityConfig)
+	if err != nil {
+		return err
+	}
+	input := inputInterface.(*models.SequenceConfig)
+
+	if err := utils.ValidateSequenceConfig(input); err != nil {
+		return utils.Validation("invalid sequence config: %v", err)
+	}
+
+	clientID, err := utils.GetIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := utils.GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	config := models.SequenceConfig{
+		Formats:   input.Formats,
+		UpdatedBy: clientID,
+		UpdatedAt: timestamp,
+		DocType:   models.Config,
+	}
+
+	return utils.PutConfig(ctx, utils.SequenceConfig, config)
+}
+
+func (s *SmartContract) GetSequenceConfig(ctx contractapi.TransactionContextInterface) (*models.SequenceConfig, error) {
+	return utils.GetSequenceConfig(ctx)
+}
@@ -0,0 +1,182 @@
+package chaincode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/mocks"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func allocate(t *testing.T, ctx *mocks.MockContext, function string, fn func() (string, error)) string {
+	t.Helper()
+	var id string
+	mustSubmit(t, ctx, function, func() (err error) {
+		id, err = fn()
+		return err
+	})
+	return id
+}
+
+func TestCreateAllocatesIds(t *testing.T) {
+	s, ctx := newTestContract()
+
+	// Imported ids are skipped instead of clashing with the sequence.
+	mustSubmit(t, ctx, "CreateFarmerFromCsv", func() error {
+		return s.CreateFarmerFromCsv(ctx, `[{"id":"F000002"}]`)
+	})
+
+	var farmerIds []string
+	for i := 0; i < 3; i++ {
+		farmerIds = append(farmerIds, allocate(t, ctx, "CreateFarmerProfile", func() (string, error) {
+			return s.CreateFarmerProfile(ctx, `{"certId":"C1"}`)
+		}))
+	}
+	assertIDs(t, farmerIds, "F000001", "F000003", "F000004")
+
+	if farmer, err := s.ReadFarmerProfile(ctx, "F000003"); err != nil || farmer.CertId != "C1" {
+		t.Fatalf("unexpected farmer %+v, %v", farmer, err)
+	}
+
+	// Explicit ids are kept and do not advance the sequence.
+	if id := allocate(t, ctx, "CreatePacker", func() (string, error) {
+		return s.CreatePacker(ctx, `{"id":"PK-OWN"}`)
+	}); id != "PK-OWN" {
+		t.Fatalf("packer id = %q, want PK-OWN", id)
+	}
+	if id := allocate(t, ctx, "CreatePacker", func() (string, error) {
+		return s.CreatePacker(ctx, `{"certId":"C-1"}`)
+	}); id != "PK000001" {
+		t.Fatalf("packer id = %q, want PK000001", id)
+	}
+	if id := allocate(t, ctx, "CreateExporter", func() (string, error) {
+		return s.CreateExporter(ctx, `{"certId":"EC-1"}`)
+	}); id != "E000001" {
+		t.Fatalf("exporter id = %q, want E000001", id)
+	}
+}
+
+func TestFailedCreateDoesNotUseId(t *testing.T) {
+	s, ctx := newTestContract()
+
+	err := submit(ctx, "CreateFarmerProfile", func() error {
+		_, err := s.CreateFarmerProfile(ctx, `{"certId":"C1","unknown":true}`)
+		return err
+	})
+	if errorCode(err) != models.ErrValidation {
+		t.Fatalf("expected VALIDATION, got %v", err)
+	}
+	err = submit(ctx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(ctx, "", `{"office":"BKK","status":"4"}`)
+		return err
+	})
+	if errorCode(err) != models.ErrValidation {
+		t.Fatalf("expected VALIDATION, got %v", err)
+	}
+
+	// The rolled back transactions left the counters untouched, so the
+	// resubmitted creates get the first ids.
+	if id := allocate(t, ctx, "CreateFarmerProfile", func() (string, error) {
+		return s.CreateFarmerProfile(ctx, `{"certId":"C1"}`)
+	}); id != "F000001" {
+		t.Fatalf("farmer id = %q, want F000001", id)
+	}
+	seedExporterPlantTypes(t, s, ctx, "E1")
+	if id := allocate(t, ctx, "CreateFormE", func() (string, error) {
+		return s.CreateFormE(ctx, "", `{"office":"BKK","createdById":"E1"}`)
+	}); id != "FE2024000001" {
+		t.Fatalf("form E id = %q, want FE2024000001", id)
+	}
+}
+
+func TestFormEReferenceNumbers(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	seedExporterPlantTypes(t, s, ctx, "E1")
+
+	create := func(id, office string) *models.TransactionFormE {
+		t.Helper()
+		id = allocate(t, exporterCtx, "CreateFormE", func() (string, error) {
+			return s.CreateFormE(exporterCtx, id, `{"office":"`+office+`","createdById":"E1"}`)
+		})
+		formE, err := s.ReadFormE(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return formE
+	}
+
+	var refs []string
+	for _, office := range []string{"BKK", "BKK", "CNX", "BKK"} {
+		refs = append(refs, create("", office).ReferenceNo)
+	}
+	assertIDs(t, refs, "BKK-2024-00001", "BKK-2024-00002", "CNX-2024-00001", "BKK-2024-00003")
+
+	// Reference numbers given by the client are kept and skipped later on.
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE-OWN", `{"referenceNo":"CNX-2024-00002","createdById":"E1"}`)
+		return err
+	})
+	if ref := create("", "CNX").ReferenceNo; ref != "CNX-2024-00003" {
+		t.Fatalf("reference = %q, want CNX-2024-00003", ref)
+	}
+
+	// Every year starts counting again.
+	ctx.Stub.Advance(366 * 24 * time.Hour)
+	formE := create("", "BKK")
+	if formE.ReferenceNo != "BKK-2025-00001" || formE.Id != "FE2025000001" {
+		t.Fatalf("unexpected form E %s / %s", formE.Id, formE.ReferenceNo)
+	}
+
+	err := submit(exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "", `{"createdById":"E1"}`)
+		return err
+	})
+	if errorCode(err) != models.ErrValidation {
+		t.Fatalf("form E without office: expected VALIDATION, got %v", err)
+	}
+}
+
+func TestSequenceConfig(t *testing.T) {
+	s, ctx := newTestContract()
+
+	config, err := s.GetSequenceConfig(ctx)
+	if err != nil || config.Formats["farmer"] != "F{seq:6}" {
+		t.Fatalf("unexpected default config %+v, %v", config, err)
+	}
+
+	if err := submit(ctx.As(farmerUser), "SetSequenceConfig", func() error {
+		return s.SetSequenceConfig(ctx.As(farmerUser), `{"formats":{"farmer":"X{seq}"}}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("farmer: expected access denied, got %v", err)
+	}
+
+	for _, formats := range []string{
+		`{"gmp":"M{seq}"}`,
+		`{"farmer":"F-{yyyy}"}`,
+		`{"farmer":"{seq}{seq}"}`,
+		`{"farmer":"F{day}{seq}"}`,
+		`{"farmer":"F{seq:x}"}`,
+	} {
+		err := submit(ctx, "SetSequenceConfig", func() error {
+			return s.SetSequenceConfig(ctx, `{"formats":`+formats+`}`)
+		})
+		if errorCode(err) != models.ErrValidation {
+			t.Errorf("%s: expected VALIDATION, got %v", formats, err)
+		}
+	}
+
+	mustSubmit(t, ctx, "SetSequenceConfig", func() error {
+		return s.SetSequenceConfig(ctx, `{"formats":{"farmer":"FM{yy}{mm}-{seq:3}"}}`)
+	})
+	config, err = s.GetSequenceConfig(ctx)
+	if err != nil || config.Formats["farmer"] != "FM{yy}{mm}-{seq:3}" || config.Formats["packer"] != "PK{seq:6}" {
+		t.Fatalf("unexpected config %+v, %v", config, err)
+	}
+
+	if id := allocate(t, ctx, "CreateFarmerProfile", func() (string, error) {
+		return s.CreateFarmerProfile(ctx, `{}`)
+	}); id != "FM2401-001" {
+		t.Fatalf("farmer id = %q, want FM2401-001", id)
+	}
+}
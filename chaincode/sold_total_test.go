@@ -0,0 +1,143 @@
+package chaincode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+func TestGapSoldTotals(t *testing.T) {
+	s, ctx := newTestContract()
+	seedCertificates(t, s, ctx)
+	packerCtx := ctx.As(packerUser)
+
+	assertSold := func(gap string, want int) {
+		t.Helper()
+		if total, err := s.CalculateTotalSold(ctx, gap); err != nil || total != want {
+			t.Fatalf("total sold from %s = %d, %v, want %d", gap, total, err, want)
+		}
+	}
+
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P1","gap":"GAP-1","actualWeight":10,"processStatus":1}`)
+	})
+	mustSubmit(t, packerCtx, "CreatePacking", func() error {
+		return s.CreatePacking(packerCtx, `{"id":"P2","gap":"GAP-2","actualWeight":4,"processStatus":1}`)
+	})
+	assertSold("GAP-1", 0)
+
+	advancePacking(t, s, ctx, "P1", models.StatusApproved)
+	advancePacking(t, s, ctx, "P2", models.StatusApproved)
+	assertSold("GAP-1", 10)
+	assertSold("GAP-2", 4)
+
+	// An order writes only the total of its own GAP.
+	packing, _ := s.ReadPacking(ctx, "P1")
+	packing.FinalWeight = 8
+	packing.ProcessStatus = models.StatusCompleted
+	mustSubmit(t, packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, toJSON(t, packing))
+	})
+	assertChanges(t, lastStateChanges(t, ctx).Changes,
+		models.StateChange{Type: models.ChangeUpdate, DocType: models.Packing, Id: "P1"},
+		models.StateChange{Type: models.ChangeUpdate, DocType: models.SoldTotal, Id: "GAP-1"},
+	)
+	assertSold("GAP-1", 8)
+
+	// A sold order cannot move to another GAP.
+	if err := submit(packerCtx, "UpdatePacking", func() error {
+		return s.UpdatePacking(packerCtx, `{"id":"P2","gap":"GAP-1","actualWeight":4,"processStatus":2}`)
+	}); errorCode(err) != models.ErrConflict {
+		t.Fatalf("gap change of an approved order: expected a conflict, got %v", err)
+	}
+	assertSold("GAP-1", 8)
+	assertSold("GAP-2", 4)
+
+	mustSubmit(t, packerCtx, "CancelPacking", func() error {
+		return s.CancelPacking(packerCtx, `{"id":"P2","cancelReason":"spoiled"}`)
+	})
+	assertSold("GAP-2", 0)
+
+	mustSubmit(t, packerCtx, "DeletePacking", func() error {
+		return s.DeletePacking(packerCtx, "P1", "entered twice")
+	})
+	assertSold("GAP-1", 0)
+	mustSubmit(t, packerCtx, "RestorePacking", func() error {
+		return s.RestorePacking(packerCtx, "P1")
+	})
+	assertSold("GAP-1", 8)
+}
+
+func TestRebuildGapSoldTotals(t *testing.T) {
+	s, ctx := newTestContract()
+
+	// Orders written before the totals were kept, and a stale total.
+	ctx.Stub.PutState("P1", []byte(`{"id":"P1","docType":"packing","gap":"GAP-1","actualWeight":10,"processStatus":2}`))
+	ctx.Stub.PutState("P2", []byte(`{"id":"P2","docType":"packing","gap":"GAP-1","actualWeight":5,"processStatus":3}`))
+	ctx.Stub.PutState("P3", []byte(`{"id":"P3","docType":"packing","gap":"GAP-1","actualWeight":7,"processStatus":1}`))
+	staleKey, _ := ctx.Stub.CreateCompositeKey("soldTotal", []string{"GAP-9"})
+	ctx.Stub.PutState(staleKey, []byte(`{"gap":"GAP-9","totalSold":3,"docType":"soldTotal"}`))
+
+	if err := submit(ctx.As(regulatorUser), "RebuildGapSoldTotals", func() error {
+		_, err := s.RebuildGapSoldTotals(ctx.As(regulatorUser))
+		return err
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator: expected access denied, got %v", err)
+	}
+
+	var totals []models.GapSoldTotal
+	mustSubmit(t, ctx, "RebuildGapSoldTotals", func() (err error) {
+		totals, err = s.RebuildGapSoldTotals(ctx)
+		return err
+	})
+	if len(totals) != 1 || totals[0].Gap != "GAP-1" || totals[0].TotalSold != 15 {
+		t.Fatalf("unexpected totals %+v", totals)
+	}
+	if total, _ := s.CalculateTotalSold(ctx, "GAP-1"); total != 15 {
+		t.Fatalf("total sold = %d, want 15", total)
+	}
+	if stale, _ := ctx.Stub.GetState(staleKey); stale != nil {
+		t.Fatalf("stale total kept: %s", stale)
+	}
+
+	// Rebuilding again changes nothing.
+	events := len(ctx.Stub.Events)
+	mustSubmit(t, ctx, "RebuildGapSoldTotals", func() (err error) {
+		totals, err = s.RebuildGapSoldTotals(ctx)
+		return err
+	})
+	if len(totals) != 1 || len(ctx.Stub.Events) != events {
+		t.Fatalf("second rebuild: totals %+v", totals)
+	}
+}
+
+func TestGapSoldTotalDoesNotDrift(t *testing.T) {
+	s, ctx := newTestContract()
+	seedCertificates(t, s, ctx)
+	packerCtx := ctx.As(packerUser)
+
+	var ids []string
+	for i := 1; i <= 10; i++ {
+		id := fmt.Sprintf("P%d", i)
+		ids = append(ids, id)
+		mustSubmit(t, packerCtx, "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, `{"id":"`+id+`","gap":"GAP-1","actualWeight":0.1,"processStatus":1}`)
+		})
+		advancePacking(t, s, ctx, id, models.StatusApproved)
+	}
+	for _, id := range ids {
+		mustSubmit(t, packerCtx, "CancelPacking", func() error {
+			return s.CancelPacking(packerCtx, `{"id":"`+id+`","cancelReason":"spoiled"}`)
+		})
+	}
+
+	total, err := utils.GetGapSoldTotal(ctx, "GAP-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.TotalSold != 0 {
+		t.Fatalf("total sold = %v after cancelling every order, want 0", total.TotalSold)
+	}
+}
@@ -0,0 +1,44 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// SetSoftDeleteConfig sets how long deleted records may still be restored.
+func (s *SmartContract) SetSoftDeleteConfig(ctx contractapi.TransactionContextInterface, args string) error {
+	entityConfig := models.SoftDeleteConfig{}
+	inputInterface, err := utils.Unmarshal(args, entityConfig)
+	if err != nil {
+		return err
+	}
+	input := inputInterface.(*models.SoftDeleteConfig)
+
+	if err := utils.ValidateSoftDeleteConfig(input); err != nil {
+		return utils.Validation("invalid soft delete config: %v", err)
+	}
+
+	clientID, err := utils.GetIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := utils.GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	config := models.SoftDeleteConfig{
+		RestoreWindowDays: input.RestoreWindowDays,
+		UpdatedBy:         clientID,
+		UpdatedAt:         timestamp,
+		DocType:           models.Config,
+	}
+
+	return utils.PutConfig(ctx, utils.SoftDeleteConfig, config)
+}
+
+func (s *SmartContract) GetSoftDeleteConfig(ctx contractapi.TransactionContextInterface) (*models.SoftDeleteConfig, error) {
+	return utils.GetSoftDeleteConfig(ctx)
+}
@@ -0,0 +1,181 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestSoftDelete(t *testing.T) {
+	s, ctx := newTestContract()
+
+	seedDaily(t, ctx, "CreateGmpCsv", func(args string) error {
+		return s.CreateGmpCsv(ctx, args)
+	},
+		`[{"id":"M1","packingHouseRegisterNumber":"PH-1","packingHouseName":"Alpha"}]`,
+		`[{"id":"M2","packingHouseRegisterNumber":"PH-2","packingHouseName":"Beta"}]`,
+	)
+
+	if err := submit(ctx, "DeleteGmp", func() error {
+		return s.DeleteGmp(ctx, "M1", "")
+	}); err == nil {
+		t.Fatal("expected delete without a reason to fail")
+	}
+
+	mustSubmit(t, ctx, "DeleteGmp", func() error {
+		return s.DeleteGmp(ctx, "M1", "registered twice")
+	})
+
+	var stored models.TransactionGmp
+	storedJSON, _ := ctx.Stub.GetState("M1")
+	if err := json.Unmarshal(storedJSON, &stored); err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := ctx.Stub.TxTimestamp.Format(time.RFC3339)
+	if stored.DeletedAt != deletedAt || stored.DeletedBy != nectecUser.ID || stored.DeleteReason != "registered twice" || stored.PackingHouseName != "Alpha" {
+		t.Fatalf("unexpected tombstone %+v", stored)
+	}
+
+	if _, err := s.ReadGmp(ctx, "M1"); err == nil {
+		t.Fatal("expected deleted gmp to be unreadable")
+	}
+	if err := submit(ctx, "UpdateGmp", func() error {
+		return s.UpdateGmp(ctx, `{"id":"M1","packingHouseName":"Alpha 2"}`)
+	}); err == nil {
+		t.Fatal("expected update of deleted gmp to fail")
+	}
+	if err := submit(ctx, "DeleteGmp", func() error {
+		return s.DeleteGmp(ctx, "M1", "registered twice")
+	}); err == nil {
+		t.Fatal("expected second delete to fail")
+	}
+	if err := submit(ctx, "CreateGMP", func() error {
+		return s.CreateGMP(ctx, `{"id":"M1","packingHouseRegisterNumber":"PH-1"}`)
+	}); err == nil {
+		t.Fatal("expected create over a deleted gmp to fail")
+	}
+	if byNumber, err := s.GetGmpByPackingHouseNumber(ctx, "PH-1"); err == nil && byNumber.Obj != nil && byNumber.Obj.Id != "" {
+		t.Fatalf("deleted gmp found by number %+v", byNumber.Obj)
+	}
+
+	tests := []struct {
+		name    string
+		filter  models.FilterGetAllGmp
+		wantIds []string
+	}{
+		{"live only", models.FilterGetAllGmp{}, []string{"M2"}},
+		{"include deleted", models.FilterGetAllGmp{Pagination: models.Pagination{IncludeDeleted: true}}, []string{"M2", "M1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.GetAllGMP(ctx, toJSON(t, tt.filter))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != len(tt.wantIds) {
+				t.Errorf("total = %d, want %d", res.Total, len(tt.wantIds))
+			}
+			var ids []string
+			for _, g := range res.Obj {
+				ids = append(ids, g.Id)
+				if (g.Id == "M1") != (g.DeletedAt != "") {
+					t.Errorf("unexpected tombstone on %s: %+v", g.Id, g.Tombstone)
+				}
+			}
+			assertIDs(t, ids, tt.wantIds...)
+		})
+	}
+
+	packerCtx := ctx.As(packerUser)
+	if err := submit(packerCtx, "RestoreGmp", func() error {
+		return s.RestoreGmp(packerCtx, "M1")
+	}); !isAccessDenied(err) {
+		t.Fatalf("packer restore: expected access denied, got %v", err)
+	}
+	if err := submit(ctx, "RestoreGmp", func() error {
+		return s.RestoreGmp(ctx, "M2")
+	}); err == nil {
+		t.Fatal("expected restore of a live gmp to fail")
+	}
+
+	mustSubmit(t, ctx, "RestoreGmp", func() error {
+		return s.RestoreGmp(ctx, "M1")
+	})
+	gmp, err := s.ReadGmp(ctx, "M1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gmp.IsDeleted() || gmp.DeletedBy != "" || gmp.DeleteReason != "" || gmp.UpdatedAt != ctx.Stub.TxTimestamp.Format(time.RFC3339) {
+		t.Fatalf("tombstone not cleared %+v", gmp)
+	}
+	res, err := s.GetAllGMP(ctx, `{}`)
+	if err != nil || res.Total != 2 {
+		t.Fatalf("restored gmp not listed: %+v, %v", res, err)
+	}
+}
+
+func TestRestoreWindow(t *testing.T) {
+	s, ctx := newTestContract()
+
+	config, err := s.GetSoftDeleteConfig(ctx)
+	if err != nil || config.RestoreWindowDays != 30 {
+		t.Fatalf("unexpected default config %+v, %v", config, err)
+	}
+
+	regulatorCtx := ctx.As(regulatorUser)
+	if err := submit(regulatorCtx, "SetSoftDeleteConfig", func() error {
+		return s.SetSoftDeleteConfig(regulatorCtx, `{"restoreWindowDays":7}`)
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator set config: expected access denied, got %v", err)
+	}
+	if err := submit(ctx, "SetSoftDeleteConfig", func() error {
+		return s.SetSoftDeleteConfig(ctx, `{"restoreWindowDays":-1}`)
+	}); err == nil {
+		t.Fatal("expected negative window to be rejected")
+	}
+	mustSubmit(t, ctx, "SetSoftDeleteConfig", func() error {
+		return s.SetSoftDeleteConfig(ctx, `{"restoreWindowDays":7}`)
+	})
+
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[
+			{"id":"H1","hscode":"0804.50","order":1},
+			{"id":"H2","hscode":"0810.60","order":2}
+		]`)
+	})
+	mustSubmit(t, ctx, "DeleteAllHscodes", func() error {
+		return s.DeleteAllHscodes(ctx, "new tariff schedule")
+	})
+
+	ctx.Stub.Advance(6 * 24 * time.Hour)
+	mustSubmit(t, ctx, "RestoreHscode", func() error {
+		return s.RestoreHscode(ctx, "H1")
+	})
+
+	ctx.Stub.Advance(2 * 24 * time.Hour)
+	if err := submit(ctx, "RestoreHscode", func() error {
+		return s.RestoreHscode(ctx, "H2")
+	}); err == nil {
+		t.Fatal("expected restore after the window to fail")
+	}
+
+	res, err := s.QueryHscodeWithPagination(ctx, `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, h := range res.Data {
+		ids = append(ids, h.Id)
+	}
+	assertIDs(t, ids, "H1")
+
+	res, err = s.QueryHscodeWithPagination(ctx, `{"includeDeleted":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 2 || res.Data[1].DeleteReason != "new tariff schedule" {
+		t.Fatalf("unexpected listing with deleted hscodes %+v", res.Data)
+	}
+}
@@ -0,0 +1,195 @@
+package chaincode
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// traceKeys are the packaging fields a traced key is looked up in, in the
+// order they are reported as matched.
+var traceKeys = []string{"boxId", "gtin13", "gtin14", "containerId"}
+
+// TraceProduct walks the chain from an export box back to the farm. key is a
+// box id, a GTIN-13 or GTIN-14, a container id or a Form E reference number.
+func (s *SmartContract) TraceProduct(ctx contractapi.TransactionContextInterface, key string) (*models.ProductTrace, error) {
+	if strings.TrimSpace(key) == "" {
+		return nil, utils.Validation("key is required")
+	}
+
+	trace := &models.ProductTrace{Key: key}
+
+	var packagingKeys []*utils.Selector
+	for _, field := range traceKeys {
+		packagingKeys = append(packagingKeys, utils.NewSelector("").Eq(field, key))
+	}
+	packagings, err := utils.FetchAll[models.TransactionPackaging](ctx, utils.NewSelector(models.Packaging).Or(packagingKeys...).Query())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(packagings) > 0 {
+		trace.MatchedBy = matchedPackagingKey(packagings[0], key)
+		trace.Packagings = packagings
+
+		trace.FormEs, err = traceFormEs(ctx, packagings)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var formE models.TransactionFormE
+		found, err := utils.FormEReferenceNoIndex.Get(ctx, key, &formE)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, utils.NotFound("no product found for %s", key)
+		}
+		trace.MatchedBy = "referenceNo"
+		trace.FormEs = []*models.TransactionFormE{&formE}
+
+		trace.Packagings, err = tracePackagings(ctx, trace.FormEs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var exporterIds, gmps, gapCerts []string
+	var sources []*utils.Selector
+	for _, packaging := range trace.Packagings {
+		exporterIds = appendDistinct(exporterIds, packaging.CreatedById)
+		gmps = appendDistinct(gmps, packaging.Gmp)
+		gapCerts = appendDistinct(gapCerts, packaging.Gap)
+
+		// Packing orders that sold this box's GAP produce to its packing house
+		if packaging.Gap != "" {
+			source := utils.NewSelector("").Eq("gap", packaging.Gap)
+			if packaging.Gmp != "" {
+				source.Eq("gmp", packaging.Gmp)
+			}
+			sources = append(sources, source)
+		}
+	}
+	for _, formE := range trace.FormEs {
+		exporterIds = appendDistinct(exporterIds, formE.CreatedById)
+	}
+
+	if trace.Exporters, err = fetchIn[models.TransactionExporter](ctx, models.Exporter, "id", exporterIds); err != nil {
+		return nil, err
+	}
+	if trace.PlantTypes, err = fetchIn[models.PlantTypeModel](ctx, models.PlantType, "exporterId", exporterIds); err != nil {
+		return nil, err
+	}
+	if trace.Gmps, err = fetchIn[models.TransactionGmp](ctx, models.Gmp, "packingHouseRegisterNumber", gmps); err != nil {
+		return nil, err
+	}
+	if trace.Gaps, err = fetchIn[models.TransactionGap](ctx, models.Gap, "certId", gapCerts); err != nil {
+		return nil, err
+	}
+
+	trace.Packings = []*models.TransactionPacking{}
+	if len(sources) > 0 {
+		trace.Packings, err = utils.FetchAll[models.TransactionPacking](ctx, utils.NewSelector(models.Packing).Or(sources...).Query().Sort("createdAt", "asc"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var farmerIds []string
+	for _, gap := range trace.Gaps {
+		farmerIds = appendDistinct(farmerIds, gap.FarmerID)
+	}
+	if trace.Farmers, err = fetchIn[models.TransactionFarmer](ctx, models.Farmer, "id", farmerIds); err != nil {
+		return nil, err
+	}
+
+	return trace, nil
+}
+
+// traceFormEs returns the Form E certificates covering packagings, linked by
+// container number or export number.
+func traceFormEs(ctx contractapi.TransactionContextInterface, packagings []*models.TransactionPackaging) ([]*models.TransactionFormE, error) {
+	var containerIds, exportIds []string
+	for _, packaging := range packagings {
+		containerIds = appendDistinct(containerIds, packaging.ContainerId)
+		exportIds = appendDistinct(exportIds, packaging.ExportId)
+	}
+
+	var links []*utils.Selector
+	if len(containerIds) > 0 {
+		links = append(links, utils.NewSelector("").ElemMatch("invoice.productAndPackaging", utils.NewSelector("").In("containerNumber", containerIds)))
+	}
+	if len(exportIds) > 0 {
+		links = append(links, utils.NewSelector("").In("invoice.exportNumber", exportIds))
+	}
+	if len(links) == 0 {
+		return []*models.TransactionFormE{}, nil
+	}
+
+	return utils.FetchAll[models.TransactionFormE](ctx, utils.NewSelector(models.FormE).Or(links...).Query())
+}
+
+// tracePackagings returns the packagings shipped under formEs, linked by
+// container number or export number.
+func tracePackagings(ctx contractapi.TransactionContextInterface, formEs []*models.TransactionFormE) ([]*models.TransactionPackaging, error) {
+	var containerIds, exportIds []string
+	for _, formE := range formEs {
+		if formE.Invoice == nil {
+			continue
+		}
+		exportIds = appendDistinct(exportIds, formE.Invoice.ExportNumber)
+		for _, product := range formE.Invoice.ProductAndPackaging {
+			containerIds = appendDistinct(containerIds, product.ContainerNumber)
+		}
+	}
+
+	var links []*utils.Selector
+	if len(containerIds) > 0 {
+		links = append(links, utils.NewSelector("").In("containerId", containerIds))
+	}
+	if len(exportIds) > 0 {
+		links = append(links, utils.NewSelector("").In("exportId", exportIds))
+	}
+	if len(links) == 0 {
+		return []*models.TransactionPackaging{}, nil
+	}
+
+	return utils.FetchAll[models.TransactionPackaging](ctx, utils.NewSelector(models.Packaging).Or(links...).Query())
+}
+
+// fetchIn returns the documents of docType whose field is one of values.
+func fetchIn[T any](ctx contractapi.TransactionContextInterface, docType models.DocType, field string, values []string) ([]*T, error) {
+	if len(values) == 0 {
+		return []*T{}, nil
+	}
+	return utils.FetchAll[T](ctx, utils.NewSelector(docType).In(field, values).Query())
+}
+
+func matchedPackagingKey(packaging *models.TransactionPackaging, key string) string {
+	values := map[string]string{
+		"boxId":       packaging.BoxId,
+		"gtin13":      packaging.Gtin13,
+		"gtin14":      packaging.Gtin14,
+		"containerId": packaging.ContainerId,
+	}
+	for _, field := range traceKeys {
+		if values[field] == key {
+			return field
+		}
+	}
+	return ""
+}
+
+func appendDistinct(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,107 @@
+package chaincode
+
+import (
+	"testing"
+)
+
+func TestTraceProduct(t *testing.T) {
+	s, ctx := newTestContract()
+	packerCtx := ctx.As(packerUser)
+	exporterCtx := ctx.As(exporterUser)
+
+	mustSubmit(t, ctx, "CreateFarmerFromCsv", func() error {
+		return s.CreateFarmerFromCsv(ctx, `[{"id":"F1","certId":"FC-1"},{"id":"F2","certId":"FC-2"}]`)
+	})
+	mustSubmit(t, ctx, "CreateGapCsv", func() error {
+		return s.CreateGapCsv(ctx, `[{"id":"G1","certId":"GAP-1","farmerId":"F1","areaRai":4},{"id":"G2","certId":"GAP-2","farmerId":"F2"}]`)
+	})
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[{"id":"M1","packingHouseRegisterNumber":"PH-1","packerId":"PK1"},{"id":"M2","packingHouseRegisterNumber":"PH-2"}]`)
+	})
+	mustSubmit(t, ctx, "CreateExporterCsv", func() error {
+		return s.CreateExporterCsv(ctx, `[{"id":"E1","certId":"EC-1"},{"id":"E2","certId":"EC-2"}]`)
+	})
+	mustSubmit(t, ctx, "TransferAsset", func() error {
+		return s.TransferAsset(ctx, "E1", exporterUser.ID)
+	})
+	mustSubmit(t, ctx, "CreatePlantTypeCsv", func() error {
+		return s.CreatePlantTypeCsv(ctx, `[{"id":"T1","plantType":"PT-1","exporterId":"E1"},{"id":"T2","plantType":"PT-2","exporterId":"E2"}]`)
+	})
+	for _, packing := range []string{
+		`{"id":"P1","gap":"GAP-1","gmp":"PH-1","farmerId":"F1","actualWeight":10,"finalWeight":10,"processStatus":1}`,
+		`{"id":"P2","gap":"GAP-1","gmp":"PH-2","farmerId":"F1","actualWeight":3,"processStatus":1}`,
+		`{"id":"P3","gap":"GAP-2","gmp":"PH-1","farmerId":"F2","actualWeight":7,"processStatus":1}`,
+	} {
+		mustSubmit(t, packerCtx, "CreatePacking", func() error {
+			return s.CreatePacking(packerCtx, packing)
+		})
+	}
+	mustSubmit(t, exporterCtx, "CreatePackagingCsv", func() error {
+		return s.CreatePackagingCsv(exporterCtx, `[
+			{"id":"B1","boxId":"BOX-1","containerId":"C1","gtin13":"8850001","gtin14":"18850001","gap":"GAP-1","gmp":"PH-1","createdById":"E1"},
+			{"id":"B2","boxId":"BOX-2","containerId":"C1","gap":"GAP-2","gmp":"PH-1","createdById":"E1"},
+			{"id":"B3","boxId":"BOX-3","containerId":"C9","gap":"GAP-2","gmp":"PH-2","createdById":"E2"}
+		]`)
+	})
+	mustSubmit(t, ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[{"id":"H1","hscode":"0810.60"}]`)
+	})
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"referenceNo":"REF-1","createdById":"E1","invoice":{"exportNumber":"EX-1","productAndPackaging":[{"hscode":"0810.60","containerNumber":"C1"}]}}`)
+		return err
+	})
+
+	ids := func(n int, id func(i int) string) []string {
+		var out []string
+		for i := 0; i < n; i++ {
+			out = append(out, id(i))
+		}
+		return out
+	}
+
+	tests := []struct {
+		key        string
+		matchedBy  string
+		packagings []string
+		packings   []string
+		gaps       []string
+		farmers    []string
+	}{
+		{"BOX-1", "boxId", []string{"B1"}, []string{"P1"}, []string{"G1"}, []string{"F1"}},
+		{"8850001", "gtin13", []string{"B1"}, []string{"P1"}, []string{"G1"}, []string{"F1"}},
+		{"18850001", "gtin14", []string{"B1"}, []string{"P1"}, []string{"G1"}, []string{"F1"}},
+		{"C1", "containerId", []string{"B1", "B2"}, []string{"P1", "P3"}, []string{"G1", "G2"}, []string{"F1", "F2"}},
+		{"REF-1", "referenceNo", []string{"B1", "B2"}, []string{"P1", "P3"}, []string{"G1", "G2"}, []string{"F1", "F2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			trace, err := s.TraceProduct(ctx, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trace.MatchedBy != tt.matchedBy {
+				t.Errorf("matchedBy = %q, want %q", trace.MatchedBy, tt.matchedBy)
+			}
+			assertIDs(t, ids(len(trace.Packagings), func(i int) string { return trace.Packagings[i].Id }), tt.packagings...)
+			assertIDs(t, ids(len(trace.Packings), func(i int) string { return trace.Packings[i].Id }), tt.packings...)
+			assertIDs(t, ids(len(trace.Gaps), func(i int) string { return trace.Gaps[i].Id }), tt.gaps...)
+			assertIDs(t, ids(len(trace.Farmers), func(i int) string { return trace.Farmers[i].Id }), tt.farmers...)
+			assertIDs(t, ids(len(trace.Gmps), func(i int) string { return trace.Gmps[i].Id }), "M1")
+			assertIDs(t, ids(len(trace.Exporters), func(i int) string { return trace.Exporters[i].Id }), "E1")
+			assertIDs(t, ids(len(trace.PlantTypes), func(i int) string { return trace.PlantTypes[i].Id }), "T1")
+			assertIDs(t, ids(len(trace.FormEs), func(i int) string { return trace.FormEs[i].Id }), "FE1")
+		})
+	}
+
+	trace, err := s.TraceProduct(ctx, "BOX-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.FormEs) != 0 || len(trace.Packings) != 0 || trace.Gaps[0].FarmerID != "F2" {
+		t.Fatalf("unexpected trace of a box without form E or sales %+v", trace)
+	}
+
+	if _, err := s.TraceProduct(ctx, "nope"); err == nil {
+		t.Fatal("expected unknown key to fail")
+	}
+}
@@ -0,0 +1,22 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/utils"
+)
+
+// RebuildUniqueIndexes indexes the business keys of records written before
+// the unique indexes existed. Records whose key is held by another record are
+// returned so they can be corrected; their keys are left to the first record.
+func (s *SmartContract) RebuildUniqueIndexes(ctx contractapi.TransactionContextInterface) ([]models.UniqueConflict, error) {
+	conflicts := []models.UniqueConflict{}
+	for _, index := range utils.UniqueIndexes {
+		indexConflicts, err := index.Rebuild(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, indexConflicts...)
+	}
+	return conflicts, nil
+}
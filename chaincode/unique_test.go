@@ -0,0 +1,194 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+func TestUniqueGapCertId(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGAP", func() error {
+		return s.CreateGAP(ctx, `{"id":"G1","certId":"GAP-1"}`)
+	})
+	mustSubmit(t, ctx, "CreateGAP", func() error {
+		return s.CreateGAP(ctx, `{"id":"G2","certId":"GAP-2"}`)
+	})
+
+	err := submit(ctx, "CreateGAP", func() error {
+		return s.CreateGAP(ctx, `{"id":"G3","certId":"GAP-1"}`)
+	})
+	if errorCode(err) != models.ErrAlreadyExists {
+		t.Fatalf("create with a taken certId: expected ALREADY_EXISTS, got %v", err)
+	}
+	err = submit(ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G2","certId":"GAP-1"}`)
+	})
+	if errorCode(err) != models.ErrAlreadyExists {
+		t.Fatalf("update to a taken certId: expected ALREADY_EXISTS, got %v", err)
+	}
+	err = submit(ctx, "UpdateMultipleGap", func() error {
+		return s.UpdateMultipleGap(ctx, `[{"id":"G1","certId":"GAP-7"},{"id":"G2","certId":"GAP-7"}]`)
+	})
+	if errorCode(err) != models.ErrAlreadyExists {
+		t.Fatalf("updating two gaps to one certId: expected ALREADY_EXISTS, got %v", err)
+	}
+
+	// A changed certId frees the old one.
+	mustSubmit(t, ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G1","certId":"GAP-9"}`)
+	})
+	mustSubmit(t, ctx, "UpdateGap", func() error {
+		return s.UpdateGap(ctx, `{"id":"G2","certId":"GAP-1"}`)
+	})
+	for certId, want := range map[string]string{"GAP-9": "G1", "GAP-1": "G2"} {
+		res, err := s.GetGapByCertID(ctx, certId)
+		if err != nil || res.Obj == nil || res.Obj.Id != want {
+			t.Fatalf("GetGapByCertID(%s) = %+v, %v, want %s", certId, res, err, want)
+		}
+	}
+	if res, err := s.GetGapByCertID(ctx, "GAP-2"); err != nil || res.Obj != nil {
+		t.Fatalf("released certId still found: %+v, %v", res, err)
+	}
+
+	// A deleted gap gives up its certId and cannot be restored once it is
+	// taken.
+	mustSubmit(t, ctx, "DeleteGap", func() error {
+		return s.DeleteGap(ctx, "G1", "entered twice")
+	})
+	if res, err := s.GetGapByCertID(ctx, "GAP-9"); err != nil || res.Obj != nil {
+		t.Fatalf("deleted gap still found: %+v, %v", res, err)
+	}
+	mustSubmit(t, ctx, "CreateGAP", func() error {
+		return s.CreateGAP(ctx, `{"id":"G3","certId":"GAP-9"}`)
+	})
+	err = submit(ctx, "RestoreGap", func() error {
+		return s.RestoreGap(ctx, "G1")
+	})
+	if errorCode(err) != models.ErrConflict {
+		t.Fatalf("restore with a taken certId: expected CONFLICT, got %v", err)
+	}
+}
+
+func TestUniqueKeysInBatches(t *testing.T) {
+	s, ctx := newTestContract()
+
+	mustSubmit(t, ctx, "CreateGmpCsv", func() error {
+		return s.CreateGmpCsv(ctx, `[{"id":"M1","packingHouseRegisterNumber":"PH-1"},{"id":"M2","packingHouseRegisterNumber":"PH-2"}]`)
+	})
+
+	var report *models.BatchImportResult
+	mustSubmit(t, ctx, "DryRunBatchImport", func() (err error) {
+		report, err = s.DryRunBatchImport(ctx, "CreateGmpCsv", `[
+			{"id":"M3","packingHouseRegisterNumber":"PH-1"},
+			{"id":"M4","packingHouseRegisterNumber":"PH-4"},
+			{"id":"M5","packingHouseRegisterNumber":"PH-4"}
+		]`)
+		return err
+	})
+	var statuses []string
+	for _, row := range report.Rows {
+		statuses = append(statuses, string(row.Status))
+	}
+	assertIDs(t, statuses, "duplicate", "ok", "duplicate")
+	if report.Rows[0].Field != "packingHouseRegisterNumber" {
+		t.Fatalf("unexpected row %+v", report.Rows[0])
+	}
+
+	err := submit(ctx, "UpdateMultipleGmp", func() error {
+		return s.UpdateMultipleGmp(ctx, `[{"id":"M1","packingHouseRegisterNumber":"PH-2"}]`)
+	})
+	if errorCode(err) != models.ErrAlreadyExists {
+		t.Fatalf("expected ALREADY_EXISTS, got %v", err)
+	}
+
+	res, err := s.GetGmpByPackingHouseNumber(ctx, "PH-2")
+	if err != nil || res.Obj == nil || res.Obj.Id != "M2" {
+		t.Fatalf("unexpected gmp %+v, %v", res, err)
+	}
+
+	err = submit(ctx, "CreateTransactionHscodes", func() error {
+		return s.CreateTransactionHscodes(ctx, `[{"id":"H1","hscode":"0810.60"},{"id":"H2","hscode":"0810.60"}]`)
+	})
+	if errorCode(err) != models.ErrValidation {
+		t.Fatalf("duplicate hscodes: expected VALIDATION, got %v", err)
+	}
+}
+
+func TestUniqueFormEReferenceNo(t *testing.T) {
+	s, ctx := newTestContract()
+	exporterCtx := ctx.As(exporterUser)
+	seedExporterPlantTypes(t, s, ctx, "E1")
+
+	mustSubmit(t, exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE1", `{"referenceNo":"REF-1","createdById":"E1"}`)
+		return err
+	})
+	err := submit(exporterCtx, "CreateFormE", func() error {
+		_, err := s.CreateFormE(exporterCtx, "FE2", `{"referenceNo":"REF-1","createdById":"E1"}`)
+		return err
+	})
+	if errorCode(err) != models.ErrAlreadyExists {
+		t.Fatalf("expected ALREADY_EXISTS, got %v", err)
+	}
+
+	formE, err := s.GetFormEByReferenceId(ctx, "REF-1")
+	if err != nil || formE.Id != "FE1" {
+		t.Fatalf("unexpected form E %+v, %v", formE, err)
+	}
+	if formE, err := s.GetFormEByReferenceId(ctx, "REF-9"); err != nil || formE.Id != "" {
+		t.Fatalf("unexpected form E %+v, %v", formE, err)
+	}
+}
+
+func TestRebuildUniqueIndexes(t *testing.T) {
+	s, ctx := newTestContract()
+
+	// Records written before the indexes existed.
+	for id, certId := range map[string]string{"L1": "GAP-1", "L2": "GAP-1", "L3": "GAP-3"} {
+		ctx.Stub.PutState(id, []byte(`{"id":"`+id+`","certId":"`+certId+`","docType":"gap"}`))
+	}
+	// Lookups fall back to searching the records until the index is rebuilt.
+	if res, err := s.GetGapByCertID(ctx, "GAP-3"); err != nil || res.Obj == nil || res.Obj.Id != "L3" {
+		t.Fatalf("unindexed gap: %+v, %v", res, err)
+	}
+	ctx.Stub.PutState("LFE", []byte(`{"id":"LFE","referenceNo":"REF-L","docType":"formE"}`))
+	if formE, err := s.GetFormEByReferenceId(ctx, "REF-L"); err != nil || formE.Id != "LFE" {
+		t.Fatalf("unindexed form E: %+v, %v", formE, err)
+	}
+
+	if err := submit(ctx.As(regulatorUser), "RebuildUniqueIndexes", func() error {
+		_, err := s.RebuildUniqueIndexes(ctx.As(regulatorUser))
+		return err
+	}); !isAccessDenied(err) {
+		t.Fatalf("regulator: expected access denied, got %v", err)
+	}
+
+	var conflicts []models.UniqueConflict
+	mustSubmit(t, ctx, "RebuildUniqueIndexes", func() (err error) {
+		conflicts, err = s.RebuildUniqueIndexes(ctx)
+		return err
+	})
+	if len(conflicts) != 1 || conflicts[0].Value != "GAP-1" || conflicts[0].Id == conflicts[0].HeldBy {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+
+	res, err := s.GetGapByCertID(ctx, "GAP-1")
+	if err != nil || res.Obj == nil || res.Obj.Id != conflicts[0].HeldBy {
+		t.Fatalf("unexpected gap %+v, %v", res, err)
+	}
+	if res, err := s.GetGapByCertID(ctx, "GAP-3"); err != nil || res.Obj == nil || res.Obj.Id != "L3" {
+		t.Fatalf("unexpected gap %+v, %v", res, err)
+	}
+
+	// Rebuilding again changes nothing.
+	events := len(ctx.Stub.Events)
+	mustSubmit(t, ctx, "RebuildUniqueIndexes", func() (err error) {
+		conflicts, err = s.RebuildUniqueIndexes(ctx)
+		return err
+	})
+	if len(conflicts) != 1 || len(ctx.Stub.Events) != events {
+		t.Fatalf("second rebuild: conflicts %+v", conflicts)
+	}
+}
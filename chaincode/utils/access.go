@@ -0,0 +1,448 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const AccessPolicyConfig = "accessPolicy"
+
+// AccessDeniedError is returned when the caller holds none of the roles a
+// transaction function or asset requires.
+type AccessDeniedError struct {
+	Function string        `json:"function"`
+	ClientId string        `json:"clientId"`
+	MspId    string        `json:"mspId"`
+	Roles    []models.Role `json:"roles"`
+	Required []models.Role `json:"required"`
+	Reason   string        `json:"reason"`
+}
+
+func (e *AccessDeniedError) Error() string {
+	return e.catalogue().Error()
+}
+
+func (e *AccessDeniedError) catalogue() *Error {
+	message := fmt.Sprintf("access denied for %s: caller %s with roles %v requires one of %v", e.Function, e.MspId, e.Roles, e.Required)
+	if e.Reason != "" {
+		message = fmt.Sprintf("access denied for %s: %s", e.Function, e.Reason)
+	}
+	return &Error{Code: models.ErrUnauthorized, Message: message, Details: e}
+}
+
+var (
+	anyRole    = models.AllRoles
+	staffRoles = []models.Role{models.RoleNectec, models.RoleRegulator}
+	nectecOnly = []models.Role{models.RoleNectec}
+)
+
+func staffAnd(roles ...models.Role) []models.Role {
+	return append(append([]models.Role{}, staffRoles...), roles...)
+}
+
+// DefaultFunctionRoles lists every transaction function with the roles
+// allowed to invoke it. Functions not listed here or in the on-chain policy
+// are denied.
+var DefaultFunctionRoles = map[string][]models.Role{
+	// access
+	"SetAccessPolicy": nectecOnly,
+	"GetAccessPolicy": anyRole,
+	"GetCallerAccess": anyRole,
+
+	// soft delete
+	"SetSoftDeleteConfig": nectecOnly,
+	"GetSoftDeleteConfig": anyRole,
+
+	// sequence
+	"SetSequenceConfig": nectecOnly,
+	"GetSequenceConfig": anyRole,
+
+	// unique indexes
+	"RebuildUniqueIndexes": nectecOnly,
+
+	// embedded copies
+	"CheckEmbeddedCopies": staffRoles,
+
+	// batch import; the import function's own roles are checked on dry run
+	"DryRunBatchImport": anyRole,
+
+	// farmer
+	"CreateFarmerProfile":  staffAnd(models.RoleFarmer),
+	"UpdateFarmerProfile":  staffAnd(models.RoleFarmer),
+	"DeleteFarmerProfile":  staffRoles,
+	"RestoreFarmerProfile": staffRoles,
+	"CreateFarmerFromCsv":  staffRoles,
+	"ReadFarmerProfile":    anyRole,
+	"GetAllFarmerProfile":  anyRole,
+	"FilterFarmer":         anyRole,
+	"GetFarmerHistory":     anyRole,
+	"GetLastIdFarmer":      anyRole,
+
+	// gap
+	"CreateGAP":         staffRoles,
+	"UpdateGap":         staffRoles,
+	"UpdateMultipleGap": staffRoles,
+	"CreateGapCsv":      staffRoles,
+	"DeleteGap":         staffRoles,
+	"RestoreGap":        staffRoles,
+	"ReadGap":           anyRole,
+	"GetGapByFarmerID":  anyRole,
+	"GetGapByCertID":    anyRole,
+	"GetAllGAP":         anyRole,
+	"FilterGap":         anyRole,
+	"SetGapYieldConfig": nectecOnly,
+	"GetGapYieldConfig": anyRole,
+	"GetGapQuota":       anyRole,
+
+	// gmp
+	"CreateGMP":                  staffRoles,
+	"UpdateGmp":                  staffRoles,
+	"UpdateMultipleGmp":          staffRoles,
+	"CreateGmpCsv":               staffRoles,
+	"DeleteGmp":                  staffRoles,
+	"RestoreGmp":                 staffRoles,
+	"ClearGmpPacker":             staffRoles,
+	"ReadGmp":                    anyRole,
+	"GetAllGMP":                  anyRole,
+	"GetGmpByPackingHouseNumber": anyRole,
+	"FilterGmp":                  anyRole,
+
+	// packer
+	"CreatePacker":        staffRoles,
+	"UpdatePacker":        staffAnd(models.RolePacker),
+	"CreatePackerCsv":     staffRoles,
+	"DeletePacker":        staffRoles,
+	"RestorePacker":       staffRoles,
+	"ReadPacker":          anyRole,
+	"GetPackerByPackerId": anyRole,
+	"GetPackerById":       anyRole,
+	"GetAllPacker":        anyRole,
+	"GetLastIdPacker":     anyRole,
+
+	// packing
+	"CreatePacking":          staffAnd(models.RolePacker),
+	"UpdatePacking":          staffAnd(models.RolePacker, models.RoleFarmer),
+	"SavePacking":            staffAnd(models.RolePacker),
+	"ApprovePacking":         staffAnd(models.RoleFarmer),
+	"CompletePacking":        staffAnd(models.RolePacker),
+	"CancelPacking":          staffAnd(models.RolePacker, models.RoleFarmer),
+	"DeletePacking":          staffAnd(models.RolePacker),
+	"RestorePacking":         staffAnd(models.RolePacker),
+	"TransferPacking":        staffAnd(models.RolePacker),
+	"ReadPacking":            anyRole,
+	"GetAllPacking":          anyRole,
+	"CalculateTotalSold":     anyRole,
+	"RebuildGapSoldTotals":   nectecOnly,
+	"FilterPacking":          anyRole,
+	"GetLatestHistoryForKey": anyRole,
+	"GetHistoryForKey":       anyRole,
+
+	// packaging
+	"CreatePackagingCsv":           staffAnd(models.RolePacker, models.RoleExporter),
+	"ReadPackaging":                anyRole,
+	"GetPackagingGmp":              anyRole,
+	"QueryPackagingWithPagination": anyRole,
+
+	// trace
+	"TraceProduct": anyRole,
+
+	// exporter
+	"CreateExporter":              staffRoles,
+	"CreateExporterCsv":           staffRoles,
+	"UpdateExporter":              staffAnd(models.RoleExporter),
+	"UpdateMultipleExporter":      staffRoles,
+	"DeleteExporter":              staffRoles,
+	"DeleteExporterFromRegulator": staffRoles,
+	"RestoreExporter":             staffRoles,
+	"TransferAsset":               staffAnd(models.RoleExporter),
+	"ReadExporter":                anyRole,
+	"GetExporterByExporterId":     anyRole,
+	"GetAllExporterImportData":    anyRole,
+	"GetAllExporter":              anyRole,
+	"GetExporterList":             anyRole,
+	"FilterExporter":              anyRole,
+	"GetLastIdExporter":           anyRole,
+
+	// plant type
+	"CreatePlantTypeCsv":          staffRoles,
+	"UpdatePlantType":             staffRoles,
+	"UpdateMultiplePlantType":     staffRoles,
+	"DeletePlantType":             staffRoles,
+	"RestorePlantType":            staffRoles,
+	"ReadPlanType":                anyRole,
+	"QueryPlanTypeWithPagination": anyRole,
+	"GetPlantTypeByPlantType":     anyRole,
+	"GetPlantTypeList":            anyRole,
+
+	// hscode
+	"CreateTransactionHscodes":  staffRoles,
+	"DeleteAllHscodes":          staffRoles,
+	"RestoreHscode":             staffRoles,
+	"QueryHscodeWithPagination": anyRole,
+
+	// form e
+	"CreateFormE":              staffAnd(models.RolePacker, models.RoleExporter),
+	"CancelFormE":              staffAnd(models.RolePacker, models.RoleExporter),
+	"SubmitFormE":              staffAnd(models.RolePacker, models.RoleExporter),
+	"AmendFormE":               staffAnd(models.RolePacker, models.RoleExporter),
+	"ReplaceFormE":             staffAnd(models.RolePacker, models.RoleExporter),
+	"ReviewFormE":              staffRoles,
+	"IssueFormE":               staffRoles,
+	"RejectFormE":              staffRoles,
+	"ValidateFormE":            staffAnd(models.RolePacker, models.RoleExporter),
+	"ReadFormE":                anyRole,
+	"QueryFormEWithPagination": anyRole,
+	"GetFormEHistoryForKey":    anyRole,
+	"GetFormEByReferenceId":    anyRole,
+
+	// document
+	"AttachDocument":              anyRole,
+	"VerifyDocument":              anyRole,
+	"QueryDocumentWithPagination": anyRole,
+
+	// regulator
+	"CreateRegulatorProfile":       nectecOnly,
+	"UpdateRegulatorProfile":       staffRoles,
+	"DeleteRegulator":              nectecOnly,
+	"RestoreRegulator":             nectecOnly,
+	"ReadRegulatorProfile":         anyRole,
+	"QueryRegulatorWithPagination": anyRole,
+	"GetRegulatorByUserId":         anyRole,
+
+	// nectec staff
+	"CreateNectecStaff":           nectecOnly,
+	"UpdateNectecStaff":           nectecOnly,
+	"DeleteNectecStaff":           nectecOnly,
+	"DeleteNectecStaffFromCertId": nectecOnly,
+	"RestoreNectecStaff":          nectecOnly,
+	"QueryNectecStaffByCertId":    anyRole,
+	"ReadNectecStaff":             anyRole,
+	"GetAllNectecStaff":           anyRole,
+	"FilterNstdaStaff":            anyRole,
+}
+
+// DefaultAccessPolicy is used until a policy is written with SetAccessPolicy.
+// Fabric CA admins (hf.Type=admin) are treated as nectec so that they can
+// bootstrap the on-chain policy.
+func DefaultAccessPolicy() *models.AccessPolicy {
+	policy := &models.AccessPolicy{
+		MspRoles: map[string][]models.Role{},
+		AttributeRoles: []models.AttributeRole{
+			{Attribute: "hf.Type", Value: "admin", Role: models.RoleNectec},
+		},
+		Functions: map[string][]models.Role{},
+		DocType:   models.Config,
+	}
+
+	for _, role := range models.AllRoles {
+		policy.AttributeRoles = append(policy.AttributeRoles,
+			models.AttributeRole{Attribute: "role", Value: string(role), Role: role},
+			models.AttributeRole{Attribute: string(role) + ".creator", Value: "true", Role: role},
+		)
+	}
+
+	return policy
+}
+
+func GetAccessPolicy(ctx contractapi.TransactionContextInterface) (*models.AccessPolicy, error) {
+	var policy models.AccessPolicy
+	found, err := GetConfig(ctx, AccessPolicyConfig, &policy)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return DefaultAccessPolicy(), nil
+	}
+	return &policy, nil
+}
+
+func ValidateAccessPolicy(policy *models.AccessPolicy) error {
+	check := func(roles []models.Role, where string) error {
+		for _, role := range roles {
+			if !role.IsValid() {
+				return fmt.Errorf("unknown role %q in %s", role, where)
+			}
+		}
+		return nil
+	}
+
+	for msp, roles := range policy.MspRoles {
+		if err := check(roles, "mspRoles."+msp); err != nil {
+			return err
+		}
+	}
+	for _, attr := range policy.AttributeRoles {
+		if attr.Attribute == "" {
+			return fmt.Errorf("attributeRoles entry for role %q has no attribute", attr.Role)
+		}
+		if err := check([]models.Role{attr.Role}, "attributeRoles."+attr.Attribute); err != nil {
+			return err
+		}
+	}
+	for fn, roles := range policy.Functions {
+		if err := check(roles, "functions."+fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FunctionRoles returns the roles allowed to call fn under policy and
+// whether fn is known at all.
+func FunctionRoles(policy *models.AccessPolicy, fn string) ([]models.Role, bool) {
+	if roles, ok := policy.Functions[fn]; ok {
+		return roles, true
+	}
+	roles, ok := DefaultFunctionRoles[fn]
+	return roles, ok
+}
+
+func GetCallerAccess(ctx contractapi.TransactionContextInterface) (*models.CallerAccess, error) {
+	policy, err := GetAccessPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return callerAccess(ctx, policy)
+}
+
+func callerAccess(ctx contractapi.TransactionContextInterface, policy *models.AccessPolicy) (*models.CallerAccess, error) {
+	clientID, err := GetIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+
+	var roles []models.Role
+	seen := map[models.Role]bool{}
+	grant := func(role models.Role) {
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	for _, role := range policy.MspRoles[mspID] {
+		grant(role)
+	}
+
+	for _, attr := range policy.AttributeRoles {
+		value, found, err := ctx.GetClientIdentity().GetAttributeValue(attr.Attribute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attribute %s: %w", attr.Attribute, err)
+		}
+		if !found {
+			continue
+		}
+		for _, v := range strings.Split(value, ",") {
+			if strings.TrimSpace(v) == attr.Value {
+				grant(attr.Role)
+				break
+			}
+		}
+	}
+
+	if roles == nil {
+		roles = []models.Role{}
+	}
+
+	return &models.CallerAccess{
+		Id:    clientID,
+		MspId: mspID,
+		Roles: roles,
+	}, nil
+}
+
+func hasAnyRole(roles []models.Role, required []models.Role) bool {
+	for _, role := range roles {
+		for _, r := range required {
+			if role == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FunctionName strips the contract namespace from a chaincode function name
+// and capitalises it the same way contractapi does when dispatching.
+func FunctionName(nsFcn string) string {
+	fn := nsFcn
+	if i := strings.LastIndex(nsFcn, ":"); i != -1 {
+		fn = nsFcn[i+1:]
+	}
+	if fn == "" {
+		return fn
+	}
+	return strings.ToUpper(fn[:1]) + fn[1:]
+}
+
+// AssertFunctionAccess checks the caller against the roles configured for fn.
+func AssertFunctionAccess(ctx contractapi.TransactionContextInterface, fn string) error {
+	policy, err := GetAccessPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	caller, err := callerAccess(ctx, policy)
+	if err != nil {
+		return err
+	}
+
+	required, known := FunctionRoles(policy, fn)
+	if !known {
+		return &AccessDeniedError{
+			Function: fn,
+			ClientId: caller.Id,
+			MspId:    caller.MspId,
+			Roles:    caller.Roles,
+			Reason:   "function is not covered by the access policy",
+		}
+	}
+
+	if !hasAnyRole(caller.Roles, required) {
+		return &AccessDeniedError{
+			Function: fn,
+			ClientId: caller.Id,
+			MspId:    caller.MspId,
+			Roles:    caller.Roles,
+			Required: required,
+		}
+	}
+
+	return nil
+}
+
+// AssertOwnerOrRole allows the asset owner, or any caller holding one of
+// roles, to modify an asset.
+func AssertOwnerOrRole(ctx contractapi.TransactionContextInterface, owner string, roles ...models.Role) error {
+	caller, err := GetCallerAccess(ctx)
+	if err != nil {
+		return err
+	}
+
+	if caller.Id == owner || hasAnyRole(caller.Roles, roles) {
+		return nil
+	}
+
+	fn, _ := ctx.GetStub().GetFunctionAndParameters()
+	return &AccessDeniedError{
+		Function: FunctionName(fn),
+		ClientId: caller.Id,
+		MspId:    caller.MspId,
+		Roles:    caller.Roles,
+		Required: roles,
+		Reason:   UNAUTHORIZE,
+	}
+}
+
+// AssertStaffOrOwner is the ownership check used by update and delete
+// transactions: nectec and regulator staff may act on any record.
+func AssertStaffOrOwner(ctx contractapi.TransactionContextInterface, owner string) error {
+	return AssertOwnerOrRole(ctx, owner, staffRoles...)
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// BatchRowError rejects a single row of a batch import. Validators return it
+// to classify the problem; any other error aborts the whole import.
+type BatchRowError struct {
+	Status  models.BatchRowStatus
+	Field   string
+	Message string
+}
+
+func (e *BatchRowError) Error() string {
+	return e.Message
+}
+
+func InvalidField(field string, format string, a ...interface{}) error {
+	return &BatchRowError{Status: models.BatchRowInvalidField, Field: field, Message: fmt.Sprintf(format, a...)}
+}
+
+func UnknownReference(field string, format string, a ...interface{}) error {
+	return &BatchRowError{Status: models.BatchRowUnknownReference, Field: field, Message: fmt.Sprintf(format, a...)}
+}
+
+// RequireField rejects a row whose field is empty.
+func RequireField(field string, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return InvalidField(field, "%s is required", field)
+	}
+	return nil
+}
+
+// CertificateDateField rejects a row whose certificate date cannot be parsed.
+// Empty dates are allowed.
+func CertificateDateField(field string, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := ParseCertificateDate(value, false); err != nil {
+		return InvalidField(field, "%s: %s", field, ErrorMessage(err))
+	}
+	return nil
+}
+
+// CertificateReference classifies the error of a certificate check on field:
+// certificates that do not exist are unknown references and certificates
+// that are not in force are invalid fields.
+func CertificateReference(field string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrUnknownCertificate):
+		return UnknownReference(field, "%s", ErrorMessage(err))
+	default:
+		return InvalidField(field, "%s", ErrorMessage(err))
+	}
+}
+
+// BatchMeta holds what every asset written by one batch import shares.
+type BatchMeta struct {
+	Owner     string
+	OrgName   string
+	Timestamp string
+}
+
+// BatchImport creates records of type T from a JSON array. Rows are checked
+// for a missing id, duplicate ids and the Unique keys of Repository within
+// the batch and on the ledger, and then with Validate. Build turns a valid row
+// into the record to store under its id. Seal, when set, moves the personal
+// fields of a built record into a private data collection. Fill, when set,
+// makes the copies the built records hold of other records, for all of them
+// at once. The records are then written together through Repository, like
+// any other write of a T.
+type BatchImport[T any] struct {
+	Function   string
+	Repository Repository[T]
+	Validate   func(ctx contractapi.TransactionContextInterface, row *T) error
+	Build      func(row *T, meta BatchMeta) *T
+	Seal       func(ctx contractapi.TransactionContextInterface, asset *T) error
+	Fill       func(ctx contractapi.TransactionContextInterface, assets []*T) error
+}
+
+// Run imports args. A dry run only reports the status of every row. Otherwise
+// the rows are written only if all of them are valid, and a rejected batch
+// fails with an error listing the rows at fault. Dry runs and committed
+// imports record their summary for the event of the transaction.
+func (b BatchImport[T]) Run(ctx contractapi.TransactionContextInterface, args string, dryRun bool) (*models.BatchImportResult, error) {
+	var rows []T
+	if err := DecodeStrict(args, &rows); err != nil {
+		return nil, err
+	}
+
+	clientID, err := GetIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitting client's identity: %w", err)
+	}
+	orgName, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	timestamp, err := GenerateTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	meta := BatchMeta{Owner: clientID, OrgName: orgName, Timestamp: timestamp}
+
+	result := &models.BatchImportResult{
+		BatchSummary: models.BatchSummary{
+			Function: b.Function,
+			DocType:  b.Repository.DocType,
+			DryRun:   dryRun,
+			Total:    len(rows),
+		},
+		Rows: []models.BatchRowResult{},
+	}
+
+	seen := map[string]bool{}
+	seenKeys := map[UniqueIndex]map[string]bool{}
+	for _, field := range b.Repository.Unique {
+		seenKeys[field.Index] = map[string]bool{}
+	}
+	for i := range rows {
+		row := &rows[i]
+		id := b.Repository.Id(row)
+
+		rowErr, err := b.check(ctx, row, id, seen, seenKeys)
+		if err != nil {
+			return nil, fmt.Errorf("row %d (%s): %s", i+1, id, ErrorMessage(err))
+		}
+		seen[id] = true
+		for _, field := range b.Repository.Unique {
+			seenKeys[field.Index][field.Value(row)] = true
+		}
+
+		rowResult := models.BatchRowResult{Row: i + 1, Id: id, Status: models.BatchRowOk}
+		if rowErr != nil {
+			rowResult.Status = rowErr.Status
+			rowResult.Field = rowErr.Field
+			rowResult.Message = rowErr.Message
+			result.Failed++
+		} else {
+			result.Ok++
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	if !dryRun && result.Failed == 0 {
+		assets := make([]*T, 0, len(rows))
+		for i := range rows {
+			asset := b.Build(&rows[i], meta)
+			if b.Seal != nil {
+				if err := b.Seal(ctx, asset); err != nil {
+					return nil, fmt.Errorf("row %d (%s): %s", i+1, result.Rows[i].Id, ErrorMessage(err))
+				}
+			}
+			assets = append(assets, asset)
+		}
+		if b.Fill != nil {
+			if err := b.Fill(ctx, assets); err != nil {
+				return nil, err
+			}
+		}
+		if err := b.Repository.PutAll(ctx, assets); err != nil {
+			return nil, err
+		}
+		result.Committed = true
+	}
+
+	if !dryRun && !result.Committed {
+		return result, &Error{
+			Code: models.ErrValidation,
+			Message: fmt.Sprintf("%s rejected %d of %d rows, nothing was written: %s",
+				b.Function, result.Failed, result.Total, summarizeRejectedRows(result.Rows)),
+			Details: result.Rows,
+		}
+	}
+
+	RecordBatch(ctx, result.BatchSummary)
+	return result, nil
+}
+
+// check returns the reason row cannot be imported, or an error if the check
+// itself failed.
+func (b BatchImport[T]) check(ctx contractapi.TransactionContextInterface, row *T, id string, seen map[string]bool, seenKeys map[UniqueIndex]map[string]bool) (*BatchRowError, error) {
+	if err := RequireField("id", id); err != nil {
+		return err.(*BatchRowError), nil
+	}
+	if seen[id] {
+		return &BatchRowError{Status: models.BatchRowDuplicate, Field: "id", Message: fmt.Sprintf("the asset %s appears more than once in the batch", id)}, nil
+	}
+
+	exists, err := AssetExists(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error checking if asset exists: %w", err)
+	}
+	if exists {
+		return &BatchRowError{Status: models.BatchRowDuplicate, Field: "id", Message: fmt.Sprintf("the asset %s already exists", id)}, nil
+	}
+
+	for _, field := range b.Repository.Unique {
+		value := field.Value(row)
+		if value == "" {
+			continue
+		}
+		if seenKeys[field.Index][value] {
+			return &BatchRowError{Status: models.BatchRowDuplicate, Field: field.Index.Field, Message: fmt.Sprintf("the %s %s appears more than once in the batch", field.Index, value)}, nil
+		}
+		holder, err := field.Index.Holder(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		if holder != "" {
+			return &BatchRowError{Status: models.BatchRowDuplicate, Field: field.Index.Field, Message: fmt.Sprintf("the %s %s is already used by %s", field.Index, value, holder)}, nil
+		}
+	}
+
+	if errs := ValidateStruct(row); len(errs) > 0 {
+		return InvalidField(errs[0].Field, "%s %s", errs[0].Field, errs[0].Message).(*BatchRowError), nil
+	}
+
+	if b.Validate == nil {
+		return nil, nil
+	}
+	err = b.Validate(ctx, row)
+	var rowErr *BatchRowError
+	if errors.As(err, &rowErr) {
+		return rowErr, nil
+	}
+	return nil, err
+}
+
+func summarizeRejectedRows(rows []models.BatchRowResult) string {
+	var parts []string
+	for _, row := range rows {
+		if row.Status != models.BatchRowOk {
+			parts = append(parts, fmt.Sprintf("row %d (%s) %s: %s", row.Row, row.Id, row.Status, row.Message))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// CachingStub passes every call through to the stub of a transaction and
+// keeps the state and rich query results it reads. A transaction only ever
+// reads committed state, so reading a key or running a query a second time
+// gives the same answer and is served without going back to the state
+// database.
+type CachingStub struct {
+	shim.ChaincodeStubInterface
+
+	states  map[string][]byte
+	queries map[string][]*queryresult.KV
+}
+
+func NewCachingStub(stub shim.ChaincodeStubInterface) *CachingStub {
+	return &CachingStub{
+		ChaincodeStubInterface: stub,
+		states:                 map[string][]byte{},
+		queries:                map[string][]*queryresult.KV{},
+	}
+}
+
+func (s *CachingStub) GetState(key string) ([]byte, error) {
+	if value, ok := s.states[key]; ok {
+		return value, nil
+	}
+	value, err := s.ChaincodeStubInterface.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	s.states[key] = value
+	return value, nil
+}
+
+func (s *CachingStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	if kvs, ok := s.queries[query]; ok {
+		return &cachedIterator{kvs: kvs}, nil
+	}
+	resultsIterator, err := s.ChaincodeStubInterface.GetQueryResult(query)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	kvs := []*queryresult.KV{}
+	for resultsIterator.HasNext() {
+		kv, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next query result: %w", err)
+		}
+		kvs = append(kvs, kv)
+	}
+	s.queries[query] = kvs
+	return &cachedIterator{kvs: kvs}, nil
+}
+
+// cachedIterator replays the results of a query read earlier.
+type cachedIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (it *cachedIterator) HasNext() bool {
+	return len(it.kvs) > 0
+}
+
+func (it *cachedIterator) Next() (*queryresult.KV, error) {
+	if len(it.kvs) == 0 {
+		return nil, fmt.Errorf("no more query results")
+	}
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+func (it *cachedIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// ErrUnknownCertificate is wrapped by the errors of certificate checks when
+// the certificate does not exist.
+var ErrUnknownCertificate = errors.New("does not exist")
+
+// certificateDateFormats are tried in order when parsing certificate dates.
+// Dates without a time cover the whole day in Thai time.
+var certificateDateFormats = []struct {
+	layout  string
+	dayOnly bool
+}{
+	{time.RFC3339, false},
+	{"2006-01-02", true},
+	{DATEFORMAT, true},
+}
+
+// ParseCertificateDate parses an issue or expiry date. For dates without a
+// time, endOfDay selects the last second of the day instead of the first.
+func ParseCertificateDate(value string, endOfDay bool) (time.Time, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), "–", "-")
+	location := time.FixedZone("UTC+7", offset*3600)
+	for _, format := range certificateDateFormats {
+		parsed, err := time.ParseInLocation(format.layout, value, location)
+		if err != nil {
+			continue
+		}
+		if format.dayOnly && endOfDay {
+			parsed = parsed.Add(24*time.Hour - time.Second)
+		}
+		return parsed, nil
+	}
+	return time.Time{}, Validation("invalid date %q", value)
+}
+
+// AssertCertificateValid checks that a certificate is in force at now. Empty
+// dates are not checked.
+func AssertCertificateValid(kind, id, issueDate, expireDate string, now time.Time) error {
+	if issueDate != "" {
+		issued, err := ParseCertificateDate(issueDate, false)
+		if err != nil {
+			return Validation("%s %s has an invalid issue date: %s", kind, id, ErrorMessage(err))
+		}
+		if now.Before(issued) {
+			return Validation("%s %s is not valid until %s", kind, id, issueDate)
+		}
+	}
+	if expireDate != "" {
+		expires, err := ParseCertificateDate(expireDate, true)
+		if err != nil {
+			return Validation("%s %s has an invalid expire date: %s", kind, id, ErrorMessage(err))
+		}
+		if now.After(expires) {
+			return Validation("%s %s expired on %s", kind, id, expireDate)
+		}
+	}
+	return nil
+}
+
+func findGmpByRegisterNumber(ctx contractapi.TransactionContextInterface, registerNumber string) (*models.TransactionGmp, error) {
+	var gmp models.TransactionGmp
+	found, err := GmpRegisterNumberIndex.Get(ctx, registerNumber, &gmp)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &gmp, nil
+}
+
+// AssertGapCertificate checks that the GAP certificate certID exists and is
+// in force at the transaction timestamp.
+func AssertGapCertificate(ctx contractapi.TransactionContextInterface, certID string) error {
+	now, err := GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	gap, err := findGapByCertID(ctx, certID)
+	if err != nil {
+		return err
+	}
+	if gap == nil {
+		return NotFound("gap certificate %s %w", certID, ErrUnknownCertificate)
+	}
+	return AssertCertificateValid("gap certificate", certID, gap.IssueDate, gap.ExpireDate, now)
+}
+
+// AssertGmpCertificate checks that the GMP certificate with the packing house
+// register number registerNumber exists and is in force at the transaction
+// timestamp.
+func AssertGmpCertificate(ctx contractapi.TransactionContextInterface, registerNumber string) error {
+	now, err := GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	gmp, err := findGmpByRegisterNumber(ctx, registerNumber)
+	if err != nil {
+		return err
+	}
+	if gmp == nil {
+		return NotFound("gmp certificate %s %w", registerNumber, ErrUnknownCertificate)
+	}
+	return AssertCertificateValid("gmp certificate", registerNumber, gmp.IssueDate, gmp.ExpireDate, now)
+}
+
+// AssertCertificates checks the GAP and GMP certificates a packing or
+// packaging refers to. Empty references are not checked.
+func AssertCertificates(ctx contractapi.TransactionContextInterface, gap, gmp string) error {
+	if gap != "" {
+		if err := AssertGapCertificate(ctx, gap); err != nil {
+			return err
+		}
+	}
+	if gmp != "" {
+		if err := AssertGmpCertificate(ctx, gmp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertExporterPlantTypes checks that the exporter holds at least one plant
+// type certificate in force at the transaction timestamp.
+func AssertExporterPlantTypes(ctx contractapi.TransactionContextInterface, exporterId string) error {
+	if exporterId == "" {
+		return Validation("a form E needs the exporter it was created by")
+	}
+	now, err := GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	query, err := NewSelector(models.PlantType).Eq("exporterId", exporterId).Query().Build()
+	if err != nil {
+		return err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
+	if err != nil {
+		return fmt.Errorf("failed to query plant types of exporter %s: %w", exporterId, err)
+	}
+	defer resultsIterator.Close()
+
+	var lastErr error
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		var plantType models.PlantTypeModel
+		if err := json.Unmarshal(queryResponse.Value, &plantType); err != nil {
+			return err
+		}
+
+		lastErr = AssertCertificateValid("plant type certificate", plantType.Id, plantType.IssueDate, plantType.ExpiredDate, now)
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	if lastErr != nil {
+		return Validation("exporter %s has no plant type certificate in force: %s", exporterId, ErrorMessage(lastErr))
+	}
+	return Validation("exporter %s has no plant type certificate", exporterId)
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const configObjectType = "config"
+
+// ConfigKey returns the composite key a named configuration document is
+// stored under. Composite keys keep configuration out of range scans.
+func ConfigKey(ctx contractapi.TransactionContextInterface, name string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(configObjectType, []string{name})
+}
+
+// GetConfig loads the named configuration document into out. It reports
+// false when the document has never been written.
+func GetConfig(ctx contractapi.TransactionContextInterface, name string, out interface{}) (bool, error) {
+	key, err := ConfigKey(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config %s: %w", name, err)
+	}
+	if configJSON == nil {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(configJSON, out); err != nil {
+		return false, fmt.Errorf("failed to unmarshal config %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func PutConfig(ctx contractapi.TransactionContextInterface, name string, value interface{}) error {
+	key, err := ConfigKey(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	configJSON, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config %s: %w", name, err)
+	}
+
+	return ctx.GetStub().PutState(key, configJSON)
+}
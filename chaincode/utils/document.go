@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// DocumentKey returns the ledger key of the attachment of the file with
+// digest sha256 to assetId, so the same file is only attached once. Composite
+// keys are not used as they are left out of rich query results.
+func DocumentKey(assetId string, sha256 string) string {
+	return fmt.Sprintf("%s:%s:%s", models.Document, assetId, sha256)
+}
+
+// NormalizeSha256 lower-cases a hex encoded SHA-256 digest and checks its
+// length.
+func NormalizeSha256(digest string) (string, error) {
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	raw, err := hex.DecodeString(digest)
+	if err != nil || len(raw) != 32 {
+		return "", Validation("sha256 must be a hex encoded SHA-256 digest")
+	}
+	return digest, nil
+}
+
+func ValidateAttachDocumentInput(input *models.AttachDocumentInput) error {
+	digest, err := NormalizeSha256(input.Sha256)
+	if err != nil {
+		return err
+	}
+	input.Sha256 = digest
+
+	if _, _, err := mime.ParseMediaType(input.MediaType); err != nil {
+		return Validation("mediaType %q is not a valid media type", input.MediaType)
+	}
+	uri, err := url.Parse(input.StorageUri)
+	if err != nil || uri.Scheme == "" {
+		return Validation("storageUri %q must be an absolute URI", input.StorageUri)
+	}
+	return nil
+}
+
+// AssetHeader holds the fields every stored asset shares.
+type AssetHeader struct {
+	DocType models.DocType `json:"docType"`
+	Owner   string         `json:"owner"`
+	models.Tombstone
+}
+
+// ReadAssetOwner returns the owner of the asset stored under id, checking that
+// it exists, is of docType and has not been deleted.
+func ReadAssetOwner(ctx contractapi.TransactionContextInterface, id string, docType models.DocType) (string, error) {
+	header, err := ReadAssetHeader(ctx, id, docType)
+	if err != nil {
+		return "", err
+	}
+	if header.IsDeleted() {
+		return "", DeletedError(id)
+	}
+	return header.Owner, nil
+}
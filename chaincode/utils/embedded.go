@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Change is one write to a record. Previous is nil for a new record and
+// Current is nil for a deleted one.
+type Change[T any] struct {
+	Previous *T
+	Current  *T
+}
+
+// Embedding keeps what is derived from records of type T up to date, such as
+// the copies other records embed of them. Repositories and batch imports pass
+// it every write to a T, all writes of a transaction at once: the
+// transaction does not read its own writes, so a copy made from one write at
+// a time would miss the others.
+type Embedding[T any] interface {
+	Changed(ctx contractapi.TransactionContextInterface, changes []Change[T]) error
+}
+
+// DriftReporter finds embedded copies that no longer match what they copy.
+type DriftReporter interface {
+	Drift(ctx contractapi.TransactionContextInterface) ([]models.EmbeddedDrift, error)
+}
+
+// Embedded declares that records of type P embed a copy of the live records
+// of type S linked to them, those whose Link field holds the id of the P
+// record. Set makes the copy from the linked records, ordered by id, and may
+// change any field of P that is derived from them.
+type Embedded[P any, S any] struct {
+	Parents  Repository[P]
+	Source   models.DocType
+	Link     string
+	SourceId func(source *S) string
+	ParentId func(source *S) string
+	Set      func(parent *P, sources []*S)
+}
+
+// Changed rewrites the copies held by the parents the changed records were
+// or are now linked to. Parents that do not exist or are deleted are left
+// alone.
+func (e Embedded[P, S]) Changed(ctx contractapi.TransactionContextInterface, changes []Change[S]) error {
+	var parentIds []string
+	seen := map[string]bool{}
+	changed := map[string]*S{}
+	for _, change := range changes {
+		for _, source := range []*S{change.Previous, change.Current} {
+			if source == nil {
+				continue
+			}
+			changed[e.SourceId(source)] = change.Current
+			if parentId := e.ParentId(source); parentId != "" && !seen[parentId] {
+				seen[parentId] = true
+				parentIds = append(parentIds, parentId)
+			}
+		}
+	}
+
+	for _, parentId := range parentIds {
+		parent, err := e.Parents.Get(ctx, parentId)
+		if err != nil {
+			if CatalogueError(err).Code == models.ErrNotFound {
+				continue
+			}
+			return err
+		}
+
+		stored, err := FetchAll[S](ctx, NewSelector(e.Source).Eq(e.Link, parentId).Query())
+		if err != nil {
+			return err
+		}
+		// The query reads the committed state, so the changed records take
+		// the place of their stored versions.
+		var sources []*S
+		for _, source := range stored {
+			if _, ok := changed[e.SourceId(source)]; !ok {
+				sources = append(sources, source)
+			}
+		}
+		for _, current := range changed {
+			if current != nil && e.ParentId(current) == parentId {
+				sources = append(sources, current)
+			}
+		}
+
+		before, err := json.Marshal(parent)
+		if err != nil {
+			return err
+		}
+		e.set(parent, sources)
+		after, err := json.Marshal(parent)
+		if err != nil {
+			return err
+		}
+		if string(before) == string(after) {
+			continue
+		}
+		if err := e.Parents.Update(ctx, parent); err != nil {
+			return fmt.Errorf("failed to update the copies held by %s: %w", parentId, err)
+		}
+	}
+	return nil
+}
+
+// Fill makes the copies held by parents the transaction is about to write
+// from the live records linked to them, in place of whatever the client sent.
+// The linked records of all parents are read in one query.
+func (e Embedded[P, S]) Fill(ctx contractapi.TransactionContextInterface, parents []*P) error {
+	if len(parents) == 0 {
+		return nil
+	}
+	parentIds := make([]string, 0, len(parents))
+	for _, parent := range parents {
+		parentIds = append(parentIds, e.Parents.Id(parent))
+	}
+	sources, err := FetchAll[S](ctx, NewSelector(e.Source).In(e.Link, parentIds).Query())
+	if err != nil {
+		return err
+	}
+	linked := map[string][]*S{}
+	for _, source := range sources {
+		linked[e.ParentId(source)] = append(linked[e.ParentId(source)], source)
+	}
+	for _, parent := range parents {
+		e.set(parent, linked[e.Parents.Id(parent)])
+	}
+	return nil
+}
+
+func (e Embedded[P, S]) set(parent *P, sources []*S) {
+	sort.SliceStable(sources, func(i, j int) bool {
+		return e.SourceId(sources[i]) < e.SourceId(sources[j])
+	})
+	e.Set(parent, sources)
+}
+
+// Drift reports the live parents whose stored fields differ from what Set
+// makes of the records linked to them, and the records linked to a parent
+// that does not exist.
+func (e Embedded[P, S]) Drift(ctx contractapi.TransactionContextInterface) ([]models.EmbeddedDrift, error) {
+	sources, err := FetchAll[S](ctx, NewSelector(e.Source).Query())
+	if err != nil {
+		return nil, err
+	}
+	linked := map[string][]*S{}
+	for _, source := range sources {
+		if parentId := e.ParentId(source); parentId != "" {
+			linked[parentId] = append(linked[parentId], source)
+		}
+	}
+
+	parents, err := FetchAll[map[string]json.RawMessage](ctx, e.Parents.Selector().Query())
+	if err != nil {
+		return nil, err
+	}
+	drift := []models.EmbeddedDrift{}
+	for _, stored := range parents {
+		var id string
+		_ = json.Unmarshal((*stored)["id"], &id)
+
+		fields, err := e.driftedFields(*stored, linked[id])
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) > 0 {
+			drift = append(drift, models.EmbeddedDrift{DocType: e.Parents.DocType, Id: id, Fields: fields})
+		}
+		delete(linked, id)
+	}
+
+	for parentId, orphans := range linked {
+		for _, orphan := range orphans {
+			drift = append(drift, models.EmbeddedDrift{
+				DocType: e.Source,
+				Id:      e.SourceId(orphan),
+				Fields:  []string{e.Link},
+				Message: fmt.Sprintf("%s %s does not exist", e.Parents.DocType, parentId),
+			})
+		}
+	}
+	sort.SliceStable(drift, func(i, j int) bool {
+		if drift[i].DocType != drift[j].DocType {
+			return drift[i].DocType < drift[j].DocType
+		}
+		return drift[i].Id < drift[j].Id
+	})
+	return drift, nil
+}
+
+// driftedFields returns the fields of the stored parent that Set changes.
+func (e Embedded[P, S]) driftedFields(stored map[string]json.RawMessage, sources []*S) ([]string, error) {
+	storedJSON, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	var parent P
+	if err := json.Unmarshal(storedJSON, &parent); err != nil {
+		return nil, err
+	}
+	before, err := jsonFields(&parent)
+	if err != nil {
+		return nil, err
+	}
+	e.set(&parent, sources)
+	after, err := jsonFields(&parent)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for field, value := range after {
+		if string(before[field]) != string(value) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+func jsonFields(v interface{}) (map[string]json.RawMessage, error) {
+	valueJSON, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(valueJSON, &fields)
+	return fields, err
+}
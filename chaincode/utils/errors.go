@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Error is an error from the catalogue. Its message is the error encoded as
+// JSON, which is what a failed transaction returns to the gateway.
+type Error struct {
+	Code    models.ErrorCode `json:"code"`
+	Message string           `json:"message"`
+	Details interface{}      `json:"details,omitempty"`
+	cause   error
+}
+
+func (e *Error) Error() string {
+	errJSON, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"code":%q,"message":%q}`, e.Code, e.Message)
+	}
+	return string(errJSON)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) catalogue() *Error {
+	return e
+}
+
+// catalogued is implemented by the errors that belong to the catalogue.
+type catalogued interface {
+	error
+	catalogue() *Error
+}
+
+func newError(code models.ErrorCode, format string, a ...interface{}) *Error {
+	err := fmt.Errorf(format, a...)
+	return &Error{Code: code, Message: err.Error(), cause: errors.Unwrap(err)}
+}
+
+// NotFound reports a record that does not exist or has been deleted.
+func NotFound(format string, a ...interface{}) error {
+	return newError(models.ErrNotFound, format, a...)
+}
+
+// AlreadyExists reports an id or unique value that is already taken.
+func AlreadyExists(format string, a ...interface{}) error {
+	return newError(models.ErrAlreadyExists, format, a...)
+}
+
+// Unauthorized reports a caller that may not do what it asked.
+func Unauthorized(format string, a ...interface{}) error {
+	return newError(models.ErrUnauthorized, format, a...)
+}
+
+// Validation reports arguments that are malformed or break a rule.
+func Validation(format string, a ...interface{}) error {
+	return newError(models.ErrValidation, format, a...)
+}
+
+// Conflict reports a request that the current state of the ledger does not
+// allow, such as an illegal status change.
+func Conflict(format string, a ...interface{}) error {
+	return newError(models.ErrConflict, format, a...)
+}
+
+// Internal reports a failure of the chaincode or the peer.
+func Internal(format string, a ...interface{}) error {
+	return newError(models.ErrInternal, format, a...)
+}
+
+// CatalogueError returns err as an Error. An error wrapping a catalogued one
+// with %w keeps its code and details, with the wrapping text in front of its
+// message. Errors outside the catalogue are INTERNAL.
+func CatalogueError(err error) *Error {
+	var c catalogued
+	if !errors.As(err, &c) {
+		return &Error{Code: models.ErrInternal, Message: err.Error(), cause: err}
+	}
+	inner := c.catalogue()
+	if err == error(c) {
+		return inner
+	}
+	return &Error{
+		Code:    inner.Code,
+		Message: strings.Replace(err.Error(), c.Error(), inner.Message, 1),
+		Details: inner.Details,
+		cause:   err,
+	}
+}
+
+// ErrorMessage returns the message of err without the JSON encoding, for
+// errors reported as part of another.
+func ErrorMessage(err error) string {
+	return CatalogueError(err).Message
+}
+
+// ParseError decodes the message of a failed transaction. The message of an
+// error wrapping a catalogued one is decoded as CatalogueError would. Messages
+// that are not a catalogued error are returned as INTERNAL.
+func ParseError(message string) *Error {
+	var e Error
+	if err := json.Unmarshal([]byte(message), &e); err == nil && e.Code != "" {
+		return &e
+	}
+	if i := strings.Index(message, `{"code":`); i > 0 {
+		if err := json.Unmarshal([]byte(message[i:]), &e); err == nil && e.Code != "" {
+			e.Message = message[:i] + e.Message
+			return &e
+		}
+	}
+	return &Error{Code: models.ErrInternal, Message: message}
+}
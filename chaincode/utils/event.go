@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const StateChangeEvent = "stateChange"
+
+// compositeKeyNamespace starts every composite key, see shim.CreateCompositeKey.
+const compositeKeyNamespace = "\x00"
+
+// RecordingStub passes every call through to the stub of a transaction and
+// records the public state it writes, so the whole transaction can be
+// reported in one event. Private data is not recorded.
+type RecordingStub struct {
+	shim.ChaincodeStubInterface
+
+	changes []*recordedChange
+	byKey   map[string]*recordedChange
+	batch   *models.BatchSummary
+}
+
+type recordedChange struct {
+	key    string
+	before []byte
+	after  []byte
+}
+
+func NewRecordingStub(stub shim.ChaincodeStubInterface) *RecordingStub {
+	return &RecordingStub{ChaincodeStubInterface: stub, byKey: map[string]*recordedChange{}}
+}
+
+func (s *RecordingStub) PutState(key string, value []byte) error {
+	if err := s.record(key, value); err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.PutState(key, value)
+}
+
+func (s *RecordingStub) DelState(key string) error {
+	if err := s.record(key, nil); err != nil {
+		return err
+	}
+	return s.ChaincodeStubInterface.DelState(key)
+}
+
+// record keeps the committed value of key from its first write and the value
+// of its last one.
+func (s *RecordingStub) record(key string, value []byte) error {
+	if change, ok := s.byKey[key]; ok {
+		change.after = value
+		return nil
+	}
+	before, err := s.ChaincodeStubInterface.GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read state of %s: %w", key, err)
+	}
+	change := &recordedChange{key: key, before: before, after: value}
+	s.changes = append(s.changes, change)
+	s.byKey[key] = change
+	return nil
+}
+
+// Changes returns the records written so far, in the order they were first
+// written. Records written back unchanged are left out.
+func (s *RecordingStub) Changes() []models.StateChange {
+	changes := []models.StateChange{}
+	for _, c := range s.changes {
+		if string(c.before) == string(c.after) {
+			continue
+		}
+		changes = append(changes, s.stateChange(c))
+	}
+	return changes
+}
+
+func (s *RecordingStub) stateChange(c *recordedChange) models.StateChange {
+	before := decodeHeader(c.before)
+	after := decodeHeader(c.after)
+
+	change := models.StateChange{
+		Id:           c.key,
+		BeforeDigest: digest(c.before),
+		AfterDigest:  digest(c.after),
+	}
+	if strings.HasPrefix(c.key, compositeKeyNamespace) {
+		if objectType, attributes, err := s.SplitCompositeKey(c.key); err == nil {
+			change.DocType = models.DocType(objectType)
+			change.Id = strings.Join(attributes, ":")
+		}
+	}
+	if after != nil && after.DocType != "" {
+		change.DocType = after.DocType
+	} else if before != nil && before.DocType != "" {
+		change.DocType = before.DocType
+	}
+
+	switch {
+	case before == nil:
+		change.Type = models.ChangeCreate
+	case after == nil || (!before.IsDeleted() && after.IsDeleted()):
+		change.Type = models.ChangeDelete
+	case before.IsDeleted() && !after.IsDeleted():
+		change.Type = models.ChangeRestore
+	case before.Owner != after.Owner:
+		change.Type = models.ChangeTransfer
+	default:
+		change.Type = models.ChangeUpdate
+	}
+	return change
+}
+
+func decodeHeader(value []byte) *AssetHeader {
+	if value == nil {
+		return nil
+	}
+	header := &AssetHeader{}
+	// Values that are not JSON objects still count as present
+	_ = json.Unmarshal(value, header)
+	return header
+}
+
+func digest(value []byte) string {
+	if value == nil {
+		return ""
+	}
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordBatch adds the summary of a batch import to the StateChangeEvent of
+// the transaction, which is then emitted even by a dry run that wrote
+// nothing.
+func RecordBatch(ctx contractapi.TransactionContextInterface, summary models.BatchSummary) {
+	if stub, ok := ctx.GetStub().(*RecordingStub); ok {
+		stub.batch = &summary
+	}
+}
+
+// EmitStateChanges sets the StateChangeEvent of the transaction when its stub
+// is a RecordingStub that wrote anything or recorded a batch import. Fabric
+// keeps only the last event of a transaction, so nothing else may set one.
+func EmitStateChanges(ctx contractapi.TransactionContextInterface) error {
+	stub, ok := ctx.GetStub().(*RecordingStub)
+	if !ok {
+		return nil
+	}
+	changes := stub.Changes()
+	if len(changes) == 0 && stub.batch == nil {
+		return nil
+	}
+
+	actor, err := GetIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get submitting client's identity: %w", err)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	timestamp, err := GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	function, _ := stub.GetFunctionAndParameters()
+
+	event := models.StateChangeEvent{
+		Version:   models.EventVersion,
+		TxId:      stub.GetTxID(),
+		Function:  FunctionName(function),
+		Actor:     actor,
+		MspId:     mspID,
+		Timestamp: timestamp,
+		Changes:   changes,
+		Batch:     stub.batch,
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload JSON: %w", err)
+	}
+	if err := stub.SetEvent(StateChangeEvent, eventJSON); err != nil {
+		return fmt.Errorf("failed to set event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// FormEApplicants may submit, amend, replace and cancel the Form Es they own.
+var FormEApplicants = staffAnd(models.RolePacker, models.RoleExporter)
+
+// FormETransitions lists the allowed Form E status transitions and the roles
+// that may apply each of them. Regulators review and issue, applicants act on
+// their own certificates. Cancelled and replaced Form Es are final.
+var FormETransitions = map[models.FormEStatus]map[models.FormEStatus][]models.Role{
+	models.FormEDraft: {
+		models.FormESubmitted: FormEApplicants,
+		models.FormECancelled: FormEApplicants,
+	},
+	models.FormESubmitted: {
+		models.FormEUnderReview: staffRoles,
+		models.FormEAmended:     FormEApplicants,
+		models.FormECancelled:   FormEApplicants,
+	},
+	models.FormEUnderReview: {
+		models.FormEIssued:   staffRoles,
+		models.FormERejected: staffRoles,
+	},
+	models.FormERejected: {
+		models.FormEAmended:   FormEApplicants,
+		models.FormECancelled: FormEApplicants,
+	},
+	models.FormEAmended: {
+		models.FormESubmitted: FormEApplicants,
+		models.FormECancelled: FormEApplicants,
+	},
+	models.FormEIssued: {
+		models.FormEReplaced:  FormEApplicants,
+		models.FormECancelled: staffRoles,
+	},
+}
+
+// FormEReasonRequired lists the statuses a Form E may only be moved to with a
+// reason.
+var FormEReasonRequired = map[models.FormEStatus]bool{
+	models.FormECancelled: true,
+	models.FormERejected:  true,
+	models.FormEAmended:   true,
+	models.FormEReplaced:  true,
+}
+
+// InitialFormEStatuses are the statuses a Form E may be created in.
+var InitialFormEStatuses = []models.FormEStatus{models.FormEDraft, models.FormESubmitted}
+
+func ValidateInitialFormEStatus(status models.FormEStatus) error {
+	for _, s := range InitialFormEStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return Validation("form E cannot be created with status %s", status)
+}
+
+// AssertFormETransition checks that formE may move to status to and that the
+// caller holds a role allowed to move it. Packers and exporters may only move
+// Form Es they own.
+func AssertFormETransition(ctx contractapi.TransactionContextInterface, formE *models.TransactionFormE, to models.FormEStatus) error {
+	from := formE.Status
+	required, ok := FormETransitions[from][to]
+	if !ok {
+		return Conflict("form E %s cannot move from %s to %s", formE.Id, from, to)
+	}
+
+	caller, err := GetCallerAccess(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range caller.Roles {
+		if !hasAnyRole([]models.Role{role}, required) {
+			continue
+		}
+		switch role {
+		case models.RolePacker, models.RoleExporter:
+			if caller.Id == formE.Owner {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+
+	fn, _ := ctx.GetStub().GetFunctionAndParameters()
+	return &AccessDeniedError{
+		Function: FunctionName(fn),
+		ClientId: caller.Id,
+		MspId:    caller.MspId,
+		Roles:    caller.Roles,
+		Required: required,
+		Reason:   fmt.Sprintf("cannot move form E %s from %s to %s", formE.Id, from, to),
+	}
+}
+
+// ValidateFormEContent checks every product line of formE's invoice against
+// the ledger and returns the discrepancies found. Each line needs a registered
+// HS code, and its container and pallet numbers must match packagings created
+// by the Form E's exporter. The declared total weight must not exceed the
+// weight of the completed packing orders behind those packagings.
+func ValidateFormEContent(ctx contractapi.TransactionContextInterface, formE *models.TransactionFormE) ([]models.FormEDiscrepancy, error) {
+	discrepancies := []models.FormEDiscrepancy{}
+	if formE.Invoice == nil {
+		return discrepancies, nil
+	}
+	lines := formE.Invoice.ProductAndPackaging
+
+	var hscodes, containers []string
+	for _, line := range lines {
+		if line.HsCode != "" {
+			hscodes = append(hscodes, line.HsCode)
+		}
+		if line.ContainerNumber != "" {
+			containers = append(containers, line.ContainerNumber)
+		}
+	}
+
+	knownHscodes := map[string]bool{}
+	for _, hscode := range hscodes {
+		holder, err := HscodeIndex.Holder(ctx, hscode)
+		if err != nil {
+			return nil, err
+		}
+		knownHscodes[hscode] = holder != ""
+	}
+
+	var packagings []*models.TransactionPackaging
+	if len(containers) > 0 {
+		var err error
+		packagings, err = FetchAll[models.TransactionPackaging](ctx, NewSelector(models.Packaging).
+			Eq("createdById", formE.CreatedById).
+			In("containerId", containers).
+			Query())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Packagings shipped under the Form E, keyed by id so boxes declared on
+	// several lines are only counted once.
+	shipped := map[string]*models.TransactionPackaging{}
+	for i, line := range lines {
+		field := fmt.Sprintf("invoice.productAndPackaging[%d]", i)
+
+		if line.HsCode == "" {
+			discrepancies = append(discrepancies, models.FormEDiscrepancy{
+				Field: field + ".hscode", Message: "hscode is required",
+			})
+		} else if !knownHscodes[line.HsCode] {
+			discrepancies = append(discrepancies, models.FormEDiscrepancy{
+				Field: field + ".hscode", Value: line.HsCode, Message: "hscode is not registered",
+			})
+		}
+
+		if line.ContainerNumber == "" {
+			discrepancies = append(discrepancies, models.FormEDiscrepancy{
+				Field: field + ".containerNumber", Message: "containerNumber is required",
+			})
+			continue
+		}
+
+		var inContainer, onPallet []*models.TransactionPackaging
+		for _, packaging := range packagings {
+			if packaging.ContainerId != line.ContainerNumber {
+				continue
+			}
+			inContainer = append(inContainer, packaging)
+			if line.PalletNumber == "" || packaging.PalletNumber == line.PalletNumber {
+				onPallet = append(onPallet, packaging)
+			}
+		}
+
+		switch {
+		case len(inContainer) == 0:
+			discrepancies = append(discrepancies, models.FormEDiscrepancy{
+				Field:   field + ".containerNumber",
+				Value:   line.ContainerNumber,
+				Message: fmt.Sprintf("no packaging of exporter %s is in this container", formE.CreatedById),
+			})
+		case len(onPallet) == 0:
+			discrepancies = append(discrepancies, models.FormEDiscrepancy{
+				Field:   field + ".palletNumber",
+				Value:   line.PalletNumber,
+				Message: fmt.Sprintf("no packaging of exporter %s is on this pallet in container %s", formE.CreatedById, line.ContainerNumber),
+			})
+		}
+		for _, packaging := range onPallet {
+			shipped[packaging.Id] = packaging
+		}
+	}
+
+	if formE.Invoice.TotalWeight == "" {
+		return discrepancies, nil
+	}
+	declared, err := strconv.ParseFloat(strings.ReplaceAll(formE.Invoice.TotalWeight, ",", ""), 32)
+	if err != nil {
+		return append(discrepancies, models.FormEDiscrepancy{
+			Field: "invoice.totalWeight", Value: formE.Invoice.TotalWeight, Message: "totalWeight is not a number",
+		}), nil
+	}
+
+	completed, err := completedPackingWeight(ctx, shipped)
+	if err != nil {
+		return nil, err
+	}
+	if float32(declared) > completed+quotaTolerance {
+		discrepancies = append(discrepancies, models.FormEDiscrepancy{
+			Field:   "invoice.totalWeight",
+			Value:   formE.Invoice.TotalWeight,
+			Message: fmt.Sprintf("totalWeight exceeds the %.2f kg of completed packing orders behind the declared packagings", completed),
+		})
+	}
+
+	return discrepancies, nil
+}
+
+// completedPackingWeight sums the weight of the completed packing orders the
+// packagings were packed from. Packagings imported before they named their
+// packing order are matched on GAP certificate and packing house instead.
+func completedPackingWeight(ctx contractapi.TransactionContextInterface, packagings map[string]*models.TransactionPackaging) (float32, error) {
+	linked := map[string]bool{}
+	seen := map[[2]string]bool{}
+	var sources []*Selector
+	for _, packaging := range packagings {
+		if packaging.PackingId != "" {
+			linked[packaging.PackingId] = true
+			continue
+		}
+		source := [2]string{packaging.Gap, packaging.Gmp}
+		if packaging.Gap == "" || seen[source] {
+			continue
+		}
+		seen[source] = true
+
+		selector := NewSelector("").Eq("gap", packaging.Gap)
+		if packaging.Gmp != "" {
+			selector.Eq("gmp", packaging.Gmp)
+		}
+		sources = append(sources, selector)
+	}
+	if len(linked) > 0 {
+		ids := make([]string, 0, len(linked))
+		for id := range linked {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		sources = append(sources, NewSelector("").In("id", ids))
+	}
+	if len(sources) == 0 {
+		return 0, nil
+	}
+
+	packings, err := FetchAll[models.TransactionPacking](ctx, NewSelector(models.Packing).
+		Eq("processStatus", models.StatusCompleted).
+		Or(sources...).
+		Query())
+	if err != nil {
+		return 0, err
+	}
+
+	var weight float32
+	for _, packing := range packings {
+		weight += PackedWeight(packing)
+	}
+	return weight, nil
+}
+
+// FormEContentError reports discrepancies as a single error, or nil when there
+// are none.
+func FormEContentError(id string, discrepancies []models.FormEDiscrepancy) error {
+	if len(discrepancies) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		if d.Value != "" {
+			messages = append(messages, fmt.Sprintf("%s %q: %s", d.Field, d.Value, d.Message))
+		} else {
+			messages = append(messages, fmt.Sprintf("%s: %s", d.Field, d.Message))
+		}
+	}
+	return &Error{
+		Code:    models.ErrValidation,
+		Message: fmt.Sprintf("form E %s is invalid: %s", id, strings.Join(messages, "; ")),
+		Details: discrepancies,
+	}
+}
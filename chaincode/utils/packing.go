@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -10,71 +11,181 @@ import (
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
 )
 
-func PackingSetFilter(input *models.FilterGetAllPacking) map[string]interface{} {
-	var filter = map[string]interface{}{}
+// PackingTransitions lists the allowed packing status transitions and the
+// roles that may apply each of them. Completed and cancelled orders are final.
+var PackingTransitions = map[models.ProcessStatus]map[models.ProcessStatus][]models.Role{
+	models.StatusDraft: {
+		models.StatusPackerSaved: staffAnd(models.RolePacker),
+		models.StatusCancelled:   staffAnd(models.RolePacker),
+	},
+	models.StatusPackerSaved: {
+		models.StatusApproved:  staffAnd(models.RoleFarmer),
+		models.StatusCancelled: staffAnd(models.RolePacker, models.RoleFarmer),
+	},
+	models.StatusApproved: {
+		models.StatusCompleted: staffAnd(models.RolePacker),
+		models.StatusCancelled: staffAnd(models.RolePacker),
+	},
+}
 
-	const RegexKey = "$regex"
+// PackingEditableFields lists the fields UpdatePacking may still change once
+// an order has left draft and packerSaved: approved orders take the final
+// weight and reasons they are completed or cancelled with, and final orders
+// only a remark. Open orders may change all of them.
+var PackingEditableFields = map[models.ProcessStatus][]string{
+	models.StatusApproved:  {"finalWeight", "remark", "cancelReason"},
+	models.StatusCompleted: {"remark"},
+	models.StatusCancelled: {"remark"},
+}
 
-	if input.Gap != nil {
-		filter["gap"] = *input.Gap
+// AssertPackingFields refuses the changes input makes to fields of packing
+// that its status has locked.
+func AssertPackingFields(packing *models.TransactionPacking, input *models.TransactionPacking) error {
+	editable, locked := PackingEditableFields[packing.ProcessStatus]
+	if !locked {
+		return nil
+	}
+	fields := []struct {
+		name    string
+		changed bool
+	}{
+		{"forecastWeight", input.ForecastWeight != packing.ForecastWeight},
+		{"actualWeight", input.ActualWeight != packing.ActualWeight},
+		{"savedTime", input.SavedTime != packing.SavedTime},
+		{"approvedDate", input.ApprovedDate != packing.ApprovedDate},
+		{"approvedType", input.ApprovedType != packing.ApprovedType},
+		{"finalWeight", input.FinalWeight != packing.FinalWeight},
+		{"province", input.Province != packing.Province},
+		{"district", input.District != packing.District},
+		{"remark", input.Remark != packing.Remark},
+		{"cancelReason", input.CancelReason != packing.CancelReason},
+		{"gmp", input.Gmp != packing.Gmp},
+		{"gap", input.Gap != packing.Gap},
+	}
+	for _, field := range fields {
+		if field.changed && !slices.Contains(editable, field.name) {
+			return Conflict("the %s of packing %s can no longer change once it is %s", field.name, packing.Id, packing.ProcessStatus)
+		}
 	}
+	return nil
+}
 
-	if input.CertID != nil {
-		filter["certId"] = *input.CertID
+func farmerOwner(ctx contractapi.TransactionContextInterface, farmerId string) (string, error) {
+	if farmerId == "" {
+		return "", nil
+	}
+	farmerJSON, err := ctx.GetStub().GetState(farmerId)
+	if err != nil {
+		return "", fmt.Errorf("failed to read farmer %s: %w", farmerId, err)
+	}
+	if farmerJSON == nil {
+		return "", nil
+	}
+	var farmer models.TransactionFarmer
+	if err := json.Unmarshal(farmerJSON, &farmer); err != nil {
+		return "", err
 	}
+	return farmer.Owner, nil
+}
+
+// InitialPackingStatuses are the statuses a packing order may be created in.
+var InitialPackingStatuses = []models.ProcessStatus{models.StatusDraft, models.StatusPackerSaved}
+
+func ValidateInitialPackingStatus(status models.ProcessStatus) error {
+	for _, s := range InitialPackingStatuses {
+		if s == status {
+			return nil
+		}
+	}
+	return Validation("packing cannot be created with status %s", status)
+}
 
-	if input.Search != nil {
-		filter["search"] = *input.Search
+// AssertPackingTransition checks that packing may move to status to and that
+// the caller holds a role allowed to move it. Packers may only move orders
+// they own and farmers only orders placed against their own profile.
+func AssertPackingTransition(ctx contractapi.TransactionContextInterface, packing *models.TransactionPacking, to models.ProcessStatus) error {
+	from := packing.ProcessStatus
+	required, ok := PackingTransitions[from][to]
+	if !ok {
+		return Conflict("packing %s cannot move from %s to %s", packing.Id, from, to)
+	}
+	return assertPackingParty(ctx, packing, required, fmt.Sprintf("cannot move packing %s from %s to %s", packing.Id, from, to))
+}
+
+// AssertPackingEditor checks that the caller may edit packing: staff, the
+// packer owning it or the farmer it was placed against.
+func AssertPackingEditor(ctx contractapi.TransactionContextInterface, packing *models.TransactionPacking) error {
+	return assertPackingParty(ctx, packing, staffAnd(models.RolePacker, models.RoleFarmer), fmt.Sprintf("cannot edit packing %s", packing.Id))
+}
+
+// assertPackingParty checks that the caller holds one of required and, as a
+// packer or farmer, is a party to packing.
+func assertPackingParty(ctx contractapi.TransactionContextInterface, packing *models.TransactionPacking, required []models.Role, reason string) error {
+	caller, err := GetCallerAccess(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range caller.Roles {
+		if !hasAnyRole([]models.Role{role}, required) {
+			continue
+		}
+		switch role {
+		case models.RolePacker:
+			if caller.Id == packing.Owner {
+				return nil
+			}
+		case models.RoleFarmer:
+			owner, err := farmerOwner(ctx, packing.FarmerID)
+			if err != nil {
+				return err
+			}
+			if owner != "" && caller.Id == owner {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+
+	fn, _ := ctx.GetStub().GetFunctionAndParameters()
+	return &AccessDeniedError{
+		Function: FunctionName(fn),
+		ClientId: caller.Id,
+		MspId:    caller.MspId,
+		Roles:    caller.Roles,
+		Required: required,
+		Reason:   reason,
+	}
+}
+
+func PackingSetFilter(input *models.FilterGetAllPacking) *Selector {
+	filter := NewSelector(models.Packing)
+
+	if input.Gap != nil {
+		filter.Eq("gap", *input.Gap)
+	}
+
+	if input.CertID != nil {
+		filter.Eq("certId", *input.CertID)
 	}
 
 	if (input.FarmerID != nil) {
-		filter["farmerId"] = *input.FarmerID
+		filter.Eq("farmerId", *input.FarmerID)
 	}
 
 	if (input.PackerId != nil) {
-		filter["packerId"] = *input.PackerId
+		filter.Eq("packerId", *input.PackerId)
 	}
 	
 	if input.Province != nil {
-		filter["province"] = *input.Province
+		filter.Eq("province", *input.Province)
 	}
 	if input.District != nil {
-		filter["district"] = *input.District
+		filter.Eq("district", *input.District)
 	}
 
-	if input.StartDate != nil && input.EndDate != nil {
-		fromDate, err1 := FormatDate(*input.StartDate, false, offset)
-		toDate, err2 := FormatDate(*input.EndDate, true, offset)
-
-		if err1 == nil && err2 == nil {
-			filter["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1, err2)
-		}
-	} else if (input.StartDate != nil) {
-		fromDate, err1 := FormatDate(*input.StartDate, false, offset)
-
-		if err1 == nil {
-			filter["createdAt"] = map[string]interface{}{
-				"$gte": fromDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err1)
-		}
-	} else if (input.EndDate != nil) {
-		toDate, err2 := FormatDate(*input.EndDate, true, offset)
-
-		if err2 == nil {
-			filter["createdAt"] = map[string]interface{}{
-				"$lte": toDate,
-			}
-		} else {
-			fmt.Printf("Error formatting issue dates: %v, %v\n", err2)
-		}
-	}
+	filter.DateRange("createdAt", input.StartDate, input.EndDate)
 
 	if input.ProcessStatus != nil {
 		// Split the comma-separated string
@@ -93,31 +204,24 @@ func PackingSetFilter(input *models.FilterGetAllPacking) map[string]interface{}
 			}
 		}
 		if len(trimmedArray) > 0 {
-			filter["processStatus"] = map[string]interface{}{
-				"$in": trimmedArray,
-			}
+			filter.In("processStatus", trimmedArray)
 		}
 	}
 
-	if input.ForecastWeightFrom != nil && input.ForecastWeightTo != nil {
-		filter["forecastWeight"] = map[string]interface{}{
-			"$gte": *input.ForecastWeightFrom,
-			"$lte": *input.ForecastWeightTo,
-		}
-	} else if (input.ForecastWeightFrom != nil) {
-		filter["forecastWeight"] = map[string]interface{}{
-			"$gte": *input.ForecastWeightFrom,
-		}
-	} else if (input.ForecastWeightTo != nil) {
-		filter["forecastWeight"] = map[string]interface{}{
-			"$gte": 0,
-			"$lte": *input.ForecastWeightTo,
-		}
+	if input.ForecastWeightFrom != nil {
+		filter.Gte("forecastWeight", *input.ForecastWeightFrom)
+	} else if input.ForecastWeightTo != nil {
+		filter.Gte("forecastWeight", 0)
+	}
+	if input.ForecastWeightTo != nil {
+		filter.Lte("forecastWeight", *input.ForecastWeightTo)
+	}
+
+	if input.Search != nil && *input.Search != "" {
+		filter.Search(*input.Search, "gmp", "packingHouseName", "gap", "displayCertId")
 	}
-	
-	filter["docType"] = "packing"
 
-	filterJSON, err := json.Marshal(filter)
+	filterJSON, err := json.Marshal(filter.Map())
 	if err != nil {
 		fmt.Printf("Error marshalling filter to JSON: %v\n", err)
 	} else {
@@ -126,55 +230,3 @@ func PackingSetFilter(input *models.FilterGetAllPacking) map[string]interface{}
 
 	return filter
 }
-
-func PackingFetchResultsWithPagination(ctx contractapi.TransactionContextInterface, input *models.FilterGetAllPacking, selector map[string]interface{}) ([]*models.PackingTransactionResponse, map[string]interface{}, error) {
-	if input.Skip > 0 {
-		selector["skip"] = input.Skip
-	}
-	if input.Limit > 0 {
-		selector["limit"] = input.Limit
-	}
-
-	selector["use_index"] = []string {
-		"_design/indexFarmerCreatedAt",
-		"indexFarmerCreatedAt",
-	}
-
-	selector["sort"] = []map[string]interface{} {
-		{
-			"createdAt": "desc",
-		},
-	}
-		
-	getStringPacking, err := json.Marshal(selector)
-
-	if err != nil {
-		return nil, selector, err
-	}
-
-	fmt.Printf("Packing %s query\n", getStringPacking)
-
-	queryPacking, _, err := ctx.GetStub().GetQueryResultWithPagination(string(getStringPacking), int32(input.Limit), "")
-	if err != nil {
-		return nil, selector, err
-	}
-	defer queryPacking.Close()
-
-	var dataPacking []*models.PackingTransactionResponse
-	for queryPacking.HasNext() {
-		queryResponse, err := queryPacking.Next()
-		if err != nil {
-			return nil, selector, err
-		}
-
-		var asset models.PackingTransactionResponse
-		err = json.Unmarshal(queryResponse.Value, &asset)
-		if err != nil {
-			return nil, selector, err
-		}
-
-		dataPacking = append(dataPacking, &asset)
-	}
-
-	return dataPacking, selector, nil
-}
\ No newline at end of file
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// MaxTotal caps how many matches a listing counts towards its total.
+const MaxTotal = 1000
+
+// DefaultLimit is the size of a page when the caller does not set one.
+const DefaultLimit = 100
+
+// Page is one page of a listing of T.
+type Page[T any] struct {
+	Items []*T
+	Total int
+	models.PageInfo
+}
+
+// Paginate fetches the page of query selected by page. The page starts at
+// the bookmark, or at page.Skip when there is none, and the returned
+// bookmark continues after it. A page holds page.Limit records, or
+// DefaultLimit when it is zero. Unless page.SkipTotal is set, the matches are
+// counted up to MaxTotal. Deleted records are only listed when
+// page.IncludeDeleted is set.
+func Paginate[T any](ctx contractapi.TransactionContextInterface, query *Query, page models.Pagination) (*Page[T], error) {
+	result := &Page[T]{Items: []*T{}}
+
+	if page.IncludeDeleted {
+		query.selector.IncludeDeleted()
+	}
+
+	if !page.SkipTotal {
+		total, capped, err := countCapped(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = total
+		result.TotalCapped = capped
+	}
+
+	skip := page.Skip
+	if page.Bookmark != "" {
+		skip = 0
+	}
+	limit := page.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	queryString, err := query.Page(skip, 0).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(queryString, int32(limit), page.Bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query result with pagination: %w", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next query result: %w", err)
+		}
+
+		var item T
+		if err := json.Unmarshal(queryResponse.Value, &item); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal query result: %w", err)
+		}
+		result.Items = append(result.Items, &item)
+	}
+
+	if metadata != nil {
+		result.Bookmark = metadata.Bookmark
+	}
+
+	return result, nil
+}
+
+// countCapped counts the matches of query, stopping after MaxTotal.
+func countCapped(ctx contractapi.TransactionContextInterface, query *Query) (int, bool, error) {
+	countQueryString, err := query.BuildCount()
+	if err != nil {
+		return 0, false, err
+	}
+
+	resultsIterator, _, err := ctx.GetStub().GetQueryResultWithPagination(countQueryString, MaxTotal+1, "")
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get count query result: %w", err)
+	}
+	defer resultsIterator.Close()
+
+	total := 0
+	for resultsIterator.HasNext() {
+		if _, err := resultsIterator.Next(); err != nil {
+			return 0, false, fmt.Errorf("failed to get next count query result: %w", err)
+		}
+		total++
+	}
+
+	if total > MaxTotal {
+		return MaxTotal, true, nil
+	}
+	return total, false, nil
+}
+
+// FetchAll returns every match of query.
+func FetchAll[T any](ctx contractapi.TransactionContextInterface, query *Query) ([]*T, error) {
+	queryString, err := query.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query result: %w", err)
+	}
+	defer resultsIterator.Close()
+
+	items := []*T{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next query result: %w", err)
+		}
+
+		var item T
+		if err := json.Unmarshal(queryResponse.Value, &item); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal query result: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
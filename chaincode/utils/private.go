@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Private data collections, as declared in collections_config.json.
+const (
+	FarmerCollection   = "farmerPrivateDetails"
+	ExporterCollection = "exporterPrivateDetails"
+)
+
+// Transient fields read by the functions that write private data.
+// TransientPrivateDetails holds a JSON object of private details by record id,
+// so that they stay out of the transaction arguments. TransientSalt is mixed
+// into the hash kept on the public record and is required whenever private
+// details are written: a public value such as the transaction id would make
+// guessable details easy to brute force from the hash.
+const (
+	TransientPrivateDetails = "privateDetails"
+	TransientSalt           = "salt"
+)
+
+// CollectionMembers lists the member MSPs of every private data collection.
+var CollectionMembers = map[string][]string{}
+
+var memberPattern = regexp.MustCompile(`'([^'.]+)\.member'`)
+
+// LoadCollections reads the collection members from a collections config. It
+// fails unless every collection the chaincode writes has members.
+func LoadCollections(config []byte) error {
+	var collections []struct {
+		Name   string `json:"name"`
+		Policy string `json:"policy"`
+	}
+	if err := json.Unmarshal(config, &collections); err != nil {
+		return fmt.Errorf("failed to unmarshal collections config: %w", err)
+	}
+
+	members := map[string][]string{}
+	for _, collection := range collections {
+		for _, match := range memberPattern.FindAllStringSubmatch(collection.Policy, -1) {
+			members[collection.Name] = append(members[collection.Name], match[1])
+		}
+	}
+	for _, name := range []string{FarmerCollection, ExporterCollection} {
+		if len(members[name]) == 0 {
+			return fmt.Errorf("the collections config gives no members for %s", name)
+		}
+	}
+	CollectionMembers = members
+	return nil
+}
+
+// IsCollectionMember tells whether the caller's organisation may read the
+// private data of collection.
+func IsCollectionMember(ctx contractapi.TransactionContextInterface, collection string) (bool, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	for _, member := range CollectionMembers[collection] {
+		if member == mspID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// TransientDetails returns the private details of id passed in the transient
+// field privateDetails, or fromArgs when there are none.
+func TransientDetails[T any](ctx contractapi.TransactionContextInterface, id string, fromArgs T) (T, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fromArgs, fmt.Errorf("failed to read transient data: %w", err)
+	}
+	detailsJSON, ok := transient[TransientPrivateDetails]
+	if !ok {
+		return fromArgs, nil
+	}
+
+	var byId map[string]T
+	if err := json.Unmarshal(detailsJSON, &byId); err != nil {
+		return fromArgs, Validation("failed to unmarshal transient %s: %v", TransientPrivateDetails, err)
+	}
+	if details, ok := byId[id]; ok {
+		return details, nil
+	}
+	return fromArgs, nil
+}
+
+// PutPrivateDetails stores details in collection under id and returns the
+// salted hash to keep on the public record. Empty details leave the
+// collection untouched, so that updates by callers who cannot read the
+// details do not erase them; the returned hash is then empty.
+func PutPrivateDetails[T comparable](ctx contractapi.TransactionContextInterface, collection string, id string, details T) (string, error) {
+	var empty T
+	if details == empty {
+		return "", nil
+	}
+
+	salt, err := privateSalt(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return "", err
+	}
+
+	// The salt is stored alongside the details so that members can recompute
+	// the hash.
+	var record map[string]interface{}
+	if err := json.Unmarshal(detailsJSON, &record); err != nil {
+		return "", err
+	}
+	record["salt"] = salt
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, id, recordJSON); err != nil {
+		return "", fmt.Errorf("failed to put private data of %s: %w", id, err)
+	}
+
+	hash := sha256.Sum256(append([]byte(salt), detailsJSON...))
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// GetPrivateDetails reads the private details of id from collection into
+// details. It reports false, without an error, when the caller's organisation
+// is not a member of collection or there are no details.
+func GetPrivateDetails(ctx contractapi.TransactionContextInterface, collection string, id string, details interface{}) (bool, error) {
+	member, err := IsCollectionMember(ctx, collection)
+	if err != nil || !member {
+		return false, err
+	}
+
+	recordJSON, err := ctx.GetStub().GetPrivateData(collection, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private data of %s: %w", id, err)
+	}
+	if recordJSON == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(recordJSON, details); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PrivateIds returns the ids of the records whose private details in
+// collection match selector. Only members of collection may filter on
+// private details.
+func PrivateIds(ctx contractapi.TransactionContextInterface, collection string, selector *Selector) ([]string, error) {
+	member, err := IsCollectionMember(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, Unauthorized("filtering on private details requires access to the %s collection", collection)
+	}
+
+	query, err := selector.Query().Build()
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetPrivateDataQueryResult(collection, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query private data: %w", err)
+	}
+	defer resultsIterator.Close()
+
+	ids := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, queryResponse.Key)
+	}
+	return ids, nil
+}
+
+func privateSalt(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to read transient data: %w", err)
+	}
+	base, ok := transient[TransientSalt]
+	if !ok || len(base) == 0 {
+		return "", Validation("the transient field %s is required to write the private details of %s", TransientSalt, id)
+	}
+
+	salt := sha256.Sum256(append(append(base, ':'), id...))
+	return hex.EncodeToString(salt[:]), nil
+}
+
+// SealFarmer moves the personal fields of farmer into the farmer collection
+// and keeps only their salted hash on the record.
+func SealFarmer(ctx contractapi.TransactionContextInterface, farmer *models.TransactionFarmer) error {
+	details, err := TransientDetails(ctx, farmer.Id, models.FarmerPrivateDetails{ProfileImg: farmer.ProfileImg})
+	if err != nil {
+		return err
+	}
+	hash, err := PutPrivateDetails(ctx, FarmerCollection, farmer.Id, details)
+	if err != nil {
+		return err
+	}
+	if hash != "" {
+		farmer.PrivateHash = hash
+	}
+	farmer.ProfileImg = ""
+	return nil
+}
+
+// UnsealFarmer fills in the personal fields of farmer for callers whose
+// organisation is a member of the farmer collection.
+func UnsealFarmer(ctx contractapi.TransactionContextInterface, farmer *models.TransactionFarmer) error {
+	var details models.FarmerPrivateDetails
+	found, err := GetPrivateDetails(ctx, FarmerCollection, farmer.Id, &details)
+	if err != nil || !found {
+		return err
+	}
+	farmer.ProfileImg = details.ProfileImg
+	return nil
+}
+
+// SealContact moves the contact details of plantType, stored under id, into
+// the exporter collection and keeps only their salted hash on the record.
+func SealContact(ctx contractapi.TransactionContextInterface, id string, plantType *models.PlantTypeModel) error {
+	details, err := TransientDetails(ctx, id, models.ContactDetails{
+		Address:  plantType.Address,
+		District: plantType.District,
+		PostCode: plantType.PostCode,
+		Email:    plantType.Email,
+	})
+	if err != nil {
+		return err
+	}
+	hash, err := PutPrivateDetails(ctx, ExporterCollection, id, details)
+	if err != nil {
+		return err
+	}
+	if hash != "" {
+		plantType.PrivateHash = hash
+	}
+	ClearContact(plantType)
+	return nil
+}
+
+// UnsealContact fills in the contact details of plantType, stored under id,
+// for callers whose organisation is a member of the exporter collection.
+func UnsealContact(ctx contractapi.TransactionContextInterface, id string, plantType *models.PlantTypeModel) error {
+	var details models.ContactDetails
+	found, err := GetPrivateDetails(ctx, ExporterCollection, id, &details)
+	if err != nil || !found {
+		return err
+	}
+	plantType.Address = details.Address
+	plantType.District = details.District
+	plantType.PostCode = details.PostCode
+	plantType.Email = details.Email
+	return nil
+}
+
+// ClearContact removes the contact details from plantType.
+func ClearContact(plantType *models.PlantTypeModel) {
+	plantType.Address = ""
+	plantType.District = ""
+	plantType.PostCode = ""
+	plantType.Email = ""
+}
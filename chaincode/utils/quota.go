@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const GapYieldConfig = "gapYield"
+
+// quotaTolerance absorbs float32 rounding when comparing weights in kg.
+const quotaTolerance = 0.001
+
+func GetGapYieldConfig(ctx contractapi.TransactionContextInterface) (*models.GapYieldConfig, error) {
+	config := models.GapYieldConfig{DocType: models.Config}
+	if _, err := GetConfig(ctx, GapYieldConfig, &config); err != nil {
+		return nil, err
+	}
+	if config.YieldPerRai == nil {
+		config.YieldPerRai = map[string]float32{}
+	}
+	return &config, nil
+}
+
+func ValidateGapYieldConfig(config *models.GapYieldConfig) error {
+	if config.DefaultYieldPerRai < 0 {
+		return fmt.Errorf("defaultYieldPerRai must not be negative")
+	}
+	for plantType, yield := range config.YieldPerRai {
+		if plantType == "" {
+			return fmt.Errorf("yieldPerRai has an empty plant type")
+		}
+		if yield < 0 {
+			return fmt.Errorf("yieldPerRai for %s must not be negative", plantType)
+		}
+	}
+	return nil
+}
+
+// YieldPerRai returns the configured yield for plantType, falling back to the
+// default yield.
+func YieldPerRai(config *models.GapYieldConfig, plantType string) float32 {
+	if yield, ok := config.YieldPerRai[plantType]; ok {
+		return yield
+	}
+	return config.DefaultYieldPerRai
+}
+
+// PackingQuotaWeight returns the weight a packing takes from its GAP quota.
+// Orders not yet approved reserve their forecast until a packed weight is
+// known; cancelled orders take nothing.
+func PackingQuotaWeight(packing *models.TransactionPacking) float32 {
+	if packing.ProcessStatus == models.StatusCancelled {
+		return 0
+	}
+	if weight := PackedWeight(packing); weight > 0 || packing.ProcessStatus.IsSold() {
+		return weight
+	}
+	return packing.ForecastWeight
+}
+
+func findGapByCertID(ctx contractapi.TransactionContextInterface, certID string) (*models.TransactionGap, error) {
+	var gap models.TransactionGap
+	found, err := GapCertIdIndex.Get(ctx, certID, &gap)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &gap, nil
+}
+
+// GetGapQuota computes the quota of the GAP certificate certID, leaving out
+// the packing excludeId. It returns nil when no such certificate exists.
+//
+// The sold part comes from the total kept per certificate. Only the open
+// orders still reserving quota are queried, so the cost of the check does not
+// grow with the orders already sold from the certificate.
+func GetGapQuota(ctx contractapi.TransactionContextInterface, certID string, excludeId string) (*models.GapQuota, error) {
+	gap, err := findGapByCertID(ctx, certID)
+	if err != nil || gap == nil {
+		return nil, err
+	}
+
+	config, err := GetGapYieldConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &models.GapQuota{
+		CertID:      gap.CertID,
+		PlantType:   gap.PlantType,
+		AreaRai:     gap.AreaRai,
+		YieldPerRai: YieldPerRai(config, gap.PlantType),
+	}
+	quota.Limited = quota.YieldPerRai > 0
+	quota.Capacity = quota.AreaRai * quota.YieldPerRai
+
+	sold, err := gapSoldExcluding(ctx, certID, excludeId)
+	if err != nil {
+		return nil, err
+	}
+	quota.Sold = float32(sold)
+
+	query, err := NewSelector(models.Packing).
+		Eq("gap", certID).
+		Ne("_id", excludeId).
+		In("processStatus", models.OpenStatuses).
+		Query().Build()
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packings of gap %s: %w", certID, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var packing models.TransactionPacking
+		if err := json.Unmarshal(queryResponse.Value, &packing); err != nil {
+			return nil, err
+		}
+
+		quota.Reserved += PackingQuotaWeight(&packing)
+	}
+
+	if quota.Limited {
+		quota.Remaining = quota.Capacity - quota.Sold - quota.Reserved
+	}
+
+	return quota, nil
+}
+
+// AssertGapQuota rejects packing when its weight exceeds what remains of the
+// quota of its GAP certificate. Packings without a known certificate or
+// whose plant type has no yield configured are not limited.
+func AssertGapQuota(ctx contractapi.TransactionContextInterface, packing *models.TransactionPacking) error {
+	if packing.Gap == "" {
+		return nil
+	}
+
+	quota, err := GetGapQuota(ctx, packing.Gap, packing.Id)
+	if err != nil {
+		return err
+	}
+	if quota == nil || !quota.Limited {
+		return nil
+	}
+
+	weight := PackingQuotaWeight(packing)
+	if weight > quota.Remaining+quotaTolerance {
+		return Conflict("packing %s needs %.2f kg from gap %s but only %.2f kg of %.2f kg remain",
+			packing.Id, weight, packing.Gap, quota.Remaining, quota.Capacity)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Dependency describes the records of DocType that refer to a record through
+// Field. Detachable references may be cut on a cascading delete by clearing
+// Field on the referring record; all others block the delete.
+type Dependency struct {
+	DocType    models.DocType
+	Field      string
+	Selector   *Selector
+	Detachable bool
+}
+
+// DependsOn describes the records of docType whose field holds value.
+func DependsOn(docType models.DocType, field string, value string) Dependency {
+	return Dependency{
+		DocType:  docType,
+		Field:    field,
+		Selector: NewSelector(docType).Eq(field, value),
+	}
+}
+
+// Detach marks the references of d as cut on a cascading delete.
+func (d Dependency) Detach() Dependency {
+	d.Detachable = true
+	return d
+}
+
+// The supply chain graph: what refers to each kind of record.
+
+func FarmerDependencies(id string) []Dependency {
+	return []Dependency{
+		DependsOn(models.Gap, "farmerId", id),
+		DependsOn(models.Packing, "farmerId", id),
+	}
+}
+
+func GapDependencies(certId string) []Dependency {
+	return []Dependency{
+		DependsOn(models.Packing, "gap", certId),
+		DependsOn(models.Packaging, "gap", certId),
+	}
+}
+
+func GmpDependencies(registerNumber string) []Dependency {
+	return []Dependency{
+		DependsOn(models.Packing, "gmp", registerNumber),
+		DependsOn(models.Packaging, "gmp", registerNumber),
+	}
+}
+
+// PackerDependencies lists what refers to a packer. Its GMPs may be released
+// on a cascading delete, as ClearGmpPacker does.
+func PackerDependencies(id string) []Dependency {
+	return []Dependency{
+		DependsOn(models.Packing, "packerId", id),
+		DependsOn(models.FormE, "createdById", id),
+		DependsOn(models.Packaging, "createdById", id),
+		DependsOn(models.Gmp, "packerId", id).Detach(),
+	}
+}
+
+// ExporterDependencies lists what refers to an exporter. Its plant types may
+// be released on a cascading delete.
+func ExporterDependencies(id string) []Dependency {
+	return []Dependency{
+		DependsOn(models.FormE, "createdById", id),
+		DependsOn(models.Packaging, "createdById", id),
+		DependsOn(models.PlantType, "exporterId", id).Detach(),
+	}
+}
+
+// PlantTypeDependencies lists what refers to a plant type assigned to
+// exporterId: the Form Es of that exporter.
+func PlantTypeDependencies(exporterId string) []Dependency {
+	if exporterId == "" {
+		return nil
+	}
+	return []Dependency{
+		DependsOn(models.FormE, "createdById", exporterId),
+	}
+}
+
+func HscodeDependencies(hscode string) []Dependency {
+	return []Dependency{{
+		DocType: models.FormE,
+		Field:   "invoice.productAndPackaging.hscode",
+		Selector: NewSelector(models.FormE).
+			ElemMatch("invoice.productAndPackaging", NewSelector("").Eq("hscode", hscode)),
+	}}
+}
+
+// ReferenceError reports the references that keep a record from being
+// deleted.
+type ReferenceError struct {
+	Id         string
+	References []models.Reference
+}
+
+func (e *ReferenceError) Error() string {
+	return e.catalogue().Error()
+}
+
+func (e *ReferenceError) catalogue() *Error {
+	refs := make([]string, 0, len(e.References))
+	for _, ref := range e.References {
+		refs = append(refs, fmt.Sprintf("%s %s (%s)", ref.DocType, ref.Id, ref.Field))
+	}
+	return &Error{
+		Code:    models.ErrConflict,
+		Message: fmt.Sprintf("the asset %s is still referenced by %s", e.Id, strings.Join(refs, ", ")),
+		Details: e.References,
+	}
+}
+
+type referenceId struct {
+	Id string `json:"id"`
+}
+
+// FindReferences returns the live records that refer through deps, split
+// into the ones that block a delete and the ones a cascade may detach.
+func FindReferences(ctx contractapi.TransactionContextInterface, deps []Dependency) ([]models.Reference, []models.Reference, error) {
+	blocking, detachable := []models.Reference{}, []models.Reference{}
+	for _, dep := range deps {
+		records, err := FetchAll[referenceId](ctx, dep.Selector.Query())
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, record := range records {
+			ref := models.Reference{DocType: dep.DocType, Id: record.Id, Field: dep.Field}
+			if dep.Detachable {
+				detachable = append(detachable, ref)
+			} else {
+				blocking = append(blocking, ref)
+			}
+		}
+	}
+	return blocking, detachable, nil
+}
+
+// CanDelete reports whether nothing but detachable references point through
+// deps.
+func CanDelete(ctx contractapi.TransactionContextInterface, deps []Dependency) (bool, error) {
+	blocking, _, err := FindReferences(ctx, deps)
+	if err != nil {
+		return false, err
+	}
+	return len(blocking) == 0, nil
+}
+
+// ReferencedValues returns which of values live records of docType refer to
+// through the top-level field, looked up in one query for a whole page of
+// records instead of one query each.
+func ReferencedValues(ctx contractapi.TransactionContextInterface, docType models.DocType, field string, values []string) (map[string]bool, error) {
+	referenced := map[string]bool{}
+	if len(values) == 0 {
+		return referenced, nil
+	}
+	records, err := FetchAll[map[string]interface{}](ctx, NewSelector(docType).In(field, values).Query())
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if value, ok := (*record)[field].(string); ok {
+			referenced[value] = true
+		}
+	}
+	return referenced, nil
+}
+
+// ReferencedByAny merges ReferencedValues over docTypes, which all refer
+// through field.
+func ReferencedByAny(ctx contractapi.TransactionContextInterface, docTypes []models.DocType, field string, values []string) (map[string]bool, error) {
+	referenced := map[string]bool{}
+	for _, docType := range docTypes {
+		found, err := ReferencedValues(ctx, docType, field, values)
+		if err != nil {
+			return nil, err
+		}
+		for value := range found {
+			referenced[value] = true
+		}
+	}
+	return referenced, nil
+}
+
+// CertificateReferrers are the records referring to GAPs and GMPs, as
+// GapDependencies and GmpDependencies list them.
+var CertificateReferrers = []models.DocType{models.Packing, models.Packaging}
+
+// AssertDeletable refuses with a *ReferenceError while records refer to id
+// through deps. With cascade set, detachable references are cut instead of
+// refusing.
+func AssertDeletable(ctx contractapi.TransactionContextInterface, id string, deps []Dependency, cascade bool) error {
+	blocking, detachable, err := FindReferences(ctx, deps)
+	if err != nil {
+		return err
+	}
+	if !cascade {
+		blocking = append(blocking, detachable...)
+	}
+	if len(blocking) > 0 {
+		return &ReferenceError{Id: id, References: blocking}
+	}
+
+	for _, ref := range detachable {
+		if err := detach(ctx, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertKeyChange refuses with a *ReferenceError to change the value other
+// records refer to record id by from old to new while deps(old) finds any of
+// them, as they would be left pointing at nothing.
+func AssertKeyChange(ctx contractapi.TransactionContextInterface, id string, old string, new string, deps func(string) []Dependency) error {
+	if old == "" || old == new {
+		return nil
+	}
+	return AssertDeletable(ctx, id, deps(old), false)
+}
+
+// detach clears the field through which ref refers to a deleted record.
+func detach(ctx contractapi.TransactionContextInterface, ref models.Reference) error {
+	fields, _, err := readAsset(ctx, ref.Id, ref.DocType)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	fields[ref.Field] = json.RawMessage(`""`)
+	return putAsset(ctx, ref.Id, fields, map[string]string{"updatedAt": timestamp})
+}
@@ -0,0 +1,328 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Repository stores records of type T, each under its own id. Writes tag the
+// record with DocType and stamp the Owner, OrgName, CreatedAt and UpdatedAt
+// fields of T that exist, taking the values from the submitting transaction.
+// Every write is reported by the transaction's StateChangeEvent, and keeps
+// the Unique indexes of the record and the copies Embedded in other records
+// up to date.
+type Repository[T any] struct {
+	DocType  models.DocType
+	Id       func(asset *T) string
+	Unique   []UniqueField[T]
+	Embedded []Embedding[T]
+}
+
+// Exists reports whether anything, deleted records included, is stored
+// under id.
+func (r Repository[T]) Exists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	return AssetExists(ctx, id)
+}
+
+// Get reads the live record stored under id.
+func (r Repository[T]) Get(ctx contractapi.TransactionContextInterface, id string) (*T, error) {
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if assetJSON == nil {
+		return nil, NotFound("the asset %s does not exist", id)
+	}
+
+	var header AssetHeader
+	if err := json.Unmarshal(assetJSON, &header); err != nil {
+		return nil, err
+	}
+	if header.IsDeleted() {
+		return nil, DeletedError(id)
+	}
+
+	var asset T
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// Create stores a new record, stamped as created by the caller. It fails if
+// the id is taken, even by a deleted record.
+func (r Repository[T]) Create(ctx contractapi.TransactionContextInterface, asset *T) error {
+	id := r.Id(asset)
+	exists, err := r.Exists(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking if asset exists: %w", err)
+	}
+	if exists {
+		return AlreadyExists("the asset %s already exists", id)
+	}
+
+	clientID, err := GetIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get submitting client's identity: %w", err)
+	}
+	orgName, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get submitting client's MSP ID: %w", err)
+	}
+	timestamp, err := GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.stamp(asset, map[string]string{
+		"Owner":     clientID,
+		"OrgName":   orgName,
+		"CreatedAt": timestamp,
+		"UpdatedAt": timestamp,
+	})
+	return r.Put(ctx, asset)
+}
+
+// Update stores a changed record with a new UpdatedAt. Ownership is left
+// alone, so callers check it first.
+func (r Repository[T]) Update(ctx contractapi.TransactionContextInterface, asset *T) error {
+	return r.UpdateAll(ctx, []*T{asset})
+}
+
+// UpdateAll stores several changed records with a new UpdatedAt. A
+// transaction writing more than one record uses it rather than Update, so
+// the copies embedding them are rewritten once from all of the records.
+func (r Repository[T]) UpdateAll(ctx contractapi.TransactionContextInterface, assets []*T) error {
+	timestamp, err := GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, asset := range assets {
+		r.stamp(asset, map[string]string{"UpdatedAt": timestamp})
+	}
+	return r.PutAll(ctx, assets)
+}
+
+// Put stores asset as it is, tagged with DocType. It fails if another record
+// holds one of its unique keys.
+func (r Repository[T]) Put(ctx contractapi.TransactionContextInterface, asset *T) error {
+	return r.PutAll(ctx, []*T{asset})
+}
+
+// PutAll stores several records as they are, tagged with DocType.
+func (r Repository[T]) PutAll(ctx contractapi.TransactionContextInterface, assets []*T) error {
+	changes := make([]Change[T], 0, len(assets))
+	for _, asset := range assets {
+		r.stamp(asset, nil)
+		id := r.Id(asset)
+
+		var previous *T
+		if len(r.Unique) > 0 || len(r.Embedded) > 0 {
+			var err error
+			if previous, err = r.stored(ctx, id); err != nil {
+				return err
+			}
+		}
+		if err := claimAll(ctx, r.Unique, id, asset, previous); err != nil {
+			return err
+		}
+
+		assetJSON, err := json.Marshal(asset)
+		if err != nil {
+			return fmt.Errorf("failed to marshal asset JSON: %w", err)
+		}
+		if err := ctx.GetStub().PutState(id, assetJSON); err != nil {
+			return fmt.Errorf("failed to put state for asset %s: %w", id, err)
+		}
+		changes = append(changes, Change[T]{Previous: previous, Current: asset})
+	}
+	return r.changed(ctx, changes)
+}
+
+// Delete soft deletes the live record stored under id, see SoftDelete.
+func (r Repository[T]) Delete(ctx contractapi.TransactionContextInterface, id string, reason string) error {
+	asset, err := r.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := SoftDelete(ctx, id, r.DocType, reason); err != nil {
+		return err
+	}
+	return r.changed(ctx, []Change[T]{{Previous: asset}})
+}
+
+// Restore brings back the deleted record stored under id, see Restore.
+func (r Repository[T]) Restore(ctx contractapi.TransactionContextInterface, id string) error {
+	if err := Restore(ctx, id, r.DocType); err != nil {
+		return err
+	}
+	if len(r.Embedded) == 0 {
+		return nil
+	}
+	// The transaction does not read its own writes, so this is the record as
+	// it was deleted.
+	asset, err := r.stored(ctx, id)
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(asset).Elem()
+	if field := value.FieldByName("Tombstone"); field.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+	}
+	return r.changed(ctx, []Change[T]{{Current: asset}})
+}
+
+// changed passes changes to every embedding of the repository.
+func (r Repository[T]) changed(ctx contractapi.TransactionContextInterface, changes []Change[T]) error {
+	for _, embedding := range r.Embedded {
+		if err := embedding.Changed(ctx, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stored reads the record stored under id, deleted or not, or nil if there is
+// none.
+func (r Repository[T]) stored(ctx contractapi.TransactionContextInterface, id string) (*T, error) {
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if assetJSON == nil {
+		return nil, nil
+	}
+	var asset T
+	if err := json.Unmarshal(assetJSON, &asset); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// AssertDistinct fails if two of assets share a unique key.
+func (r Repository[T]) AssertDistinct(assets []T) error {
+	return AssertDistinct(r.Unique, assets)
+}
+
+// stamp sets the DocType field and each of fields that T has.
+func (r Repository[T]) stamp(asset *T, fields map[string]string) {
+	value := reflect.ValueOf(asset).Elem()
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	if field := value.FieldByName("DocType"); field.IsValid() && field.Kind() == reflect.String {
+		field.SetString(string(r.DocType))
+	}
+	for name, v := range fields {
+		if field := value.FieldByName(name); field.IsValid() && field.Kind() == reflect.String {
+			field.SetString(v)
+		}
+	}
+}
+
+// Selector starts a selector over the live records of the repository.
+func (r Repository[T]) Selector() *Selector {
+	return NewSelector(r.DocType)
+}
+
+// Find returns every record matching query.
+func (r Repository[T]) Find(ctx contractapi.TransactionContextInterface, query *Query) ([]*T, error) {
+	return FetchAll[T](ctx, query)
+}
+
+// reservedFilterKeys are the fields Filter refuses, as they decide which
+// records a query may see.
+var reservedFilterKeys = map[string]bool{
+	"docType":     true,
+	"deletedAt":   true,
+	"_id":         true,
+	"privateHash": true,
+}
+
+// Filter returns the live records whose top level field key holds value,
+// newest first. key must be one of the JSON fields of T.
+func (r Repository[T]) Filter(ctx contractapi.TransactionContextInterface, key, value string) ([]*T, error) {
+	if reservedFilterKeys[key] || !hasJSONField(reflect.TypeOf((*T)(nil)).Elem(), key) {
+		return nil, Validation("%s records cannot be filtered on %q", r.DocType, key)
+	}
+	return r.Find(ctx, r.Selector().Eq(key, value).Query().
+		Sort("createdAt", "desc").
+		UseIndex("_design/index-CreatedAt", "index-CreatedAt"))
+}
+
+// hasJSONField reports whether the struct type t, or a struct it embeds,
+// encodes a field as name.
+func hasJSONField(t reflect.Type, name string) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && hasJSONField(field.Type, name) {
+			return true
+		}
+		if field.IsExported() && jsonName(field) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// History returns every write to the record stored under id, oldest first.
+func (r Repository[T]) History(ctx contractapi.TransactionContextInterface, id string) ([]*HistoryEntry[T], error) {
+	return ReadHistory[T](ctx, id)
+}
+
+// HistoryEntry is one write to a record. Value is nil when the key was
+// removed; IsDelete is also set when the write tombstoned the record.
+type HistoryEntry[T any] struct {
+	TxId      string
+	Timestamp string
+	IsDelete  bool
+	Value     *T
+}
+
+// ReadHistory decodes every write to the key id as a V, oldest first. V is
+// usually the stored model or its response type.
+func ReadHistory[V any](ctx contractapi.TransactionContextInterface, id string) ([]*HistoryEntry[V], error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history for key %s: %w", id, err)
+	}
+	defer resultsIterator.Close()
+
+	var history []*HistoryEntry[V]
+	for resultsIterator.HasNext() {
+		record, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next history record for key %s: %w", id, err)
+		}
+
+		entry := &HistoryEntry[V]{
+			TxId:      record.TxId,
+			Timestamp: time.Unix(record.Timestamp.Seconds, int64(record.Timestamp.Nanos)).Format(TIMEFORMAT),
+			IsDelete:  record.IsDelete,
+		}
+		if !record.IsDelete {
+			var header AssetHeader
+			if err := json.Unmarshal(record.Value, &header); err != nil {
+				return nil, err
+			}
+			var value V
+			if err := json.Unmarshal(record.Value, &value); err != nil {
+				return nil, err
+			}
+			entry.Value = &value
+			entry.IsDelete = header.IsDeleted()
+		}
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Selector builds the selector of a CouchDB query. Every value is compared
+// through an explicit operator and marshalled as JSON, so caller input can
+// only ever be matched against and never changes the shape of the query.
+type Selector struct {
+	fields  map[string]map[string]interface{}
+	clauses []map[string]interface{}
+	live    bool
+	err     error
+}
+
+// NewSelector starts a selector matching documents of docType that have not
+// been deleted. An empty docType matches documents of any type, deleted or
+// not, as used for nested selectors.
+func NewSelector(docType models.DocType) *Selector {
+	s := &Selector{fields: map[string]map[string]interface{}{}}
+	if docType != "" {
+		s.Eq("docType", docType)
+		s.live = true
+	}
+	return s
+}
+
+// IncludeDeleted lets s match deleted documents as well.
+func (s *Selector) IncludeDeleted() *Selector {
+	s.live = false
+	return s
+}
+
+func (s *Selector) op(field string, op string, value interface{}) *Selector {
+	// The docType set by NewSelector decides what the results decode as
+	if field == "docType" && s.fields[field] != nil {
+		s.fail(Validation("the docType of a query cannot be changed"))
+		return s
+	}
+	if s.fields[field] == nil {
+		s.fields[field] = map[string]interface{}{}
+	}
+	s.fields[field][op] = value
+	return s
+}
+
+func (s *Selector) Eq(field string, value interface{}) *Selector {
+	return s.op(field, "$eq", value)
+}
+
+func (s *Selector) Ne(field string, value interface{}) *Selector {
+	return s.op(field, "$ne", value)
+}
+
+func (s *Selector) Gte(field string, value interface{}) *Selector {
+	return s.op(field, "$gte", value)
+}
+
+func (s *Selector) Lte(field string, value interface{}) *Selector {
+	return s.op(field, "$lte", value)
+}
+
+func (s *Selector) In(field string, values interface{}) *Selector {
+	return s.op(field, "$in", values)
+}
+
+// DateRange matches documents whose timestamp field falls within the days
+// from and to, given as DD-MM-YYYY in Thai time. Either bound may be nil. A
+// bound that cannot be parsed fails the query with a validation error when it
+// is built.
+func (s *Selector) DateRange(field string, from *string, to *string) *Selector {
+	if from != nil {
+		if fromDate, err := FormatDate(*from, false, offset); err == nil {
+			s.Gte(field, fromDate)
+		} else {
+			s.fail(Validation("the lower bound %q of %s must be a date such as 31-12-2024", *from, field))
+		}
+	}
+	if to != nil {
+		if toDate, err := FormatDate(*to, true, offset); err == nil {
+			s.Lte(field, toDate)
+		} else {
+			s.fail(Validation("the upper bound %q of %s must be a date such as 31-12-2024", *to, field))
+		}
+	}
+	return s
+}
+
+// fail keeps the first error met while building s.
+func (s *Selector) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Contains matches documents whose field contains term literally.
+func (s *Selector) Contains(field string, term string) *Selector {
+	return s.op(field, "$regex", regexp.QuoteMeta(term))
+}
+
+// ElemMatch matches documents with an element of the array field that
+// matches element.
+func (s *Selector) ElemMatch(field string, element *Selector) *Selector {
+	if element.err != nil {
+		s.fail(element.err)
+	}
+	return s.op(field, "$elemMatch", element.Map())
+}
+
+// Or matches documents that match at least one of alternatives, on top of
+// everything else in s.
+func (s *Selector) Or(alternatives ...*Selector) *Selector {
+	var or []map[string]interface{}
+	for _, alternative := range alternatives {
+		if alternative.err != nil {
+			s.fail(alternative.err)
+		}
+		or = append(or, alternative.Map())
+	}
+	s.clauses = append(s.clauses, map[string]interface{}{"$or": or})
+	return s
+}
+
+// Search matches documents where at least one of fields contains term
+// literally.
+func (s *Selector) Search(term string, fields ...string) *Selector {
+	var alternatives []*Selector
+	for _, field := range fields {
+		alternatives = append(alternatives, NewSelector("").Contains(field, term))
+	}
+	return s.Or(alternatives...)
+}
+
+// Map returns the selector as it is sent to CouchDB.
+func (s *Selector) Map() map[string]interface{} {
+	selector := map[string]interface{}{}
+	for field, ops := range s.fields {
+		selector[field] = ops
+	}
+	if s.live && s.fields["deletedAt"] == nil {
+		selector["deletedAt"] = map[string]interface{}{"$exists": false}
+	}
+	if len(s.clauses) > 0 {
+		selector["$and"] = s.clauses
+	}
+	return selector
+}
+
+// Query wraps s in a query with no sort, index or paging.
+func (s *Selector) Query() *Query {
+	return &Query{selector: s}
+}
+
+// Query is a CouchDB query built around a Selector.
+type Query struct {
+	selector *Selector
+	sort     []map[string]string
+	useIndex []string
+	skip     int
+	limit    int
+}
+
+func (q *Query) Sort(field string, direction string) *Query {
+	q.sort = append(q.sort, map[string]string{field: direction})
+	return q
+}
+
+func (q *Query) UseIndex(designDoc string, name string) *Query {
+	q.useIndex = []string{designDoc, name}
+	return q
+}
+
+// Page skips the first skip results and returns at most limit. Zero values
+// leave the query unbounded.
+func (q *Query) Page(skip int, limit int) *Query {
+	q.skip = skip
+	q.limit = limit
+	return q
+}
+
+func (q *Query) build(paged bool) (string, error) {
+	if q.selector.err != nil {
+		return "", q.selector.err
+	}
+	query := map[string]interface{}{
+		"selector": q.selector.Map(),
+	}
+	if len(q.useIndex) > 0 {
+		query["use_index"] = q.useIndex
+	}
+	if paged {
+		if len(q.sort) > 0 {
+			query["sort"] = q.sort
+		}
+		if q.skip > 0 {
+			query["skip"] = q.skip
+		}
+		if q.limit > 0 {
+			query["limit"] = q.limit
+		}
+	}
+
+	queryString, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	return string(queryString), nil
+}
+
+// Build returns the query as it is sent to CouchDB.
+func (q *Query) Build() (string, error) {
+	return q.build(true)
+}
+
+// BuildCount returns the query without sort or paging, for counting every
+// match.
+func (q *Query) BuildCount() (string, error) {
+	return q.build(false)
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const SequenceConfig = "sequence"
+
+const sequenceObjectType = "sequence"
+
+// Names of the sequences ids are allocated from.
+const (
+	FarmerSequence         = "farmer"
+	PackerSequence         = "packer"
+	ExporterSequence       = "exporter"
+	FormESequence          = "formE"
+	FormEReferenceSequence = "formEReference"
+)
+
+// DefaultSequenceFormats are used for sequences the sequence config does not
+// set.
+var DefaultSequenceFormats = map[string]string{
+	FarmerSequence:         "F{seq:6}",
+	PackerSequence:         "PK{seq:6}",
+	ExporterSequence:       "E{seq:6}",
+	FormESequence:          "FE{yyyy}{seq:6}",
+	FormEReferenceSequence: "{office}-{yyyy}-{seq:5}",
+}
+
+var sequencePlaceholder = regexp.MustCompile(`\{(\w+)(?::(\d+))?\}`)
+
+// SequenceVars holds the values of the placeholders of a format other than
+// the counter and the date.
+type SequenceVars struct {
+	Office string
+}
+
+func GetSequenceConfig(ctx contractapi.TransactionContextInterface) (*models.SequenceConfig, error) {
+	config := models.SequenceConfig{DocType: models.Config}
+	if _, err := GetConfig(ctx, SequenceConfig, &config); err != nil {
+		return nil, err
+	}
+
+	formats := map[string]string{}
+	for name, format := range DefaultSequenceFormats {
+		formats[name] = format
+	}
+	for name, format := range config.Formats {
+		formats[name] = format
+	}
+	config.Formats = formats
+	return &config, nil
+}
+
+// ValidateSequenceConfig checks that every format is of a known sequence and
+// has exactly one counter.
+func ValidateSequenceConfig(config *models.SequenceConfig) error {
+	for name, format := range config.Formats {
+		if _, ok := DefaultSequenceFormats[name]; !ok {
+			return fmt.Errorf("unknown sequence %q", name)
+		}
+		counters := 0
+		for _, match := range sequencePlaceholder.FindAllStringSubmatch(format, -1) {
+			switch match[1] {
+			case "seq":
+				counters++
+			case "yyyy", "yy", "mm", "office":
+			default:
+				return fmt.Errorf("format of %s has an unknown placeholder {%s}", name, match[1])
+			}
+		}
+		if counters != 1 {
+			return fmt.Errorf("format of %s must have exactly one {seq}", name)
+		}
+	}
+	return nil
+}
+
+// FormatSequence fills in format for the value of the counter at the time
+// now. The placeholders are:
+//
+//	{seq}, {seq:N} the counter, zero padded to N digits
+//	{yyyy}, {yy}   the year in Thai time
+//	{mm}           the month in Thai time
+//	{office}       vars.Office
+//
+// Without a counter value, {seq} is left in place, which gives the scope the
+// counter belongs to.
+func FormatSequence(format string, now time.Time, vars SequenceVars, value *int64) (string, error) {
+	now = now.In(time.FixedZone("UTC+7", offset*3600))
+	var err error
+	formatted := sequencePlaceholder.ReplaceAllStringFunc(format, func(placeholder string) string {
+		match := sequencePlaceholder.FindStringSubmatch(placeholder)
+		switch match[1] {
+		case "seq":
+			if value == nil {
+				return "{seq}"
+			}
+			width, _ := strconv.Atoi(match[2])
+			return fmt.Sprintf("%0*d", width, *value)
+		case "yyyy":
+			return now.Format("2006")
+		case "yy":
+			return now.Format("06")
+		case "mm":
+			return now.Format("01")
+		case "office":
+			if strings.TrimSpace(vars.Office) == "" {
+				err = Validation("office is required to allocate an id from %s", format)
+			}
+			return vars.Office
+		}
+		return placeholder
+	})
+	return formatted, err
+}
+
+// NextId allocates the next id of the sequence name. Ids for which exists
+// reports true, for example because they were imported, are skipped.
+//
+// The counter is read and written by the transaction, so concurrent
+// allocations from the same scope fail with an MVCC read conflict at commit
+// instead of handing out the same id twice. A failed transaction writes
+// nothing, so the client can simply submit it again. As a transaction does
+// not read its own writes, it may allocate only one id from each scope.
+func NextId(ctx contractapi.TransactionContextInterface, name string, vars SequenceVars, exists func(id string) (bool, error)) (string, error) {
+	config, err := GetSequenceConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	format, ok := config.Formats[name]
+	if !ok {
+		return "", fmt.Errorf("unknown sequence %q", name)
+	}
+	now, err := GetTxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	scope, err := FormatSequence(format, now, vars, nil)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(sequenceObjectType, []string{name, scope})
+	if err != nil {
+		return "", err
+	}
+	counterJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read sequence %s: %w", scope, err)
+	}
+	counter := models.SequenceCounter{Name: name, Scope: scope, DocType: models.Sequence}
+	if counterJSON != nil {
+		if err := json.Unmarshal(counterJSON, &counter); err != nil {
+			return "", fmt.Errorf("failed to unmarshal sequence %s: %w", scope, err)
+		}
+	}
+
+	var id string
+	for {
+		counter.Value++
+		if id, err = FormatSequence(format, now, vars, &counter.Value); err != nil {
+			return "", err
+		}
+		taken, err := exists(id)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			break
+		}
+	}
+
+	if counter.UpdatedAt, err = GenerateTimestamp(ctx); err != nil {
+		return "", err
+	}
+	counterJSON, err = json.Marshal(counter)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(key, counterJSON); err != nil {
+		return "", fmt.Errorf("failed to put sequence %s: %w", scope, err)
+	}
+	return id, nil
+}
+
+// AssetIdTaken reports whether anything is stored under id, for NextId.
+func AssetIdTaken(ctx contractapi.TransactionContextInterface) func(id string) (bool, error) {
+	return func(id string) (bool, error) {
+		return AssetExists(ctx, id)
+	}
+}
+
+// LastId returns the id of the first record query finds, or "" if there is
+// none. Queries sort by _id descending to find the highest id.
+func LastId(ctx contractapi.TransactionContextInterface, query *Query) (string, error) {
+	records, err := FetchAll[referenceId](ctx, query.Page(0, 1))
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", nil
+	}
+	return records[0].Id, nil
+}
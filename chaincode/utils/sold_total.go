@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const soldTotalObjectType = "soldTotal"
+
+// PackedWeight returns the weight of produce packing takes from its GAP: the
+// final weight once it is known, the actual weight before that. The sold
+// totals, the GAP quota and the Form E checks all weigh orders this way.
+func PackedWeight(packing *models.TransactionPacking) float32 {
+	if packing.FinalWeight > 0 {
+		return packing.FinalWeight
+	}
+	return packing.ActualWeight
+}
+
+// SoldWeight returns the weight packing adds to the total sold from its GAP.
+func SoldWeight(packing *models.TransactionPacking) float32 {
+	if packing == nil || packing.IsDeleted() || !packing.ProcessStatus.IsSold() {
+		return 0
+	}
+	return PackedWeight(packing)
+}
+
+func soldTotalKey(ctx contractapi.TransactionContextInterface, gap string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(soldTotalObjectType, []string{gap})
+}
+
+// GetGapSoldTotal returns the total sold from the GAP certificate gap. A
+// certificate nothing was sold from has a zero total.
+func GetGapSoldTotal(ctx contractapi.TransactionContextInterface, gap string) (*models.GapSoldTotal, error) {
+	total := models.GapSoldTotal{Gap: gap, DocType: models.SoldTotal}
+	if gap == "" {
+		return &total, nil
+	}
+	key, err := soldTotalKey(ctx, gap)
+	if err != nil {
+		return nil, err
+	}
+	totalJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the total sold from %s: %w", gap, err)
+	}
+	if totalJSON != nil {
+		if err := json.Unmarshal(totalJSON, &total); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the total sold from %s: %w", gap, err)
+		}
+	}
+	return &total, nil
+}
+
+func putGapSoldTotal(ctx contractapi.TransactionContextInterface, total *models.GapSoldTotal) error {
+	key, err := soldTotalKey(ctx, total.Gap)
+	if err != nil {
+		return err
+	}
+	if total.UpdatedAt, err = GenerateTimestamp(ctx); err != nil {
+		return err
+	}
+	totalJSON, err := json.Marshal(total)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, totalJSON); err != nil {
+		return fmt.Errorf("failed to put the total sold from %s: %w", total.Gap, err)
+	}
+	return nil
+}
+
+// GapSoldTotals keeps the total sold from every GAP certificate up to date as
+// packing orders are written. Only the totals of the certificates the orders
+// were or are now packed from are read and written.
+type GapSoldTotals struct{}
+
+// Changed adds what every changed order now sells to the total of its GAP and
+// takes off what it sold before.
+func (GapSoldTotals) Changed(ctx contractapi.TransactionContextInterface, changes []Change[models.TransactionPacking]) error {
+	var gaps []string
+	deltas := map[string]float64{}
+	add := func(packing *models.TransactionPacking, sign float64) {
+		if packing == nil || packing.Gap == "" {
+			return
+		}
+		if _, ok := deltas[packing.Gap]; !ok {
+			gaps = append(gaps, packing.Gap)
+		}
+		deltas[packing.Gap] += sign * float64(SoldWeight(packing))
+	}
+	for _, change := range changes {
+		add(change.Previous, -1)
+		add(change.Current, 1)
+	}
+
+	for _, gap := range gaps {
+		if deltas[gap] == 0 {
+			continue
+		}
+		total, err := GetGapSoldTotal(ctx, gap)
+		if err != nil {
+			return err
+		}
+		total.TotalSold += deltas[gap]
+		if err := putGapSoldTotal(ctx, total); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildGapSoldTotals recomputes the total sold from every GAP certificate
+// from the packing orders, for orders written before the totals were kept or
+// totals that drifted. Totals that are already right are left alone, and
+// totals of certificates nothing is sold from any more are removed.
+func RebuildGapSoldTotals(ctx contractapi.TransactionContextInterface) ([]models.GapSoldTotal, error) {
+	packings, err := FetchAll[models.TransactionPacking](ctx, NewSelector(models.Packing).
+		In("processStatus", models.SoldStatuses).
+		Query())
+	if err != nil {
+		return nil, err
+	}
+	sold := map[string]float64{}
+	for _, packing := range packings {
+		if packing.Gap != "" {
+			sold[packing.Gap] += float64(SoldWeight(packing))
+		}
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(soldTotalObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the totals sold: %w", err)
+	}
+	defer resultsIterator.Close()
+
+	stored := map[string]float64{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var total models.GapSoldTotal
+		if err := json.Unmarshal(queryResponse.Value, &total); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the total sold %s: %w", queryResponse.Key, err)
+		}
+		stored[total.Gap] = total.TotalSold
+		if _, ok := sold[total.Gap]; ok {
+			continue
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return nil, fmt.Errorf("failed to delete the total sold from %s: %w", total.Gap, err)
+		}
+	}
+
+	gaps := make([]string, 0, len(sold))
+	for gap := range sold {
+		gaps = append(gaps, gap)
+	}
+	sort.Strings(gaps)
+
+	totals := []models.GapSoldTotal{}
+	for _, gap := range gaps {
+		total := models.GapSoldTotal{Gap: gap, TotalSold: sold[gap], DocType: models.SoldTotal}
+		if current, ok := stored[gap]; !ok || current != total.TotalSold {
+			if err := putGapSoldTotal(ctx, &total); err != nil {
+				return nil, err
+			}
+		}
+		totals = append(totals, total)
+	}
+	return totals, nil
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const SoftDeleteConfig = "softDelete"
+
+// DefaultRestoreWindowDays is how long a deleted record may be restored when
+// no soft delete config has been set.
+const DefaultRestoreWindowDays = 30
+
+func GetSoftDeleteConfig(ctx contractapi.TransactionContextInterface) (*models.SoftDeleteConfig, error) {
+	config := models.SoftDeleteConfig{DocType: models.Config}
+	if _, err := GetConfig(ctx, SoftDeleteConfig, &config); err != nil {
+		return nil, err
+	}
+	if config.RestoreWindowDays == 0 {
+		config.RestoreWindowDays = DefaultRestoreWindowDays
+	}
+	return &config, nil
+}
+
+func ValidateSoftDeleteConfig(config *models.SoftDeleteConfig) error {
+	if config.RestoreWindowDays < 0 {
+		return fmt.Errorf("restoreWindowDays must not be negative")
+	}
+	return nil
+}
+
+// DeletedError reports that the asset stored under id has been deleted.
+func DeletedError(id string) error {
+	return NotFound("the asset %s has been deleted", id)
+}
+
+// ReadAssetHeader returns the shared fields of the asset stored under id,
+// checking that it exists and is of docType. Deleted assets are returned
+// too.
+func ReadAssetHeader(ctx contractapi.TransactionContextInterface, id string, docType models.DocType) (*AssetHeader, error) {
+	_, header, err := readAsset(ctx, id, docType)
+	return header, err
+}
+
+func readAsset(ctx contractapi.TransactionContextInterface, id string, docType models.DocType) (map[string]json.RawMessage, *AssetHeader, error) {
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if assetJSON == nil {
+		return nil, nil, NotFound("the asset %s does not exist", id)
+	}
+
+	var header AssetHeader
+	if err := json.Unmarshal(assetJSON, &header); err != nil {
+		return nil, nil, err
+	}
+	if header.DocType != docType {
+		return nil, nil, NotFound("the asset %s is a %s, not a %s", id, header.DocType, docType)
+	}
+
+	// The asset is kept as raw fields so a tombstone can be set or cleared
+	// on any entity without touching the rest of the document.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(assetJSON, &fields); err != nil {
+		return nil, nil, err
+	}
+	return fields, &header, nil
+}
+
+func putAsset(ctx contractapi.TransactionContextInterface, id string, fields map[string]json.RawMessage, values map[string]string) error {
+	for field, value := range values {
+		if value == "" {
+			delete(fields, field)
+			continue
+		}
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fields[field] = valueJSON
+	}
+
+	assetJSON, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(id, assetJSON)
+}
+
+// SoftDelete writes a tombstone on the asset stored under id instead of
+// removing it, recording when, by whom and why it was deleted.
+func SoftDelete(ctx contractapi.TransactionContextInterface, id string, docType models.DocType, reason string) error {
+	if reason == "" {
+		return Validation("a reason is required to delete %s", id)
+	}
+
+	fields, header, err := readAsset(ctx, id, docType)
+	if err != nil {
+		return err
+	}
+	if header.IsDeleted() {
+		return DeletedError(id)
+	}
+
+	clientID, err := GetIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	timestamp, err := GenerateTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	return putAsset(ctx, id, fields, map[string]string{
+		"deletedAt":    timestamp,
+		"deletedBy":    clientID,
+		"deleteReason": reason,
+		"updatedAt":    timestamp,
+	})
+}
+
+// Restore clears the tombstone of the asset stored under id. Assets may only
+// be restored within the configured restore window after their deletion, and
+// only while no other record has taken their unique keys.
+func Restore(ctx contractapi.TransactionContextInterface, id string, docType models.DocType) error {
+	fields, header, err := readAsset(ctx, id, docType)
+	if err != nil {
+		return err
+	}
+	if !header.IsDeleted() {
+		return Conflict("the asset %s is not deleted", id)
+	}
+
+	config, err := GetSoftDeleteConfig(ctx)
+	if err != nil {
+		return err
+	}
+	deletedAt, err := time.Parse(time.RFC3339, header.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("the asset %s has an invalid deletedAt: %w", id, err)
+	}
+	now, err := GetTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if now.After(deletedAt.AddDate(0, 0, config.RestoreWindowDays)) {
+		return Conflict("the asset %s was deleted more than %d days ago and can no longer be restored", id, config.RestoreWindowDays)
+	}
+	if err := claimStoredKeys(ctx, id, docType, fields); err != nil {
+		return err
+	}
+
+	return putAsset(ctx, id, fields, map[string]string{
+		"deletedAt":    "",
+		"deletedBy":    "",
+		"deleteReason": "",
+		"updatedAt":    now.Format(time.RFC3339),
+	})
+}
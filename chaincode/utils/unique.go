@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+const uniqueObjectType = "unique"
+
+// UniqueIndex maps every value of a business key field of DocType to the
+// record holding it. Field is the JSON name of the field. Entries are written
+// in the same transaction as the record, so the index is always as current as
+// the records it points to.
+//
+// Only live records hold a key: the entry of a deleted record may be taken
+// over, and restoring the record claims its keys again.
+type UniqueIndex struct {
+	DocType models.DocType
+	Field   string
+}
+
+var (
+	GapCertIdIndex         = UniqueIndex{DocType: models.Gap, Field: "certId"}
+	GmpRegisterNumberIndex = UniqueIndex{DocType: models.Gmp, Field: "packingHouseRegisterNumber"}
+	FormEReferenceNoIndex  = UniqueIndex{DocType: models.FormE, Field: "referenceNo"}
+	HscodeIndex            = UniqueIndex{DocType: models.Hscode, Field: "hscode"}
+)
+
+// UniqueIndexes lists every unique index.
+var UniqueIndexes = []UniqueIndex{GapCertIdIndex, GmpRegisterNumberIndex, FormEReferenceNoIndex, HscodeIndex}
+
+// UniqueField reads the value a record of type T has for Index.
+type UniqueField[T any] struct {
+	Index UniqueIndex
+	Value func(asset *T) string
+}
+
+func (i UniqueIndex) String() string {
+	return fmt.Sprintf("%s %s", i.DocType, i.Field)
+}
+
+func (i UniqueIndex) key(ctx contractapi.TransactionContextInterface, value string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(uniqueObjectType, []string{string(i.DocType), i.Field, value})
+}
+
+// Lookup returns the id of the record value was last claimed by, which may
+// have been deleted since, or "" if it was never claimed.
+func (i UniqueIndex) Lookup(ctx contractapi.TransactionContextInterface, value string) (string, error) {
+	key, err := i.key(ctx, value)
+	if err != nil {
+		return "", err
+	}
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the %s index: %w", i, err)
+	}
+	if entryJSON == nil {
+		return "", nil
+	}
+
+	var entry models.UniqueEntry
+	if err := json.Unmarshal(entryJSON, &entry); err != nil {
+		return "", fmt.Errorf("failed to unmarshal the %s index entry of %s: %w", i, value, err)
+	}
+	return entry.Id, nil
+}
+
+// Holder returns the id of the live record holding value, or "".
+func (i UniqueIndex) Holder(ctx contractapi.TransactionContextInterface, value string) (string, error) {
+	id, err := i.Lookup(ctx, value)
+	if err != nil || id == "" {
+		return "", err
+	}
+	live, err := i.isLive(ctx, id)
+	if err != nil || !live {
+		return "", err
+	}
+	return id, nil
+}
+
+func (i UniqueIndex) isLive(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if assetJSON == nil {
+		return false, nil
+	}
+	var header AssetHeader
+	if err := json.Unmarshal(assetJSON, &header); err != nil {
+		return false, err
+	}
+	return header.DocType == i.DocType && !header.IsDeleted(), nil
+}
+
+// Get reads the live record holding value into asset. It reports false when
+// there is none. Records written before the index existed have no entry until
+// Rebuild runs, so when value has no live holder the records are searched by
+// Field instead.
+func (i UniqueIndex) Get(ctx contractapi.TransactionContextInterface, value string, asset interface{}) (bool, error) {
+	id, err := i.Holder(ctx, value)
+	if err != nil {
+		return false, err
+	}
+	if id == "" {
+		return i.search(ctx, value, asset)
+	}
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if err := json.Unmarshal(assetJSON, asset); err != nil {
+		return false, fmt.Errorf("failed to unmarshal asset %s: %w", id, err)
+	}
+	return true, nil
+}
+
+func (i UniqueIndex) search(ctx contractapi.TransactionContextInterface, value string, asset interface{}) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	records, err := FetchAll[json.RawMessage](ctx, NewSelector(i.DocType).Eq(i.Field, value).Query())
+	if err != nil || len(records) == 0 {
+		return false, err
+	}
+	if err := json.Unmarshal(*records[0], asset); err != nil {
+		return false, fmt.Errorf("failed to unmarshal the %s %s: %w", i.DocType, value, err)
+	}
+	return true, nil
+}
+
+// Claim records that the record id holds value. It fails if another live
+// record holds it. Empty values are not indexed.
+func (i UniqueIndex) Claim(ctx contractapi.TransactionContextInterface, value string, id string) error {
+	if value == "" {
+		return nil
+	}
+	holder, err := i.Lookup(ctx, value)
+	if err != nil {
+		return err
+	}
+	if holder == id {
+		return nil
+	}
+	if holder != "" {
+		live, err := i.isLive(ctx, holder)
+		if err != nil {
+			return err
+		}
+		if live {
+			return AlreadyExists("the %s %s is already used by %s", i, value, holder)
+		}
+	}
+
+	key, err := i.key(ctx, value)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := json.Marshal(models.UniqueEntry{
+		Of:      i.DocType,
+		Field:   i.Field,
+		Value:   value,
+		Id:      id,
+		DocType: models.Unique,
+	})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, entryJSON); err != nil {
+		return fmt.Errorf("failed to put the %s index entry of %s: %w", i, value, err)
+	}
+	return nil
+}
+
+// Release removes the entry of value if the record id holds it.
+func (i UniqueIndex) Release(ctx contractapi.TransactionContextInterface, value string, id string) error {
+	if value == "" {
+		return nil
+	}
+	holder, err := i.Lookup(ctx, value)
+	if err != nil || holder != id {
+		return err
+	}
+	key, err := i.key(ctx, value)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to delete the %s index entry of %s: %w", i, value, err)
+	}
+	return nil
+}
+
+// Rebuild claims the keys of every live record, for records written before
+// the index existed. Records whose key another record holds are reported
+// rather than indexed.
+func (i UniqueIndex) Rebuild(ctx contractapi.TransactionContextInterface) ([]models.UniqueConflict, error) {
+	records, err := FetchAll[map[string]json.RawMessage](ctx, NewSelector(i.DocType).Query())
+	if err != nil {
+		return nil, err
+	}
+
+	// The transaction does not read its own writes, so keys claimed by the
+	// rebuild itself are tracked here.
+	claimed := map[string]string{}
+	conflicts := []models.UniqueConflict{}
+	for _, record := range records {
+		var id, value string
+		_ = json.Unmarshal((*record)["id"], &id)
+		_ = json.Unmarshal((*record)[i.Field], &value)
+		if id == "" || value == "" {
+			continue
+		}
+
+		holder := claimed[value]
+		if holder == "" {
+			if err := i.Claim(ctx, value, id); err != nil {
+				if CatalogueError(err).Code != models.ErrAlreadyExists {
+					return nil, err
+				}
+				holder, err = i.Holder(ctx, value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		if holder != "" && holder != id {
+			conflicts = append(conflicts, models.UniqueConflict{
+				DocType: i.DocType, Field: i.Field, Value: value, Id: id, HeldBy: holder,
+			})
+			continue
+		}
+		claimed[value] = id
+	}
+	return conflicts, nil
+}
+
+// claimStoredKeys claims the keys of the stored fields of the record id of
+// docType, as it is restored.
+func claimStoredKeys(ctx contractapi.TransactionContextInterface, id string, docType models.DocType, fields map[string]json.RawMessage) error {
+	for _, index := range UniqueIndexes {
+		if index.DocType != docType {
+			continue
+		}
+		var value string
+		_ = json.Unmarshal(fields[index.Field], &value)
+		if err := index.Claim(ctx, value, id); err != nil {
+			return Conflict("the asset %s can not be restored: %s", id, ErrorMessage(err))
+		}
+	}
+	return nil
+}
+
+// claimAll claims the value every field has in asset for the record id,
+// releasing the values previous had.
+func claimAll[T any](ctx contractapi.TransactionContextInterface, fields []UniqueField[T], id string, asset *T, previous *T) error {
+	for _, field := range fields {
+		value := field.Value(asset)
+		if previous != nil {
+			if old := field.Value(previous); old != value {
+				if err := field.Index.Release(ctx, old, id); err != nil {
+					return err
+				}
+			}
+		}
+		if err := field.Index.Claim(ctx, value, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AssertDistinct fails if two of assets have the same value for one of
+// fields. A transaction does not read its own writes, so one writing several
+// records checks them against each other first.
+func AssertDistinct[T any](fields []UniqueField[T], assets []T) error {
+	for _, field := range fields {
+		seen := map[string]bool{}
+		for i := range assets {
+			value := field.Value(&assets[i])
+			if value == "" {
+				continue
+			}
+			if seen[value] {
+				return AlreadyExists("the %s %s appears more than once", field.Index, value)
+			}
+			seen[value] = true
+		}
+	}
+	return nil
+}
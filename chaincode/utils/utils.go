@@ -55,58 +55,43 @@ func FormatDate(dateStr string, isEndDate bool, offset int) (string, error) {
 
 func Unmarshal(args string, entityType interface{}) (interface{}, error) {
 	entityValue := reflect.New(reflect.TypeOf(entityType)).Interface()
-	err := json.Unmarshal([]byte(args), entityValue)
-	if err != nil {
-		return nil, fmt.Errorf("unmarshal json string: %v", err)
+	if err := DecodeInput(args, entityValue); err != nil {
+		return nil, err
 	}
 	return entityValue, nil
 }
 
-func BuildQueryString(filter map[string]interface{}) (string, error) {
-	selector := map[string]interface{}{
-		"selector": filter,
-	}
-	
-	queryString, err := json.Marshal(selector)
+func ParseDate(input string) (string, error) {
+	sanitizedInput := strings.ReplaceAll(input, "–", "-")
+
+	parsedTime, err := time.Parse(DATEFORMAT, sanitizedInput)
 	if err != nil {
 		return "", err
 	}
-	return string(queryString), nil
+
+	return parsedTime.Format(COUCHDB_DATEFORMAT), nil
 }
 
-func CountTotalResults(ctx contractapi.TransactionContextInterface, queryString string) (int, error) {
-	fmt.Printf("Query String for Counting Results: %s\n", queryString) 
 
-	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+// GetTxTime returns the timestamp the client set on the transaction proposal.
+// Unlike the wall clock it is the same on every endorsing peer.
+func GetTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
 	if err != nil {
-		return 0, err
-	}
-	defer resultsIterator.Close()
-
-	total := 0
-	for resultsIterator.HasNext() {
-		_, err := resultsIterator.Next()
-		if err != nil {
-			return 0, err
-		}
-		total++
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %w", err)
 	}
-	return total, nil
+	return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC(), nil
 }
 
-func ParseDate(input string) (string, error) {
-	sanitizedInput := strings.ReplaceAll(input, "–", "-")
-
-	parsedTime, err := time.Parse(DATEFORMAT, sanitizedInput)
+// GenerateTimestamp formats the transaction timestamp as RFC 3339. Every
+// createdAt and updatedAt the chaincode writes comes from here; timestamps
+// sent by clients are ignored.
+func GenerateTimestamp(ctx contractapi.TransactionContextInterface) (string, error) {
+	now, err := GetTxTime(ctx)
 	if err != nil {
 		return "", err
 	}
-
-	return parsedTime.Format(COUCHDB_DATEFORMAT), nil
-}
-
-func GenerateTimestamp() string {
-    return time.Now().UTC().Format(time.RFC3339)
+	return now.Format(time.RFC3339), nil
 }
 
 func SanitizeDate(dateStr string) (string, error) {
@@ -119,19 +104,6 @@ func SanitizeDate(dateStr string) (string, error) {
 }
 
 
-func GetTimeNow() time.Time {
-	now := time.Now()
-	
-	formattedTime := now.Format(TIMEFORMAT)
-	
-	parsedTime, err := time.ParseInLocation(TIMEFORMAT, formattedTime, time.Local)
-	if err != nil {
-		fmt.Println("Error parsing time:", err)
-	}
-	
-	return parsedTime
-}
-
 func ReturnError(data string) error {
 	return fmt.Errorf(data)
 }
@@ -140,11 +112,11 @@ func GetIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
 
 	b64ID, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
-		return "", fmt.Errorf("failed to read clientID: %v", err)
+		return "", fmt.Errorf("failed to read clientID: %w", err)
 	}
 	decodeID, err := base64.StdEncoding.DecodeString(b64ID)
 	if err != nil {
-		return "", fmt.Errorf("failed to base64 decode clientID: %v", err)
+		return "", fmt.Errorf("failed to base64 decode clientID: %w", err)
 	}
 	return string(decodeID), nil
 }
@@ -152,19 +124,12 @@ func AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool,
 
 	assetJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, fmt.Errorf("failed to read from world state: %w", err)
 	}
 
 	return assetJSON != nil, nil
 }
 
-func HandleError(err error) error {
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func StructToMap(v interface{}) (map[string]interface{}, error) {
     var m map[string]interface{}
     data, err := json.Marshal(v)
@@ -178,46 +143,40 @@ func StructToMap(v interface{}) (map[string]interface{}, error) {
     return m, nil
 }
 
-func GetTotalSoldSnapShot(ctx contractapi.TransactionContextInterface, gap string, actualWeight float32, processStatus int) (float32, error) {
-	//Query all packing to calculate current totalSold
-	queryPacking := fmt.Sprintf(`{
-		"selector": {
-			"docType": "packing",
-			"gap": "%s",
-			"processStatus": {
-				"$in": [2,3]
-			}
-		}
-	}`, gap)
-
-	totalSoldSnapShot := float32(0)
-
-	fmt.Printf("Query all packing to calculate current totalSold %v", queryPacking)
-
-	packingResultsIterator, err := ctx.GetStub().GetQueryResult(queryPacking)
+// GetTotalSoldSnapShot returns the weight sold from the GAP of packing once
+// packing replaces its stored copy.
+func GetTotalSoldSnapShot(ctx contractapi.TransactionContextInterface, packing *models.TransactionPacking) (float32, error) {
+	sold, err := gapSoldExcluding(ctx, packing.Gap, packing.Id)
 	if err != nil {
-		return 0, fmt.Errorf("failed to query packingResultsIterator sales: %v", err)
+		return 0, err
 	}
-	defer packingResultsIterator.Close()
-
-	for packingResultsIterator.HasNext() {
-		queryResponse, err := packingResultsIterator.Next()
-		if err != nil {
-			return 0, err
-		}
-
-		var packingDoc *models.TransactionPacking
-		err = json.Unmarshal(queryResponse.Value, &packingDoc)
-		if err != nil {
-			return 0, err
-		}
+	return float32(sold + float64(SoldWeight(packing))), nil
+}
 
-		totalSoldSnapShot += packingDoc.ActualWeight
+// gapSoldExcluding returns the total sold from gap without the stored copy of
+// the packing id.
+func gapSoldExcluding(ctx contractapi.TransactionContextInterface, gap string, id string) (float64, error) {
+	total, err := GetGapSoldTotal(ctx, gap)
+	if err != nil {
+		return 0, err
 	}
-
-	if (processStatus == 2) {
-		totalSoldSnapShot += actualWeight
+	if id == "" {
+		return total.TotalSold, nil
 	}
 
-	return totalSoldSnapShot, nil
+	packingJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %w", err)
+	}
+	if packingJSON == nil {
+		return total.TotalSold, nil
+	}
+	var stored models.TransactionPacking
+	if err := json.Unmarshal(packingJSON, &stored); err != nil {
+		return 0, err
+	}
+	if stored.Gap != gap {
+		return total.TotalSold, nil
+	}
+	return total.TotalSold - float64(SoldWeight(&stored)), nil
 }
\ No newline at end of file
@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode/models"
+)
+
+// Models declare their rules in a validate struct tag, as a comma separated
+// list of:
+//
+//	required     the value is not empty
+//	min=N, max=N the number is at least or at most N
+//	date         the string is a date such as 31-12-2024, 2024-12-31 or an
+//	             RFC 3339 time
+//	oneof=A B    the value is one of the space separated values
+//	lte=Field    the number is at most the value of Field in the same struct
+//	             when Field is set and positive
+//
+// Rules other than required pass for empty values. Nested structs, pointers
+// and slices are validated too, unless the field is tagged validate:"-".
+const validateTag = "validate"
+
+type rule struct {
+	name  string
+	param string
+}
+
+func parseRules(tag string) []rule {
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(part, "=")
+		rules = append(rules, rule{name: name, param: param})
+	}
+	return rules
+}
+
+// DecodeInput decodes the JSON argument args into v, rejecting fields v does
+// not have, and checks the rules of v.
+func DecodeInput(args string, v interface{}) error {
+	if err := DecodeStrict(args, v); err != nil {
+		return err
+	}
+	return ValidationError(ValidateStruct(v))
+}
+
+// DecodeStrict decodes the JSON argument args into v, rejecting fields v does
+// not have. Arguments that are only looked up, not stored, are decoded with
+// it.
+func DecodeStrict(args string, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(args)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field, _ = strconv.Unquote(field)
+			return ValidationError([]models.FieldError{{Field: field, Rule: "unknown", Message: "is not a known field"}})
+		}
+		return Validation("failed to unmarshal input: %v", err)
+	}
+	return nil
+}
+
+// ValidationError reports errs as one VALIDATION error with the field errors
+// as details, or nil when there are none.
+func ValidationError(errs []models.FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Field+" "+e.Message)
+	}
+	return &Error{
+		Code:    models.ErrValidation,
+		Message: "invalid input: " + strings.Join(messages, "; "),
+		Details: errs,
+	}
+}
+
+// ValidateStruct checks the rules of v and returns every field that breaks
+// one.
+func ValidateStruct(v interface{}) []models.FieldError {
+	var errs []models.FieldError
+	validateValue(reflect.ValueOf(v), "", &errs)
+	return errs
+}
+
+func validateValue(value reflect.Value, path string, errs *[]models.FieldError) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			validateValue(value.Elem(), path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Anonymous {
+				validateValue(value.Field(i), path, errs)
+				continue
+			}
+			name := jsonName(field)
+			if name == "" || field.Tag.Get(validateTag) == "-" {
+				continue
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			for _, r := range parseRules(field.Tag.Get(validateTag)) {
+				if message := r.check(value, value.Field(i)); message != "" {
+					*errs = append(*errs, models.FieldError{Field: name, Rule: r.name, Message: message})
+				}
+			}
+			validateValue(value.Field(i), name, errs)
+		}
+	}
+}
+
+// jsonName returns the name field is encoded with, or "" if it is not
+// encoded.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// check returns why value breaks r, or "" if it does not. parent is the
+// struct holding value.
+func (r rule) check(parent, value reflect.Value) string {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return r.empty()
+		}
+		value = value.Elem()
+	}
+	if value.IsZero() || (value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "") {
+		return r.empty()
+	}
+
+	switch r.name {
+	case "min", "max":
+		limit, _ := strconv.ParseFloat(r.param, 64)
+		n, ok := number(value)
+		if ok && r.name == "min" && n < limit {
+			return "must be at least " + r.param
+		}
+		if ok && r.name == "max" && n > limit {
+			return "must be at most " + r.param
+		}
+	case "date":
+		if value.Kind() == reflect.String {
+			if _, err := ParseCertificateDate(value.String(), false); err != nil {
+				return "must be a date such as 31-12-2024"
+			}
+		}
+	case "oneof":
+		s := fmt.Sprint(value.Interface())
+		for _, allowed := range strings.Fields(r.param) {
+			if s == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(r.param), ", ")
+	case "lte":
+		other := parent.FieldByName(r.param)
+		for other.IsValid() && other.Kind() == reflect.Ptr && !other.IsNil() {
+			other = other.Elem()
+		}
+		limit, ok := number(other)
+		n, _ := number(value)
+		if ok && limit > 0 && n > limit {
+			field, _ := parent.Type().FieldByName(r.param)
+			return "must not exceed " + jsonName(field)
+		}
+	}
+	return ""
+}
+
+func (r rule) empty() string {
+	if r.name == "required" {
+		return "is required"
+	}
+	return ""
+}
+
+func number(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+// datePattern matches the dates the date rule accepts.
+const datePattern = `^(\d{2}[-–]\d{2}[-–]\d{4}|\d{4}-\d{2}-\d{2}(T.+)?)$`
+
+// ValidationSchema describes the JSON accepted for v, a struct, with its
+// rules, in the form of the component schemas of the contract metadata.
+func ValidationSchema(v interface{}) metadata.ObjectMetadata {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	schema := structSchema(t)
+	return metadata.ObjectMetadata{
+		ID:                   t.Name(),
+		Properties:           schema.Properties,
+		Required:             schema.Required,
+		AdditionalProperties: false,
+	}
+}
+
+func structSchema(t reflect.Type) *spec.Schema {
+	schema := &spec.Schema{}
+	schema.Typed("object", "")
+	schema.Properties = map[string]spec.Schema{}
+	schema.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+	addProperties(schema, t)
+	return schema
+}
+
+func addProperties(schema *spec.Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			addProperties(schema, field.Type)
+			continue
+		}
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+
+		property := typeSchema(field.Type)
+		if field.Tag.Get(validateTag) == "-" {
+			property = &spec.Schema{}
+		}
+		for _, r := range parseRules(field.Tag.Get(validateTag)) {
+			switch r.name {
+			case "required":
+				schema.Required = append(schema.Required, name)
+				if property.Type.Contains("string") {
+					property.WithMinLength(1)
+				}
+			case "min":
+				limit, _ := strconv.ParseFloat(r.param, 64)
+				property.WithMinimum(limit, false)
+			case "max":
+				limit, _ := strconv.ParseFloat(r.param, 64)
+				property.WithMaximum(limit, false)
+			case "date":
+				property.WithPattern(datePattern)
+			case "oneof":
+				for _, allowed := range strings.Fields(r.param) {
+					property.Enum = append(property.Enum, allowed)
+				}
+			case "lte":
+				other, _ := t.FieldByName(r.param)
+				property.AddExtension("x-lte", jsonName(other))
+			}
+		}
+		schema.Properties[name] = *property
+	}
+}
+
+func typeSchema(t reflect.Type) *spec.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return spec.StringProperty()
+	case reflect.Bool:
+		return spec.BoolProperty()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return spec.Int64Property()
+	case reflect.Float32, reflect.Float64:
+		return spec.Float64Property()
+	case reflect.Slice, reflect.Array:
+		return spec.ArrayProperty(typeSchema(t.Elem()))
+	case reflect.Map:
+		return spec.MapProperty(typeSchema(t.Elem()))
+	case reflect.Struct:
+		return structSchema(t)
+	}
+	return &spec.Schema{}
+}
@@ -6,7 +6,6 @@ package main
 import (
 	"log"
 
-	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/zeabix-cloud-native/nectec-blockchain-smart-contract/chaincode"
 )
 
@@ -16,9 +15,12 @@ type SmartContractWrapper struct {
 
 func main() {
 	sc := &chaincode.SmartContract{}
+	sc.TransactionContextHandler = new(chaincode.TransactionContext)
+	sc.BeforeTransaction = chaincode.BeforeTransaction
+	sc.AfterTransaction = chaincode.AfterTransaction
     scWrapper := &SmartContractWrapper{SmartContract: sc}
 
-    chaincode, err := contractapi.NewChaincode(scWrapper)
+    chaincode, err := chaincode.NewChaincode(scWrapper)
 	
 	if err != nil {
 		log.Panicf("Error creating nectec chaincode: %v", err)